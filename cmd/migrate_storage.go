@@ -19,6 +19,7 @@ import (
 	packages_module "code.gitea.io/gitea/modules/packages"
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/storage"
+	packages_service "code.gitea.io/gitea/services/packages"
 
 	"github.com/urfave/cli"
 )
@@ -79,6 +80,10 @@ var CmdMigrateStorage = cli.Command{
 			Name:  "minio-use-ssl",
 			Usage: "Enable SSL for minio",
 		},
+		cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Only report the files which would be copied (only supported for 'packages')",
+		},
 	},
 }
 
@@ -118,12 +123,63 @@ func migrateRepoArchivers(ctx context.Context, dstStorage storage.ObjectStorage)
 	})
 }
 
-func migratePackages(ctx context.Context, dstStorage storage.ObjectStorage) error {
-	return db.IterateObjects(ctx, func(pb *packages_model.PackageBlob) error {
+// packageMigrationProgressInterval is the number of package blobs after which the progress is logged
+const packageMigrationProgressInterval = 1000
+
+// migratePackages copies the package blobs to the destination storage and verifies them after the copy.
+// Blobs which are already present at the destination are skipped, so an interrupted migration can be resumed.
+// Blobs which are missing in the source storage are reported but don't abort the migration.
+func migratePackages(ctx context.Context, dstStorage storage.ObjectStorage, dryRun bool) error {
+	var total, copied, present, missing int
+	err := db.IterateObjects(ctx, func(pb *packages_model.PackageBlob) error {
+		total++
+		if total%packageMigrationProgressInterval == 0 {
+			log.Info("Processed %d package blobs: %d copied, %d already present, %d missing", total, copied, present, missing)
+		}
+
+		err := packages_service.VerifyBlob(dstStorage, pb, false)
+		if err == nil {
+			present++
+			return nil
+		}
+		if err != packages_service.ErrBlobMissing && err != packages_service.ErrBlobCorrupt {
+			return err
+		}
+
+		if err := packages_service.VerifyBlob(storage.Packages, pb, false); err != nil {
+			if err != packages_service.ErrBlobMissing && err != packages_service.ErrBlobCorrupt {
+				return err
+			}
+			missing++
+			log.Warn("Package blob %d (%s) can't be copied: %v", pb.ID, pb.HashSHA256, err)
+			return nil
+		}
+
+		if dryRun {
+			copied++
+			return nil
+		}
+
 		p := packages_module.KeyToRelativePath(packages_module.BlobHash256Key(pb.HashSHA256))
-		_, err := storage.Copy(dstStorage, p, storage.Packages, p)
-		return err
+		if _, err := storage.Copy(dstStorage, p, storage.Packages, p); err != nil {
+			return err
+		}
+		if err := packages_service.VerifyBlob(dstStorage, pb, true); err != nil {
+			return fmt.Errorf("verifying copied package blob %d (%s) failed: %w", pb.ID, pb.HashSHA256, err)
+		}
+		copied++
+		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		log.Info("Dry run: %d of %d package blobs would be copied, %d already present, %d missing", copied, total, present, missing)
+	} else {
+		log.Info("%d of %d package blobs copied, %d already present, %d missing", copied, total, present, missing)
+	}
+	return nil
 }
 
 func runMigrateStorage(ctx *cli.Context) error {
@@ -184,16 +240,23 @@ func runMigrateStorage(ctx *cli.Context) error {
 		return err
 	}
 
+	dryRun := ctx.Bool("dry-run")
+	tp := strings.ToLower(ctx.String("type"))
+	if dryRun && tp != "packages" {
+		return fmt.Errorf("dry run is not supported for %s", ctx.String("type"))
+	}
+
 	migratedMethods := map[string]func(context.Context, storage.ObjectStorage) error{
 		"attachments":    migrateAttachments,
 		"lfs":            migrateLFS,
 		"avatars":        migrateAvatars,
 		"repo-avatars":   migrateRepoAvatars,
 		"repo-archivers": migrateRepoArchivers,
-		"packages":       migratePackages,
+		"packages": func(ctx context.Context, dstStorage storage.ObjectStorage) error {
+			return migratePackages(ctx, dstStorage, dryRun)
+		},
 	}
 
-	tp := strings.ToLower(ctx.String("type"))
 	if m, ok := migratedMethods[tp]; ok {
 		if err := m(stdCtx, dstStorage); err != nil {
 			return err
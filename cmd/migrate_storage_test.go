@@ -62,12 +62,35 @@ func TestMigratePackages(t *testing.T) {
 		})
 	assert.NoError(t, err)
 
-	err = migratePackages(ctx, dstStorage)
+	pb, err := packages.GetBlobByID(ctx, f.BlobID)
 	assert.NoError(t, err)
 
+	// a dry run doesn't copy anything
+	err = migratePackages(ctx, dstStorage, true)
+	assert.NoError(t, err)
+	assert.Equal(t, packages_service.ErrBlobMissing, packages_service.VerifyBlob(dstStorage, pb, false))
+
+	err = migratePackages(ctx, dstStorage, false)
+	assert.NoError(t, err)
+	assert.NoError(t, packages_service.VerifyBlob(dstStorage, pb, true))
+
 	entries, err := os.ReadDir(p)
 	assert.NoError(t, err)
 	assert.EqualValues(t, 2, len(entries))
 	assert.EqualValues(t, "01", entries[0].Name())
 	assert.EqualValues(t, "tmp", entries[1].Name())
+
+	// the migration can be resumed and blobs missing in the source are skipped
+	_, _, err = packages.GetOrInsertBlob(ctx, &packages.PackageBlob{
+		Size:       1,
+		HashMD5:    "migrate-storage-missing",
+		HashSHA1:   "migrate-storage-missing",
+		HashSHA256: "migrate-storage-missing",
+		HashSHA512: "migrate-storage-missing",
+	})
+	assert.NoError(t, err)
+
+	err = migratePackages(ctx, dstStorage, false)
+	assert.NoError(t, err)
+	assert.NoError(t, packages_service.VerifyBlob(dstStorage, pb, true))
 }
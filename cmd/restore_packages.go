@@ -0,0 +1,77 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/migrations"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/storage"
+	packages_service "code.gitea.io/gitea/services/packages"
+
+	"github.com/urfave/cli"
+)
+
+// CmdRestorePackages represents the available restore packages sub-command.
+var CmdRestorePackages = cli.Command{
+	Name:        "restore-packages",
+	Usage:       "Restore the package files from a dump",
+	Description: "Copies the content of package blobs which are missing in the package storage from the data/packages directory of a dump. The content is only restored if its hash matches the database.",
+	Action:      runRestorePackages,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "path, p",
+			Value: "./data/packages",
+			Usage: "Package directory of the dump to restore from",
+		},
+	},
+}
+
+func runRestorePackages(ctx *cli.Context) error {
+	stdCtx, cancel := installSignals()
+	defer cancel()
+
+	if err := initDB(stdCtx); err != nil {
+		return err
+	}
+
+	log.Info("AppPath: %s", setting.AppPath)
+	log.Info("AppWorkPath: %s", setting.AppWorkPath)
+	log.Info("Custom path: %s", setting.CustomPath)
+	log.Info("Log path: %s", setting.LogRootPath)
+	log.Info("Configuration file: %s", setting.CustomConf)
+
+	if err := db.InitEngineWithMigration(stdCtx, migrations.Migrate); err != nil {
+		log.Fatal("Failed to initialize ORM engine: %v", err)
+		return err
+	}
+
+	if err := storage.Init(); err != nil {
+		return err
+	}
+
+	srcStorage, err := storage.NewLocalStorage(
+		stdCtx,
+		storage.LocalStorageConfig{
+			Path: ctx.String("path"),
+		})
+	if err != nil {
+		return err
+	}
+
+	result, err := packages_service.RestoreBlobs(stdCtx, srcStorage)
+	if err != nil {
+		return err
+	}
+
+	log.Info("%d package blobs restored, %d already present.", result.Restored, result.Present)
+	if len(result.Failed) > 0 {
+		return fmt.Errorf("%d package blobs are missing or corrupt in %s", len(result.Failed), ctx.String("path"))
+	}
+	return nil
+}
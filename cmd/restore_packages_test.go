@@ -0,0 +1,90 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	packages_module "code.gitea.io/gitea/modules/packages"
+	"code.gitea.io/gitea/modules/storage"
+	packages_service "code.gitea.io/gitea/services/packages"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestorePackages(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	creator := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1})
+
+	addFile := func(filename, content string) *packages.PackageBlob {
+		buf, err := packages_module.CreateHashedBufferFromReader(strings.NewReader(content), 1024)
+		assert.NoError(t, err)
+		defer buf.Close()
+
+		_, pf, err := packages_service.CreatePackageOrAddFileToExisting(&packages_service.PackageCreationInfo{
+			PackageInfo: packages_service.PackageInfo{
+				Owner:       creator,
+				PackageType: packages.TypeGeneric,
+				Name:        "restore-packages",
+				Version:     "1.0.0",
+			},
+			Creator:           creator,
+			SemverCompatible:  true,
+			VersionProperties: map[string]string{},
+		}, &packages_service.PackageFileCreationInfo{
+			PackageFileInfo: packages_service.PackageFileInfo{
+				Filename: filename,
+			},
+			Data: buf,
+		})
+		assert.NoError(t, err)
+
+		pb, err := packages.GetBlobByID(db.DefaultContext, pf.BlobID)
+		assert.NoError(t, err)
+		return pb
+	}
+
+	relativePath := func(pb *packages.PackageBlob) string {
+		return packages_module.KeyToRelativePath(packages_module.BlobHash256Key(pb.HashSHA256))
+	}
+
+	intact := addFile("intact.txt", "restore-packages intact content")
+	corrupt := addFile("corrupt.txt", "restore-packages corrupt content")
+
+	src, err := storage.NewLocalStorage(context.Background(), storage.LocalStorageConfig{Path: t.TempDir()})
+	assert.NoError(t, err)
+
+	_, err = storage.Copy(src, relativePath(intact), storage.Packages, relativePath(intact))
+	assert.NoError(t, err)
+	// same size but different content
+	_, err = src.Save(relativePath(corrupt), strings.NewReader("restore-packages CORRUPT content"), -1)
+	assert.NoError(t, err)
+
+	for _, pb := range []*packages.PackageBlob{intact, corrupt} {
+		assert.NoError(t, storage.Packages.Delete(relativePath(pb)))
+		assert.Equal(t, packages_service.ErrBlobMissing, packages_service.VerifyBlob(storage.Packages, pb, true))
+	}
+	assert.Equal(t, packages_service.ErrBlobCorrupt, packages_service.VerifyBlob(src, corrupt, true))
+
+	result, err := packages_service.RestoreBlobs(context.Background(), src)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Restored)
+	failed := make([]int64, 0, len(result.Failed))
+	for _, pb := range result.Failed {
+		failed = append(failed, pb.ID)
+	}
+	assert.Contains(t, failed, corrupt.ID)
+	assert.NotContains(t, failed, intact.ID)
+
+	assert.NoError(t, packages_service.VerifyBlob(storage.Packages, intact, true))
+	assert.Equal(t, packages_service.ErrBlobMissing, packages_service.VerifyBlob(storage.Packages, corrupt, true))
+}
@@ -413,6 +413,56 @@ var migrations = []Migration{
 	NewMigration("Add badges to users", createUserBadgesTable),
 	// v225 -> v226
 	NewMigration("Alter gpg_key/public_key content TEXT fields to MEDIUMTEXT", alterPublicGPGKeyContentFieldsToMediumText),
+	// v226 -> v227
+	NewMigration("Add download count to package files", addDownloadCountToPackageFile),
+	// v227 -> v228
+	NewMigration("Add last download timestamp to package versions", addLastDownloadToPackageVersion),
+	// v228 -> v229
+	NewMigration("Add updated timestamp to packages", addUpdatedUnixToPackage),
+	// v229 -> v230
+	NewMigration("Add package quota table", addPackageQuotaTable),
+	// v230 -> v231
+	NewMigration("Add package type limit table", addPackageTypeLimitTable),
+	// v231 -> v232
+	NewMigration("Add package size summary table", addPackageSizeSummaryTable),
+	// v232 -> v233
+	NewMigration("Add package audit table", addPackageAuditTable),
+	// v233 -> v234
+	NewMigration("Add ref name index to package property table", addPackagePropertyRefNameIndex),
+	// v234 -> v235
+	NewMigration("Add numeric values to package properties", addPackagePropertyNumericValue),
+	// v235 -> v236
+	NewMigration("Add description to package table", addPackageDescription),
+	// v236 -> v237
+	NewMigration("Add yank columns to package version table", addPackageVersionYank),
+	// v237 -> v238
+	NewMigration("Add is_immutable column to package table", addPackageIsImmutable),
+	// v238 -> v239
+	NewMigration("Add creator_id column to package table", addPackageCreatorID),
+	// v239 -> v240
+	NewMigration("Add composite indexes to package_version table", addPackageVersionCompositeIndexes),
+	// v240 -> v241
+	NewMigration("Add download_count column to package table", addPackageDownloadCount),
+	// v241 -> v242
+	NewMigration("Add is_prerelease column to package_version table", addPackageVersionIsPrerelease),
+	// v242 -> v243
+	NewMigration("Add reference_count column to package_blob table", addPackageBlobReferenceCount),
+	// v243 -> v244
+	NewMigration("Replace package_blob_upload table with package_upload_session table", replacePackageBlobUploadWithUploadSession),
+	// v244 -> v245
+	NewMigration("Add visibility column to package table", addPackageVisibility),
+	// v245 -> v246
+	NewMigration("Add team_package_type table", addTeamPackageTypeTable),
+	// v246 -> v247
+	NewMigration("Add package_version_release table", addPackageVersionReleaseTable),
+	// v247 -> v248
+	NewMigration("Add package_alias table", addPackageAliasTable),
+	// v248 -> v249
+	NewMigration("Add created_unix column to package table", addPackageCreatedUnix),
+	// v249 -> v250
+	NewMigration("Add release_notes column to package_version table", addPackageVersionReleaseNotes),
+	// v250 -> v251
+	NewMigration("Add groupId property to Maven packages", addPackageMavenGroupID),
 }
 
 // GetCurrentDBVersion returns the current db version
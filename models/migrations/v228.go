@@ -0,0 +1,25 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func addUpdatedUnixToPackage(x *xorm.Engine) error {
+	type Package struct {
+		UpdatedUnix timeutil.TimeStamp `xorm:"INDEX NOT NULL DEFAULT 0"`
+	}
+
+	if err := x.Sync2(new(Package)); err != nil {
+		return err
+	}
+
+	// use the newest version of a package as last activity
+	_, err := x.Exec("UPDATE `package` SET `updated_unix` = (SELECT COALESCE(MAX(`created_unix`), 0) FROM `package_version` WHERE `package_version`.`package_id` = `package`.`id`)")
+	return err
+}
@@ -0,0 +1,32 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"xorm.io/xorm"
+)
+
+func addPackageQuotaTable(x *xorm.Engine) error {
+	type PackageQuota struct {
+		ID        int64 `xorm:"pk autoincr"`
+		OwnerID   int64 `xorm:"UNIQUE NOT NULL"`
+		SizeLimit int64 `xorm:"NOT NULL DEFAULT -1"`
+		UsedSize  int64 `xorm:"NOT NULL DEFAULT 0"`
+	}
+
+	if err := x.Sync2(new(PackageQuota)); err != nil {
+		return err
+	}
+
+	// blobs referenced by multiple files of an owner are counted once
+	_, err := x.Exec("INSERT INTO `package_quota` (`owner_id`, `size_limit`, `used_size`) " +
+		"SELECT `owner_id`, -1, SUM(`size`) FROM (" +
+		"SELECT DISTINCT `package`.`owner_id`, `package_blob`.`id`, `package_blob`.`size` FROM `package_blob` " +
+		"INNER JOIN `package_file` ON `package_file`.`blob_id` = `package_blob`.`id` " +
+		"INNER JOIN `package_version` ON `package_version`.`id` = `package_file`.`version_id` " +
+		"INNER JOIN `package` ON `package`.`id` = `package_version`.`package_id`" +
+		") `blobs` GROUP BY `owner_id`")
+	return err
+}
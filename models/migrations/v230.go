@@ -0,0 +1,21 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"xorm.io/xorm"
+)
+
+func addPackageTypeLimitTable(x *xorm.Engine) error {
+	type PackageTypeLimit struct {
+		ID             int64  `xorm:"pk autoincr"`
+		Type           string `xorm:"UNIQUE NOT NULL"`
+		MaxVersions    int64  `xorm:"NOT NULL DEFAULT -1"`
+		MaxVersionSize int64  `xorm:"NOT NULL DEFAULT -1"`
+		MaxOwnerSize   int64  `xorm:"NOT NULL DEFAULT -1"`
+	}
+
+	return x.Sync2(new(PackageTypeLimit))
+}
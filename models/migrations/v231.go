@@ -0,0 +1,21 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"xorm.io/xorm"
+)
+
+func addPackageSizeSummaryTable(x *xorm.Engine) error {
+	type PackageSizeSummary struct {
+		ID           int64  `xorm:"pk autoincr"`
+		Type         string `xorm:"UNIQUE NOT NULL"`
+		LogicalSize  int64  `xorm:"NOT NULL DEFAULT 0"`
+		PhysicalSize int64  `xorm:"NOT NULL DEFAULT 0"`
+		UpdatedUnix  int64  `xorm:"NOT NULL DEFAULT 0"`
+	}
+
+	return x.Sync2(new(PackageSizeSummary))
+}
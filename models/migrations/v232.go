@@ -0,0 +1,29 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func addPackageAuditTable(x *xorm.Engine) error {
+	type PackageAudit struct {
+		ID          int64              `xorm:"pk autoincr"`
+		OwnerID     int64              `xorm:"INDEX NOT NULL"`
+		ActorID     int64              `xorm:"INDEX NOT NULL DEFAULT 0"`
+		Action      string             `xorm:"INDEX NOT NULL"`
+		PackageID   int64              `xorm:"INDEX NOT NULL"`
+		PackageType string             `xorm:"NOT NULL"`
+		PackageName string             `xorm:"NOT NULL"`
+		VersionID   int64              `xorm:"NOT NULL DEFAULT 0"`
+		Version     string             `xorm:"NOT NULL DEFAULT ''"`
+		Details     string             `xorm:"TEXT"`
+		CreatedUnix timeutil.TimeStamp `xorm:"created INDEX NOT NULL"`
+	}
+
+	return x.Sync2(new(PackageAudit))
+}
@@ -0,0 +1,35 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"xorm.io/xorm"
+	"xorm.io/xorm/schemas"
+)
+
+type addPackagePropertyRefNameIndexPackageProperty struct {
+	ID      int64  `xorm:"pk autoincr"`
+	RefType int64  `xorm:"INDEX NOT NULL"`
+	RefID   int64  `xorm:"INDEX NOT NULL"`
+	Name    string `xorm:"INDEX NOT NULL"`
+	Value   string `xorm:"TEXT NOT NULL"`
+}
+
+// TableName sets the name of this table
+func (*addPackagePropertyRefNameIndexPackageProperty) TableName() string {
+	return "package_property"
+}
+
+// TableIndices implements xorm's TableIndices interface
+func (*addPackagePropertyRefNameIndexPackageProperty) TableIndices() []*schemas.Index {
+	refNameIndex := schemas.NewIndex("r_r_n", schemas.IndexType)
+	refNameIndex.AddColumn("ref_type", "ref_id", "name")
+
+	return []*schemas.Index{refNameIndex}
+}
+
+func addPackagePropertyRefNameIndex(x *xorm.Engine) error {
+	return x.Sync2(new(addPackagePropertyRefNameIndexPackageProperty))
+}
@@ -0,0 +1,94 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"fmt"
+	"strconv"
+
+	"xorm.io/builder"
+	"xorm.io/xorm"
+	"xorm.io/xorm/schemas"
+)
+
+type addPackagePropertyNumericValuePackageProperty struct {
+	ID           int64  `xorm:"pk autoincr"`
+	RefType      int64  `xorm:"INDEX NOT NULL"`
+	RefID        int64  `xorm:"INDEX NOT NULL"`
+	Name         string `xorm:"INDEX NOT NULL"`
+	Value        string `xorm:"TEXT NOT NULL"`
+	ValueType    int64  `xorm:"NOT NULL DEFAULT 0"`
+	NumericValue int64  `xorm:"NOT NULL DEFAULT 0"`
+}
+
+// TableName sets the name of this table
+func (*addPackagePropertyNumericValuePackageProperty) TableName() string {
+	return "package_property"
+}
+
+// TableIndices implements xorm's TableIndices interface
+func (*addPackagePropertyNumericValuePackageProperty) TableIndices() []*schemas.Index {
+	refNameIndex := schemas.NewIndex("r_r_n", schemas.IndexType)
+	refNameIndex.AddColumn("ref_type", "ref_id", "name")
+
+	numericIndex := schemas.NewIndex("r_n_n", schemas.IndexType)
+	numericIndex.AddColumn("ref_type", "name", "numeric_value")
+
+	return []*schemas.Index{refNameIndex, numericIndex}
+}
+
+func addPackagePropertyNumericValue(x *xorm.Engine) error {
+	if err := x.Sync2(new(addPackagePropertyNumericValuePackageProperty)); err != nil {
+		return err
+	}
+
+	// the well-known numeric properties at the time of this migration
+	numericProperties := []string{"container.image.size", "container.image.created"}
+
+	const batchSize = 100
+
+	var start int
+	pps := make([]*addPackagePropertyNumericValuePackageProperty, 0, batchSize)
+	for {
+		if err := x.Select("id, value").
+			Where(builder.In("name", numericProperties)).
+			OrderBy("id").
+			Limit(batchSize, start).
+			Find(&pps); err != nil {
+			return err
+		}
+
+		err := func() error {
+			sess := x.NewSession()
+			defer sess.Close()
+			if err := sess.Begin(); err != nil {
+				return fmt.Errorf("unable to allow start session. Error: %w", err)
+			}
+			for _, pp := range pps {
+				n, err := strconv.ParseInt(pp.Value, 10, 64)
+				if err != nil {
+					// values which can't be parsed stay available as string only
+					continue
+				}
+				pp.ValueType = 1
+				pp.NumericValue = n
+				if _, err := sess.ID(pp.ID).Cols("value_type", "numeric_value").Update(pp); err != nil {
+					return fmt.Errorf("unable to update numeric value of package property[%d]: %w", pp.ID, err)
+				}
+			}
+			return sess.Commit()
+		}()
+		if err != nil {
+			return err
+		}
+
+		if len(pps) < batchSize {
+			break
+		}
+		start += batchSize
+		pps = pps[:0]
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"fmt"
+	"strings"
+
+	"code.gitea.io/gitea/modules/json"
+
+	"xorm.io/xorm"
+)
+
+type addPackageDescriptionPackage struct {
+	ID          int64  `xorm:"pk autoincr"`
+	Description string `xorm:"TEXT"`
+}
+
+// TableName sets the name of this table
+func (*addPackageDescriptionPackage) TableName() string {
+	return "package"
+}
+
+func addPackageDescription(x *xorm.Engine) error {
+	if err := x.Sync2(new(addPackageDescriptionPackage)); err != nil {
+		return err
+	}
+
+	const (
+		batchSize            = 100
+		maxDescriptionLength = 1024
+	)
+
+	type packageVersion struct {
+		PackageID    int64
+		MetadataJSON string
+	}
+
+	var start int
+	ps := make([]*addPackageDescriptionPackage, 0, batchSize)
+	for {
+		if err := x.Select("id").
+			OrderBy("id").
+			Limit(batchSize, start).
+			Find(&ps); err != nil {
+			return err
+		}
+
+		err := func() error {
+			sess := x.NewSession()
+			defer sess.Close()
+			if err := sess.Begin(); err != nil {
+				return fmt.Errorf("unable to allow start session. Error: %w", err)
+			}
+			for _, p := range ps {
+				pv := &packageVersion{}
+				has, err := sess.Table("package_version").
+					Select("package_id, metadata_json").
+					Where("package_id = ? AND is_internal = ?", p.ID, false).
+					OrderBy("created_unix DESC, id DESC").
+					Get(pv)
+				if err != nil {
+					return err
+				}
+				if !has {
+					continue
+				}
+
+				var metadata struct {
+					Description string `json:"description"`
+					Summary     string `json:"summary"`
+				}
+				if err := json.Unmarshal([]byte(pv.MetadataJSON), &metadata); err != nil {
+					// versions with unexpected metadata get their description on the next publish
+					continue
+				}
+
+				description := metadata.Summary
+				if description == "" {
+					description = metadata.Description
+				}
+				description = strings.TrimSpace(description)
+				if runes := []rune(description); len(runes) > maxDescriptionLength {
+					description = string(runes[:maxDescriptionLength])
+				}
+				if description == "" {
+					continue
+				}
+
+				p.Description = description
+				if _, err := sess.ID(p.ID).Cols("description").Update(p); err != nil {
+					return fmt.Errorf("unable to update description of package[%d]: %w", p.ID, err)
+				}
+			}
+			return sess.Commit()
+		}()
+		if err != nil {
+			return err
+		}
+
+		if len(ps) < batchSize {
+			break
+		}
+		start += batchSize
+		ps = ps[:0]
+	}
+	return nil
+}
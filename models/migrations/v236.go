@@ -0,0 +1,18 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"xorm.io/xorm"
+)
+
+func addPackageVersionYank(x *xorm.Engine) error {
+	type PackageVersion struct {
+		IsYanked   bool   `xorm:"INDEX NOT NULL DEFAULT false"`
+		YankReason string `xorm:"TEXT"`
+	}
+
+	return x.Sync2(new(PackageVersion))
+}
@@ -0,0 +1,83 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"fmt"
+
+	"xorm.io/xorm"
+)
+
+type addPackageCreatorIDPackage struct {
+	ID        int64 `xorm:"pk autoincr"`
+	CreatorID int64 `xorm:"INDEX NOT NULL DEFAULT 0"`
+}
+
+// TableName sets the name of this table
+func (*addPackageCreatorIDPackage) TableName() string {
+	return "package"
+}
+
+func addPackageCreatorID(x *xorm.Engine) error {
+	if err := x.Sync2(new(addPackageCreatorIDPackage)); err != nil {
+		return err
+	}
+
+	const batchSize = 100
+
+	type packageVersion struct {
+		CreatorID int64
+	}
+
+	var start int
+	ps := make([]*addPackageCreatorIDPackage, 0, batchSize)
+	for {
+		if err := x.Select("id").
+			OrderBy("id").
+			Limit(batchSize, start).
+			Find(&ps); err != nil {
+			return err
+		}
+
+		err := func() error {
+			sess := x.NewSession()
+			defer sess.Close()
+			if err := sess.Begin(); err != nil {
+				return fmt.Errorf("unable to allow start session. Error: %w", err)
+			}
+			for _, p := range ps {
+				// the creator of the earliest version introduced the package
+				pv := &packageVersion{}
+				has, err := sess.Table("package_version").
+					Select("creator_id").
+					Where("package_id = ?", p.ID).
+					OrderBy("created_unix ASC, id ASC").
+					Get(pv)
+				if err != nil {
+					return err
+				}
+				if !has || pv.CreatorID == 0 {
+					continue
+				}
+
+				p.CreatorID = pv.CreatorID
+				if _, err := sess.ID(p.ID).Cols("creator_id").Update(p); err != nil {
+					return fmt.Errorf("unable to update creator of package[%d]: %w", p.ID, err)
+				}
+			}
+			return sess.Commit()
+		}()
+		if err != nil {
+			return err
+		}
+
+		if len(ps) < batchSize {
+			break
+		}
+		start += batchSize
+		ps = ps[:0]
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"xorm.io/xorm"
+	"xorm.io/xorm/schemas"
+)
+
+type addPackageVersionCompositeIndexesPackageVersion struct {
+	ID               int64  `xorm:"pk autoincr"`
+	PackageID        int64  `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	LowerVersion     string `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	CreatedUnix      int64  `xorm:"created INDEX NOT NULL"`
+	IsInternal       bool   `xorm:"INDEX NOT NULL DEFAULT false"`
+	LastDownloadUnix int64  `xorm:"INDEX NOT NULL DEFAULT 0"`
+	IsYanked         bool   `xorm:"INDEX NOT NULL DEFAULT false"`
+}
+
+// TableName sets the name of this table
+func (*addPackageVersionCompositeIndexesPackageVersion) TableName() string {
+	return "package_version"
+}
+
+// TableIndices implements xorm's TableIndices interface
+func (*addPackageVersionCompositeIndexesPackageVersion) TableIndices() []*schemas.Index {
+	internalIndex := schemas.NewIndex("p_i_c", schemas.IndexType)
+	internalIndex.AddColumn("package_id", "is_internal", "created_unix")
+
+	yankedIndex := schemas.NewIndex("p_y_c", schemas.IndexType)
+	yankedIndex.AddColumn("package_id", "is_yanked", "created_unix")
+
+	return []*schemas.Index{internalIndex, yankedIndex}
+}
+
+func addPackageVersionCompositeIndexes(x *xorm.Engine) error {
+	return x.Sync2(new(addPackageVersionCompositeIndexesPackageVersion))
+}
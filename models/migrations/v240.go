@@ -0,0 +1,27 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"xorm.io/xorm"
+)
+
+type addPackageDownloadCountPackage struct {
+	ID            int64 `xorm:"pk autoincr"`
+	DownloadCount int64 `xorm:"INDEX NOT NULL DEFAULT 0"`
+}
+
+func (*addPackageDownloadCountPackage) TableName() string {
+	return "package"
+}
+
+func addPackageDownloadCount(x *xorm.Engine) error {
+	if err := x.Sync2(new(addPackageDownloadCountPackage)); err != nil {
+		return err
+	}
+
+	_, err := x.Exec("UPDATE `package` SET `download_count` = (SELECT COALESCE(SUM(`download_count`), 0) FROM `package_version` WHERE `package_version`.`package_id` = `package`.`id` AND `package_version`.`is_internal` = ?)", false)
+	return err
+}
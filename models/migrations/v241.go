@@ -0,0 +1,91 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"xorm.io/xorm"
+)
+
+var addPackageVersionIsPrereleasePEP440Pattern = regexp.MustCompile(`(?i)[0-9][._-]?(a|alpha|b|beta|c|rc|pre|preview|dev)[._-]?[0-9]*([._+-]|$)`)
+
+// addPackageVersionIsPrereleaseCheck is a frozen copy of the prerelease detection of modules/packages at the time of this migration
+func addPackageVersionIsPrereleaseCheck(packageType, v string) bool {
+	switch packageType {
+	case "composer", "helm", "npm", "nuget", "pub", "vagrant":
+		sv, err := version.NewSemver(v)
+		return err == nil && sv.Prerelease() != ""
+	case "rubygems":
+		return strings.IndexFunc(v, func(r rune) bool {
+			return ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z')
+		}) != -1
+	case "pypi":
+		if i := strings.IndexByte(v, '+'); i != -1 {
+			v = v[:i]
+		}
+		return addPackageVersionIsPrereleasePEP440Pattern.MatchString(v)
+	}
+	return false
+}
+
+type addPackageVersionIsPrereleasePackageVersion struct {
+	ID           int64 `xorm:"pk autoincr"`
+	IsPrerelease bool  `xorm:"INDEX NOT NULL DEFAULT false"`
+}
+
+func (*addPackageVersionIsPrereleasePackageVersion) TableName() string {
+	return "package_version"
+}
+
+func addPackageVersionIsPrerelease(x *xorm.Engine) error {
+	if err := x.Sync2(new(addPackageVersionIsPrereleasePackageVersion)); err != nil {
+		return err
+	}
+
+	const batchSize = 100
+
+	type packageVersion struct {
+		ID      int64
+		Type    string
+		Version string
+	}
+
+	var lastID int64
+	pvs := make([]*packageVersion, 0, batchSize)
+	for {
+		if err := x.Table("package_version").
+			Select("package_version.id, package.type, package_version.version").
+			Join("INNER", "package", "package.id = package_version.package_id").
+			Where("package_version.id > ?", lastID).
+			OrderBy("package_version.id").
+			Limit(batchSize).
+			Find(&pvs); err != nil {
+			return err
+		}
+		if len(pvs) == 0 {
+			break
+		}
+
+		for _, pv := range pvs {
+			if !addPackageVersionIsPrereleaseCheck(pv.Type, pv.Version) {
+				continue
+			}
+			if _, err := x.ID(pv.ID).Cols("is_prerelease").Update(&addPackageVersionIsPrereleasePackageVersion{IsPrerelease: true}); err != nil {
+				return fmt.Errorf("unable to update package_version[%d]: %w", pv.ID, err)
+			}
+		}
+
+		if len(pvs) < batchSize {
+			break
+		}
+		lastID = pvs[len(pvs)-1].ID
+		pvs = pvs[:0]
+	}
+	return nil
+}
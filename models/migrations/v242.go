@@ -0,0 +1,27 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"xorm.io/xorm"
+)
+
+type addPackageBlobReferenceCountPackageBlob struct {
+	ID             int64 `xorm:"pk autoincr"`
+	ReferenceCount int64 `xorm:"INDEX NOT NULL DEFAULT 0"`
+}
+
+func (*addPackageBlobReferenceCountPackageBlob) TableName() string {
+	return "package_blob"
+}
+
+func addPackageBlobReferenceCount(x *xorm.Engine) error {
+	if err := x.Sync2(new(addPackageBlobReferenceCountPackageBlob)); err != nil {
+		return err
+	}
+
+	_, err := x.Exec("UPDATE `package_blob` SET `reference_count` = (SELECT COUNT(*) FROM `package_file` WHERE `package_file`.`blob_id` = `package_blob`.`id`)")
+	return err
+}
@@ -0,0 +1,41 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func replacePackageBlobUploadWithUploadSession(x *xorm.Engine) error {
+	type PackageUploadSession struct {
+		ID             string             `xorm:"pk"`
+		OwnerID        int64              `xorm:"INDEX NOT NULL"`
+		CreatorID      int64              `xorm:"NOT NULL DEFAULT 0"`
+		Type           string             `xorm:"INDEX NOT NULL"`
+		PackageName    string             `xorm:"NOT NULL"`
+		PackageVersion string             `xorm:"NOT NULL DEFAULT ''"`
+		Filename       string             `xorm:"NOT NULL DEFAULT ''"`
+		BytesReceived  int64              `xorm:"NOT NULL DEFAULT 0"`
+		HashStateBytes []byte             `xorm:"BLOB"`
+		ExpiresUnix    timeutil.TimeStamp `xorm:"INDEX NOT NULL"`
+		CreatedUnix    timeutil.TimeStamp `xorm:"created NOT NULL"`
+		UpdatedUnix    timeutil.TimeStamp `xorm:"updated NOT NULL"`
+	}
+
+	if err := x.Sync2(new(PackageUploadSession)); err != nil {
+		return err
+	}
+
+	// The pending blob uploads are taken over as already expired sessions without owner,
+	// so the cleanup task removes their data. The clients have to restart these uploads.
+	if _, err := x.Exec("INSERT INTO `package_upload_session` (`id`, `owner_id`, `creator_id`, `type`, `package_name`, `package_version`, `filename`, `bytes_received`, `hash_state_bytes`, `expires_unix`, `created_unix`, `updated_unix`) " +
+		"SELECT `id`, 0, 0, 'container', '', '', '', `bytes_received`, `hash_state_bytes`, 0, `created_unix`, `updated_unix` FROM `package_blob_upload`"); err != nil {
+		return err
+	}
+
+	return x.DropTables("package_blob_upload")
+}
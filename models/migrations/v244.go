@@ -0,0 +1,17 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"xorm.io/xorm"
+)
+
+func addPackageVisibility(x *xorm.Engine) error {
+	type Package struct {
+		Visibility int `xorm:"NOT NULL DEFAULT 0"`
+	}
+
+	return x.Sync2(new(Package))
+}
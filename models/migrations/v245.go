@@ -0,0 +1,21 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"xorm.io/xorm"
+)
+
+func addTeamPackageTypeTable(x *xorm.Engine) error {
+	type TeamPackageType struct {
+		ID          int64  `xorm:"pk autoincr"`
+		OrgID       int64  `xorm:"INDEX"`
+		TeamID      int64  `xorm:"UNIQUE(s)"`
+		PackageType string `xorm:"UNIQUE(s)"`
+		AccessMode  int
+	}
+
+	return x.Sync2(new(TeamPackageType))
+}
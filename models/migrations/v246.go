@@ -0,0 +1,19 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"xorm.io/xorm"
+)
+
+func addPackageVersionReleaseTable(x *xorm.Engine) error {
+	type PackageVersionRelease struct {
+		ID        int64 `xorm:"pk autoincr"`
+		VersionID int64 `xorm:"UNIQUE(s) INDEX NOT NULL"`
+		ReleaseID int64 `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	}
+
+	return x.Sync2(new(PackageVersionRelease))
+}
@@ -0,0 +1,21 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"xorm.io/xorm"
+)
+
+func addPackageAliasTable(x *xorm.Engine) error {
+	type PackageAlias struct {
+		ID        int64  `xorm:"pk autoincr"`
+		OwnerID   int64  `xorm:"UNIQUE(s) INDEX NOT NULL"`
+		Type      string `xorm:"UNIQUE(s) INDEX NOT NULL"`
+		LowerName string `xorm:"UNIQUE(s) INDEX NOT NULL"`
+		PackageID int64  `xorm:"INDEX NOT NULL"`
+	}
+
+	return x.Sync2(new(PackageAlias))
+}
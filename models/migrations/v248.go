@@ -0,0 +1,19 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func addPackageCreatedUnix(x *xorm.Engine) error {
+	type Package struct {
+		CreatedUnix timeutil.TimeStamp `xorm:"created INDEX NOT NULL DEFAULT 0"`
+	}
+
+	return x.Sync2(new(Package))
+}
@@ -0,0 +1,92 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"xorm.io/xorm"
+)
+
+type addPackageMavenGroupIDProperty struct {
+	ID      int64  `xorm:"pk autoincr"`
+	RefType int64  `xorm:"INDEX NOT NULL"`
+	RefID   int64  `xorm:"INDEX NOT NULL"`
+	Name    string `xorm:"INDEX NOT NULL"`
+	Value   string `xorm:"TEXT NOT NULL"`
+}
+
+func (*addPackageMavenGroupIDProperty) TableName() string {
+	return "package_property"
+}
+
+// addPackageMavenGroupID stores the groupId of the existing Maven packages as package property.
+// The groupId is taken from the metadata of the newest version which has one.
+func addPackageMavenGroupID(x *xorm.Engine) error {
+	const (
+		refTypePackage    = 2
+		propertyGroupID   = "maven.group_id"
+		packageTypeMaven  = "maven"
+		versionsBatchSize = 100
+	)
+
+	type packageVersion struct {
+		ID           int64
+		PackageID    int64
+		MetadataJSON string `xorm:"metadata_json"`
+	}
+
+	var lastID int64
+	groupIDs := make(map[int64]string)
+	pvs := make([]*packageVersion, 0, versionsBatchSize)
+	for {
+		pvs = pvs[:0]
+		if err := x.Table("package_version").
+			Select("package_version.id, package_version.package_id, package_version.metadata_json").
+			Join("INNER", "package", "package.id = package_version.package_id").
+			Where("package.type = ? AND package_version.id > ?", packageTypeMaven, lastID).
+			OrderBy("package_version.id").
+			Limit(versionsBatchSize).
+			Find(&pvs); err != nil {
+			return err
+		}
+		if len(pvs) == 0 {
+			break
+		}
+
+		for _, pv := range pvs {
+			var metadata struct {
+				GroupID string `json:"group_id"`
+			}
+			// versions are ordered by id, so the newest version with a groupId wins
+			if err := json.Unmarshal([]byte(pv.MetadataJSON), &metadata); err == nil && metadata.GroupID != "" {
+				groupIDs[pv.PackageID] = metadata.GroupID
+			}
+		}
+
+		lastID = pvs[len(pvs)-1].ID
+	}
+
+	for packageID, groupID := range groupIDs {
+		has, err := x.Where("ref_type = ? AND ref_id = ? AND name = ?", refTypePackage, packageID, propertyGroupID).
+			Exist(&addPackageMavenGroupIDProperty{})
+		if err != nil {
+			return err
+		}
+		if has {
+			continue
+		}
+		if _, err := x.Insert(&addPackageMavenGroupIDProperty{
+			RefType: refTypePackage,
+			RefID:   packageID,
+			Name:    propertyGroupID,
+			Value:   groupID,
+		}); err != nil {
+			return fmt.Errorf("unable to insert the groupId of package[%d]: %w", packageID, err)
+		}
+	}
+	return nil
+}
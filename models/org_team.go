@@ -446,6 +446,13 @@ func DeleteTeam(t *organization.Team) error {
 		return err
 	}
 
+	// Delete team-package-type.
+	if _, err := sess.
+		Where("team_id=?", t.ID).
+		Delete(new(organization.TeamPackageType)); err != nil {
+		return err
+	}
+
 	// Delete team.
 	if _, err := sess.ID(t.ID).Delete(new(organization.Team)); err != nil {
 		return err
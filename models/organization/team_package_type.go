@@ -0,0 +1,92 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package organization
+
+import (
+	"context"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/perm"
+	"code.gitea.io/gitea/models/unit"
+)
+
+// TeamPackageType overrides the access mode of the packages unit of a team for a package type
+type TeamPackageType struct {
+	ID          int64  `xorm:"pk autoincr"`
+	OrgID       int64  `xorm:"INDEX"`
+	TeamID      int64  `xorm:"UNIQUE(s)"`
+	PackageType string `xorm:"UNIQUE(s)"`
+	AccessMode  perm.AccessMode
+}
+
+func init() {
+	db.RegisterModel(new(TeamPackageType))
+}
+
+// GetTeamPackageTypes gets the package type access modes of a team
+func GetTeamPackageTypes(ctx context.Context, teamID int64) ([]*TeamPackageType, error) {
+	tpts := make([]*TeamPackageType, 0, 5)
+	return tpts, db.GetEngine(ctx).Where("team_id = ?", teamID).Find(&tpts)
+}
+
+// GetOrgTeamPackageTypes gets the access modes of all teams of an organization for a package type
+func GetOrgTeamPackageTypes(ctx context.Context, orgID int64, packageType string) ([]*TeamPackageType, error) {
+	tpts := make([]*TeamPackageType, 0, 5)
+	return tpts, db.GetEngine(ctx).Where("org_id = ? AND package_type = ?", orgID, packageType).Find(&tpts)
+}
+
+// SetTeamPackageType sets the access mode of a team for a package type
+func SetTeamPackageType(team *Team, packageType string, accessMode perm.AccessMode) error {
+	ctx, committer, err := db.TxContext()
+	if err != nil {
+		return err
+	}
+	defer committer.Close()
+
+	if err := RemoveTeamPackageType(ctx, team.ID, packageType); err != nil {
+		return err
+	}
+
+	if err := db.Insert(ctx, &TeamPackageType{
+		OrgID:       team.OrgID,
+		TeamID:      team.ID,
+		PackageType: packageType,
+		AccessMode:  accessMode,
+	}); err != nil {
+		return err
+	}
+
+	return committer.Commit()
+}
+
+// RemoveTeamPackageType removes the access mode of a team for a package type, so the access mode of the packages unit applies again
+func RemoveTeamPackageType(ctx context.Context, teamID int64, packageType string) error {
+	_, err := db.GetEngine(ctx).Where("team_id = ? AND package_type = ?", teamID, packageType).Delete(&TeamPackageType{})
+	return err
+}
+
+// PackageTypeAccessMode returns the access mode of the team for packages of the given type.
+// The access mode of the packages unit applies if the team has no access mode for the type.
+// Admin and owner teams always have full access.
+func (t *Team) PackageTypeAccessMode(ctx context.Context, packageType string) (perm.AccessMode, error) {
+	if t.AccessMode >= perm.AccessModeAdmin {
+		return t.AccessMode, nil
+	}
+
+	tpt := &TeamPackageType{}
+	has, err := db.GetEngine(ctx).Where("team_id = ? AND package_type = ?", t.ID, packageType).Get(tpt)
+	if err != nil {
+		return perm.AccessModeNone, err
+	}
+	if has {
+		return tpt.AccessMode, nil
+	}
+
+	accessMode := t.UnitAccessModeCtx(ctx, unit.TypePackages)
+	if accessMode < t.AccessMode {
+		accessMode = t.AccessMode
+	}
+	return accessMode, nil
+}
@@ -9,6 +9,7 @@ import (
 
 	"code.gitea.io/gitea/models/db"
 	"code.gitea.io/gitea/models/organization"
+	"code.gitea.io/gitea/models/perm"
 	"code.gitea.io/gitea/models/unittest"
 
 	"github.com/stretchr/testify/assert"
@@ -198,3 +199,44 @@ func TestUsersInTeamsCount(t *testing.T) {
 	test([]int64{1, 2, 3, 4, 5}, []int64{2, 5}, 2)    // userid 2,4
 	test([]int64{1, 2, 3, 4, 5}, []int64{2, 3, 5}, 3) // userid 2,4,5
 }
+
+func TestTeam_PackageTypeAccessMode(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	assertAccessMode := func(team *organization.Team, packageType string, expected perm.AccessMode) {
+		accessMode, err := team.PackageTypeAccessMode(db.DefaultContext, packageType)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, accessMode)
+	}
+
+	team := unittest.AssertExistsAndLoadBean(t, &organization.Team{ID: 10})
+
+	// without an access mode for the package type the team access applies
+	assertAccessMode(team, "npm", perm.AccessModeRead)
+
+	assert.NoError(t, organization.SetTeamPackageType(team, "npm", perm.AccessModeWrite))
+	assertAccessMode(team, "npm", perm.AccessModeWrite)
+	assertAccessMode(team, "maven", perm.AccessModeRead)
+
+	// setting the access mode again replaces it
+	assert.NoError(t, organization.SetTeamPackageType(team, "npm", perm.AccessModeNone))
+	assertAccessMode(team, "npm", perm.AccessModeNone)
+
+	tpts, err := organization.GetOrgTeamPackageTypes(db.DefaultContext, team.OrgID, "npm")
+	assert.NoError(t, err)
+	assert.Len(t, tpts, 1)
+	assert.Equal(t, team.ID, tpts[0].TeamID)
+
+	assert.NoError(t, organization.RemoveTeamPackageType(db.DefaultContext, team.ID, "npm"))
+	assertAccessMode(team, "npm", perm.AccessModeRead)
+
+	tpts, err = organization.GetTeamPackageTypes(db.DefaultContext, team.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, tpts)
+
+	// owner teams can't be restricted
+	team = unittest.AssertExistsAndLoadBean(t, &organization.Team{ID: 1})
+	assert.NoError(t, organization.SetTeamPackageType(team, "npm", perm.AccessModeNone))
+	assertAccessMode(team, "npm", perm.AccessModeOwner)
+	assert.NoError(t, organization.RemoveTeamPackageType(db.DefaultContext, team.ID, "npm"))
+}
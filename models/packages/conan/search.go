@@ -26,11 +26,12 @@ func buildCondition(name, value string) builder.Cond {
 }
 
 type RecipeSearchOptions struct {
-	OwnerID int64
-	Name    string
-	Version string
-	User    string
-	Channel string
+	OwnerID     int64
+	Name        string
+	Version     string
+	User        string
+	Channel     string
+	HidePrivate bool
 }
 
 // SearchRecipes gets all recipes matching the search options
@@ -45,6 +46,9 @@ func SearchRecipes(ctx context.Context, opts *RecipeSearchOptions) ([]string, er
 	if opts.Name != "" {
 		cond = cond.And(buildCondition("package.lower_name", strings.ToLower(opts.Name)))
 	}
+	if opts.HidePrivate {
+		cond = cond.And(builder.Not{packages.PrivatePackageCond()})
+	}
 	if opts.Version != "" {
 		cond = cond.And(buildCondition("package_version.lower_version", strings.ToLower(opts.Version)))
 	}
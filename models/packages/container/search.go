@@ -248,6 +248,9 @@ func GetRepositories(ctx context.Context, actor *user_model.User, n int, last st
 	}
 
 	cond = cond.And(user_model.BuildCanSeeUserCondition(actor))
+	if privateCond := packages.BuildCanSeePrivatePackageCondition(actor); privateCond != nil {
+		cond = cond.And(privateCond)
+	}
 
 	sess := db.GetEngine(ctx).
 		Table("package").
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"net/url"
 
+	"code.gitea.io/gitea/models/db"
 	repo_model "code.gitea.io/gitea/models/repo"
 	user_model "code.gitea.io/gitea/models/user"
 	"code.gitea.io/gitea/modules/json"
@@ -25,6 +26,7 @@ import (
 	"code.gitea.io/gitea/modules/packages/vagrant"
 
 	"github.com/hashicorp/go-version"
+	"xorm.io/builder"
 )
 
 // PackagePropertyList is a list of package properties
@@ -48,6 +50,7 @@ type PackageDescriptor struct {
 	Version           *PackageVersion
 	SemVer            *version.Version
 	Creator           *user_model.User
+	PackageCreator    *user_model.User // the user who created the package, Ghost if the user does not exist anymore
 	PackageProperties PackagePropertyList
 	VersionProperties PackagePropertyList
 	Metadata          interface{}
@@ -71,6 +74,11 @@ func (pd *PackageDescriptor) FullWebLink() string {
 	return fmt.Sprintf("%s/%s", pd.PackageWebLink(), url.PathEscape(pd.Version.LowerVersion))
 }
 
+// IsPrivate checks if the package is private, either by its own visibility or because the linked repository is private
+func (pd *PackageDescriptor) IsPrivate() bool {
+	return pd.Package.Visibility == VisibilityPrivate || (pd.Repository != nil && pd.Repository.IsPrivate)
+}
+
 // CalculateBlobSize returns the total blobs size in bytes
 func (pd *PackageDescriptor) CalculateBlobSize() int64 {
 	size := int64(0)
@@ -82,7 +90,7 @@ func (pd *PackageDescriptor) CalculateBlobSize() int64 {
 
 // GetPackageDescriptor gets the package description for a version
 func GetPackageDescriptor(ctx context.Context, pv *PackageVersion) (*PackageDescriptor, error) {
-	p, err := GetPackageByID(ctx, pv.PackageID)
+	p, err := GetPackageByID(ctx, pv.PackageID, false)
 	if err != nil {
 		return nil, err
 	}
@@ -98,6 +106,13 @@ func GetPackageDescriptor(ctx context.Context, pv *PackageVersion) (*PackageDesc
 	if err != nil {
 		return nil, err
 	}
+	packageCreator, err := user_model.GetUserByIDCtx(ctx, p.CreatorID)
+	if err != nil {
+		if !user_model.IsErrUserNotExist(err) {
+			return nil, err
+		}
+		packageCreator = user_model.NewGhostUser()
+	}
 	var semVer *version.Version
 	if p.SemverCompatible {
 		semVer, err = version.NewVersion(pv.Version)
@@ -127,35 +142,7 @@ func GetPackageDescriptor(ctx context.Context, pv *PackageVersion) (*PackageDesc
 		pfds = append(pfds, pfd)
 	}
 
-	var metadata interface{}
-	switch p.Type {
-	case TypeComposer:
-		metadata = &composer.Metadata{}
-	case TypeConan:
-		metadata = &conan.Metadata{}
-	case TypeContainer:
-		metadata = &container.Metadata{}
-	case TypeGeneric:
-		// generic packages have no metadata
-	case TypeHelm:
-		metadata = &helm.Metadata{}
-	case TypeNuGet:
-		metadata = &nuget.Metadata{}
-	case TypeNpm:
-		metadata = &npm.Metadata{}
-	case TypeMaven:
-		metadata = &maven.Metadata{}
-	case TypePub:
-		metadata = &pub.Metadata{}
-	case TypePyPI:
-		metadata = &pypi.Metadata{}
-	case TypeRubyGems:
-		metadata = &rubygems.Metadata{}
-	case TypeVagrant:
-		metadata = &vagrant.Metadata{}
-	default:
-		panic(fmt.Sprintf("unknown package type: %s", string(p.Type)))
-	}
+	metadata := newMetadata(p.Type)
 	if metadata != nil {
 		if err := json.Unmarshal([]byte(pv.MetadataJSON), &metadata); err != nil {
 			return nil, err
@@ -169,6 +156,7 @@ func GetPackageDescriptor(ctx context.Context, pv *PackageVersion) (*PackageDesc
 		Version:           pv,
 		SemVer:            semVer,
 		Creator:           creator,
+		PackageCreator:    packageCreator,
 		PackageProperties: PackagePropertyList(pps),
 		VersionProperties: PackagePropertyList(pvps),
 		Metadata:          metadata,
@@ -193,15 +181,219 @@ func GetPackageFileDescriptor(ctx context.Context, pf *PackageFile) (*PackageFil
 	}, nil
 }
 
-// GetPackageDescriptors gets the package descriptions for the versions
-func GetPackageDescriptors(ctx context.Context, pvs []*PackageVersion) ([]*PackageDescriptor, error) {
+// newMetadata returns the metadata struct of the package type, nil if the type has no metadata
+func newMetadata(packageType Type) interface{} {
+	switch packageType {
+	case TypeComposer:
+		return &composer.Metadata{}
+	case TypeConan:
+		return &conan.Metadata{}
+	case TypeContainer:
+		return &container.Metadata{}
+	case TypeGeneric:
+		// generic packages have no metadata
+		return nil
+	case TypeHelm:
+		return &helm.Metadata{}
+	case TypeNuGet:
+		return &nuget.Metadata{}
+	case TypeNpm:
+		return &npm.Metadata{}
+	case TypeMaven:
+		return &maven.Metadata{}
+	case TypePub:
+		return &pub.Metadata{}
+	case TypePyPI:
+		return &pypi.Metadata{}
+	case TypeRubyGems:
+		return &rubygems.Metadata{}
+	case TypeVagrant:
+		return &vagrant.Metadata{}
+	default:
+		panic(fmt.Sprintf("unknown package type: %s", string(packageType)))
+	}
+}
+
+// DescriptorLoadOptions defines which parts of the package descriptors are loaded by GetPackageDescriptors
+type DescriptorLoadOptions struct {
+	// SkipMetadata leaves Metadata nil, so lists which don't display the metadata don't need to parse the metadata JSON of every version
+	SkipMetadata bool
+}
+
+// GetPackageDescriptors gets the package descriptions for the versions.
+// The related data of all versions is loaded with a fixed number of queries, regardless of the number of versions.
+func GetPackageDescriptors(ctx context.Context, pvs []*PackageVersion, opts DescriptorLoadOptions) ([]*PackageDescriptor, error) {
 	pds := make([]*PackageDescriptor, 0, len(pvs))
+	if len(pvs) == 0 {
+		return pds, nil
+	}
+
+	e := db.GetEngine(ctx)
+
+	versionIDs := make([]int64, 0, len(pvs))
+	packageIDs := make([]int64, 0, len(pvs))
 	for _, pv := range pvs {
-		pd, err := GetPackageDescriptor(ctx, pv)
-		if err != nil {
+		versionIDs = append(versionIDs, pv.ID)
+		packageIDs = append(packageIDs, pv.PackageID)
+	}
+
+	packages := make(map[int64]*Package, len(pvs))
+	if err := e.In("id", uniqueIDs(packageIDs)).Find(&packages); err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]int64, 0, 3*len(pvs))
+	repoIDs := make([]int64, 0, len(pvs))
+	for _, pv := range pvs {
+		p, ok := packages[pv.PackageID]
+		if !ok {
+			return nil, ErrPackageNotExist
+		}
+		userIDs = append(userIDs, p.OwnerID, p.CreatorID, pv.CreatorID)
+		if p.RepoID != 0 {
+			repoIDs = append(repoIDs, p.RepoID)
+		}
+	}
+
+	users := make(map[int64]*user_model.User, len(userIDs))
+	if err := e.In("id", uniqueIDs(userIDs)).Find(&users); err != nil {
+		return nil, err
+	}
+	repositories := make(map[int64]*repo_model.Repository, len(repoIDs))
+	if len(repoIDs) > 0 {
+		if err := e.In("id", uniqueIDs(repoIDs)).Find(&repositories); err != nil {
+			return nil, err
+		}
+	}
+
+	packageProperties, err := getPropertiesByRefIDs(ctx, PropertyTypePackage, uniqueIDs(packageIDs))
+	if err != nil {
+		return nil, err
+	}
+	versionProperties, err := getPropertiesByRefIDs(ctx, PropertyTypeVersion, versionIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	pfs := make([]*PackageFile, 0, len(pvs))
+	if err := e.In("version_id", versionIDs).Find(&pfs); err != nil {
+		return nil, err
+	}
+	fileIDs := make([]int64, 0, len(pfs))
+	blobIDs := make([]int64, 0, len(pfs))
+	for _, pf := range pfs {
+		fileIDs = append(fileIDs, pf.ID)
+		blobIDs = append(blobIDs, pf.BlobID)
+	}
+	blobs := make(map[int64]*PackageBlob, len(blobIDs))
+	if len(blobIDs) > 0 {
+		if err := e.In("id", uniqueIDs(blobIDs)).Find(&blobs); err != nil {
 			return nil, err
 		}
-		pds = append(pds, pd)
+	}
+	fileProperties, err := getPropertiesByRefIDs(ctx, PropertyTypeFile, fileIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[int64][]*PackageFileDescriptor, len(pvs))
+	for _, pf := range pfs {
+		pb, ok := blobs[pf.BlobID]
+		if !ok {
+			return nil, ErrPackageBlobNotExist
+		}
+		files[pf.VersionID] = append(files[pf.VersionID], &PackageFileDescriptor{
+			pf,
+			pb,
+			fileProperties[pf.ID],
+		})
+	}
+
+	for _, pv := range pvs {
+		p := packages[pv.PackageID]
+
+		o, ok := users[p.OwnerID]
+		if !ok {
+			return nil, user_model.ErrUserNotExist{UID: p.OwnerID}
+		}
+		creator, ok := users[pv.CreatorID]
+		if !ok {
+			return nil, user_model.ErrUserNotExist{UID: pv.CreatorID}
+		}
+		packageCreator, ok := users[p.CreatorID]
+		if !ok {
+			packageCreator = user_model.NewGhostUser()
+		}
+
+		var semVer *version.Version
+		if p.SemverCompatible {
+			semVer, err = version.NewVersion(pv.Version)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		var metadata interface{}
+		if !opts.SkipMetadata {
+			metadata = newMetadata(p.Type)
+			if metadata != nil {
+				if err := json.Unmarshal([]byte(pv.MetadataJSON), &metadata); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		pfds := files[pv.ID]
+		if pfds == nil {
+			pfds = []*PackageFileDescriptor{}
+		}
+
+		pds = append(pds, &PackageDescriptor{
+			Package:           p,
+			Owner:             o,
+			Repository:        repositories[p.RepoID],
+			Version:           pv,
+			SemVer:            semVer,
+			Creator:           creator,
+			PackageCreator:    packageCreator,
+			PackageProperties: packageProperties[p.ID],
+			VersionProperties: versionProperties[pv.ID],
+			Metadata:          metadata,
+			Files:             pfds,
+		})
 	}
 	return pds, nil
 }
+
+// getPropertiesByRefIDs gets the properties of the refs grouped by ref id
+func getPropertiesByRefIDs(ctx context.Context, refType PropertyType, refIDs []int64) (map[int64]PackagePropertyList, error) {
+	properties := make(map[int64]PackagePropertyList, len(refIDs))
+	if len(refIDs) == 0 {
+		return properties, nil
+	}
+
+	pps := make([]*PackageProperty, 0, len(refIDs))
+	if err := db.GetEngine(ctx).
+		Where(builder.Eq{"ref_type": refType}.And(builder.In("ref_id", refIDs))).
+		Find(&pps); err != nil {
+		return nil, err
+	}
+	for _, pp := range pps {
+		properties[pp.RefID] = append(properties[pp.RefID], pp)
+	}
+	return properties, nil
+}
+
+// uniqueIDs removes duplicated and zero ids
+func uniqueIDs(ids []int64) []int64 {
+	seen := make(map[int64]bool, len(ids))
+	unique := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+	return unique
+}
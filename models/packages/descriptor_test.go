@@ -0,0 +1,146 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/unittest"
+	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/packages/npm"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm/contexts"
+)
+
+// queryCounter counts the executed queries while it is enabled
+type queryCounter struct {
+	enabled bool
+	count   int
+}
+
+func (c *queryCounter) BeforeProcess(ch *contexts.ContextHook) (context.Context, error) {
+	if c.enabled {
+		c.count++
+	}
+	return ch.Ctx, nil
+}
+
+func (c *queryCounter) AfterProcess(ch *contexts.ContextHook) error {
+	return nil
+}
+
+func TestGetPackageDescriptors(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	metadataJSON, err := json.Marshal(&npm.Metadata{Description: "descriptor test"})
+	assert.NoError(t, err)
+
+	pvs := make([]*packages_model.PackageVersion, 0, 6)
+	for i := 0; i < 3; i++ {
+		p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+			OwnerID:   2,
+			CreatorID: 2,
+			Type:      packages_model.TypeNpm,
+			Name:      fmt.Sprintf("descriptor-test-%d", i),
+			LowerName: fmt.Sprintf("descriptor-test-%d", i),
+		})
+		assert.NoError(t, err)
+		_, err = packages_model.InsertProperty(db.DefaultContext, packages_model.PropertyTypePackage, p.ID, "package", p.Name)
+		assert.NoError(t, err)
+
+		for _, version := range []string{"1.0.0", "2.0.0"} {
+			pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+				PackageID:    p.ID,
+				CreatorID:    2,
+				Version:      version,
+				LowerVersion: version,
+				MetadataJSON: string(metadataJSON),
+			})
+			assert.NoError(t, err)
+			_, err = packages_model.InsertProperty(db.DefaultContext, packages_model.PropertyTypeVersion, pv.ID, "version", version)
+			assert.NoError(t, err)
+
+			pf, err := packages_model.TryInsertFile(db.DefaultContext, &packages_model.PackageFile{
+				VersionID: pv.ID,
+				BlobID:    insertTestBlob(t, fmt.Sprintf("descriptor-test-%d-%s", i, version)).ID,
+				Name:      "package.tgz",
+				LowerName: "package.tgz",
+			})
+			assert.NoError(t, err)
+			_, err = packages_model.InsertProperty(db.DefaultContext, packages_model.PropertyTypeFile, pf.ID, "file", pf.Name)
+			assert.NoError(t, err)
+
+			pvs = append(pvs, pv)
+		}
+	}
+
+	t.Run("SameAsSingle", func(t *testing.T) {
+		pds, err := packages_model.GetPackageDescriptors(db.DefaultContext, pvs, packages_model.DescriptorLoadOptions{})
+		assert.NoError(t, err)
+		assert.Len(t, pds, len(pvs))
+
+		for i, pd := range pds {
+			expected, err := packages_model.GetPackageDescriptor(db.DefaultContext, pvs[i])
+			assert.NoError(t, err)
+
+			assert.Equal(t, expected.Package.ID, pd.Package.ID)
+			assert.Equal(t, expected.Version.ID, pd.Version.ID)
+			assert.Equal(t, expected.Owner.ID, pd.Owner.ID)
+			assert.Equal(t, expected.Creator.ID, pd.Creator.ID)
+			assert.Equal(t, expected.PackageCreator.ID, pd.PackageCreator.ID)
+			assert.Equal(t, expected.PackageProperties.GetByName("package"), pd.PackageProperties.GetByName("package"))
+			assert.Equal(t, expected.VersionProperties.GetByName("version"), pd.VersionProperties.GetByName("version"))
+			assert.Equal(t, expected.Metadata, pd.Metadata)
+			assert.Len(t, pd.Files, 1)
+			assert.Equal(t, expected.Files[0].File.ID, pd.Files[0].File.ID)
+			assert.Equal(t, expected.Files[0].Blob.ID, pd.Files[0].Blob.ID)
+			assert.Equal(t, "package.tgz", pd.Files[0].Properties.GetByName("file"))
+		}
+	})
+
+	t.Run("SkipMetadata", func(t *testing.T) {
+		pds, err := packages_model.GetPackageDescriptors(db.DefaultContext, pvs, packages_model.DescriptorLoadOptions{SkipMetadata: true})
+		assert.NoError(t, err)
+		for _, pd := range pds {
+			assert.Nil(t, pd.Metadata)
+		}
+	})
+
+	t.Run("QueryCount", func(t *testing.T) {
+		counter := &queryCounter{}
+		unittest.GetXORMEngine().AddHook(counter)
+		defer func() {
+			counter.enabled = false
+		}()
+
+		countQueries := func(pvs []*packages_model.PackageVersion) int {
+			counter.count = 0
+			counter.enabled = true
+			_, err := packages_model.GetPackageDescriptors(db.DefaultContext, pvs, packages_model.DescriptorLoadOptions{})
+			counter.enabled = false
+			assert.NoError(t, err)
+			return counter.count
+		}
+
+		single := countQueries(pvs[:1])
+		assert.LessOrEqual(t, single, 8)
+		// the number of queries doesn't grow with the number of versions
+		assert.Equal(t, single, countQueries(pvs))
+
+		counter.count = 0
+		counter.enabled = true
+		_, err := packages_model.GetPackageDescriptor(db.DefaultContext, pvs[0])
+		counter.enabled = false
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, counter.count, single)
+
+		assert.Equal(t, 0, countQueries(nil))
+	})
+}
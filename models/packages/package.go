@@ -9,8 +9,14 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"code.gitea.io/gitea/models/db"
+	repo_model "code.gitea.io/gitea/models/repo"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/packages/maven"
+	"code.gitea.io/gitea/modules/timeutil"
 
 	"xorm.io/builder"
 )
@@ -24,6 +30,8 @@ var (
 	ErrDuplicatePackage = errors.New("Package does exist already")
 	// ErrPackageNotExist indicates a package not exist error
 	ErrPackageNotExist = errors.New("Package does not exist")
+	// ErrNamespaceNotSupported indicates that the package type has no namespaces
+	ErrNamespaceNotSupported = errors.New("Package type does not support namespaces")
 )
 
 // Type of a package
@@ -45,6 +53,22 @@ const (
 	TypeVagrant   Type = "vagrant"
 )
 
+// TypeList contains all supported package types
+var TypeList = []Type{
+	TypeComposer,
+	TypeConan,
+	TypeContainer,
+	TypeGeneric,
+	TypeHelm,
+	TypeMaven,
+	TypeNpm,
+	TypeNuGet,
+	TypePub,
+	TypePyPI,
+	TypeRubyGems,
+	TypeVagrant,
+}
+
 // Name gets the name of the package type
 func (pt Type) Name() string {
 	switch pt {
@@ -107,28 +131,77 @@ func (pt Type) SVGName() string {
 	panic(fmt.Sprintf("unknown package type: %s", string(pt)))
 }
 
+// Visibility defines who can see a package
+type Visibility int
+
+const (
+	// VisibilityInherit makes the package visible to everyone who can see the packages of the owner
+	VisibilityInherit Visibility = iota
+	// VisibilityPrivate makes the package visible only to members of the owner and site admins
+	VisibilityPrivate
+)
+
+// String returns the name of the visibility
+func (v Visibility) String() string {
+	switch v {
+	case VisibilityInherit:
+		return "inherit"
+	case VisibilityPrivate:
+		return "private"
+	}
+	return fmt.Sprintf("unknown (%d)", int(v))
+}
+
+// ParseVisibility returns the visibility with the given name
+func ParseVisibility(name string) (Visibility, bool) {
+	switch strings.ToLower(name) {
+	case "inherit":
+		return VisibilityInherit, true
+	case "private":
+		return VisibilityPrivate, true
+	}
+	return VisibilityInherit, false
+}
+
 // Package represents a package
 type Package struct {
-	ID               int64  `xorm:"pk autoincr"`
-	OwnerID          int64  `xorm:"UNIQUE(s) INDEX NOT NULL"`
-	RepoID           int64  `xorm:"INDEX"`
-	Type             Type   `xorm:"UNIQUE(s) INDEX NOT NULL"`
-	Name             string `xorm:"NOT NULL"`
-	LowerName        string `xorm:"UNIQUE(s) INDEX NOT NULL"`
-	SemverCompatible bool   `xorm:"NOT NULL DEFAULT false"`
+	ID               int64              `xorm:"pk autoincr"`
+	OwnerID          int64              `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	RepoID           int64              `xorm:"INDEX"`
+	CreatorID        int64              `xorm:"INDEX NOT NULL DEFAULT 0"` // user who created the package, kept when its versions are deleted
+	Type             Type               `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	Name             string             `xorm:"NOT NULL"`
+	LowerName        string             `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	SemverCompatible bool               `xorm:"NOT NULL DEFAULT false"`
+	CreatedUnix      timeutil.TimeStamp `xorm:"created INDEX NOT NULL DEFAULT 0"`
+	UpdatedUnix      timeutil.TimeStamp `xorm:"INDEX NOT NULL DEFAULT 0"`
+	Description      string             `xorm:"TEXT"` // description of the latest version, only used for searching
+	IsImmutable      bool               `xorm:"NOT NULL DEFAULT false"`
+	DownloadCount    int64              `xorm:"INDEX NOT NULL DEFAULT 0"` // sum of the download counts of the non-internal versions, used for sorting
+	Visibility       Visibility         `xorm:"NOT NULL DEFAULT 0"`
+
+	Versions []*PackageVersion `xorm:"-"` // non-internal versions, only loaded by GetPackageByID
+}
+
+// NormalizeName returns the form in which package names are stored in LowerName and looked up
+func NormalizeName(name string) string {
+	return strings.ToLower(name)
 }
 
-// TryInsertPackage inserts a package. If a package exists already, ErrDuplicatePackage is returned
+// MaxDescriptionLength is the maximum number of characters of the stored package description
+const MaxDescriptionLength = 1024
+
+// TryInsertPackage inserts a package. If a package exists already, ErrDuplicatePackage is returned.
+// A package which has the name as alias counts as existing package.
 func TryInsertPackage(ctx context.Context, p *Package) (*Package, error) {
 	e := db.GetEngine(ctx)
 
-	key := &Package{
-		OwnerID:   p.OwnerID,
-		Type:      p.Type,
-		LowerName: p.LowerName,
-	}
+	key := &Package{}
 
-	has, err := e.Get(key)
+	has, err := e.Where(builder.Eq{
+		"package.owner_id": p.OwnerID,
+		"package.type":     p.Type,
+	}.And(nameCond(p.LowerName))).Get(key)
 	if err != nil {
 		return nil, err
 	}
@@ -138,13 +211,30 @@ func TryInsertPackage(ctx context.Context, p *Package) (*Package, error) {
 	if _, err = e.Insert(p); err != nil {
 		return nil, err
 	}
+	emitPackageEvent(ctx, PackageEventCreate, p, 0)
 	return p, nil
 }
 
 // DeletePackageByID deletes a package by id
 func DeletePackageByID(ctx context.Context, packageID int64) error {
-	_, err := db.GetEngine(ctx).ID(packageID).Delete(&Package{})
-	return err
+	e := db.GetEngine(ctx)
+
+	p := &Package{}
+	has, err := e.ID(packageID).Get(p)
+	if err != nil {
+		return err
+	}
+	if !has {
+		return nil
+	}
+	if _, err := e.ID(packageID).Delete(&Package{}); err != nil {
+		return err
+	}
+	if err := DeleteAliasesByPackageID(ctx, packageID); err != nil {
+		return err
+	}
+	emitPackageEvent(ctx, PackageEventDelete, p, 0)
+	return nil
 }
 
 // SetRepositoryLink sets the linked repository
@@ -153,6 +243,221 @@ func SetRepositoryLink(ctx context.Context, packageID, repoID int64) error {
 	return err
 }
 
+// SetImmutable sets if the published versions of a package can't be overwritten or deleted
+func SetImmutable(ctx context.Context, packageID int64, immutable bool) error {
+	_, err := db.GetEngine(ctx).ID(packageID).Cols("is_immutable").Update(&Package{IsImmutable: immutable})
+	return err
+}
+
+// SetVisibility sets the visibility of a package
+func SetVisibility(ctx context.Context, packageID int64, visibility Visibility) error {
+	_, err := db.GetEngine(ctx).ID(packageID).Cols("visibility").Update(&Package{Visibility: visibility})
+	return err
+}
+
+// IsPackagePrivate checks if a package is private. A package linked to a private repository is private regardless of its own visibility.
+func IsPackagePrivate(ctx context.Context, p *Package) (bool, error) {
+	if p.Visibility == VisibilityPrivate {
+		return true, nil
+	}
+	if p.RepoID == 0 {
+		return false, nil
+	}
+	return db.GetEngine(ctx).Where(builder.Eq{"id": p.RepoID, "is_private": true}).Exist(new(repo_model.Repository))
+}
+
+// PrivatePackageCond matches the private packages, see IsPackagePrivate
+func PrivatePackageCond() builder.Cond {
+	return builder.Or(
+		builder.Eq{"package.visibility": VisibilityPrivate},
+		builder.In("package.repo_id", builder.Select("repository.id").From("repository").Where(builder.Eq{"repository.is_private": true})),
+	)
+}
+
+// BuildCanSeePrivatePackageCondition creates a condition which restricts the results to packages which are public or private packages the actor (anonymous if nil) can see.
+// Private packages can be seen by site admins, the owner and members of the owning organization. nil is returned if the actor can see every package.
+func BuildCanSeePrivatePackageCondition(actor *user_model.User) builder.Cond {
+	if actor == nil {
+		return builder.Not{PrivatePackageCond()}
+	}
+	if actor.IsAdmin {
+		return nil
+	}
+	return builder.Or(
+		builder.Not{PrivatePackageCond()},
+		builder.Eq{"package.owner_id": actor.ID},
+		builder.In("package.owner_id", builder.Select("org_id").From("org_user").Where(builder.Eq{"uid": actor.ID})),
+	)
+}
+
+// SetDescription stores the description of the latest version of a package. Longer descriptions are truncated to MaxDescriptionLength characters.
+func SetDescription(ctx context.Context, packageID int64, description string) error {
+	description = strings.TrimSpace(description)
+	if runes := []rune(description); len(runes) > MaxDescriptionLength {
+		description = string(runes[:MaxDescriptionLength])
+	}
+	_, err := db.GetEngine(ctx).ID(packageID).Cols("description").Update(&Package{Description: description})
+	return err
+}
+
+// TouchPackage sets the last activity timestamp of a package to the current time
+func TouchPackage(ctx context.Context, packageID int64) error {
+	return touchPackage(ctx, packageID, timeutil.TimeStampNow())
+}
+
+func touchPackage(ctx context.Context, packageID int64, now timeutil.TimeStamp) error {
+	_, err := db.GetEngine(ctx).ID(packageID).Cols("updated_unix").Update(&Package{UpdatedUnix: now})
+	return err
+}
+
+// RenamePackage changes the name of a package. If the owner has a package with the same type and name already, ErrDuplicatePackage is returned.
+// The old name is kept as alias of the package, an alias of another package with the new name is removed.
+func RenamePackage(ctx context.Context, p *Package, newName string) error {
+	e := db.GetEngine(ctx)
+
+	lowerName := NormalizeName(newName)
+
+	has, err := e.Where(builder.Eq{
+		"owner_id":   p.OwnerID,
+		"type":       p.Type,
+		"lower_name": lowerName,
+	}.And(builder.Neq{"id": p.ID})).Exist(&Package{})
+	if err != nil {
+		return err
+	}
+	if has {
+		return ErrDuplicatePackage
+	}
+
+	if err := deleteAlias(ctx, p.OwnerID, p.Type, lowerName); err != nil {
+		return err
+	}
+	if lowerName != p.LowerName {
+		if err := setAlias(ctx, p, p.LowerName); err != nil {
+			return err
+		}
+	}
+
+	if _, err := e.ID(p.ID).Cols("name", "lower_name").Update(&Package{Name: newName, LowerName: lowerName}); err != nil {
+		return err
+	}
+
+	p.Name = newName
+	p.LowerName = lowerName
+
+	return nil
+}
+
+// RenormalizePackageNames recomputes the LowerName of all packages and the LowerVersion of all versions with NormalizeName and NormalizeVersion.
+// It is needed if the normalization rules change. Rows whose normalized value collides with another row are logged and skipped.
+func RenormalizePackageNames(ctx context.Context) (int64, error) {
+	e := db.GetEngine(ctx)
+
+	var updated int64
+	if err := db.IterateObjects(ctx, func(p *Package) error {
+		lowerName := NormalizeName(p.Name)
+		if lowerName == p.LowerName {
+			return nil
+		}
+
+		has, err := e.Where(builder.Eq{
+			"owner_id":   p.OwnerID,
+			"type":       p.Type,
+			"lower_name": lowerName,
+		}.And(builder.Neq{"id": p.ID})).Exist(&Package{})
+		if err != nil {
+			return err
+		}
+		if has {
+			log.Warn("Skipping package %d: normalized name %s collides with another package", p.ID, lowerName)
+			return nil
+		}
+
+		if _, err := e.ID(p.ID).Cols("lower_name").Update(&Package{LowerName: lowerName}); err != nil {
+			return err
+		}
+		updated++
+		return nil
+	}); err != nil {
+		return updated, err
+	}
+
+	err := db.IterateObjects(ctx, func(pv *PackageVersion) error {
+		lowerVersion := NormalizeVersion(pv.Version)
+		if lowerVersion == pv.LowerVersion {
+			return nil
+		}
+
+		has, err := e.Where(builder.Eq{
+			"package_id":    pv.PackageID,
+			"lower_version": lowerVersion,
+		}.And(builder.Neq{"id": pv.ID})).Exist(&PackageVersion{})
+		if err != nil {
+			return err
+		}
+		if has {
+			log.Warn("Skipping package version %d: normalized version %s collides with another version", pv.ID, lowerVersion)
+			return nil
+		}
+
+		if _, err := e.ID(pv.ID).Cols("lower_version").Update(&PackageVersion{LowerVersion: lowerVersion}); err != nil {
+			return err
+		}
+		updated++
+		return nil
+	})
+	return updated, err
+}
+
+// TransferOwnership moves a package to a new owner. If the new owner has a package with the same type and name already, ErrDuplicatePackage is returned.
+// The repository link is kept only if the linked repository belongs to the new owner.
+func TransferOwnership(ctx context.Context, p *Package, newOwnerID int64) error {
+	e := db.GetEngine(ctx)
+
+	has, err := e.Exist(&Package{
+		OwnerID:   newOwnerID,
+		Type:      p.Type,
+		LowerName: p.LowerName,
+	})
+	if err != nil {
+		return err
+	}
+	if has {
+		return ErrDuplicatePackage
+	}
+
+	// aliases only resolve for the owner they were created for
+	if err := DeleteAliasesByPackageID(ctx, p.ID); err != nil {
+		return err
+	}
+	if err := deleteAlias(ctx, newOwnerID, p.Type, p.LowerName); err != nil {
+		return err
+	}
+
+	if p.RepoID != 0 {
+		repo, err := repo_model.GetRepositoryByIDCtx(ctx, p.RepoID)
+		if err != nil && !repo_model.IsErrRepoNotExist(err) {
+			return err
+		}
+		if repo == nil || repo.OwnerID != newOwnerID {
+			p.RepoID = 0
+		}
+	}
+
+	oldOwnerID := p.OwnerID
+	p.OwnerID = newOwnerID
+
+	if _, err = e.ID(p.ID).Cols("owner_id", "repo_id").Update(p); err != nil {
+		return err
+	}
+	emitPackageEvent(ctx, PackageEventTransfer, p, oldOwnerID)
+
+	if err := RecalculateQuotaUsedSize(ctx, oldOwnerID); err != nil {
+		return err
+	}
+	return RecalculateQuotaUsedSize(ctx, newOwnerID)
+}
+
 // UnlinkRepositoryFromAllPackages unlinks every package from the repository
 func UnlinkRepositoryFromAllPackages(ctx context.Context, repoID int64) error {
 	_, err := db.GetEngine(ctx).Where("repo_id = ?", repoID).Cols("repo_id").Update(&Package{})
@@ -160,7 +465,8 @@ func UnlinkRepositoryFromAllPackages(ctx context.Context, repoID int64) error {
 }
 
 // GetPackageByID gets a package by id
-func GetPackageByID(ctx context.Context, packageID int64) (*Package, error) {
+// If withVersions is set, the non-internal versions of the package are loaded too, newest first.
+func GetPackageByID(ctx context.Context, packageID int64, withVersions bool) (*Package, error) {
 	p := &Package{}
 
 	has, err := db.GetEngine(ctx).ID(packageID).Get(p)
@@ -170,16 +476,28 @@ func GetPackageByID(ctx context.Context, packageID int64) (*Package, error) {
 	if !has {
 		return nil, ErrPackageNotExist
 	}
+
+	if withVersions {
+		p.Versions = make([]*PackageVersion, 0, 10)
+		if err := db.GetEngine(ctx).
+			Where(builder.Eq{
+				"package_id":  p.ID,
+				"is_internal": false,
+			}).
+			OrderBy("created_unix DESC, id DESC").
+			Find(&p.Versions); err != nil {
+			return nil, err
+		}
+	}
 	return p, nil
 }
 
 // GetPackageByName gets a package by name
 func GetPackageByName(ctx context.Context, ownerID int64, packageType Type, name string) (*Package, error) {
-	var cond builder.Cond = builder.Eq{
-		"package.owner_id":   ownerID,
-		"package.type":       packageType,
-		"package.lower_name": strings.ToLower(name),
-	}
+	cond := builder.Eq{
+		"package.owner_id": ownerID,
+		"package.type":     packageType,
+	}.And(nameCond(NormalizeName(name)))
 
 	p := &Package{}
 
@@ -195,6 +513,16 @@ func GetPackageByName(ctx context.Context, ownerID int64, packageType Type, name
 	return p, nil
 }
 
+// ExistsPackage checks if a package with the name exists without loading it
+func ExistsPackage(ctx context.Context, ownerID int64, packageType Type, name string) (bool, error) {
+	return db.GetEngine(ctx).
+		Where(builder.Eq{
+			"package.owner_id": ownerID,
+			"package.type":     packageType,
+		}.And(nameCond(NormalizeName(name)))).
+		Exist(&Package{})
+}
+
 // GetPackagesByType gets all packages of a specific type
 func GetPackagesByType(ctx context.Context, ownerID int64, packageType Type) ([]*Package, error) {
 	var cond builder.Cond = builder.Eq{
@@ -208,6 +536,125 @@ func GetPackagesByType(ctx context.Context, ownerID int64, packageType Type) ([]
 		Find(&ps)
 }
 
+// SearchPackagesWithFile gets all packages of an owner and type which have a non-internal version containing a file with the given name.
+// The file name is matched case-insensitive.
+func SearchPackagesWithFile(ctx context.Context, ownerID int64, packageType Type, fileName string) ([]*Package, error) {
+	fileCond := builder.
+		Select("package_file.id").
+		From("package_file").
+		InnerJoin("package_version", "package_version.id = package_file.version_id").
+		Where(builder.Expr("package_version.package_id = package.id").And(builder.Eq{
+			"package_version.is_internal": false,
+			"package_file.lower_name":     strings.ToLower(fileName),
+		}))
+
+	cond := builder.Eq{
+		"package.owner_id": ownerID,
+		"package.type":     packageType,
+	}.And(builder.Exists(fileCond))
+
+	ps := make([]*Package, 0, 10)
+	return ps, db.GetEngine(ctx).
+		Where(cond).
+		OrderBy("package.lower_name").
+		Find(&ps)
+}
+
+// SearchPackagesWithProperty gets all packages of an owner which have a package property with the given name, regardless of its value
+func SearchPackagesWithProperty(ctx context.Context, ownerID int64, key string) ([]*Package, error) {
+	propertyCond := builder.
+		Select("package_property.id").
+		From("package_property").
+		Where(builder.Expr("package_property.ref_id = package.id").And(builder.Eq{
+			"package_property.ref_type": PropertyTypePackage,
+			"package_property.name":     key,
+		}))
+
+	cond := builder.Eq{
+		"package.owner_id": ownerID,
+	}.And(builder.Exists(propertyCond))
+
+	ps := make([]*Package, 0, 10)
+	return ps, db.GetEngine(ctx).
+		Where(cond).
+		OrderBy("package.lower_name").
+		Find(&ps)
+}
+
+// namespaceDelimiters contains the delimiter between the namespace and the rest of the name for package types with namespaces
+var namespaceDelimiters = map[Type]string{
+	TypeComposer:  "/", // vendor/package
+	TypeContainer: "/", // namespace/image
+	TypeMaven:     ":", // groupId:artifactId
+	TypeNpm:       "/", // @scope/name
+}
+
+// SearchPackagesByNamespace gets all packages of an owner and type whose name is in the namespace, ordered by name.
+// The namespace may end with the delimiter of the package type. ErrNamespaceNotSupported is returned for types without namespaces.
+// The namespace of Maven packages is the groupId.
+func SearchPackagesByNamespace(ctx context.Context, ownerID int64, packageType Type, namespace string) ([]*Package, error) {
+	delimiter, ok := namespaceDelimiters[packageType]
+	if !ok {
+		return nil, ErrNamespaceNotSupported
+	}
+
+	prefix := strings.ToLower(strings.TrimSuffix(namespace, delimiter))
+	if prefix == "" {
+		return []*Package{}, nil
+	}
+
+	if packageType == TypeMaven {
+		return searchMavenPackagesByGroupID(ctx, ownerID, prefix)
+	}
+
+	prefix += delimiter
+
+	ps := make([]*Package, 0, 10)
+	if err := db.GetEngine(ctx).
+		Where(builder.Eq{
+			"package.owner_id": ownerID,
+			"package.type":     packageType,
+		}).
+		And("package.lower_name LIKE ?", prefix+"%").
+		OrderBy("package.lower_name").
+		Find(&ps); err != nil {
+		return nil, err
+	}
+
+	// the namespace may contain LIKE wildcards, so the prefix is checked again
+	matched := ps[:0]
+	for _, p := range ps {
+		if strings.HasPrefix(p.LowerName, prefix) {
+			matched = append(matched, p)
+		}
+	}
+	return matched, nil
+}
+
+// searchMavenPackagesByGroupID gets all Maven packages of an owner with the (lower case) groupId.
+// The name of a Maven package is groupId-artifactId and both parts may contain "-",
+// so the stored groupId property is matched instead of the name.
+func searchMavenPackagesByGroupID(ctx context.Context, ownerID int64, groupID string) ([]*Package, error) {
+	propertyCond := builder.
+		Select("package_property.id").
+		From("package_property").
+		Where(builder.Expr("package_property.ref_id = package.id").And(builder.Eq{
+			"package_property.ref_type": PropertyTypePackage,
+			"package_property.name":     maven.PropertyGroupID,
+		}).And(builder.Expr("LOWER(package_property.value) = ?", groupID)))
+
+	cond := builder.Eq{
+		"package.owner_id": ownerID,
+		"package.type":     TypeMaven,
+	}.And(builder.Exists(propertyCond))
+
+	ps := make([]*Package, 0, 10)
+	return ps, db.GetEngine(ctx).
+		Where(cond).
+		OrderBy("package.lower_name").
+		Find(&ps)
+}
+
 // FindUnreferencedPackages gets all packages without associated versions
 func FindUnreferencedPackages(ctx context.Context) ([]*Package, error) {
 	in := builder.
@@ -224,6 +671,205 @@ func FindUnreferencedPackages(ctx context.Context) ([]*Package, error) {
 		Find(&ps)
 }
 
+// versionlessPackagesBatchSize is the number of packages deleted in one transaction by CleanupVersionlessPackages
+const versionlessPackagesBatchSize = 100
+
+// CleanupVersionlessPackages deletes the packages without any versions, including internal ones, which were created before the threshold.
+// Recently created packages are kept, they may belong to a publish which didn't add its version yet. Immutable packages are kept too.
+// The packages are deleted with their properties and aliases in batches with a transaction per batch. It returns the number of deleted packages.
+func CleanupVersionlessPackages(ctx context.Context, olderThan time.Duration) (int, error) {
+	cond := builder.Lt{"package.created_unix": time.Now().Add(-olderThan).Unix()}.
+		And(builder.Eq{"package.is_immutable": false}).
+		And(builder.NotExists(
+			builder.Select("package_version.id").
+				From("package_version").
+				Where(builder.Expr("package_version.package_id = package.id")),
+		))
+
+	findVersionless := func(ctx context.Context, cond builder.Cond) ([]int64, error) {
+		ids := make([]int64, 0, versionlessPackagesBatchSize)
+		return ids, db.GetEngine(ctx).
+			Table("package").
+			Select("package.id").
+			Where(cond).
+			OrderBy("package.id").
+			Limit(versionlessPackagesBatchSize).
+			Find(&ids)
+	}
+
+	deleted := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return deleted, err
+		}
+
+		ids, err := findVersionless(ctx, cond)
+		if err != nil {
+			return deleted, err
+		}
+		if len(ids) == 0 {
+			return deleted, nil
+		}
+
+		count := 0
+		if err := db.WithTx(func(ctx context.Context) error {
+			// a version may have been added since the package was found
+			versionlessIDs, err := findVersionless(ctx, cond.And(builder.In("package.id", ids)))
+			if err != nil {
+				return err
+			}
+			for _, id := range versionlessIDs {
+				if err := DeleteAllProperties(ctx, PropertyTypePackage, id); err != nil {
+					return err
+				}
+				if err := DeletePackageByID(ctx, id); err != nil {
+					return err
+				}
+			}
+			count = len(versionlessIDs)
+			return nil
+		}, ctx); err != nil {
+			return deleted, err
+		}
+		deleted += count
+
+		if len(ids) < versionlessPackagesBatchSize {
+			return deleted, nil
+		}
+	}
+}
+
+// OrphanedPackages gets packages whose owner does not exist anymore, ordered by id.
+// These are left over if the packages were not removed when the owner got deleted.
+func OrphanedPackages(ctx context.Context, limit int) ([]*Package, error) {
+	ps := make([]*Package, 0, limit)
+	return ps, db.GetEngine(ctx).
+		Table("package").
+		Join("LEFT", "`user`", "`user`.id = package.owner_id").
+		Where(builder.IsNull{"`user`.id"}).
+		OrderBy("package.id ASC").
+		Limit(limit).
+		Find(&ps)
+}
+
+// RecentlyActivePackages gets the packages of an owner with the most recent activity first.
+// Packages which only have internal versions are not included. If ownerID is 0 the packages of all owners are considered.
+func RecentlyActivePackages(ctx context.Context, ownerID int64, limit int) ([]*Package, error) {
+	cond := builder.Gt{"package.updated_unix": 0}.
+		And(builder.Exists(
+			builder.Select("package_version.id").
+				From("package_version").
+				Where(builder.Expr("package_version.package_id = package.id").And(builder.Eq{"package_version.is_internal": false})),
+		))
+	if ownerID != 0 {
+		cond = cond.And(builder.Eq{"package.owner_id": ownerID})
+	}
+
+	ps := make([]*Package, 0, limit)
+	return ps, db.GetEngine(ctx).
+		Where(cond).
+		OrderBy("package.updated_unix DESC, package.id DESC").
+		Limit(limit).
+		Find(&ps)
+}
+
+// StalePackages gets the packages of an owner whose newest version was created before the cutoff, ordered by name.
+// Internal versions are ignored, so packages which only have internal versions are not included. If ownerID is 0 the packages of all owners are considered.
+func StalePackages(ctx context.Context, ownerID int64, olderThan time.Duration, limit int) ([]*Package, error) {
+	cutoff := time.Now().Add(-olderThan).Unix()
+
+	cond := builder.In("package.id",
+		builder.Select("package_version.package_id").
+			From("package_version").
+			Where(builder.Eq{"package_version.is_internal": false}).
+			GroupBy("package_version.package_id").
+			Having(fmt.Sprintf("MAX(package_version.created_unix) < %d", cutoff)),
+	)
+	if ownerID != 0 {
+		cond = cond.And(builder.Eq{"package.owner_id": ownerID})
+	}
+
+	ps := make([]*Package, 0, limit)
+	return ps, db.GetEngine(ctx).
+		Where(cond).
+		OrderBy("package.lower_name ASC, package.id ASC").
+		Limit(limit).
+		Find(&ps)
+}
+
+// ownerPackagesBatchSize is the number of packages deleted in one transaction by DeleteAllPackages
+const ownerPackagesBatchSize = 50
+
+// DeleteAllPackages deletes all packages of an owner with their versions, files, properties, aliases and release links.
+// The reference counts of the blobs are decreased, blobs without references are removed by the package cleanup.
+// The packages are deleted in batches with a transaction per batch. beforeDelete may be nil, otherwise it is called
+// within the transaction for every package before it gets deleted. afterCommit may be nil, otherwise it is called
+// after the transaction of every batch is committed. It returns the number of deleted packages.
+func DeleteAllPackages(ctx context.Context, ownerID int64, beforeDelete func(ctx context.Context, p *Package) error, afterCommit func()) (int, error) {
+	deleted := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return deleted, err
+		}
+
+		count := 0
+		if err := db.WithTx(func(ctx context.Context) error {
+			ps := make([]*Package, 0, ownerPackagesBatchSize)
+			if err := db.GetEngine(ctx).
+				Where(builder.Eq{"package.owner_id": ownerID}).
+				OrderBy("package.id").
+				Limit(ownerPackagesBatchSize).
+				Find(&ps); err != nil {
+				return err
+			}
+			for _, p := range ps {
+				if beforeDelete != nil {
+					if err := beforeDelete(ctx, p); err != nil {
+						return err
+					}
+				}
+				if err := deletePackageWithVersions(ctx, p.ID); err != nil {
+					return err
+				}
+			}
+			count = len(ps)
+			return nil
+		}, ctx); err != nil {
+			return deleted, err
+		}
+		deleted += count
+
+		if afterCommit != nil && count > 0 {
+			afterCommit()
+		}
+
+		if count < ownerPackagesBatchSize {
+			return deleted, nil
+		}
+	}
+}
+
+// deletePackageWithVersions deletes a package with its properties, aliases and all versions including the internal ones
+func deletePackageWithVersions(ctx context.Context, packageID int64) error {
+	versionIDs := make([]int64, 0, 10)
+	if err := db.GetEngine(ctx).
+		Table("package_version").
+		Select("id").
+		Where(builder.Eq{"package_id": packageID}).
+		Find(&versionIDs); err != nil {
+		return err
+	}
+	for _, versionID := range versionIDs {
+		if err := deleteVersionWithFiles(ctx, versionID); err != nil {
+			return err
+		}
+	}
+	if err := DeleteAllProperties(ctx, PropertyTypePackage, packageID); err != nil {
+		return err
+	}
+	return DeletePackageByID(ctx, packageID)
+}
+
 // HasOwnerPackages tests if a user/org has accessible packages
 func HasOwnerPackages(ctx context.Context, ownerID int64) (bool, error) {
 	return db.GetEngine(ctx).
@@ -236,7 +882,93 @@ func HasOwnerPackages(ctx context.Context, ownerID int64) (bool, error) {
 		Exist(&PackageVersion{})
 }
 
+// CountByType counts the packages of an owner per package type.
+// Like in the package list, only packages with a non-internal and non-yanked version are counted.
+func CountByType(ctx context.Context, ownerID int64) (map[Type]int64, error) {
+	return countByType(ctx, ownerID, false)
+}
+
+// CountPublicByType counts the packages of an owner per package type like CountByType, but skips private packages
+func CountPublicByType(ctx context.Context, ownerID int64) (map[Type]int64, error) {
+	return countByType(ctx, ownerID, true)
+}
+
+func countByType(ctx context.Context, ownerID int64, hidePrivate bool) (map[Type]int64, error) {
+	var rows []struct {
+		Type  Type
+		Count int64
+	}
+
+	var cond builder.Cond = builder.Eq{
+		"package.owner_id":            ownerID,
+		"package_version.is_internal": false,
+		"package_version.is_yanked":   false,
+	}
+	if hidePrivate {
+		cond = cond.And(builder.Not{PrivatePackageCond()})
+	}
+
+	if err := db.GetEngine(ctx).
+		Table("package").
+		Select("package.type, COUNT(DISTINCT package.id) AS count").
+		Join("INNER", "package_version", "package_version.package_id = package.id").
+		Where(cond).
+		GroupBy("package.type").
+		Find(&rows); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[Type]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Type] = row.Count
+	}
+	return counts, nil
+}
+
+// OwnerPackageTypes gets the distinct types of the packages of an owner, sorted by name.
+// Only packages with a non-internal version are considered.
+func OwnerPackageTypes(ctx context.Context, ownerID int64) ([]Type, error) {
+	types := make([]Type, 0, len(TypeList))
+	return types, db.GetEngine(ctx).
+		Table("package").
+		Distinct("package.type").
+		Join("INNER", "package_version", "package_version.package_id = package.id").
+		Where(builder.Eq{
+			"package.owner_id":            ownerID,
+			"package_version.is_internal": false,
+		}).
+		OrderBy("package.type").
+		Find(&types)
+}
+
 // HasRepositoryPackages tests if a repository has packages
 func HasRepositoryPackages(ctx context.Context, repositoryID int64) (bool, error) {
 	return db.GetEngine(ctx).Where("repo_id = ?", repositoryID).Exist(&Package{})
 }
+
+// ReposWithPackages tests which of the repositories have packages with non-internal versions.
+// The result contains an entry for every requested repository.
+func ReposWithPackages(ctx context.Context, repoIDs []int64) (map[int64]bool, error) {
+	result := make(map[int64]bool, len(repoIDs))
+	if len(repoIDs) == 0 {
+		return result, nil
+	}
+	for _, id := range repoIDs {
+		result[id] = false
+	}
+
+	ids := make([]int64, 0, len(repoIDs))
+	if err := db.GetEngine(ctx).
+		Table("package_version").
+		Join("INNER", "package", "package.id = package_version.package_id").
+		Distinct("package.repo_id").
+		Where(builder.Eq{"package_version.is_internal": false}).
+		And(builder.In("package.repo_id", repoIDs)).
+		Find(&ids); err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		result[id] = true
+	}
+	return result, nil
+}
@@ -0,0 +1,139 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"errors"
+
+	"code.gitea.io/gitea/models/db"
+
+	"xorm.io/builder"
+)
+
+var (
+	// ErrAliasCollision indicates that an alias has the name of a package, including the package the alias is for
+	ErrAliasCollision = errors.New("Alias collides with the name of a package")
+	// ErrDuplicateAlias indicates that the alias belongs to another package
+	ErrDuplicateAlias = errors.New("Alias does exist already")
+)
+
+func init() {
+	db.RegisterModel(new(PackageAlias))
+}
+
+// PackageAlias is an additional name under which a package of an owner can be found, e.g. the old name of a renamed package.
+// Lookups by name fall back to the aliases if no package has the name.
+type PackageAlias struct {
+	ID        int64  `xorm:"pk autoincr"`
+	OwnerID   int64  `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	Type      Type   `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	LowerName string `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	PackageID int64  `xorm:"INDEX NOT NULL"`
+}
+
+// setAlias points the alias to the package, replacing an alias with the same name
+func setAlias(ctx context.Context, p *Package, lowerName string) error {
+	if err := deleteAlias(ctx, p.OwnerID, p.Type, lowerName); err != nil {
+		return err
+	}
+	_, err := db.GetEngine(ctx).Insert(&PackageAlias{
+		OwnerID:   p.OwnerID,
+		Type:      p.Type,
+		LowerName: lowerName,
+		PackageID: p.ID,
+	})
+	return err
+}
+
+func deleteAlias(ctx context.Context, ownerID int64, packageType Type, lowerName string) error {
+	_, err := db.GetEngine(ctx).Delete(&PackageAlias{
+		OwnerID:   ownerID,
+		Type:      packageType,
+		LowerName: lowerName,
+	})
+	return err
+}
+
+// AddAlias adds an alias for the package. Adding an alias the package has already is a no-op.
+// An alias can't have the name of a package, so a package can't be an alias of itself and lookups never match two packages.
+func AddAlias(ctx context.Context, p *Package, alias string) error {
+	e := db.GetEngine(ctx)
+
+	lowerName := NormalizeName(alias)
+
+	has, err := e.Exist(&Package{
+		OwnerID:   p.OwnerID,
+		Type:      p.Type,
+		LowerName: lowerName,
+	})
+	if err != nil {
+		return err
+	}
+	if has {
+		return ErrAliasCollision
+	}
+
+	existing := &PackageAlias{
+		OwnerID:   p.OwnerID,
+		Type:      p.Type,
+		LowerName: lowerName,
+	}
+	has, err = e.Get(existing)
+	if err != nil {
+		return err
+	}
+	if has {
+		if existing.PackageID != p.ID {
+			return ErrDuplicateAlias
+		}
+		return nil
+	}
+
+	_, err = e.Insert(&PackageAlias{
+		OwnerID:   p.OwnerID,
+		Type:      p.Type,
+		LowerName: lowerName,
+		PackageID: p.ID,
+	})
+	return err
+}
+
+// RemoveAlias removes an alias of the package
+func RemoveAlias(ctx context.Context, p *Package, alias string) error {
+	_, err := db.GetEngine(ctx).Delete(&PackageAlias{
+		OwnerID:   p.OwnerID,
+		Type:      p.Type,
+		LowerName: NormalizeName(alias),
+		PackageID: p.ID,
+	})
+	return err
+}
+
+// GetAliasesByPackageID gets the aliases of a package
+func GetAliasesByPackageID(ctx context.Context, packageID int64) ([]*PackageAlias, error) {
+	pas := make([]*PackageAlias, 0, 5)
+	return pas, db.GetEngine(ctx).
+		Where("package_id = ?", packageID).
+		OrderBy("lower_name ASC").
+		Find(&pas)
+}
+
+// DeleteAliasesByPackageID deletes all aliases of a package
+func DeleteAliasesByPackageID(ctx context.Context, packageID int64) error {
+	_, err := db.GetEngine(ctx).Where("package_id = ?", packageID).Delete(&PackageAlias{})
+	return err
+}
+
+// nameCond matches the package with the normalized name or, if no package has this name, the package with an alias of this name.
+// The alias must belong to the owner and type of the package, so aliases left behind by a transfer never match.
+func nameCond(lowerName string) builder.Cond {
+	return builder.Eq{"package.lower_name": lowerName}.Or(builder.In("package.id",
+		builder.Select("package_alias.package_id").
+			From("package_alias").
+			Where(builder.Eq{"package_alias.lower_name": lowerName}.
+				And(builder.Expr("package_alias.owner_id = package.owner_id AND package_alias.type = package.type"))),
+	))
+}
@@ -0,0 +1,115 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages_test
+
+import (
+	"testing"
+
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/unittest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPackageAlias(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	insert := func(name string) *packages_model.Package {
+		p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+			OwnerID:   2,
+			Type:      packages_model.TypePyPI,
+			Name:      name,
+			LowerName: name,
+		})
+		assert.NoError(t, err)
+		return p
+	}
+
+	p := insert("foo-bar")
+	other := insert("other")
+
+	pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+		PackageID:    p.ID,
+		Version:      "1.0.0",
+		LowerVersion: "1.0.0",
+	})
+	assert.NoError(t, err)
+
+	t.Run("Collision", func(t *testing.T) {
+		// a package can't be an alias of itself
+		assert.ErrorIs(t, packages_model.AddAlias(db.DefaultContext, p, "Foo-Bar"), packages_model.ErrAliasCollision)
+		assert.ErrorIs(t, packages_model.AddAlias(db.DefaultContext, p, "other"), packages_model.ErrAliasCollision)
+		assert.ErrorIs(t, packages_model.AddAlias(db.DefaultContext, other, "foo-bar"), packages_model.ErrAliasCollision)
+
+		unittest.AssertNotExistsBean(t, &packages_model.PackageAlias{OwnerID: 2, Type: packages_model.TypePyPI})
+	})
+
+	t.Run("Add", func(t *testing.T) {
+		assert.NoError(t, packages_model.AddAlias(db.DefaultContext, p, "Foo_Bar"))
+		// adding twice is a no-op
+		assert.NoError(t, packages_model.AddAlias(db.DefaultContext, p, "foo_bar"))
+		assert.ErrorIs(t, packages_model.AddAlias(db.DefaultContext, other, "foo_bar"), packages_model.ErrDuplicateAlias)
+
+		pas, err := packages_model.GetAliasesByPackageID(db.DefaultContext, p.ID)
+		assert.NoError(t, err)
+		assert.Len(t, pas, 1)
+		assert.Equal(t, "foo_bar", pas[0].LowerName)
+	})
+
+	t.Run("Lookup", func(t *testing.T) {
+		found, err := packages_model.GetPackageByName(db.DefaultContext, 2, packages_model.TypePyPI, "Foo_Bar")
+		assert.NoError(t, err)
+		assert.Equal(t, p.ID, found.ID)
+
+		has, err := packages_model.ExistsVersion(db.DefaultContext, 2, packages_model.TypePyPI, "foo_bar", "1.0.0")
+		assert.NoError(t, err)
+		assert.True(t, has)
+
+		pvs, err := packages_model.GetVersionsByPackageName(db.DefaultContext, 2, packages_model.TypePyPI, "foo_bar")
+		assert.NoError(t, err)
+		assert.Len(t, pvs, 1)
+		assert.Equal(t, pv.ID, pvs[0].ID)
+
+		// aliases are scoped by owner and type
+		_, err = packages_model.GetPackageByName(db.DefaultContext, 2, packages_model.TypeGeneric, "foo_bar")
+		assert.ErrorIs(t, err, packages_model.ErrPackageNotExist)
+		_, err = packages_model.GetPackageByName(db.DefaultContext, 3, packages_model.TypePyPI, "foo_bar")
+		assert.ErrorIs(t, err, packages_model.ErrPackageNotExist)
+
+		// a new package with the alias name resolves to the aliased package
+		existing, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+			OwnerID:   2,
+			Type:      packages_model.TypePyPI,
+			Name:      "foo_bar",
+			LowerName: "foo_bar",
+		})
+		assert.ErrorIs(t, err, packages_model.ErrDuplicatePackage)
+		assert.Equal(t, p.ID, existing.ID)
+	})
+
+	t.Run("RenameToAlias", func(t *testing.T) {
+		// renaming a package to its alias must not leave a self-referencing alias
+		assert.NoError(t, packages_model.RenamePackage(db.DefaultContext, p, "foo_bar"))
+		unittest.AssertNotExistsBean(t, &packages_model.PackageAlias{LowerName: "foo_bar"})
+		unittest.AssertExistsAndLoadBean(t, &packages_model.PackageAlias{LowerName: "foo-bar", PackageID: p.ID})
+
+		assert.NoError(t, packages_model.RenamePackage(db.DefaultContext, p, "foo-bar"))
+		unittest.AssertNotExistsBean(t, &packages_model.PackageAlias{LowerName: "foo-bar"})
+		unittest.AssertExistsAndLoadBean(t, &packages_model.PackageAlias{LowerName: "foo_bar", PackageID: p.ID})
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		// only the aliases of the package are removed
+		assert.NoError(t, packages_model.RemoveAlias(db.DefaultContext, other, "foo_bar"))
+		unittest.AssertExistsAndLoadBean(t, &packages_model.PackageAlias{LowerName: "foo_bar", PackageID: p.ID})
+
+		assert.NoError(t, packages_model.RemoveAlias(db.DefaultContext, p, "Foo_Bar"))
+		unittest.AssertNotExistsBean(t, &packages_model.PackageAlias{PackageID: p.ID})
+
+		_, err := packages_model.GetPackageByName(db.DefaultContext, 2, packages_model.TypePyPI, "foo_bar")
+		assert.ErrorIs(t, err, packages_model.ErrPackageNotExist)
+	})
+}
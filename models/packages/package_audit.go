@@ -0,0 +1,116 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/builder"
+)
+
+func init() {
+	db.RegisterModel(new(PackageAudit))
+}
+
+// AuditAction is the kind of operation recorded in the audit log
+type AuditAction string
+
+// List of recorded operations
+const (
+	AuditActionPublish          AuditAction = "publish"
+	AuditActionDeleteVersion    AuditAction = "delete_version"
+	AuditActionDeletePackage    AuditAction = "delete_package"
+	AuditActionLinkRepository   AuditAction = "link_repository"
+	AuditActionTransfer         AuditAction = "transfer"
+	AuditActionRename           AuditAction = "rename"
+	AuditActionYank             AuditAction = "yank"
+	AuditActionUnyank           AuditAction = "unyank"
+	AuditActionMakeImmutable    AuditAction = "make_immutable"
+	AuditActionMakeMutable      AuditAction = "make_mutable"
+	AuditActionChangeVisibility AuditAction = "change_visibility"
+	AuditActionEditReleaseNotes AuditAction = "edit_release_notes"
+)
+
+// PackageAudit records a mutating operation on a package.
+// The package name and version are copied, so the entry stays meaningful after the package is deleted.
+// An ActorID of 0 marks operations executed by the system (e.g. the cleanup task).
+type PackageAudit struct {
+	ID          int64              `xorm:"pk autoincr"`
+	OwnerID     int64              `xorm:"INDEX NOT NULL"`
+	ActorID     int64              `xorm:"INDEX NOT NULL DEFAULT 0"`
+	Action      AuditAction        `xorm:"INDEX NOT NULL"`
+	PackageID   int64              `xorm:"INDEX NOT NULL"`
+	PackageType Type               `xorm:"NOT NULL"`
+	PackageName string             `xorm:"NOT NULL"`
+	VersionID   int64              `xorm:"NOT NULL DEFAULT 0"`
+	Version     string             `xorm:"NOT NULL DEFAULT ''"`
+	Details     string             `xorm:"TEXT"`
+	CreatedUnix timeutil.TimeStamp `xorm:"created INDEX NOT NULL"`
+}
+
+// InsertAudit inserts an audit log entry
+func InsertAudit(ctx context.Context, pa *PackageAudit) error {
+	_, err := db.GetEngine(ctx).Insert(pa)
+	return err
+}
+
+// AuditSearchOptions are options for SearchAudits
+type AuditSearchOptions struct {
+	OwnerID       int64
+	PackageID     int64
+	ActorID       int64
+	Action        AuditAction
+	CreatedAfter  timeutil.TimeStamp
+	CreatedBefore timeutil.TimeStamp
+	db.Paginator
+}
+
+func (opts *AuditSearchOptions) toConds() builder.Cond {
+	cond := builder.NewCond()
+	if opts.OwnerID != 0 {
+		cond = cond.And(builder.Eq{"package_audit.owner_id": opts.OwnerID})
+	}
+	if opts.PackageID != 0 {
+		cond = cond.And(builder.Eq{"package_audit.package_id": opts.PackageID})
+	}
+	if opts.ActorID != 0 {
+		cond = cond.And(builder.Eq{"package_audit.actor_id": opts.ActorID})
+	}
+	if opts.Action != "" {
+		cond = cond.And(builder.Eq{"package_audit.action": opts.Action})
+	}
+	if opts.CreatedAfter != 0 {
+		cond = cond.And(builder.Gte{"package_audit.created_unix": opts.CreatedAfter})
+	}
+	if opts.CreatedBefore != 0 {
+		cond = cond.And(builder.Lt{"package_audit.created_unix": opts.CreatedBefore})
+	}
+	return cond
+}
+
+// SearchAudits gets the audit log entries matching the search options, newest first
+func SearchAudits(ctx context.Context, opts *AuditSearchOptions) ([]*PackageAudit, int64, error) {
+	sess := db.GetEngine(ctx).
+		Where(opts.toConds()).
+		OrderBy("package_audit.created_unix DESC, package_audit.id DESC")
+
+	if opts.Paginator != nil {
+		sess = db.SetSessionPagination(sess, opts)
+	}
+
+	pas := make([]*PackageAudit, 0, 10)
+	count, err := sess.FindAndCount(&pas)
+	return pas, count, err
+}
+
+// DeleteAuditsOlderThan deletes all audit log entries created before the timestamp
+func DeleteAuditsOlderThan(ctx context.Context, olderThan timeutil.TimeStamp) (int64, error) {
+	return db.GetEngine(ctx).
+		Where(builder.Lt{"created_unix": olderThan}).
+		Delete(&PackageAudit{})
+}
@@ -0,0 +1,87 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages_test
+
+import (
+	"testing"
+	"time"
+
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/unittest"
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPackageAudit(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	const ownerID = 9
+
+	now := time.Now()
+	insertAudit := func(packageID, actorID int64, action packages_model.AuditAction, age time.Duration) *packages_model.PackageAudit {
+		pa := &packages_model.PackageAudit{
+			OwnerID:     ownerID,
+			ActorID:     actorID,
+			Action:      action,
+			PackageID:   packageID,
+			PackageType: packages_model.TypeGeneric,
+			PackageName: "audit-package",
+		}
+		assert.NoError(t, packages_model.InsertAudit(db.DefaultContext, pa))
+
+		pa.CreatedUnix = timeutil.TimeStamp(now.Add(-age).Unix())
+		_, err := db.GetEngine(db.DefaultContext).ID(pa.ID).Cols("created_unix").NoAutoTime().Update(pa)
+		assert.NoError(t, err)
+		return pa
+	}
+
+	old := insertAudit(1001, 2, packages_model.AuditActionPublish, 48*time.Hour)
+	published := insertAudit(1001, 2, packages_model.AuditActionPublish, time.Hour)
+	deleted := insertAudit(1001, 0, packages_model.AuditActionDeleteVersion, time.Minute)
+	other := insertAudit(1002, 4, packages_model.AuditActionTransfer, time.Minute)
+
+	search := func(opts *packages_model.AuditSearchOptions) []int64 {
+		pas, count, err := packages_model.SearchAudits(db.DefaultContext, opts)
+		assert.NoError(t, err)
+		ids := make([]int64, 0, len(pas))
+		for _, pa := range pas {
+			ids = append(ids, pa.ID)
+		}
+		if opts.Paginator == nil {
+			assert.EqualValues(t, len(pas), count)
+		}
+		return ids
+	}
+
+	// newest entries come first
+	assert.Equal(t, []int64{deleted.ID, published.ID, old.ID}, search(&packages_model.AuditSearchOptions{OwnerID: ownerID, PackageID: 1001}))
+	assert.Equal(t, []int64{published.ID, old.ID}, search(&packages_model.AuditSearchOptions{PackageID: 1001, ActorID: 2}))
+	assert.Equal(t, []int64{deleted.ID}, search(&packages_model.AuditSearchOptions{PackageID: 1001, Action: packages_model.AuditActionDeleteVersion}))
+	assert.Equal(t, []int64{other.ID}, search(&packages_model.AuditSearchOptions{OwnerID: ownerID, Action: packages_model.AuditActionTransfer}))
+
+	// time range
+	assert.Equal(t, []int64{published.ID}, search(&packages_model.AuditSearchOptions{
+		PackageID:     1001,
+		CreatedAfter:  timeutil.TimeStamp(now.Add(-2 * time.Hour).Unix()),
+		CreatedBefore: timeutil.TimeStamp(now.Add(-30 * time.Minute).Unix()),
+	}))
+
+	// pagination
+	pas, count, err := packages_model.SearchAudits(db.DefaultContext, &packages_model.AuditSearchOptions{
+		PackageID: 1001,
+		Paginator: db.NewAbsoluteListOptions(1, 1),
+	})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3, count)
+	assert.Len(t, pas, 1)
+	assert.Equal(t, published.ID, pas[0].ID)
+
+	deletedCount, err := packages_model.DeleteAuditsOlderThan(db.DefaultContext, timeutil.TimeStamp(now.Add(-24*time.Hour).Unix()))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, deletedCount)
+	assert.Equal(t, []int64{deleted.ID, published.ID}, search(&packages_model.AuditSearchOptions{PackageID: 1001}))
+}
@@ -7,10 +7,14 @@ package packages
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/builder"
 )
 
 // ErrPackageBlobNotExist indicates a package blob not exist error
@@ -29,23 +33,93 @@ type PackageBlob struct {
 	HashSHA256  string             `xorm:"hash_sha256 char(64) UNIQUE(sha256) INDEX NOT NULL"`
 	HashSHA512  string             `xorm:"hash_sha512 char(128) UNIQUE(sha512) INDEX NOT NULL"`
 	CreatedUnix timeutil.TimeStamp `xorm:"created INDEX NOT NULL"`
+	// ReferenceCount is the number of files referencing the blob, it is maintained by TryInsertFile and DeleteFileByID
+	ReferenceCount int64 `xorm:"INDEX NOT NULL DEFAULT 0"`
 }
 
-// GetOrInsertBlob inserts a blob. If the blob exists already the existing blob is returned
+// GetOrInsertBlob inserts a blob. If a blob with the same size and hash exists already the existing blob is returned.
+// The returned bool is true if the blob was inserted.
 func GetOrInsertBlob(ctx context.Context, pb *PackageBlob) (*PackageBlob, bool, error) {
-	e := db.GetEngine(ctx)
-
-	has, err := e.Get(pb)
+	existing, err := getBlobBySizeAndHash(ctx, pb.Size, pb.HashSHA256, false)
 	if err != nil {
 		return nil, false, err
 	}
-	if has {
-		return pb, true, nil
+	if existing != nil {
+		return existing, false, nil
+	}
+
+	inserted, err := insertBlobIfNotExist(ctx, pb)
+	if err != nil {
+		return nil, false, err
 	}
-	if _, err = e.Insert(pb); err != nil {
+
+	// the blob may have been inserted by a concurrent upload of the same content in the meantime,
+	// so read the current row instead of the (possibly outdated) transaction snapshot
+	existing, err = getBlobBySizeAndHash(ctx, pb.Size, pb.HashSHA256, true)
+	if err != nil {
 		return nil, false, err
 	}
-	return pb, false, nil
+	if existing == nil {
+		return nil, false, ErrPackageBlobNotExist
+	}
+	return existing, inserted, nil
+}
+
+// insertBlobIfNotExist inserts the blob without failing if a blob with the same hash exists already.
+// A failing insert would abort the whole (upload) transaction on some databases.
+func insertBlobIfNotExist(ctx context.Context, pb *PackageBlob) (bool, error) {
+	pb.CreatedUnix = timeutil.TimeStampNow()
+
+	columns := "(`size`, `hash_md5`, `hash_sha1`, `hash_sha256`, `hash_sha512`, `created_unix`, `reference_count`)"
+
+	var query string
+	switch {
+	case setting.Database.UseSQLite3 || setting.Database.UsePostgreSQL:
+		query = "INSERT INTO `package_blob` " + columns + " VALUES (?,?,?,?,?,?,0) ON CONFLICT DO NOTHING"
+	case setting.Database.UseMySQL:
+		query = "INSERT INTO `package_blob` " + columns + " VALUES (?,?,?,?,?,?,0) ON DUPLICATE KEY UPDATE `id` = `id`"
+	case setting.Database.UseMSSQL:
+		// https://weblogs.sqlteam.com/dang/2009/01/31/upsert-race-condition-with-merge/
+		query = "MERGE `package_blob` WITH (HOLDLOCK) AS target " +
+			"USING (SELECT ? AS size, ? AS hash_md5, ? AS hash_sha1, ? AS hash_sha256, ? AS hash_sha512, ? AS created_unix) AS src " +
+			"ON src.hash_sha256 = target.hash_sha256 " +
+			"WHEN NOT MATCHED THEN INSERT " + columns + " " +
+			"VALUES (src.size, src.hash_md5, src.hash_sha1, src.hash_sha256, src.hash_sha512, src.created_unix, 0);"
+	default:
+		return false, fmt.Errorf("database type not supported")
+	}
+
+	res, err := db.Exec(ctx, query, pb.Size, pb.HashMD5, pb.HashSHA1, pb.HashSHA256, pb.HashSHA512, pb.CreatedUnix)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func getBlobBySizeAndHash(ctx context.Context, size int64, hashSHA256 string, forUpdate bool) (*PackageBlob, error) {
+	pb := &PackageBlob{}
+
+	sess := db.GetEngine(ctx).Where(builder.Eq{
+		"size":        size,
+		"hash_sha256": hashSHA256,
+	})
+	if forUpdate {
+		sess = sess.ForUpdate()
+	}
+
+	has, err := sess.Get(pb)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, nil
+	}
+	return pb, nil
 }
 
 // GetBlobByID gets a blob by id
@@ -62,16 +136,85 @@ func GetBlobByID(ctx context.Context, blobID int64) (*PackageBlob, error) {
 	return pb, nil
 }
 
-// FindExpiredUnreferencedBlobs gets all blobs without associated files older than the specific duration
+// FindExpiredUnreferencedBlobs gets all blobs without associated files older than the specific duration.
+// The blobs are found by their reference count. As protection against drifted counts,
+// blobs which are still referenced by a file are skipped.
 func FindExpiredUnreferencedBlobs(ctx context.Context, olderThan time.Duration) ([]*PackageBlob, error) {
 	pbs := make([]*PackageBlob, 0, 10)
 	return pbs, db.GetEngine(ctx).
-		Table("package_blob").
-		Join("LEFT", "package_file", "package_file.blob_id = package_blob.id").
-		Where("package_file.id IS NULL AND package_blob.created_unix < ?", time.Now().Add(-olderThan).Unix()).
+		Where(unreferencedBlobCond()).
+		And(builder.Lt{"package_blob.created_unix": time.Now().Add(-olderThan).Unix()}).
+		Find(&pbs)
+}
+
+// unreferencedBlobCond matches the blobs with a reference count of 0 which are really not referenced by a file
+func unreferencedBlobCond() builder.Cond {
+	return builder.Eq{"package_blob.reference_count": 0}.
+		And(builder.NotExists(builder.Select("package_file.id").From("package_file").Where(builder.Expr("package_file.blob_id = package_blob.id"))))
+}
+
+// BlobsToGarbageCollect gets up to batchSize blobs without associated files regardless of their age.
+// The content of the blobs must be removed from the storage before their records are deleted with DeleteBlobRecords.
+func BlobsToGarbageCollect(ctx context.Context, batchSize int) ([]*PackageBlob, error) {
+	pbs := make([]*PackageBlob, 0, batchSize)
+	return pbs, db.GetEngine(ctx).
+		Where(unreferencedBlobCond()).
+		OrderBy("package_blob.id").
+		Limit(batchSize).
 		Find(&pbs)
 }
 
+// DeleteBlobRecords deletes the records of the blobs after their content was removed from the storage.
+// Blobs which got referenced by a file in the meantime are kept.
+func DeleteBlobRecords(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := db.GetEngine(ctx).
+		Where(builder.In("package_blob.id", ids).And(unreferencedBlobCond())).
+		Delete(&PackageBlob{})
+	return err
+}
+
+// CountOrphanedBlobs counts all blobs without associated files regardless of their age
+func CountOrphanedBlobs(ctx context.Context) (int64, error) {
+	return db.GetEngine(ctx).
+		Where(builder.Eq{"reference_count": 0}).
+		Count(&PackageBlob{})
+}
+
+// GetOrphanedBlobsSize returns the size in bytes of all blobs without associated files regardless of their age
+func GetOrphanedBlobsSize(ctx context.Context) (int64, error) {
+	return db.GetEngine(ctx).
+		Where(builder.Eq{"reference_count": 0}).
+		SumInt(&PackageBlob{}, "size")
+}
+
+// updateBlobReferenceCount changes the reference count of a blob by delta
+func updateBlobReferenceCount(ctx context.Context, blobID, delta int64) error {
+	_, err := db.GetEngine(ctx).Exec("UPDATE `package_blob` SET `reference_count` = `reference_count` + ? WHERE `id` = ?", delta, blobID)
+	return err
+}
+
+// actualReferenceCountSubQuery is the number of files referencing the blob of the outer query
+const actualReferenceCountSubQuery = "(SELECT COUNT(*) FROM `package_file` WHERE `package_file`.`blob_id` = `package_blob`.`id`)"
+
+// CountBlobsWithWrongReferenceCount counts the blobs whose reference count does not match the number of files referencing them
+func CountBlobsWithWrongReferenceCount(ctx context.Context) (int64, error) {
+	return db.GetEngine(ctx).
+		Where("`package_blob`.`reference_count` <> " + actualReferenceCountSubQuery).
+		Count(&PackageBlob{})
+}
+
+// FixBlobReferenceCounts recalculates the reference counts of all blobs and returns the number of corrected blobs
+func FixBlobReferenceCounts(ctx context.Context) (int64, error) {
+	res, err := db.GetEngine(ctx).Exec("UPDATE `package_blob` SET `reference_count` = " + actualReferenceCountSubQuery + " WHERE `reference_count` <> " + actualReferenceCountSubQuery)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
 // DeleteBlobByID deletes a blob by id
 func DeleteBlobByID(ctx context.Context, blobID int64) error {
 	_, err := db.GetEngine(ctx).ID(blobID).Delete(&PackageBlob{})
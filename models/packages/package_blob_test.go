@@ -0,0 +1,290 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages_test
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/unittest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetOrInsertBlob(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	newBlob := func() *packages_model.PackageBlob {
+		content := []byte("get-or-insert-blob")
+		hashMD5 := md5.Sum(content)
+		hashSHA1 := sha1.Sum(content)
+		hashSHA256 := sha256.Sum256(content)
+		hashSHA512 := sha512.Sum512(content)
+		return &packages_model.PackageBlob{
+			Size:       int64(len(content)),
+			HashMD5:    hex.EncodeToString(hashMD5[:]),
+			HashSHA1:   hex.EncodeToString(hashSHA1[:]),
+			HashSHA256: hex.EncodeToString(hashSHA256[:]),
+			HashSHA512: hex.EncodeToString(hashSHA512[:]),
+		}
+	}
+
+	pb1, created, err := packages_model.GetOrInsertBlob(db.DefaultContext, newBlob())
+	assert.NoError(t, err)
+	assert.True(t, created)
+	assert.NotZero(t, pb1.ID)
+
+	pb2, created, err := packages_model.GetOrInsertBlob(db.DefaultContext, newBlob())
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, pb1.ID, pb2.ID)
+
+	unittest.AssertCount(t, &packages_model.PackageBlob{HashSHA256: pb1.HashSHA256}, 1)
+}
+
+func TestGetOrInsertBlobConcurrent(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	content := []byte("get-or-insert-blob-concurrent")
+	hashMD5 := md5.Sum(content)
+	hashSHA1 := sha1.Sum(content)
+	hashSHA256 := sha256.Sum256(content)
+	hashSHA512 := sha512.Sum512(content)
+
+	const workers = 10
+
+	var created int64
+	ids := make([]int64, workers)
+
+	// concurrent uploads of the same content must not fail because of the unique hash constraints
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, db.WithTx(func(ctx context.Context) error {
+				pb, inserted, err := packages_model.GetOrInsertBlob(ctx, &packages_model.PackageBlob{
+					Size:       int64(len(content)),
+					HashMD5:    hex.EncodeToString(hashMD5[:]),
+					HashSHA1:   hex.EncodeToString(hashSHA1[:]),
+					HashSHA256: hex.EncodeToString(hashSHA256[:]),
+					HashSHA512: hex.EncodeToString(hashSHA512[:]),
+				})
+				if err != nil {
+					return err
+				}
+				if inserted {
+					atomic.AddInt64(&created, 1)
+				}
+				ids[i] = pb.ID
+				return nil
+			}))
+		}(i)
+	}
+	wg.Wait()
+
+	assert.EqualValues(t, 1, created)
+	for _, id := range ids {
+		assert.NotZero(t, id)
+		assert.Equal(t, ids[0], id)
+	}
+
+	unittest.AssertCount(t, &packages_model.PackageBlob{HashSHA256: hex.EncodeToString(hashSHA256[:])}, 1)
+}
+
+func TestOrphanedBlobs(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	orphaned := func() (int64, int64) {
+		count, err := packages_model.CountOrphanedBlobs(db.DefaultContext)
+		assert.NoError(t, err)
+		size, err := packages_model.GetOrphanedBlobsSize(db.DefaultContext)
+		assert.NoError(t, err)
+		return count, size
+	}
+
+	startCount, startSize := orphaned()
+
+	pb := insertTestBlob(t, "orphaned-blob")
+
+	count, size := orphaned()
+	assert.Equal(t, startCount+1, count)
+	assert.Equal(t, startSize+pb.Size, size)
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   1,
+		Type:      packages_model.TypeGeneric,
+		Name:      "orphaned-blob-package",
+		LowerName: "orphaned-blob-package",
+	})
+	assert.NoError(t, err)
+	pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+		PackageID:    p.ID,
+		Version:      "1.0.0",
+		LowerVersion: "1.0.0",
+	})
+	assert.NoError(t, err)
+	_, err = packages_model.TryInsertFile(db.DefaultContext, &packages_model.PackageFile{
+		VersionID: pv.ID,
+		BlobID:    pb.ID,
+		Name:      "file.bin",
+		LowerName: "file.bin",
+	})
+	assert.NoError(t, err)
+
+	count, size = orphaned()
+	assert.Equal(t, startCount, count)
+	assert.Equal(t, startSize, size)
+}
+
+func TestBlobReferenceCount(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	pb := insertTestBlob(t, "reference-count-blob")
+
+	referenceCount := func() int64 {
+		pb, err := packages_model.GetBlobByID(db.DefaultContext, pb.ID)
+		assert.NoError(t, err)
+		return pb.ReferenceCount
+	}
+	isExpired := func() bool {
+		pbs, err := packages_model.FindExpiredUnreferencedBlobs(db.DefaultContext, -time.Hour)
+		assert.NoError(t, err)
+		for _, expired := range pbs {
+			if expired.ID == pb.ID {
+				return true
+			}
+		}
+		return false
+	}
+	setReferenceCount := func(count int64) {
+		_, err := db.GetEngine(db.DefaultContext).ID(pb.ID).Cols("reference_count").Update(&packages_model.PackageBlob{ReferenceCount: count})
+		assert.NoError(t, err)
+	}
+
+	assert.EqualValues(t, 0, referenceCount())
+	assert.True(t, isExpired())
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeGeneric,
+		Name:      "reference-count-package",
+		LowerName: "reference-count-package",
+	})
+	assert.NoError(t, err)
+
+	pfs := make([]*packages_model.PackageFile, 0, 2)
+	for _, version := range []string{"1.0.0", "2.0.0"} {
+		pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			Version:      version,
+			LowerVersion: version,
+		})
+		assert.NoError(t, err)
+		pf, err := packages_model.TryInsertFile(db.DefaultContext, &packages_model.PackageFile{
+			VersionID: pv.ID,
+			BlobID:    pb.ID,
+			Name:      "file.bin",
+			LowerName: "file.bin",
+		})
+		assert.NoError(t, err)
+		pfs = append(pfs, pf)
+	}
+
+	assert.EqualValues(t, 2, referenceCount())
+	assert.False(t, isExpired())
+
+	// a blob with a drifted count of zero is still protected by its files
+	setReferenceCount(0)
+	assert.False(t, isExpired())
+
+	count, err := packages_model.CountBlobsWithWrongReferenceCount(db.DefaultContext)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, count)
+
+	fixed, err := packages_model.FixBlobReferenceCounts(db.DefaultContext)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, fixed)
+	assert.EqualValues(t, 2, referenceCount())
+
+	count, err = packages_model.CountBlobsWithWrongReferenceCount(db.DefaultContext)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, count)
+
+	assert.NoError(t, packages_model.DeleteFileByID(db.DefaultContext, pfs[0].ID))
+	assert.EqualValues(t, 1, referenceCount())
+	assert.False(t, isExpired())
+
+	assert.NoError(t, packages_model.DeleteFileByID(db.DefaultContext, pfs[1].ID))
+	assert.EqualValues(t, 0, referenceCount())
+	assert.True(t, isExpired())
+}
+
+func TestBlobsToGarbageCollect(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	pb := insertTestBlob(t, "garbage-collect-blob")
+
+	isPending := func() bool {
+		pbs, err := packages_model.BlobsToGarbageCollect(db.DefaultContext, 1000)
+		assert.NoError(t, err)
+		for _, pending := range pbs {
+			if pending.ID == pb.ID {
+				return true
+			}
+		}
+		return false
+	}
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeGeneric,
+		Name:      "garbage-collect-package",
+		LowerName: "garbage-collect-package",
+	})
+	assert.NoError(t, err)
+	pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+		PackageID:    p.ID,
+		Version:      "1.0.0",
+		LowerVersion: "1.0.0",
+	})
+	assert.NoError(t, err)
+	pf, err := packages_model.TryInsertFile(db.DefaultContext, &packages_model.PackageFile{
+		VersionID: pv.ID,
+		BlobID:    pb.ID,
+		Name:      "file.bin",
+		LowerName: "file.bin",
+	})
+	assert.NoError(t, err)
+
+	assert.False(t, isPending())
+
+	// a referenced blob is never deleted
+	assert.NoError(t, packages_model.DeleteBlobRecords(db.DefaultContext, []int64{pb.ID}))
+	unittest.AssertExistsAndLoadBean(t, &packages_model.PackageBlob{ID: pb.ID})
+
+	assert.NoError(t, packages_model.DeleteFileByID(db.DefaultContext, pf.ID))
+	assert.True(t, isPending())
+
+	pbs, err := packages_model.BlobsToGarbageCollect(db.DefaultContext, 1)
+	assert.NoError(t, err)
+	assert.Len(t, pbs, 1)
+
+	assert.NoError(t, packages_model.DeleteBlobRecords(db.DefaultContext, []int64{pb.ID}))
+	unittest.AssertNotExistsBean(t, &packages_model.PackageBlob{ID: pb.ID})
+	assert.False(t, isPending())
+
+	assert.NoError(t, packages_model.DeleteBlobRecords(db.DefaultContext, nil))
+}
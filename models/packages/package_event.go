@@ -0,0 +1,77 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"sync"
+)
+
+// PackageEventType is the kind of change of a package
+type PackageEventType string
+
+// List of package event types
+const (
+	PackageEventCreate   PackageEventType = "create"
+	PackageEventDelete   PackageEventType = "delete"
+	PackageEventTransfer PackageEventType = "transfer"
+)
+
+// PackageEvent describes a change of a package
+type PackageEvent struct {
+	Type      PackageEventType
+	PackageID int64
+	OwnerID   int64
+	// PreviousOwnerID is the owner before a transfer, it is 0 for other events
+	PreviousOwnerID int64
+	PackageType     Type
+	Name            string
+}
+
+// PackageEventSink receives the events of package changes, e.g. to record them in an audit log.
+// The events are emitted after the change was written, but the surrounding transaction may still be rolled back.
+// Implementations must not block.
+type PackageEventSink interface {
+	PackageEvent(ctx context.Context, event *PackageEvent)
+}
+
+type noopPackageEventSink struct{}
+
+func (noopPackageEventSink) PackageEvent(context.Context, *PackageEvent) {}
+
+var (
+	eventSinkLock sync.RWMutex
+	eventSink     PackageEventSink = noopPackageEventSink{}
+)
+
+// SetPackageEventSink sets the sink which receives the package events and returns the previous sink.
+// A nil sink restores the default sink which discards the events.
+func SetPackageEventSink(sink PackageEventSink) PackageEventSink {
+	if sink == nil {
+		sink = noopPackageEventSink{}
+	}
+
+	eventSinkLock.Lock()
+	defer eventSinkLock.Unlock()
+
+	previous := eventSink
+	eventSink = sink
+	return previous
+}
+
+func emitPackageEvent(ctx context.Context, eventType PackageEventType, p *Package, previousOwnerID int64) {
+	eventSinkLock.RLock()
+	sink := eventSink
+	eventSinkLock.RUnlock()
+
+	sink.PackageEvent(ctx, &PackageEvent{
+		Type:            eventType,
+		PackageID:       p.ID,
+		OwnerID:         p.OwnerID,
+		PreviousOwnerID: previousOwnerID,
+		PackageType:     p.Type,
+		Name:            p.Name,
+	})
+}
@@ -0,0 +1,91 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages_test
+
+import (
+	"context"
+	"testing"
+
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/unittest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingEventSink struct {
+	events []*packages_model.PackageEvent
+}
+
+func (s *recordingEventSink) PackageEvent(_ context.Context, event *packages_model.PackageEvent) {
+	s.events = append(s.events, event)
+}
+
+func TestPackageEventSink(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	sink := &recordingEventSink{}
+	previous := packages_model.SetPackageEventSink(sink)
+	defer packages_model.SetPackageEventSink(previous)
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeGeneric,
+		Name:      "event-sink",
+		LowerName: "event-sink",
+	})
+	assert.NoError(t, err)
+
+	// no event is emitted for an existing package
+	_, err = packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeGeneric,
+		Name:      "event-sink",
+		LowerName: "event-sink",
+	})
+	assert.ErrorIs(t, err, packages_model.ErrDuplicatePackage)
+
+	assert.NoError(t, packages_model.TransferOwnership(db.DefaultContext, p, 5))
+	assert.NoError(t, packages_model.DeletePackageByID(db.DefaultContext, p.ID))
+
+	// no event is emitted for a package which does not exist
+	assert.NoError(t, packages_model.DeletePackageByID(db.DefaultContext, p.ID))
+
+	assert.Equal(t, []*packages_model.PackageEvent{
+		{
+			Type:        packages_model.PackageEventCreate,
+			PackageID:   p.ID,
+			OwnerID:     2,
+			PackageType: packages_model.TypeGeneric,
+			Name:        "event-sink",
+		},
+		{
+			Type:            packages_model.PackageEventTransfer,
+			PackageID:       p.ID,
+			OwnerID:         5,
+			PreviousOwnerID: 2,
+			PackageType:     packages_model.TypeGeneric,
+			Name:            "event-sink",
+		},
+		{
+			Type:        packages_model.PackageEventDelete,
+			PackageID:   p.ID,
+			OwnerID:     5,
+			PackageType: packages_model.TypeGeneric,
+			Name:        "event-sink",
+		},
+	}, sink.events)
+
+	// the default sink discards the events
+	packages_model.SetPackageEventSink(nil)
+	_, err = packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeGeneric,
+		Name:      "event-sink-discarded",
+		LowerName: "event-sink-discarded",
+	})
+	assert.NoError(t, err)
+	assert.Len(t, sink.events, 3)
+}
@@ -7,7 +7,6 @@ package packages
 import (
 	"context"
 	"errors"
-	"strconv"
 	"strings"
 	"time"
 
@@ -33,14 +32,15 @@ const EmptyFileKey = ""
 
 // PackageFile represents a package file
 type PackageFile struct {
-	ID           int64              `xorm:"pk autoincr"`
-	VersionID    int64              `xorm:"UNIQUE(s) INDEX NOT NULL"`
-	BlobID       int64              `xorm:"INDEX NOT NULL"`
-	Name         string             `xorm:"NOT NULL"`
-	LowerName    string             `xorm:"UNIQUE(s) INDEX NOT NULL"`
-	CompositeKey string             `xorm:"UNIQUE(s) INDEX"`
-	IsLead       bool               `xorm:"NOT NULL DEFAULT false"`
-	CreatedUnix  timeutil.TimeStamp `xorm:"created INDEX NOT NULL"`
+	ID            int64              `xorm:"pk autoincr"`
+	VersionID     int64              `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	BlobID        int64              `xorm:"INDEX NOT NULL"`
+	Name          string             `xorm:"NOT NULL"`
+	LowerName     string             `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	CompositeKey  string             `xorm:"UNIQUE(s) INDEX"`
+	IsLead        bool               `xorm:"NOT NULL DEFAULT false"`
+	DownloadCount int64              `xorm:"NOT NULL DEFAULT 0"`
+	CreatedUnix   timeutil.TimeStamp `xorm:"created INDEX NOT NULL"`
 }
 
 // TryInsertFile inserts a file. If the file exists already ErrDuplicatePackageFile is returned
@@ -63,6 +63,12 @@ func TryInsertFile(ctx context.Context, pf *PackageFile) (*PackageFile, error) {
 	if _, err = e.Insert(pf); err != nil {
 		return nil, err
 	}
+	if err := updateBlobReferenceCount(ctx, pf.BlobID, 1); err != nil {
+		return nil, err
+	}
+	if err := updateQuotaForFile(ctx, pf, true); err != nil {
+		return nil, err
+	}
 	return pf, nil
 }
 
@@ -110,10 +116,85 @@ func GetFileForVersionByName(ctx context.Context, versionID int64, name, key str
 	return pf, nil
 }
 
+// GetVersionFileByName gets a file of a version by its case-insensitive name regardless of the composite key.
+// If several files have the name, the first created one is returned.
+func GetVersionFileByName(ctx context.Context, versionID int64, fileName string) (*PackageFile, error) {
+	if fileName == "" {
+		return nil, ErrPackageFileNotExist
+	}
+
+	pf := &PackageFile{}
+	has, err := db.GetEngine(ctx).
+		Where("version_id = ? AND lower_name = ?", versionID, strings.ToLower(fileName)).
+		OrderBy("id").
+		Get(pf)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, ErrPackageFileNotExist
+	}
+	return pf, nil
+}
+
+// FindFilesByHash gets all files whose blob matches the hash. The hash may be prefixed with the algorithm (e.g. "sha256:").
+// The algorithm is derived from the hash length. If ownerID is 0, files of all owners are returned.
+func FindFilesByHash(ctx context.Context, ownerID int64, hash string) ([]*PackageFile, error) {
+	hash = strings.ToLower(hash)
+	if idx := strings.IndexByte(hash, ':'); idx != -1 {
+		hash = hash[idx+1:]
+	}
+
+	var cond builder.Cond
+	switch len(hash) {
+	case 32:
+		cond = builder.Eq{"package_blob.hash_md5": hash}
+	case 40:
+		cond = builder.Eq{"package_blob.hash_sha1": hash}
+	case 64:
+		cond = builder.Eq{"package_blob.hash_sha256": hash}
+	case 128:
+		cond = builder.Eq{"package_blob.hash_sha512": hash}
+	default:
+		return []*PackageFile{}, nil
+	}
+	if ownerID != 0 {
+		cond = cond.And(builder.Eq{"package.owner_id": ownerID})
+	}
+
+	pfs := make([]*PackageFile, 0, 10)
+	return pfs, db.GetEngine(ctx).
+		Table("package_file").
+		Join("INNER", "package_blob", "package_blob.id = package_file.blob_id").
+		Join("INNER", "package_version", "package_version.id = package_file.version_id").
+		Join("INNER", "package", "package.id = package_version.package_id").
+		Where(cond).
+		Find(&pfs)
+}
+
+// IncrementFileDownloadCounter increments the download counter of a file
+func IncrementFileDownloadCounter(ctx context.Context, fileID int64) error {
+	_, err := db.GetEngine(ctx).Exec("UPDATE `package_file` SET `download_count` = `download_count` + 1 WHERE `id` = ?", fileID)
+	return err
+}
+
 // DeleteFileByID deletes a file
 func DeleteFileByID(ctx context.Context, fileID int64) error {
-	_, err := db.GetEngine(ctx).ID(fileID).Delete(&PackageFile{})
-	return err
+	e := db.GetEngine(ctx)
+
+	pf := &PackageFile{}
+	has, err := e.ID(fileID).Get(pf)
+	if err != nil || !has {
+		return err
+	}
+	if err := updateQuotaForFile(ctx, pf, false); err != nil {
+		return err
+	}
+
+	if _, err = e.ID(fileID).Delete(&PackageFile{}); err != nil {
+		return err
+	}
+	return updateBlobReferenceCount(ctx, pf.BlobID, -1)
 }
 
 // PackageFileSearchOptions are options for SearchXXX methods
@@ -123,8 +204,9 @@ type PackageFileSearchOptions struct {
 	VersionID    int64
 	Query        string
 	CompositeKey string
-	Properties   map[string]string
+	Properties   map[string]string // only files are found which have all listed file properties with the specific value
 	OlderThan    time.Duration
+	HidePrivate  bool // files of private packages are excluded, only used together with OwnerID or PackageType
 	db.Paginator
 }
 
@@ -143,6 +225,9 @@ func (opts *PackageFileSearchOptions) toConds() builder.Cond {
 		if opts.PackageType != "" && opts.PackageType != "all" {
 			versionCond = versionCond.And(builder.Eq{"package.type": opts.PackageType})
 		}
+		if opts.HidePrivate {
+			versionCond = versionCond.And(builder.Not{PrivatePackageCond()})
+		}
 
 		in := builder.
 			Select("package_version.id").
@@ -160,23 +245,7 @@ func (opts *PackageFileSearchOptions) toConds() builder.Cond {
 	}
 
 	if len(opts.Properties) != 0 {
-		var propsCond builder.Cond = builder.Eq{
-			"package_property.ref_type": PropertyTypeFile,
-		}
-		propsCond = propsCond.And(builder.Expr("package_property.ref_id = package_file.id"))
-
-		propsCondBlock := builder.NewCond()
-		for name, value := range opts.Properties {
-			propsCondBlock = propsCondBlock.Or(builder.Eq{
-				"package_property.name":  name,
-				"package_property.value": value,
-			})
-		}
-		propsCond = propsCond.And(propsCondBlock)
-
-		cond = cond.And(builder.Eq{
-			strconv.Itoa(len(opts.Properties)): builder.Select("COUNT(*)").Where(propsCond).From("package_property"),
-		})
+		cond = cond.And(PropertiesCond(PropertyTypeFile, "package_file.id", opts.Properties))
 	}
 
 	if opts.OlderThan != 0 {
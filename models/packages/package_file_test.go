@@ -0,0 +1,278 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages_test
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/unittest"
+	"code.gitea.io/gitea/modules/setting"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/builder"
+)
+
+func TestFindFilesByHash(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeGeneric,
+		Name:      "hash-package",
+		LowerName: "hash-package",
+	})
+	assert.NoError(t, err)
+
+	pb := insertTestBlob(t, "find-files-by-hash")
+
+	for _, version := range []string{"1.0.0", "1.1.0"} {
+		pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			Version:      version,
+			LowerVersion: version,
+		})
+		assert.NoError(t, err)
+
+		_, err = packages_model.TryInsertFile(db.DefaultContext, &packages_model.PackageFile{
+			VersionID: pv.ID,
+			BlobID:    pb.ID,
+			Name:      "file.bin",
+			LowerName: "file.bin",
+		})
+		assert.NoError(t, err)
+	}
+
+	cases := []struct {
+		OwnerID  int64
+		Hash     string
+		Expected int
+	}{
+		{2, pb.HashMD5, 2},
+		{2, pb.HashSHA1, 2},
+		{2, pb.HashSHA256, 2},
+		{2, "sha256:" + pb.HashSHA256, 2},
+		{2, "SHA512:" + pb.HashSHA512, 2},
+		{0, pb.HashSHA256, 2},
+		{3, pb.HashSHA256, 0},
+		{2, "invalid", 0},
+	}
+
+	for _, c := range cases {
+		pfs, err := packages_model.FindFilesByHash(db.DefaultContext, c.OwnerID, c.Hash)
+		assert.NoError(t, err)
+		assert.Len(t, pfs, c.Expected, "owner %d, hash %s", c.OwnerID, c.Hash)
+		for _, pf := range pfs {
+			assert.Equal(t, pb.ID, pf.BlobID)
+		}
+	}
+}
+
+func TestGetVersionFileByName(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeGeneric,
+		Name:      "version-file-by-name",
+		LowerName: "version-file-by-name",
+	})
+	assert.NoError(t, err)
+
+	pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+		PackageID:    p.ID,
+		Version:      "1.0.0",
+		LowerVersion: "1.0.0",
+	})
+	assert.NoError(t, err)
+
+	pb := insertTestBlob(t, "get-version-file-by-name")
+
+	insert := func(name, key string) *packages_model.PackageFile {
+		pf, err := packages_model.TryInsertFile(db.DefaultContext, &packages_model.PackageFile{
+			VersionID:    pv.ID,
+			BlobID:       pb.ID,
+			Name:         name,
+			LowerName:    strings.ToLower(name),
+			CompositeKey: key,
+		})
+		assert.NoError(t, err)
+		return pf
+	}
+
+	readme := insert("README.md", "")
+	first := insert("file.bin", "a")
+	insert("file.bin", "b")
+
+	pf, err := packages_model.GetVersionFileByName(db.DefaultContext, pv.ID, "readme.MD")
+	assert.NoError(t, err)
+	assert.Equal(t, readme.ID, pf.ID)
+	assert.Equal(t, "README.md", pf.Name)
+
+	// the composite key is ignored
+	pf, err = packages_model.GetVersionFileByName(db.DefaultContext, pv.ID, "file.bin")
+	assert.NoError(t, err)
+	assert.Equal(t, first.ID, pf.ID)
+
+	for _, name := range []string{"", "missing.bin", "README"} {
+		_, err = packages_model.GetVersionFileByName(db.DefaultContext, pv.ID, name)
+		assert.ErrorIs(t, err, packages_model.ErrPackageFileNotExist, name)
+	}
+
+	_, err = packages_model.GetVersionFileByName(db.DefaultContext, -1, "README.md")
+	assert.ErrorIs(t, err, packages_model.ErrPackageFileNotExist)
+}
+
+func TestIncrementFileDownloadCounter(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeGeneric,
+		Name:      "file-download-package",
+		LowerName: "file-download-package",
+	})
+	assert.NoError(t, err)
+
+	pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+		PackageID:    p.ID,
+		Version:      "1.0.0",
+		LowerVersion: "1.0.0",
+	})
+	assert.NoError(t, err)
+
+	pb := insertTestBlob(t, "file-download-counter")
+
+	pfs := make([]*packages_model.PackageFile, 0, 2)
+	for _, name := range []string{"a.bin", "b.bin"} {
+		pf, err := packages_model.TryInsertFile(db.DefaultContext, &packages_model.PackageFile{
+			VersionID: pv.ID,
+			BlobID:    pb.ID,
+			Name:      name,
+			LowerName: name,
+		})
+		assert.NoError(t, err)
+		assert.EqualValues(t, 0, pf.DownloadCount)
+		pfs = append(pfs, pf)
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, packages_model.IncrementFileDownloadCounter(db.DefaultContext, pfs[0].ID))
+	}
+
+	pf := unittest.AssertExistsAndLoadBean(t, &packages_model.PackageFile{ID: pfs[0].ID})
+	assert.EqualValues(t, 3, pf.DownloadCount)
+	pf = unittest.AssertExistsAndLoadBean(t, &packages_model.PackageFile{ID: pfs[1].ID})
+	assert.EqualValues(t, 0, pf.DownloadCount)
+}
+
+func TestSearchFilesByProperties(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeGeneric,
+		Name:      "file-property-search",
+		LowerName: "file-property-search",
+	})
+	assert.NoError(t, err)
+	pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+		PackageID:    p.ID,
+		Version:      "1.0.0",
+		LowerVersion: "1.0.0",
+	})
+	assert.NoError(t, err)
+
+	pb := insertTestBlob(t, "file-property-search")
+
+	files := []struct {
+		Name       string
+		Properties [][2]string
+	}{
+		{"stable-amd64.deb", [][2]string{{"distribution", "stable"}, {"architecture", "amd64"}}},
+		{"stable-arm64.deb", [][2]string{{"distribution", "stable"}, {"architecture", "arm64"}}},
+		{"testing-amd64.deb", [][2]string{{"distribution", "testing"}, {"architecture", "amd64"}}},
+		// a property listed multiple times must not affect the matching of the other properties
+		{"stable.deb", [][2]string{{"distribution", "stable"}, {"distribution", "stable"}}},
+		{"none.deb", nil},
+	}
+	for _, f := range files {
+		pf, err := packages_model.TryInsertFile(db.DefaultContext, &packages_model.PackageFile{
+			VersionID: pv.ID,
+			BlobID:    pb.ID,
+			Name:      f.Name,
+			LowerName: f.Name,
+		})
+		assert.NoError(t, err)
+
+		for _, prop := range f.Properties {
+			_, err := packages_model.InsertProperty(db.DefaultContext, packages_model.PropertyTypeFile, pf.ID, prop[0], prop[1])
+			assert.NoError(t, err)
+		}
+	}
+	// version properties are not matched
+	_, err = packages_model.InsertProperty(db.DefaultContext, packages_model.PropertyTypeVersion, pv.ID, "architecture", "i386")
+	assert.NoError(t, err)
+
+	cases := []struct {
+		Properties map[string]string
+		Expected   []string
+	}{
+		{nil, []string{"none.deb", "stable-amd64.deb", "stable-arm64.deb", "stable.deb", "testing-amd64.deb"}},
+		{map[string]string{"distribution": "stable"}, []string{"stable-amd64.deb", "stable-arm64.deb", "stable.deb"}},
+		{map[string]string{"distribution": "stable", "architecture": "amd64"}, []string{"stable-amd64.deb"}},
+		{map[string]string{"architecture": "amd64"}, []string{"stable-amd64.deb", "testing-amd64.deb"}},
+		{map[string]string{"architecture": "i386"}, []string{}},
+		{map[string]string{"distribution": "stable", "component": "main"}, []string{}},
+	}
+
+	for _, c := range cases {
+		pfs, _, err := packages_model.SearchFiles(db.DefaultContext, &packages_model.PackageFileSearchOptions{
+			VersionID:  pv.ID,
+			Properties: c.Properties,
+		})
+		assert.NoError(t, err)
+
+		found := make([]string, 0, len(pfs))
+		for _, pf := range pfs {
+			found = append(found, pf.Name)
+		}
+		sort.Strings(found)
+		assert.Equal(t, c.Expected, found, "properties %v", c.Properties)
+	}
+}
+
+func TestPropertiesCondUsesIndex(t *testing.T) {
+	if !setting.Database.UseSQLite3 {
+		t.Skip("the query plan is only checked with SQLite")
+	}
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	query, args, err := builder.
+		Select("package_file.id").
+		From("package_file").
+		Where(packages_model.PropertiesCond(packages_model.PropertyTypeFile, "package_file.id", map[string]string{
+			"architecture": "amd64",
+			"distribution": "stable",
+		})).
+		ToSQL()
+	assert.NoError(t, err)
+
+	rows, err := db.GetEngine(db.DefaultContext).Query(append([]interface{}{"EXPLAIN QUERY PLAN " + query}, args...)...)
+	assert.NoError(t, err)
+
+	details := make([]string, 0, len(rows))
+	for _, row := range rows {
+		details = append(details, string(row["detail"]))
+	}
+	plan := strings.Join(details, "\n")
+
+	// both property lookups are index searches
+	assert.Equal(t, 2, strings.Count(plan, "USING INDEX IDX_package_property_r_r_n"), plan)
+	assert.NotContains(t, plan, "SCAN package_property", plan)
+}
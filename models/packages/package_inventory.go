@@ -0,0 +1,143 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"strings"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/util"
+
+	"xorm.io/builder"
+)
+
+// packageSizeSubQuery sums the sizes of the distinct blobs referenced by the files of every package.
+// It is joined as package_size, so the sizes of all packages are computed by one aggregation instead of a subquery per row.
+func packageSizeSubQuery() *builder.Builder {
+	return builder.Select("package_id", "SUM(size) AS total_size").
+		From(builder.Select("DISTINCT package_version.package_id", "package_blob.id", "package_blob.size").
+			From("package_file").
+			Join("INNER", "package_version", "package_version.id = package_file.version_id").
+			Join("INNER", "package_blob", "package_blob.id = package_file.blob_id"), "package_size").
+		GroupBy("package_id")
+}
+
+// packagePublishSubQuery gets the creation time of the latest non-internal version of every package.
+// It is joined as package_publish. Packages with only internal versions have no row.
+func packagePublishSubQuery() *builder.Builder {
+	return builder.Select("package_id", "MAX(created_unix) AS last_publish_unix").
+		From(builder.Select("package_id", "created_unix").
+			From("package_version").
+			Where(builder.Eq{"is_internal": false}), "package_publish").
+		GroupBy("package_id")
+}
+
+// InventoryEntry is a package with the storage used by its files and the time its latest version was published
+type InventoryEntry struct {
+	Package         `xorm:"extends"`
+	TotalSize       int64
+	LastPublishUnix timeutil.TimeStamp
+}
+
+// List of supported inventory sort orders besides SortBySize
+const (
+	InventorySortSmallest              = "smallest"
+	InventorySortNewest                = "newest"
+	InventorySortOldest                = "oldest"
+	InventorySortAlphabetically        = "alphabetically"
+	InventorySortReverseAlphabetically = "reversealphabetically"
+)
+
+// InventorySearchOptions are options for SearchInventory
+// All fields are optional and are not used if they have their default value (nil, "", 0)
+type InventorySearchOptions struct {
+	Type            Type
+	OwnerID         int64
+	OwnerIsActive   util.OptionalBool
+	Name            string             // only packages with the substring in their name are found
+	MinSize         int64              // only packages using at least this storage are found
+	MaxSize         int64              // only packages using at most this storage are found
+	PublishedAfter  timeutil.TimeStamp // only packages with a version published at or after the timestamp are found
+	PublishedBefore timeutil.TimeStamp // only packages without a version published at or after the timestamp are found
+	Sort            string
+	db.Paginator
+}
+
+func (opts *InventorySearchOptions) toConds() builder.Cond {
+	cond := builder.NewCond()
+	if opts.Type != "" && opts.Type != "all" {
+		cond = cond.And(builder.Eq{"package.type": opts.Type})
+	}
+	if opts.OwnerID != 0 {
+		cond = cond.And(builder.Eq{"package.owner_id": opts.OwnerID})
+	}
+	if !opts.OwnerIsActive.IsNone() {
+		cond = cond.And(builder.Eq{"`user`.is_active": opts.OwnerIsActive.IsTrue()})
+	}
+	if opts.Name != "" {
+		cond = cond.And(builder.Like{"package.lower_name", strings.ToLower(opts.Name)})
+	}
+	if opts.MinSize > 0 {
+		cond = cond.And(builder.Expr("COALESCE(package_size.total_size, 0) >= ?", opts.MinSize))
+	}
+	if opts.MaxSize > 0 {
+		cond = cond.And(builder.Expr("COALESCE(package_size.total_size, 0) <= ?", opts.MaxSize))
+	}
+	if opts.PublishedAfter != 0 {
+		cond = cond.And(builder.Gte{"package_publish.last_publish_unix": opts.PublishedAfter})
+	}
+	if opts.PublishedBefore != 0 {
+		cond = cond.And(builder.Lt{"package_publish.last_publish_unix": opts.PublishedBefore})
+	}
+	return cond
+}
+
+func (opts *InventorySearchOptions) newSession(ctx context.Context) db.Engine {
+	sess := db.GetEngine(ctx).
+		Table("package").
+		Join("INNER", packagePublishSubQuery(), "package_publish.package_id = package.id").
+		Join("LEFT", packageSizeSubQuery(), "package_size.package_id = package.id")
+	if !opts.OwnerIsActive.IsNone() {
+		sess = sess.Join("INNER", "`user`", "`user`.id = package.owner_id")
+	}
+	return sess.Where(opts.toConds())
+}
+
+// SearchInventory gets the packages matching the search options with their used storage and the time of their latest version.
+// Packages with only internal versions are not listed.
+func SearchInventory(ctx context.Context, opts *InventorySearchOptions) ([]*InventoryEntry, int64, error) {
+	count, err := opts.newSession(ctx).Count(&Package{})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sess := opts.newSession(ctx).
+		Select("package.*, COALESCE(package_size.total_size, 0) AS total_size, package_publish.last_publish_unix")
+
+	switch opts.Sort {
+	case SortBySize:
+		sess.OrderBy("total_size DESC")
+	case InventorySortSmallest:
+		sess.OrderBy("total_size ASC")
+	case InventorySortOldest:
+		sess.Asc("package_publish.last_publish_unix")
+	case InventorySortAlphabetically:
+		sess.Asc("package.lower_name")
+	case InventorySortReverseAlphabetically:
+		sess.Desc("package.lower_name")
+	default:
+		sess.Desc("package_publish.last_publish_unix")
+	}
+	sess.Asc("package.id")
+
+	if opts.Paginator != nil {
+		sess = db.SetSessionPagination(sess, opts)
+	}
+
+	entries := make([]*InventoryEntry, 0, 10)
+	return entries, count, sess.Find(&entries)
+}
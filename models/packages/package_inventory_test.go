@@ -0,0 +1,138 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/unittest"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchInventory(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	// user 9 is deactivated
+	const activeOwnerID, inactiveOwnerID = 11, 9
+
+	insert := func(ownerID int64, packageType packages_model.Type, name string, publishedUnix timeutil.TimeStamp, blobs ...*packages_model.PackageBlob) *packages_model.Package {
+		p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+			OwnerID:   ownerID,
+			Type:      packageType,
+			Name:      name,
+			LowerName: name,
+		})
+		assert.NoError(t, err)
+
+		for i, pb := range blobs {
+			version := fmt.Sprintf("1.0.%d", i)
+			pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+				PackageID:    p.ID,
+				Version:      version,
+				LowerVersion: version,
+			})
+			assert.NoError(t, err)
+			_, err = db.GetEngine(db.DefaultContext).ID(pv.ID).Cols("created_unix").NoAutoTime().Update(&packages_model.PackageVersion{CreatedUnix: publishedUnix - timeutil.TimeStamp(len(blobs)-1-i)})
+			assert.NoError(t, err)
+
+			_, err = packages_model.TryInsertFile(db.DefaultContext, &packages_model.PackageFile{
+				VersionID: pv.ID,
+				BlobID:    pb.ID,
+				Name:      "file.bin",
+				LowerName: "file.bin",
+			})
+			assert.NoError(t, err)
+		}
+		return p
+	}
+
+	shared := insertTestBlob(t, "inventory-"+strings.Repeat("s", 100))
+	large := insertTestBlob(t, "inventory-"+strings.Repeat("l", 300))
+	small := insertTestBlob(t, "inventory-small")
+
+	// the shared blob is counted once
+	sharedPackage := insert(activeOwnerID, packages_model.TypeGeneric, "inventory-shared", 3000, shared, shared)
+	largePackage := insert(activeOwnerID, packages_model.TypeContainer, "inventory-large", 1000, large, small)
+	smallPackage := insert(inactiveOwnerID, packages_model.TypeContainer, "inventory-small", 2000, small)
+
+	// packages with only internal versions are not listed
+	internal, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   activeOwnerID,
+		Type:      packages_model.TypeContainer,
+		Name:      "inventory-internal",
+		LowerName: "inventory-internal",
+	})
+	assert.NoError(t, err)
+	_, err = packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+		PackageID:    internal.ID,
+		Version:      "_upload",
+		LowerVersion: "_upload",
+		IsInternal:   true,
+	})
+	assert.NoError(t, err)
+
+	search := func(opts *packages_model.InventorySearchOptions) []int64 {
+		opts.Name = "inventory-"
+		entries, count, err := packages_model.SearchInventory(db.DefaultContext, opts)
+		assert.NoError(t, err)
+		assert.EqualValues(t, len(entries), count)
+		ids := make([]int64, 0, len(entries))
+		for _, e := range entries {
+			ids = append(ids, e.ID)
+		}
+		return ids
+	}
+
+	entries, count, err := packages_model.SearchInventory(db.DefaultContext, &packages_model.InventorySearchOptions{
+		Name: "inventory-",
+		Sort: packages_model.SortBySize,
+	})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3, count)
+	assert.Len(t, entries, 3)
+	assert.Equal(t, largePackage.ID, entries[0].ID)
+	assert.Equal(t, large.Size+small.Size, entries[0].TotalSize)
+	assert.EqualValues(t, 1000, entries[0].LastPublishUnix)
+	assert.Equal(t, sharedPackage.ID, entries[1].ID)
+	assert.Equal(t, shared.Size, entries[1].TotalSize)
+	assert.Equal(t, smallPackage.ID, entries[2].ID)
+	assert.Equal(t, small.Size, entries[2].TotalSize)
+
+	assert.Equal(t, []int64{smallPackage.ID, sharedPackage.ID, largePackage.ID}, search(&packages_model.InventorySearchOptions{Sort: packages_model.InventorySortSmallest}))
+	assert.Equal(t, []int64{sharedPackage.ID, smallPackage.ID, largePackage.ID}, search(&packages_model.InventorySearchOptions{}))
+	assert.Equal(t, []int64{largePackage.ID, smallPackage.ID, sharedPackage.ID}, search(&packages_model.InventorySearchOptions{Sort: packages_model.InventorySortOldest}))
+	assert.Equal(t, []int64{largePackage.ID, sharedPackage.ID, smallPackage.ID}, search(&packages_model.InventorySearchOptions{Sort: packages_model.InventorySortAlphabetically}))
+
+	t.Run("Filter", func(t *testing.T) {
+		assert.Equal(t, []int64{smallPackage.ID, largePackage.ID}, search(&packages_model.InventorySearchOptions{Type: packages_model.TypeContainer}))
+		assert.Equal(t, []int64{sharedPackage.ID, largePackage.ID}, search(&packages_model.InventorySearchOptions{OwnerID: activeOwnerID}))
+		assert.Equal(t, []int64{smallPackage.ID}, search(&packages_model.InventorySearchOptions{OwnerIsActive: util.OptionalBoolFalse}))
+		assert.Equal(t, []int64{smallPackage.ID}, search(&packages_model.InventorySearchOptions{Type: packages_model.TypeContainer, OwnerIsActive: util.OptionalBoolFalse}))
+		assert.Equal(t, []int64{sharedPackage.ID}, search(&packages_model.InventorySearchOptions{MinSize: shared.Size, MaxSize: shared.Size}))
+		assert.Equal(t, []int64{sharedPackage.ID, largePackage.ID}, search(&packages_model.InventorySearchOptions{MinSize: shared.Size}))
+		assert.Equal(t, []int64{sharedPackage.ID, smallPackage.ID}, search(&packages_model.InventorySearchOptions{MaxSize: shared.Size}))
+		assert.Equal(t, []int64{sharedPackage.ID, smallPackage.ID}, search(&packages_model.InventorySearchOptions{PublishedAfter: 2000}))
+		assert.Equal(t, []int64{smallPackage.ID, largePackage.ID}, search(&packages_model.InventorySearchOptions{PublishedBefore: 3000}))
+	})
+
+	t.Run("Pagination", func(t *testing.T) {
+		entries, count, err := packages_model.SearchInventory(db.DefaultContext, &packages_model.InventorySearchOptions{
+			Name:      "inventory-",
+			Sort:      packages_model.SortBySize,
+			Paginator: &db.ListOptions{Page: 2, PageSize: 2},
+		})
+		assert.NoError(t, err)
+		assert.EqualValues(t, 3, count)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, smallPackage.ID, entries[0].ID)
+	})
+}
@@ -0,0 +1,99 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/json"
+)
+
+// ErrInvalidMetadata represents a "InvalidMetadata" kind of error.
+type ErrInvalidMetadata struct {
+	Type Type
+	// Fields contains the top level fields which don't match the metadata of the package type.
+	// It is empty if the metadata isn't a JSON object at all.
+	Fields []string
+}
+
+// IsErrInvalidMetadata checks if an error is a ErrInvalidMetadata.
+func IsErrInvalidMetadata(err error) bool {
+	_, ok := err.(ErrInvalidMetadata)
+	return ok
+}
+
+func (err ErrInvalidMetadata) Error() string {
+	if len(err.Fields) == 0 {
+		return fmt.Sprintf("package metadata is malformed [type: %s]", err.Type)
+	}
+	return fmt.Sprintf("package metadata is invalid [type: %s, fields: %s]", err.Type, strings.Join(err.Fields, ", "))
+}
+
+// NormalizeMetadata validates the metadata JSON against the metadata of the package type
+// and returns it in its canonical form
+func NormalizeMetadata(packageType Type, metadataJSON string) (string, error) {
+	metadata := newMetadata(packageType)
+	if metadata == nil {
+		return "null", nil
+	}
+
+	if err := json.Unmarshal([]byte(metadataJSON), metadata); err != nil {
+		return "", ErrInvalidMetadata{Type: packageType, Fields: invalidMetadataFields(packageType, metadataJSON)}
+	}
+
+	normalized, err := json.Marshal(metadata)
+	if err != nil {
+		return "", err
+	}
+	return string(normalized), nil
+}
+
+// invalidMetadataFields returns the sorted top level fields of the metadata JSON
+// which can't be decoded into the metadata of the package type
+func invalidMetadataFields(packageType Type, metadataJSON string) []string {
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(metadataJSON), &fields); err != nil {
+		return nil
+	}
+
+	invalid := make([]string, 0, len(fields))
+	for name, value := range fields {
+		field, err := json.Marshal(map[string]interface{}{name: value})
+		if err != nil {
+			return nil
+		}
+		if err := json.Unmarshal(field, newMetadata(packageType)); err != nil {
+			invalid = append(invalid, name)
+		}
+	}
+	sort.Strings(invalid)
+	return invalid
+}
+
+// IterateVersionsWithInvalidMetadata calls fn for every package version whose metadata
+// doesn't match the metadata of its package type
+func IterateVersionsWithInvalidMetadata(ctx context.Context, fn func(p *Package, pv *PackageVersion, err error) error) error {
+	return db.IterateObjects(ctx, func(p *Package) error {
+		pvs := make([]*PackageVersion, 0, 10)
+		if err := db.GetEngine(ctx).Where("package_id = ?", p.ID).Asc("id").Find(&pvs); err != nil {
+			return err
+		}
+		for _, pv := range pvs {
+			if _, err := NormalizeMetadata(p.Type, pv.MetadataJSON); err != nil {
+				if !IsErrInvalidMetadata(err) {
+					return err
+				}
+				if err := fn(p, pv, err); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
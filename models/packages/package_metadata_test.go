@@ -0,0 +1,69 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages_test
+
+import (
+	"testing"
+
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/unittest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeMetadata(t *testing.T) {
+	normalized, err := packages_model.NormalizeMetadata(packages_model.TypeNpm, `{"unknown":1,"description":"","keywords":["a"],"name":"test"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"test","keywords":["a"]}`, normalized)
+
+	normalized, err = packages_model.NormalizeMetadata(packages_model.TypeGeneric, `{"name":"test"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "null", normalized)
+
+	_, err = packages_model.NormalizeMetadata(packages_model.TypeNpm, `{"name":"test","description":{},"keywords":"a"}`)
+	assert.True(t, packages_model.IsErrInvalidMetadata(err))
+	assert.Equal(t, []string{"description", "keywords"}, err.(packages_model.ErrInvalidMetadata).Fields)
+
+	_, err = packages_model.NormalizeMetadata(packages_model.TypeNpm, `["test"]`)
+	assert.True(t, packages_model.IsErrInvalidMetadata(err))
+	assert.Empty(t, err.(packages_model.ErrInvalidMetadata).Fields)
+}
+
+func TestIterateVersionsWithInvalidMetadata(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeNpm,
+		Name:      "invalid-metadata",
+		LowerName: "invalid-metadata",
+	})
+	assert.NoError(t, err)
+
+	for version, metadataJSON := range map[string]string{
+		"1.0.0": `{"name":"invalid-metadata"}`,
+		"2.0.0": `{"name":"invalid-metadata","description":{}}`,
+	} {
+		_, err = packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			CreatorID:    2,
+			Version:      version,
+			LowerVersion: version,
+			MetadataJSON: metadataJSON,
+		})
+		assert.NoError(t, err)
+	}
+
+	invalid := make([]string, 0, 1)
+	assert.NoError(t, packages_model.IterateVersionsWithInvalidMetadata(db.DefaultContext, func(invalidPackage *packages_model.Package, pv *packages_model.PackageVersion, err error) error {
+		assert.True(t, packages_model.IsErrInvalidMetadata(err))
+		if invalidPackage.ID == p.ID {
+			invalid = append(invalid, pv.Version)
+		}
+		return nil
+	}))
+	assert.Equal(t, []string{"2.0.0"}, invalid)
+}
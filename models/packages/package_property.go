@@ -6,8 +6,15 @@ package packages
 
 import (
 	"context"
+	"sort"
+	"strconv"
+	"strings"
 
 	"code.gitea.io/gitea/models/db"
+	container_module "code.gitea.io/gitea/modules/packages/container"
+
+	"xorm.io/builder"
+	"xorm.io/xorm/schemas"
 )
 
 func init() {
@@ -25,13 +32,83 @@ const (
 	PropertyTypePackage // 2
 )
 
+// PropertyKeyword is the name of the version properties which store the keywords of the version metadata.
+// Every keyword is stored as separate property with the normalized keyword as value.
+const PropertyKeyword = "keyword"
+
+// NormalizeKeyword returns the form in which keywords are stored and searched
+func NormalizeKeyword(keyword string) string {
+	return strings.ToLower(strings.TrimSpace(keyword))
+}
+
+// PropertyValueType is a hint how the value of a property can be interpreted
+type PropertyValueType int64
+
+const (
+	// PropertyValueTypeString means the value is only available as string
+	PropertyValueTypeString PropertyValueType = iota // 0
+	// PropertyValueTypeNumeric means the value is available as number in NumericValue too
+	PropertyValueTypeNumeric // 1
+)
+
+// numericProperties contains the well-known properties with numeric values.
+// The numeric value of these properties is stored, so they can be compared in queries.
+var numericProperties = map[string]bool{
+	container_module.PropertyImageSize:    true,
+	container_module.PropertyImageCreated: true,
+}
+
+// IsNumericProperty tests if the property is a well-known property with a numeric value
+func IsNumericProperty(name string) bool {
+	return numericProperties[name]
+}
+
 // PackageProperty represents a property of a package, version or file
 type PackageProperty struct {
-	ID      int64        `xorm:"pk autoincr"`
-	RefType PropertyType `xorm:"INDEX NOT NULL"`
-	RefID   int64        `xorm:"INDEX NOT NULL"`
-	Name    string       `xorm:"INDEX NOT NULL"`
-	Value   string       `xorm:"TEXT NOT NULL"`
+	ID           int64             `xorm:"pk autoincr"`
+	RefType      PropertyType      `xorm:"INDEX NOT NULL"`
+	RefID        int64             `xorm:"INDEX NOT NULL"`
+	Name         string            `xorm:"INDEX NOT NULL"`
+	Value        string            `xorm:"TEXT NOT NULL"`
+	ValueType    PropertyValueType `xorm:"NOT NULL DEFAULT 0"`
+	NumericValue int64             `xorm:"NOT NULL DEFAULT 0"`
+}
+
+// TableIndices implements xorm's TableIndices interface
+func (pp *PackageProperty) TableIndices() []*schemas.Index {
+	// used by the property lookups of the package search
+	refNameIndex := schemas.NewIndex("r_r_n", schemas.IndexType)
+	refNameIndex.AddColumn("ref_type", "ref_id", "name")
+
+	// used by range queries on numeric properties
+	numericIndex := schemas.NewIndex("r_n_n", schemas.IndexType)
+	numericIndex.AddColumn("ref_type", "name", "numeric_value")
+
+	return []*schemas.Index{refNameIndex, numericIndex}
+}
+
+// PropertiesCond returns a condition which matches the references having all the properties with the exact values.
+// refIDColumn is the column which contains the id of the reference, e.g. package_file.id.
+// There is one EXISTS subquery per property, so the lookup can use the (ref_type, ref_id, name) index.
+func PropertiesCond(refType PropertyType, refIDColumn string, properties map[string]string) builder.Cond {
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	cond := builder.NewCond()
+	for _, name := range names {
+		propCond := builder.Expr("package_property.ref_id = " + refIDColumn).
+			And(builder.Eq{
+				"package_property.ref_type": refType,
+				"package_property.name":     name,
+				"package_property.value":    properties[name],
+			})
+
+		cond = cond.And(builder.Exists(builder.Select("package_property.id").From("package_property").Where(propCond)))
+	}
+	return cond
 }
 
 // InsertProperty creates a property
@@ -42,6 +119,12 @@ func InsertProperty(ctx context.Context, refType PropertyType, refID int64, name
 		Name:    name,
 		Value:   value,
 	}
+	if IsNumericProperty(name) {
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			pp.ValueType = PropertyValueTypeNumeric
+			pp.NumericValue = n
+		}
+	}
 
 	_, err := db.GetEngine(ctx).Insert(pp)
 	return pp, err
@@ -0,0 +1,191 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"fmt"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/setting"
+
+	"xorm.io/builder"
+)
+
+func init() {
+	db.RegisterModel(new(PackageQuota))
+}
+
+// QuotaSizeLimitDefault indicates that the instance default quota applies to an owner
+const QuotaSizeLimitDefault = -1
+
+// PackageQuota stores the used package storage of an owner and an optional quota override.
+// Blobs referenced by multiple files of the same owner are counted once.
+type PackageQuota struct {
+	ID        int64 `xorm:"pk autoincr"`
+	OwnerID   int64 `xorm:"UNIQUE NOT NULL"`
+	SizeLimit int64 `xorm:"NOT NULL DEFAULT -1"`
+	UsedSize  int64 `xorm:"NOT NULL DEFAULT 0"`
+}
+
+// GetQuotaByOwnerID gets the quota of an owner. If no quota is stored, an empty quota using the instance default is returned.
+func GetQuotaByOwnerID(ctx context.Context, ownerID int64) (*PackageQuota, error) {
+	pq := &PackageQuota{OwnerID: ownerID}
+
+	has, err := db.GetEngine(ctx).Get(pq)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		pq.SizeLimit = QuotaSizeLimitDefault
+	}
+	return pq, nil
+}
+
+// SetQuotaSizeLimit sets the quota override of an owner. Use QuotaSizeLimitDefault to apply the instance default.
+func SetQuotaSizeLimit(ctx context.Context, ownerID, sizeLimit int64) error {
+	if err := ensureQuota(ctx, ownerID); err != nil {
+		return err
+	}
+	_, err := db.GetEngine(ctx).Where("owner_id = ?", ownerID).Cols("size_limit").Update(&PackageQuota{SizeLimit: sizeLimit})
+	return err
+}
+
+// ensureQuota creates the quota row of the owner if it does not exist.
+// Concurrent transactions may try to create the row of the same owner, so the insert must tolerate an existing row.
+// A failing insert would abort the whole (upload) transaction on some databases.
+func ensureQuota(ctx context.Context, ownerID int64) error {
+	has, err := db.GetEngine(ctx).Where("owner_id = ?", ownerID).Exist(&PackageQuota{})
+	if err != nil || has {
+		return err
+	}
+
+	switch {
+	case setting.Database.UseSQLite3 || setting.Database.UsePostgreSQL:
+		_, err = db.Exec(ctx, "INSERT INTO `package_quota` (`owner_id`, `size_limit`, `used_size`) "+
+			"VALUES (?,?,0) ON CONFLICT (`owner_id`) DO NOTHING", ownerID, QuotaSizeLimitDefault)
+	case setting.Database.UseMySQL:
+		_, err = db.Exec(ctx, "INSERT INTO `package_quota` (`owner_id`, `size_limit`, `used_size`) "+
+			"VALUES (?,?,0) ON DUPLICATE KEY UPDATE `owner_id` = `owner_id`", ownerID, QuotaSizeLimitDefault)
+	case setting.Database.UseMSSQL:
+		// https://weblogs.sqlteam.com/dang/2009/01/31/upsert-race-condition-with-merge/
+		_, err = db.Exec(ctx, "MERGE `package_quota` WITH (HOLDLOCK) AS target "+
+			"USING (SELECT ? AS owner_id) AS src "+
+			"ON src.owner_id = target.owner_id "+
+			"WHEN NOT MATCHED THEN INSERT (owner_id, size_limit, used_size) "+
+			"VALUES (src.owner_id, ?, 0);", ownerID, QuotaSizeLimitDefault)
+	default:
+		return fmt.Errorf("database type not supported")
+	}
+	return err
+}
+
+func addQuotaUsedSize(ctx context.Context, ownerID, delta int64) error {
+	if err := ensureQuota(ctx, ownerID); err != nil {
+		return err
+	}
+	_, err := db.GetEngine(ctx).Exec("UPDATE `package_quota` SET `used_size` = `used_size` + ? WHERE `owner_id` = ?", delta, ownerID)
+	return err
+}
+
+// RecalculateQuotaUsedSize recalculates the used package storage of an owner from the stored files
+func RecalculateQuotaUsedSize(ctx context.Context, ownerID int64) error {
+	blobs := builder.
+		Select("DISTINCT package_blob.id, package_blob.size").
+		From("package_blob").
+		InnerJoin("package_file", "package_file.blob_id = package_blob.id").
+		InnerJoin("package_version", "package_version.id = package_file.version_id").
+		InnerJoin("package", "package.id = package_version.package_id").
+		Where(builder.Eq{"package.owner_id": ownerID})
+
+	var usedSize int64
+	if _, err := db.GetEngine(ctx).SQL(builder.Select("COALESCE(SUM(size), 0)").From(blobs, "blobs")).Get(&usedSize); err != nil {
+		return err
+	}
+
+	if err := ensureQuota(ctx, ownerID); err != nil {
+		return err
+	}
+	_, err := db.GetEngine(ctx).Where("owner_id = ?", ownerID).Cols("used_size").Update(&PackageQuota{UsedSize: usedSize})
+	return err
+}
+
+// GetPackageSizeAddedToOwner gets the size of the distinct blobs of the package which are not referenced
+// by other packages of the owner, that is the storage the owner would use additionally if the package belonged to it.
+// If packageType is set, only the other packages of the owner with this type are considered.
+func GetPackageSizeAddedToOwner(ctx context.Context, packageID, ownerID int64, packageType Type) (int64, error) {
+	ownerCond := builder.Eq{"package.owner_id": ownerID}.And(builder.Neq{"package.id": packageID})
+	if packageType != "" {
+		ownerCond = ownerCond.And(builder.Eq{"package.type": packageType})
+	}
+
+	ownerBlobIDs := builder.
+		Select("package_file.blob_id").
+		From("package_file").
+		InnerJoin("package_version", "package_version.id = package_file.version_id").
+		InnerJoin("package", "package.id = package_version.package_id").
+		Where(ownerCond)
+
+	blobs := builder.
+		Select("DISTINCT package_blob.id, package_blob.size").
+		From("package_blob").
+		InnerJoin("package_file", "package_file.blob_id = package_blob.id").
+		InnerJoin("package_version", "package_version.id = package_file.version_id").
+		Where(builder.Eq{"package_version.package_id": packageID}.And(builder.NotIn("package_blob.id", ownerBlobIDs)))
+
+	var size int64
+	_, err := db.GetEngine(ctx).SQL(builder.Select("COALESCE(SUM(size), 0)").From(blobs, "blobs")).Get(&size)
+	return size, err
+}
+
+// IsBlobReferencedByOwner tests if a blob is referenced by any file of the owner
+func IsBlobReferencedByOwner(ctx context.Context, ownerID, blobID int64) (bool, error) {
+	return isBlobReferencedByOwner(ctx, ownerID, blobID, 0)
+}
+
+func isBlobReferencedByOwner(ctx context.Context, ownerID, blobID, excludeFileID int64) (bool, error) {
+	cond := builder.Eq{
+		"package.owner_id":     ownerID,
+		"package_file.blob_id": blobID,
+	}.And(builder.Neq{"package_file.id": excludeFileID})
+
+	return db.GetEngine(ctx).
+		Table("package_file").
+		Join("INNER", "package_version", "package_version.id = package_file.version_id").
+		Join("INNER", "package", "package.id = package_version.package_id").
+		Where(cond).
+		Exist(&PackageFile{})
+}
+
+// updateQuotaForFile adds (or removes) the blob size of a file to the used storage of the owner
+// if the file is the only one of the owner which references the blob
+func updateQuotaForFile(ctx context.Context, pf *PackageFile, added bool) error {
+	var ownerID int64
+	has, err := db.GetEngine(ctx).
+		Table("package_version").
+		Join("INNER", "package", "package.id = package_version.package_id").
+		Where("package_version.id = ?", pf.VersionID).
+		Cols("package.owner_id").
+		Get(&ownerID)
+	if err != nil || !has {
+		return err
+	}
+
+	referenced, err := isBlobReferencedByOwner(ctx, ownerID, pf.BlobID, pf.ID)
+	if err != nil || referenced {
+		return err
+	}
+
+	pb, err := GetBlobByID(ctx, pf.BlobID)
+	if err != nil {
+		return err
+	}
+
+	delta := pb.Size
+	if !added {
+		delta = -delta
+	}
+	return addQuotaUsedSize(ctx, ownerID, delta)
+}
@@ -0,0 +1,250 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/unittest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPackageQuota(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	usedSize := func(ownerID int64) int64 {
+		pq, err := packages_model.GetQuotaByOwnerID(db.DefaultContext, ownerID)
+		assert.NoError(t, err)
+		return pq.UsedSize
+	}
+
+	insertVersion := func(ownerID int64, version string) *packages_model.PackageVersion {
+		p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+			OwnerID:   ownerID,
+			Type:      packages_model.TypeGeneric,
+			Name:      "quota-package",
+			LowerName: "quota-package",
+		})
+		if err != packages_model.ErrDuplicatePackage {
+			assert.NoError(t, err)
+		}
+
+		pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			Version:      version,
+			LowerVersion: version,
+		})
+		assert.NoError(t, err)
+		return pv
+	}
+
+	insertFile := func(pv *packages_model.PackageVersion, pb *packages_model.PackageBlob, name string) *packages_model.PackageFile {
+		pf, err := packages_model.TryInsertFile(db.DefaultContext, &packages_model.PackageFile{
+			VersionID: pv.ID,
+			BlobID:    pb.ID,
+			Name:      name,
+			LowerName: name,
+		})
+		assert.NoError(t, err)
+		return pf
+	}
+
+	pbA := insertTestBlob(t, "package-quota-a")
+	pbB := insertTestBlob(t, "package-quota-blob-b")
+
+	start1 := usedSize(1)
+	start2 := usedSize(2)
+
+	pv1 := insertVersion(1, "1.0.0")
+	pv2 := insertVersion(1, "2.0.0")
+	pvOther := insertVersion(2, "1.0.0")
+
+	// first reference of a blob counts
+	fileA1 := insertFile(pv1, pbA, "a.bin")
+	assert.Equal(t, start1+pbA.Size, usedSize(1))
+
+	// the same blob in another version of the same owner is counted once
+	fileA2 := insertFile(pv2, pbA, "a.bin")
+	assert.Equal(t, start1+pbA.Size, usedSize(1))
+
+	fileB := insertFile(pv1, pbB, "b.bin")
+	assert.Equal(t, start1+pbA.Size+pbB.Size, usedSize(1))
+
+	// every owner referencing a blob is charged for it
+	fileOther := insertFile(pvOther, pbA, "a.bin")
+	assert.Equal(t, start2+pbA.Size, usedSize(2))
+	assert.Equal(t, start1+pbA.Size+pbB.Size, usedSize(1))
+
+	has, err := packages_model.IsBlobReferencedByOwner(db.DefaultContext, 1, pbB.ID)
+	assert.NoError(t, err)
+	assert.True(t, has)
+
+	// the blob size is released when the last reference of the owner is removed
+	assert.NoError(t, packages_model.DeleteFileByID(db.DefaultContext, fileA1.ID))
+	assert.Equal(t, start1+pbA.Size+pbB.Size, usedSize(1))
+
+	assert.NoError(t, packages_model.DeleteFileByID(db.DefaultContext, fileA2.ID))
+	assert.Equal(t, start1+pbB.Size, usedSize(1))
+	assert.Equal(t, start2+pbA.Size, usedSize(2))
+
+	// the running total matches a recalculation
+	assert.NoError(t, packages_model.RecalculateQuotaUsedSize(db.DefaultContext, 1))
+	assert.Equal(t, start1+pbB.Size, usedSize(1))
+
+	assert.NoError(t, packages_model.DeleteFileByID(db.DefaultContext, fileB.ID))
+	assert.Equal(t, start1, usedSize(1))
+
+	assert.NoError(t, packages_model.DeleteFileByID(db.DefaultContext, fileOther.ID))
+	assert.Equal(t, start2, usedSize(2))
+
+	has, err = packages_model.IsBlobReferencedByOwner(db.DefaultContext, 1, pbB.ID)
+	assert.NoError(t, err)
+	assert.False(t, has)
+}
+
+func TestSetQuotaSizeLimit(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	pq, err := packages_model.GetQuotaByOwnerID(db.DefaultContext, 5)
+	assert.NoError(t, err)
+	assert.EqualValues(t, packages_model.QuotaSizeLimitDefault, pq.SizeLimit)
+
+	assert.NoError(t, packages_model.SetQuotaSizeLimit(db.DefaultContext, 5, 1024))
+	assert.NoError(t, packages_model.SetQuotaSizeLimit(db.DefaultContext, 5, 1024))
+
+	pq, err = packages_model.GetQuotaByOwnerID(db.DefaultContext, 5)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1024, pq.SizeLimit)
+
+	assert.NoError(t, packages_model.SetQuotaSizeLimit(db.DefaultContext, 5, packages_model.QuotaSizeLimitDefault))
+
+	pq, err = packages_model.GetQuotaByOwnerID(db.DefaultContext, 5)
+	assert.NoError(t, err)
+	assert.EqualValues(t, packages_model.QuotaSizeLimitDefault, pq.SizeLimit)
+}
+
+func TestGetPackageSizeAddedToOwner(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	insertPackage := func(ownerID int64, packageType packages_model.Type, name string, blobs ...*packages_model.PackageBlob) *packages_model.Package {
+		p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+			OwnerID:   ownerID,
+			Type:      packageType,
+			Name:      name,
+			LowerName: name,
+		})
+		assert.NoError(t, err)
+
+		pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			Version:      "1.0.0",
+			LowerVersion: "1.0.0",
+		})
+		assert.NoError(t, err)
+
+		for i, pb := range blobs {
+			name := fmt.Sprintf("file%d.bin", i)
+			_, err := packages_model.TryInsertFile(db.DefaultContext, &packages_model.PackageFile{
+				VersionID: pv.ID,
+				BlobID:    pb.ID,
+				Name:      name,
+				LowerName: name,
+			})
+			assert.NoError(t, err)
+		}
+		return p
+	}
+
+	pbA := insertTestBlob(t, "added-size-a")
+	pbB := insertTestBlob(t, "added-size-blob-b")
+
+	// a blob referenced by multiple files of the package is counted once
+	p := insertPackage(2, packages_model.TypeGeneric, "added-size", pbA, pbB, pbA)
+
+	size, err := packages_model.GetPackageSizeAddedToOwner(db.DefaultContext, p.ID, 5, "")
+	assert.NoError(t, err)
+	assert.Equal(t, pbA.Size+pbB.Size, size)
+
+	// blobs referenced by other packages of the owner don't add storage
+	insertPackage(5, packages_model.TypeNpm, "added-size-other", pbB)
+
+	size, err = packages_model.GetPackageSizeAddedToOwner(db.DefaultContext, p.ID, 5, "")
+	assert.NoError(t, err)
+	assert.Equal(t, pbA.Size, size)
+
+	// unless only the packages of another type are considered
+	size, err = packages_model.GetPackageSizeAddedToOwner(db.DefaultContext, p.ID, 5, packages_model.TypeGeneric)
+	assert.NoError(t, err)
+	assert.Equal(t, pbA.Size+pbB.Size, size)
+
+	// the package itself is not considered
+	size, err = packages_model.GetPackageSizeAddedToOwner(db.DefaultContext, p.ID, 2, "")
+	assert.NoError(t, err)
+	assert.Equal(t, pbA.Size+pbB.Size, size)
+}
+
+func TestPackageQuotaConcurrentInsert(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	const ownerID = 6
+	const workers = 10
+
+	unittest.AssertNotExistsBean(t, &packages_model.PackageQuota{OwnerID: ownerID})
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   ownerID,
+		Type:      packages_model.TypeGeneric,
+		Name:      "concurrent-quota",
+		LowerName: "concurrent-quota",
+	})
+	assert.NoError(t, err)
+
+	pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+		PackageID:    p.ID,
+		Version:      "1.0.0",
+		LowerVersion: "1.0.0",
+	})
+	assert.NoError(t, err)
+
+	var expectedSize int64
+	blobs := make([]*packages_model.PackageBlob, 0, workers)
+	for i := 0; i < workers; i++ {
+		pb := insertTestBlob(t, fmt.Sprintf("concurrent-quota-%d", i))
+		expectedSize += pb.Size
+		blobs = append(blobs, pb)
+	}
+
+	// every upload transaction creates the missing quota row of the owner
+	var wg sync.WaitGroup
+	for i, pb := range blobs {
+		wg.Add(1)
+		go func(i int, pb *packages_model.PackageBlob) {
+			defer wg.Done()
+			assert.NoError(t, db.WithTx(func(ctx context.Context) error {
+				name := fmt.Sprintf("file%d.bin", i)
+				_, err := packages_model.TryInsertFile(ctx, &packages_model.PackageFile{
+					VersionID: pv.ID,
+					BlobID:    pb.ID,
+					Name:      name,
+					LowerName: name,
+				})
+				return err
+			}))
+		}(i, pb)
+	}
+	wg.Wait()
+
+	unittest.AssertCount(t, &packages_model.PackageQuota{OwnerID: ownerID}, 1)
+
+	pq, err := packages_model.GetQuotaByOwnerID(db.DefaultContext, ownerID)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedSize, pq.UsedSize)
+}
@@ -0,0 +1,123 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/builder"
+)
+
+func init() {
+	db.RegisterModel(new(PackageSizeSummary))
+}
+
+// SizeSummaryTypeAll is the type of the summary which covers all package types
+const SizeSummaryTypeAll Type = ""
+
+// PackageSizeSummary stores the storage used by the packages of a type.
+// The logical size is the sum of the sizes of all package files, the physical size the sum of the sizes of the distinct blobs.
+// The summaries are expensive to calculate and get refreshed periodically.
+type PackageSizeSummary struct {
+	ID           int64              `xorm:"pk autoincr"`
+	Type         Type               `xorm:"UNIQUE NOT NULL"`
+	LogicalSize  int64              `xorm:"NOT NULL DEFAULT 0"`
+	PhysicalSize int64              `xorm:"NOT NULL DEFAULT 0"`
+	UpdatedUnix  timeutil.TimeStamp `xorm:"NOT NULL DEFAULT 0"`
+}
+
+// SavedSize returns the size which is saved by sharing blobs between package files
+func (s *PackageSizeSummary) SavedSize() int64 {
+	return s.LogicalSize - s.PhysicalSize
+}
+
+// GetSizeSummaries gets the stored size summaries. The summary of all package types comes first.
+func GetSizeSummaries(ctx context.Context) ([]*PackageSizeSummary, error) {
+	summaries := make([]*PackageSizeSummary, 0, 10)
+	return summaries, db.GetEngine(ctx).OrderBy("type ASC").Find(&summaries)
+}
+
+// CalculateSizeSummaries calculates the size summaries from the stored files. This requires scanning all package files.
+func CalculateSizeSummaries(ctx context.Context) ([]*PackageSizeSummary, error) {
+	type typeSize struct {
+		Type Type
+		Size int64
+	}
+
+	logical := make([]*typeSize, 0, 10)
+	if err := db.GetEngine(ctx).
+		Table("package_file").
+		Select("package.type AS type, SUM(package_blob.size) AS size").
+		Join("INNER", "package_blob", "package_blob.id = package_file.blob_id").
+		Join("INNER", "package_version", "package_version.id = package_file.version_id").
+		Join("INNER", "package", "package.id = package_version.package_id").
+		GroupBy("package.type").
+		Find(&logical); err != nil {
+		return nil, err
+	}
+
+	blobsByType := builder.
+		Select("DISTINCT package.type, package_blob.id, package_blob.size").
+		From("package_blob").
+		InnerJoin("package_file", "package_file.blob_id = package_blob.id").
+		InnerJoin("package_version", "package_version.id = package_file.version_id").
+		InnerJoin("package", "package.id = package_version.package_id")
+
+	physical := make([]*typeSize, 0, 10)
+	if err := db.GetEngine(ctx).
+		SQL(builder.Select("type, SUM(size) AS size").From(blobsByType, "blobs").GroupBy("type")).
+		Find(&physical); err != nil {
+		return nil, err
+	}
+
+	// blobs shared between package types are counted once in the summary of all types
+	blobs := builder.
+		Select("DISTINCT package_blob.id, package_blob.size").
+		From("package_blob").
+		InnerJoin("package_file", "package_file.blob_id = package_blob.id")
+
+	var totalPhysical int64
+	if _, err := db.GetEngine(ctx).
+		SQL(builder.Select("COALESCE(SUM(size), 0)").From(blobs, "blobs")).
+		Get(&totalPhysical); err != nil {
+		return nil, err
+	}
+
+	now := timeutil.TimeStampNow()
+
+	total := &PackageSizeSummary{Type: SizeSummaryTypeAll, PhysicalSize: totalPhysical, UpdatedUnix: now}
+	summaries := []*PackageSizeSummary{total}
+	byType := make(map[Type]*PackageSizeSummary, len(logical))
+	for _, ts := range logical {
+		s := &PackageSizeSummary{Type: ts.Type, LogicalSize: ts.Size, UpdatedUnix: now}
+		summaries = append(summaries, s)
+		byType[ts.Type] = s
+		total.LogicalSize += ts.Size
+	}
+	for _, ts := range physical {
+		if s, ok := byType[ts.Type]; ok {
+			s.PhysicalSize = ts.Size
+		}
+	}
+	return summaries, nil
+}
+
+// RefreshSizeSummaries replaces the stored size summaries with freshly calculated ones
+func RefreshSizeSummaries(ctx context.Context) error {
+	summaries, err := CalculateSizeSummaries(ctx)
+	if err != nil {
+		return err
+	}
+
+	e := db.GetEngine(ctx)
+	if _, err := e.Where("1=1").Delete(&PackageSizeSummary{}); err != nil {
+		return err
+	}
+	_, err = e.Insert(summaries)
+	return err
+}
@@ -0,0 +1,78 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages_test
+
+import (
+	"testing"
+
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/unittest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshSizeSummaries(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	summary := func(packageType packages_model.Type) *packages_model.PackageSizeSummary {
+		summaries, err := packages_model.GetSizeSummaries(db.DefaultContext)
+		assert.NoError(t, err)
+		for _, s := range summaries {
+			if s.Type == packageType {
+				return s
+			}
+		}
+		return &packages_model.PackageSizeSummary{Type: packageType}
+	}
+
+	assert.NoError(t, packages_model.RefreshSizeSummaries(db.DefaultContext))
+
+	startAll := summary(packages_model.SizeSummaryTypeAll)
+	startPub := summary(packages_model.TypePub)
+	assert.NotZero(t, startAll.UpdatedUnix)
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypePub,
+		Name:      "size-summary-package",
+		LowerName: "size-summary-package",
+	})
+	assert.NoError(t, err)
+
+	pb := insertTestBlob(t, "size-summary-blob")
+
+	// the same blob is stored in two versions
+	for _, version := range []string{"1.0.0", "2.0.0"} {
+		pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			Version:      version,
+			LowerVersion: version,
+		})
+		assert.NoError(t, err)
+
+		_, err = packages_model.TryInsertFile(db.DefaultContext, &packages_model.PackageFile{
+			VersionID: pv.ID,
+			BlobID:    pb.ID,
+			Name:      "file.tar.gz",
+			LowerName: "file.tar.gz",
+		})
+		assert.NoError(t, err)
+	}
+
+	// the stored summaries are not changed until they are refreshed
+	assert.Equal(t, startPub.LogicalSize, summary(packages_model.TypePub).LogicalSize)
+
+	assert.NoError(t, packages_model.RefreshSizeSummaries(db.DefaultContext))
+
+	pub := summary(packages_model.TypePub)
+	assert.Equal(t, startPub.LogicalSize+2*pb.Size, pub.LogicalSize)
+	assert.Equal(t, startPub.PhysicalSize+pb.Size, pub.PhysicalSize)
+	assert.Equal(t, startPub.SavedSize()+pb.Size, pub.SavedSize())
+
+	all := summary(packages_model.SizeSummaryTypeAll)
+	assert.Equal(t, startAll.LogicalSize+2*pb.Size, all.LogicalSize)
+	assert.Equal(t, startAll.PhysicalSize+pb.Size, all.PhysicalSize)
+}
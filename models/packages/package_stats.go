@@ -0,0 +1,214 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"sort"
+
+	"code.gitea.io/gitea/models/db"
+
+	"xorm.io/builder"
+)
+
+// PackageTypeStats contains the number of packages and versions of a package type
+type PackageTypeStats struct {
+	Type         Type
+	PackageCount int64
+	VersionCount int64
+}
+
+// PackageStats contains the package statistics of the instance.
+// Packages and versions are counted if they are not internal, blobs regardless of their references.
+type PackageStats struct {
+	PackageCount int64
+	VersionCount int64
+	BlobCount    int64
+	// TotalBlobSize is the size of all distinct blobs
+	TotalBlobSize     int64
+	OrphanedBlobCount int64
+	OrphanedBlobSize  int64
+	// ByType contains the statistics per package type, ordered by type
+	ByType []*PackageTypeStats
+}
+
+// InstancePackageStats gets the package statistics of the instance
+func InstancePackageStats(ctx context.Context) (*PackageStats, error) {
+	stats := &PackageStats{
+		ByType: make([]*PackageTypeStats, 0, 10),
+	}
+
+	if err := db.GetEngine(ctx).
+		Table("package").
+		Select("package.type, COUNT(DISTINCT package.id) AS package_count, COUNT(package_version.id) AS version_count").
+		Join("INNER", "package_version", "package_version.package_id = package.id").
+		Where(builder.Eq{"package_version.is_internal": false}).
+		GroupBy("package.type").
+		OrderBy("package.type ASC").
+		Find(&stats.ByType); err != nil {
+		return nil, err
+	}
+	for _, ts := range stats.ByType {
+		stats.PackageCount += ts.PackageCount
+		stats.VersionCount += ts.VersionCount
+	}
+
+	type blobStats struct {
+		BlobCount int64
+		BlobSize  int64
+	}
+
+	all := make([]*blobStats, 0, 1)
+	if err := db.GetEngine(ctx).
+		Table("package_blob").
+		Select("COUNT(*) AS blob_count, COALESCE(SUM(size), 0) AS blob_size").
+		Find(&all); err != nil {
+		return nil, err
+	}
+	if len(all) == 1 {
+		stats.BlobCount = all[0].BlobCount
+		stats.TotalBlobSize = all[0].BlobSize
+	}
+
+	orphaned := make([]*blobStats, 0, 1)
+	if err := db.GetEngine(ctx).
+		Table("package_blob").
+		Select("COUNT(*) AS blob_count, COALESCE(SUM(size), 0) AS blob_size").
+		Where(builder.Eq{"reference_count": 0}).
+		Find(&orphaned); err != nil {
+		return nil, err
+	}
+	if len(orphaned) == 1 {
+		stats.OrphanedBlobCount = orphaned[0].BlobCount
+		stats.OrphanedBlobSize = orphaned[0].BlobSize
+	}
+
+	return stats, nil
+}
+
+// OwnerPackageTypeStats contains the package statistics of an owner for a package type
+type OwnerPackageTypeStats struct {
+	Type         Type
+	PackageCount int64
+	VersionCount int64
+	FileCount    int64
+	// LogicalSize is the sum of the sizes of all files
+	LogicalSize int64
+	// PhysicalSize is the sum of the sizes of the distinct blobs
+	PhysicalSize int64
+}
+
+// OwnerPackageStats contains the package statistics of an owner.
+// Packages and versions are counted if they are not internal. Files and sizes include internal versions,
+// so the physical size is the storage which counts towards the quota of the owner.
+type OwnerPackageStats struct {
+	PackageCount int64
+	VersionCount int64
+	FileCount    int64
+	LogicalSize  int64
+	// PhysicalSize is the sum of the sizes of the distinct blobs, blobs shared between package types are counted once
+	PhysicalSize int64
+	// ByType contains the statistics per package type, ordered by type
+	ByType []*OwnerPackageTypeStats
+}
+
+// GetOwnerStats gets the package statistics of an owner
+func GetOwnerStats(ctx context.Context, ownerID int64) (*OwnerPackageStats, error) {
+	stats := &OwnerPackageStats{
+		ByType: make([]*OwnerPackageTypeStats, 0, 10),
+	}
+
+	byType := make(map[Type]*OwnerPackageTypeStats)
+	typeStats := func(packageType Type) *OwnerPackageTypeStats {
+		ts, ok := byType[packageType]
+		if !ok {
+			ts = &OwnerPackageTypeStats{Type: packageType}
+			byType[packageType] = ts
+		}
+		return ts
+	}
+
+	counts := make([]*OwnerPackageTypeStats, 0, 10)
+	if err := db.GetEngine(ctx).
+		Table("package").
+		Select("package.type, COUNT(DISTINCT package.id) AS package_count, COUNT(package_version.id) AS version_count").
+		Join("INNER", "package_version", "package_version.package_id = package.id").
+		Where(builder.Eq{
+			"package.owner_id":            ownerID,
+			"package_version.is_internal": false,
+		}).
+		GroupBy("package.type").
+		Find(&counts); err != nil {
+		return nil, err
+	}
+	for _, c := range counts {
+		ts := typeStats(c.Type)
+		ts.PackageCount = c.PackageCount
+		ts.VersionCount = c.VersionCount
+	}
+
+	files := make([]*OwnerPackageTypeStats, 0, 10)
+	if err := db.GetEngine(ctx).
+		Table("package_file").
+		Select("package.type, COUNT(*) AS file_count, SUM(package_blob.size) AS logical_size").
+		Join("INNER", "package_blob", "package_blob.id = package_file.blob_id").
+		Join("INNER", "package_version", "package_version.id = package_file.version_id").
+		Join("INNER", "package", "package.id = package_version.package_id").
+		Where(builder.Eq{"package.owner_id": ownerID}).
+		GroupBy("package.type").
+		Find(&files); err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		ts := typeStats(f.Type)
+		ts.FileCount = f.FileCount
+		ts.LogicalSize = f.LogicalSize
+	}
+
+	blobsByType := builder.
+		Select("DISTINCT package.type, package_blob.id, package_blob.size").
+		From("package_blob").
+		InnerJoin("package_file", "package_file.blob_id = package_blob.id").
+		InnerJoin("package_version", "package_version.id = package_file.version_id").
+		InnerJoin("package", "package.id = package_version.package_id").
+		Where(builder.Eq{"package.owner_id": ownerID})
+
+	physical := make([]*OwnerPackageTypeStats, 0, 10)
+	if err := db.GetEngine(ctx).
+		SQL(builder.Select("type, SUM(size) AS physical_size").From(blobsByType, "blobs").GroupBy("type")).
+		Find(&physical); err != nil {
+		return nil, err
+	}
+	for _, p := range physical {
+		typeStats(p.Type).PhysicalSize = p.PhysicalSize
+	}
+
+	blobs := builder.
+		Select("DISTINCT package_blob.id, package_blob.size").
+		From("package_blob").
+		InnerJoin("package_file", "package_file.blob_id = package_blob.id").
+		InnerJoin("package_version", "package_version.id = package_file.version_id").
+		InnerJoin("package", "package.id = package_version.package_id").
+		Where(builder.Eq{"package.owner_id": ownerID})
+
+	if _, err := db.GetEngine(ctx).
+		SQL(builder.Select("COALESCE(SUM(size), 0)").From(blobs, "blobs")).
+		Get(&stats.PhysicalSize); err != nil {
+		return nil, err
+	}
+
+	for _, ts := range byType {
+		stats.ByType = append(stats.ByType, ts)
+		stats.PackageCount += ts.PackageCount
+		stats.VersionCount += ts.VersionCount
+		stats.FileCount += ts.FileCount
+		stats.LogicalSize += ts.LogicalSize
+	}
+	sort.Slice(stats.ByType, func(i, j int) bool {
+		return stats.ByType[i].Type < stats.ByType[j].Type
+	})
+
+	return stats, nil
+}
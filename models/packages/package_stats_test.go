@@ -0,0 +1,199 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages_test
+
+import (
+	"testing"
+
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/unittest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstancePackageStats(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	typeStats := func(stats *packages_model.PackageStats, packageType packages_model.Type) packages_model.PackageTypeStats {
+		for _, ts := range stats.ByType {
+			if ts.Type == packageType {
+				return *ts
+			}
+		}
+		return packages_model.PackageTypeStats{Type: packageType}
+	}
+
+	start, err := packages_model.InstancePackageStats(db.DefaultContext)
+	assert.NoError(t, err)
+
+	insertVersion := func(p *packages_model.Package, version string, isInternal bool, blobs ...*packages_model.PackageBlob) {
+		pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			Version:      version,
+			LowerVersion: version,
+			IsInternal:   isInternal,
+		})
+		assert.NoError(t, err)
+
+		for _, pb := range blobs {
+			_, err = packages_model.TryInsertFile(db.DefaultContext, &packages_model.PackageFile{
+				VersionID: pv.ID,
+				BlobID:    pb.ID,
+				Name:      pb.HashSHA256,
+				LowerName: pb.HashSHA256,
+			})
+			assert.NoError(t, err)
+		}
+	}
+
+	insertPackage := func(packageType packages_model.Type, name string) *packages_model.Package {
+		p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+			OwnerID:   2,
+			Type:      packageType,
+			Name:      name,
+			LowerName: name,
+		})
+		assert.NoError(t, err)
+		return p
+	}
+
+	shared := insertTestBlob(t, "instance-stats-shared")
+	single := insertTestBlob(t, "instance-stats-single")
+	orphaned := insertTestBlob(t, "instance-stats-orphaned")
+
+	nuget := insertPackage(packages_model.TypeNuGet, "instance-stats-nuget")
+	insertVersion(nuget, "1.0.0", false, shared)
+	insertVersion(nuget, "2.0.0", false, shared, single)
+	// internal versions are not counted
+	insertVersion(nuget, "_internal", true, shared)
+
+	helm := insertPackage(packages_model.TypeHelm, "instance-stats-helm")
+	insertVersion(helm, "1.0.0", false, shared)
+
+	// packages with only internal versions are not counted
+	internal := insertPackage(packages_model.TypeHelm, "instance-stats-internal")
+	insertVersion(internal, "_internal", true)
+
+	stats, err := packages_model.InstancePackageStats(db.DefaultContext)
+	assert.NoError(t, err)
+
+	assert.EqualValues(t, 2, stats.PackageCount-start.PackageCount)
+	assert.EqualValues(t, 3, stats.VersionCount-start.VersionCount)
+	assert.EqualValues(t, 3, stats.BlobCount-start.BlobCount)
+	// the shared blob is counted once
+	assert.Equal(t, shared.Size+single.Size+orphaned.Size, stats.TotalBlobSize-start.TotalBlobSize)
+	assert.EqualValues(t, 1, stats.OrphanedBlobCount-start.OrphanedBlobCount)
+	assert.Equal(t, orphaned.Size, stats.OrphanedBlobSize-start.OrphanedBlobSize)
+
+	startNuGet, nugetStats := typeStats(start, packages_model.TypeNuGet), typeStats(stats, packages_model.TypeNuGet)
+	assert.EqualValues(t, 1, nugetStats.PackageCount-startNuGet.PackageCount)
+	assert.EqualValues(t, 2, nugetStats.VersionCount-startNuGet.VersionCount)
+
+	startHelm, helmStats := typeStats(start, packages_model.TypeHelm), typeStats(stats, packages_model.TypeHelm)
+	assert.EqualValues(t, 1, helmStats.PackageCount-startHelm.PackageCount)
+	assert.EqualValues(t, 1, helmStats.VersionCount-startHelm.VersionCount)
+
+	var sumPackages, sumVersions int64
+	for i, ts := range stats.ByType {
+		if i > 0 {
+			assert.Less(t, string(stats.ByType[i-1].Type), string(ts.Type))
+		}
+		sumPackages += ts.PackageCount
+		sumVersions += ts.VersionCount
+	}
+	assert.Equal(t, stats.PackageCount, sumPackages)
+	assert.Equal(t, stats.VersionCount, sumVersions)
+}
+
+func TestGetOwnerStats(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	const ownerID = 12
+
+	insertVersion := func(p *packages_model.Package, version string, isInternal bool, files map[string]*packages_model.PackageBlob) {
+		pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			Version:      version,
+			LowerVersion: version,
+			IsInternal:   isInternal,
+		})
+		assert.NoError(t, err)
+
+		for name, pb := range files {
+			_, err = packages_model.TryInsertFile(db.DefaultContext, &packages_model.PackageFile{
+				VersionID: pv.ID,
+				BlobID:    pb.ID,
+				Name:      name,
+				LowerName: name,
+			})
+			assert.NoError(t, err)
+		}
+	}
+
+	insertPackage := func(packageType packages_model.Type, name string) *packages_model.Package {
+		p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+			OwnerID:   ownerID,
+			Type:      packageType,
+			Name:      name,
+			LowerName: name,
+		})
+		assert.NoError(t, err)
+		return p
+	}
+
+	stats, err := packages_model.GetOwnerStats(db.DefaultContext, ownerID)
+	assert.NoError(t, err)
+	assert.Equal(t, &packages_model.OwnerPackageStats{ByType: []*packages_model.OwnerPackageTypeStats{}}, stats)
+
+	shared := insertTestBlob(t, "owner-stats-shared")
+	single := insertTestBlob(t, "owner-stats-single")
+	upload := insertTestBlob(t, "owner-stats-upload")
+
+	pypi := insertPackage(packages_model.TypePyPI, "owner-stats-pypi")
+	insertVersion(pypi, "1.0.0", false, map[string]*packages_model.PackageBlob{"a.whl": shared, "b.tar.gz": shared})
+	insertVersion(pypi, "2.0.0", false, map[string]*packages_model.PackageBlob{"a.whl": single})
+
+	generic := insertPackage(packages_model.TypeGeneric, "owner-stats-generic")
+	insertVersion(generic, "1.0.0", false, map[string]*packages_model.PackageBlob{"file.bin": shared})
+	// files of internal versions use storage, but the version is not counted
+	insertVersion(generic, "_upload", true, map[string]*packages_model.PackageBlob{"upload.bin": upload})
+
+	stats, err = packages_model.GetOwnerStats(db.DefaultContext, ownerID)
+	assert.NoError(t, err)
+
+	assert.Equal(t, &packages_model.OwnerPackageStats{
+		PackageCount: 2,
+		VersionCount: 3,
+		FileCount:    5,
+		LogicalSize:  3*shared.Size + single.Size + upload.Size,
+		// the shared blob is counted once for all types
+		PhysicalSize: shared.Size + single.Size + upload.Size,
+		ByType: []*packages_model.OwnerPackageTypeStats{
+			{
+				Type:         packages_model.TypeGeneric,
+				PackageCount: 1,
+				VersionCount: 1,
+				FileCount:    2,
+				LogicalSize:  shared.Size + upload.Size,
+				PhysicalSize: shared.Size + upload.Size,
+			},
+			{
+				Type:         packages_model.TypePyPI,
+				PackageCount: 1,
+				VersionCount: 2,
+				FileCount:    3,
+				LogicalSize:  2*shared.Size + single.Size,
+				PhysicalSize: shared.Size + single.Size,
+			},
+		},
+	}, stats)
+
+	// the physical size matches the used storage of the quota
+	assert.NoError(t, packages_model.RecalculateQuotaUsedSize(db.DefaultContext, ownerID))
+	pq, err := packages_model.GetQuotaByOwnerID(db.DefaultContext, ownerID)
+	assert.NoError(t, err)
+	assert.Equal(t, stats.PhysicalSize, pq.UsedSize)
+}
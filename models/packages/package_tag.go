@@ -0,0 +1,61 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/packages/npm"
+
+	"xorm.io/builder"
+)
+
+// SetTags points all tags to the version of the package in one transaction.
+// A tag can only point to one version of a package, so the tags are removed from the other versions.
+// The tags are stored as version properties.
+func SetTags(ctx context.Context, packageID, versionID int64, tags []string) error {
+	seen := make(map[string]bool, len(tags))
+	unique := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if !seen[tag] {
+			seen[tag] = true
+			unique = append(unique, tag)
+		}
+	}
+	if len(unique) == 0 {
+		return nil
+	}
+
+	return db.WithTx(func(ctx context.Context) error {
+		pv, err := GetVersionByID(ctx, versionID)
+		if err != nil {
+			return err
+		}
+		if pv.PackageID != packageID {
+			return ErrPackageNotExist
+		}
+
+		versionIDs := builder.Select("id").From("package_version").Where(builder.Eq{"package_id": packageID})
+
+		if _, err := db.GetEngine(ctx).
+			Where(builder.Eq{
+				"ref_type": PropertyTypeVersion,
+				"name":     npm.TagProperty,
+			}).
+			And(builder.In("value", unique)).
+			And(builder.In("ref_id", versionIDs)).
+			Delete(&PackageProperty{}); err != nil {
+			return err
+		}
+
+		for _, tag := range unique {
+			if _, err := InsertProperty(ctx, PropertyTypeVersion, versionID, npm.TagProperty, tag); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, ctx)
+}
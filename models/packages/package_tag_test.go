@@ -0,0 +1,70 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages_test
+
+import (
+	"sort"
+	"testing"
+
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/unittest"
+	npm_module "code.gitea.io/gitea/modules/packages/npm"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetTags(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeNpm,
+		Name:      "set-tags",
+		LowerName: "set-tags",
+	})
+	assert.NoError(t, err)
+
+	insertVersion := func(version string) *packages_model.PackageVersion {
+		pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			Version:      version,
+			LowerVersion: version,
+		})
+		assert.NoError(t, err)
+		return pv
+	}
+
+	tagsOf := func(pv *packages_model.PackageVersion) []string {
+		pvps, err := packages_model.GetPropertiesByName(db.DefaultContext, packages_model.PropertyTypeVersion, pv.ID, npm_module.TagProperty)
+		assert.NoError(t, err)
+		tags := make([]string, 0, len(pvps))
+		for _, pvp := range pvps {
+			tags = append(tags, pvp.Value)
+		}
+		sort.Strings(tags)
+		return tags
+	}
+
+	v120 := insertVersion("1.2.0")
+	v130 := insertVersion("1.3.0")
+
+	assert.NoError(t, packages_model.SetTags(db.DefaultContext, p.ID, v120.ID, []string{"latest", "1", "1.2", "latest"}))
+	assert.Equal(t, []string{"1", "1.2", "latest"}, tagsOf(v120))
+	assert.Empty(t, tagsOf(v130))
+
+	assert.NoError(t, packages_model.SetTags(db.DefaultContext, p.ID, v130.ID, []string{"latest", "1"}))
+	assert.Equal(t, []string{"1.2"}, tagsOf(v120))
+	assert.Equal(t, []string{"1", "latest"}, tagsOf(v130))
+
+	// setting the tags again does not duplicate them
+	assert.NoError(t, packages_model.SetTags(db.DefaultContext, p.ID, v130.ID, []string{"latest"}))
+	assert.Equal(t, []string{"1", "latest"}, tagsOf(v130))
+
+	// the version must belong to the package
+	err = packages_model.SetTags(db.DefaultContext, p.ID+1000, v130.ID, []string{"next"})
+	assert.ErrorIs(t, err, packages_model.ErrPackageNotExist)
+	assert.Equal(t, []string{"1", "latest"}, tagsOf(v130))
+}
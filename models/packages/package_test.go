@@ -5,17 +5,29 @@
 package packages_test
 
 import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"code.gitea.io/gitea/models/db"
 	packages_model "code.gitea.io/gitea/models/packages"
 	"code.gitea.io/gitea/models/unittest"
 	user_model "code.gitea.io/gitea/models/user"
+	maven_module "code.gitea.io/gitea/modules/packages/maven"
+	"code.gitea.io/gitea/modules/timeutil"
 
 	_ "code.gitea.io/gitea/models"
 
 	"github.com/stretchr/testify/assert"
+	"xorm.io/builder"
 )
 
 func TestMain(m *testing.M) {
@@ -24,6 +36,23 @@ func TestMain(m *testing.M) {
 	})
 }
 
+func insertTestBlob(t *testing.T, content string) *packages_model.PackageBlob {
+	hashMD5 := md5.Sum([]byte(content))
+	hashSHA1 := sha1.Sum([]byte(content))
+	hashSHA256 := sha256.Sum256([]byte(content))
+	hashSHA512 := sha512.Sum512([]byte(content))
+
+	pb, _, err := packages_model.GetOrInsertBlob(db.DefaultContext, &packages_model.PackageBlob{
+		Size:       int64(len(content)),
+		HashMD5:    hex.EncodeToString(hashMD5[:]),
+		HashSHA1:   hex.EncodeToString(hashSHA1[:]),
+		HashSHA256: hex.EncodeToString(hashSHA256[:]),
+		HashSHA512: hex.EncodeToString(hashSHA512[:]),
+	})
+	assert.NoError(t, err)
+	return pb
+}
+
 func TestHasOwnerPackages(t *testing.T) {
 	assert.NoError(t, unittest.PrepareTestDatabase())
 
@@ -67,3 +96,988 @@ func TestHasOwnerPackages(t *testing.T) {
 	assert.True(t, has)
 	assert.NoError(t, err)
 }
+
+func TestDeleteAllPackages(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	const ownerID = 16
+
+	pb := insertTestBlob(t, "delete-all-packages")
+
+	refIDs := make(map[packages_model.PropertyType][]int64)
+	for _, name := range []string{"package-1", "package-2"} {
+		p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+			OwnerID:   ownerID,
+			Type:      packages_model.TypeGeneric,
+			Name:      name,
+			LowerName: name,
+		})
+		assert.NoError(t, err)
+		pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			Version:      "1.0.0",
+			LowerVersion: "1.0.0",
+		})
+		assert.NoError(t, err)
+		pf, err := packages_model.TryInsertFile(db.DefaultContext, &packages_model.PackageFile{
+			VersionID: pv.ID,
+			BlobID:    pb.ID,
+			Name:      "file.bin",
+			LowerName: "file.bin",
+		})
+		assert.NoError(t, err)
+
+		for refType, refID := range map[packages_model.PropertyType]int64{
+			packages_model.PropertyTypePackage: p.ID,
+			packages_model.PropertyTypeVersion: pv.ID,
+			packages_model.PropertyTypeFile:    pf.ID,
+		} {
+			_, err = packages_model.InsertProperty(db.DefaultContext, refType, refID, "name", "value")
+			assert.NoError(t, err)
+			refIDs[refType] = append(refIDs[refType], refID)
+		}
+	}
+
+	has, err := packages_model.HasOwnerPackages(db.DefaultContext, ownerID)
+	assert.NoError(t, err)
+	assert.True(t, has)
+
+	deleted := make([]string, 0, 2)
+	committed := 0
+	count, err := packages_model.DeleteAllPackages(db.DefaultContext, ownerID, func(ctx context.Context, p *packages_model.Package) error {
+		deleted = append(deleted, p.Name)
+		return nil
+	}, func() {
+		committed++
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.ElementsMatch(t, []string{"package-1", "package-2"}, deleted)
+	assert.Equal(t, 1, committed)
+
+	has, err = packages_model.HasOwnerPackages(db.DefaultContext, ownerID)
+	assert.NoError(t, err)
+	assert.False(t, has)
+	unittest.AssertNotExistsBean(t, &packages_model.Package{OwnerID: ownerID})
+
+	for refType, ids := range refIDs {
+		unittest.AssertCountByCond(t, "package_property", builder.Eq{"ref_type": refType}.And(builder.In("ref_id", ids)), 0)
+	}
+
+	// the blob is kept for the cleanup, but it is not referenced anymore
+	pb = unittest.AssertExistsAndLoadBean(t, &packages_model.PackageBlob{ID: pb.ID})
+	assert.EqualValues(t, 0, pb.ReferenceCount)
+}
+
+func TestCountByType(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	const ownerID = 10
+
+	insertPackage := func(packageType packages_model.Type, name string) *packages_model.Package {
+		p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+			OwnerID:   ownerID,
+			Type:      packageType,
+			Name:      name,
+			LowerName: name,
+		})
+		assert.NoError(t, err)
+		return p
+	}
+	insertVersion := func(p *packages_model.Package, version string, isInternal bool) {
+		_, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			Version:      version,
+			LowerVersion: version,
+			IsInternal:   isInternal,
+		})
+		assert.NoError(t, err)
+	}
+
+	counts, err := packages_model.CountByType(db.DefaultContext, ownerID)
+	assert.NoError(t, err)
+	assert.Empty(t, counts)
+
+	// A package without package versions is not counted
+	insertPackage(packages_model.TypeGeneric, "count-by-type-empty")
+
+	// A package with an internal package version is not counted
+	p := insertPackage(packages_model.TypeContainer, "count-by-type-internal")
+	insertVersion(p, "internal", true)
+
+	counts, err = packages_model.CountByType(db.DefaultContext, ownerID)
+	assert.NoError(t, err)
+	assert.Empty(t, counts)
+
+	// A package with a normal package version is counted once
+	insertVersion(p, "normal", false)
+	insertVersion(p, "normal2", false)
+
+	p = insertPackage(packages_model.TypeNpm, "count-by-type-npm-1")
+	insertVersion(p, "1.0.0", false)
+	p = insertPackage(packages_model.TypeNpm, "count-by-type-npm-2")
+	insertVersion(p, "1.0.0", false)
+
+	counts, err = packages_model.CountByType(db.DefaultContext, ownerID)
+	assert.NoError(t, err)
+	assert.Equal(t, map[packages_model.Type]int64{
+		packages_model.TypeContainer: 1,
+		packages_model.TypeNpm:       2,
+	}, counts)
+}
+
+func TestOwnerPackageTypes(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	const ownerID = 13
+
+	insertPackage := func(packageType packages_model.Type, name string) *packages_model.Package {
+		p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+			OwnerID:   ownerID,
+			Type:      packageType,
+			Name:      name,
+			LowerName: name,
+		})
+		assert.NoError(t, err)
+		return p
+	}
+	insertVersion := func(p *packages_model.Package, version string, isInternal bool) {
+		_, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			Version:      version,
+			LowerVersion: version,
+			IsInternal:   isInternal,
+		})
+		assert.NoError(t, err)
+	}
+
+	types, err := packages_model.OwnerPackageTypes(db.DefaultContext, ownerID)
+	assert.NoError(t, err)
+	assert.Empty(t, types)
+
+	p := insertPackage(packages_model.TypeNpm, "owner-types-npm-1")
+	insertVersion(p, "1.0.0", false)
+	insertVersion(p, "2.0.0", false)
+	p = insertPackage(packages_model.TypeNpm, "owner-types-npm-2")
+	insertVersion(p, "1.0.0", false)
+	p = insertPackage(packages_model.TypeComposer, "owner-types-composer")
+	insertVersion(p, "1.0.0", false)
+
+	// A package without versions is excluded
+	insertPackage(packages_model.TypeGeneric, "owner-types-versionless")
+
+	// A package with only internal versions is excluded
+	p = insertPackage(packages_model.TypeContainer, "owner-types-internal")
+	insertVersion(p, "internal", true)
+
+	types, err = packages_model.OwnerPackageTypes(db.DefaultContext, ownerID)
+	assert.NoError(t, err)
+	assert.Equal(t, []packages_model.Type{packages_model.TypeComposer, packages_model.TypeNpm}, types)
+}
+
+func TestReposWithPackages(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	insertPackage := func(repoID int64, name string, isInternal bool) {
+		p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+			OwnerID:   2,
+			RepoID:    repoID,
+			Type:      packages_model.TypeGeneric,
+			Name:      name,
+			LowerName: name,
+		})
+		assert.NoError(t, err)
+
+		_, err = packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			Version:      "1.0.0",
+			LowerVersion: "1.0.0",
+			IsInternal:   isInternal,
+		})
+		assert.NoError(t, err)
+	}
+
+	insertPackage(1101, "repos-with-packages-a", false)
+	insertPackage(1101, "repos-with-packages-b", false)
+	insertPackage(1103, "repos-with-packages-c", false)
+	insertPackage(1104, "repos-with-packages-internal", true)
+
+	result, err := packages_model.ReposWithPackages(db.DefaultContext, []int64{1101, 1102, 1103, 1104})
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64]bool{
+		1101: true,
+		1102: false,
+		1103: true,
+		1104: false,
+	}, result)
+
+	result, err = packages_model.ReposWithPackages(db.DefaultContext, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, result)
+}
+
+func TestTransferOwnership(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		RepoID:    1,
+		Type:      packages_model.TypeGeneric,
+		Name:      "transfer-package",
+		LowerName: "transfer-package",
+	})
+	assert.NotNil(t, p)
+	assert.NoError(t, err)
+
+	_, err = packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   4,
+		Type:      packages_model.TypeGeneric,
+		Name:      "Transfer-Package",
+		LowerName: "transfer-package",
+	})
+	assert.NoError(t, err)
+
+	// The new owner has a package with the same name already
+	err = packages_model.TransferOwnership(db.DefaultContext, p, 4)
+	assert.ErrorIs(t, err, packages_model.ErrDuplicatePackage)
+
+	// The linked repository does not belong to the new owner and gets unlinked
+	err = packages_model.TransferOwnership(db.DefaultContext, p, 5)
+	assert.NoError(t, err)
+
+	p = unittest.AssertExistsAndLoadBean(t, &packages_model.Package{ID: p.ID})
+	assert.EqualValues(t, 5, p.OwnerID)
+	assert.EqualValues(t, 0, p.RepoID)
+
+	// The linked repository belongs to the new owner and stays linked
+	assert.NoError(t, packages_model.SetRepositoryLink(db.DefaultContext, p.ID, 3))
+	p.RepoID = 3
+
+	err = packages_model.TransferOwnership(db.DefaultContext, p, 3)
+	assert.NoError(t, err)
+
+	p = unittest.AssertExistsAndLoadBean(t, &packages_model.Package{ID: p.ID})
+	assert.EqualValues(t, 3, p.OwnerID)
+	assert.EqualValues(t, 3, p.RepoID)
+}
+
+func TestTouchPackage(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeGeneric,
+		Name:      "touch-package",
+		LowerName: "touch-package",
+	})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, p.UpdatedUnix)
+
+	_, err = db.GetEngine(db.DefaultContext).ID(p.ID).Cols("updated_unix").Update(&packages_model.Package{UpdatedUnix: 1})
+	assert.NoError(t, err)
+
+	_, err = packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+		PackageID:    p.ID,
+		Version:      "1.0.0",
+		LowerVersion: "1.0.0",
+	})
+	assert.NoError(t, err)
+
+	p = unittest.AssertExistsAndLoadBean(t, &packages_model.Package{ID: p.ID})
+	assert.Greater(t, p.UpdatedUnix, timeutil.TimeStamp(1))
+
+	internal, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeGeneric,
+		Name:      "touch-package-internal",
+		LowerName: "touch-package-internal",
+	})
+	assert.NoError(t, err)
+
+	_, err = packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+		PackageID:    internal.ID,
+		Version:      "1.0.0",
+		LowerVersion: "1.0.0",
+		IsInternal:   true,
+	})
+	assert.NoError(t, err)
+
+	ps, err := packages_model.RecentlyActivePackages(db.DefaultContext, 2, 1)
+	assert.NoError(t, err)
+	assert.Len(t, ps, 1)
+	assert.Equal(t, p.ID, ps[0].ID)
+
+	ps, err = packages_model.RecentlyActivePackages(db.DefaultContext, 3, 10)
+	assert.NoError(t, err)
+	for _, pkg := range ps {
+		assert.NotEqual(t, p.ID, pkg.ID)
+	}
+}
+
+func TestSearchPackagesWithFile(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	pb := insertTestBlob(t, "search-packages-with-file")
+
+	insert := func(name, fileName string, isInternal bool) *packages_model.Package {
+		p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+			OwnerID:   2,
+			Type:      packages_model.TypeNpm,
+			Name:      name,
+			LowerName: name,
+		})
+		assert.NoError(t, err)
+
+		pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			Version:      "1.0.0",
+			LowerVersion: "1.0.0",
+			IsInternal:   isInternal,
+		})
+		assert.NoError(t, err)
+
+		_, err = packages_model.TryInsertFile(db.DefaultContext, &packages_model.PackageFile{
+			VersionID: pv.ID,
+			BlobID:    pb.ID,
+			Name:      fileName,
+			LowerName: strings.ToLower(fileName),
+		})
+		assert.NoError(t, err)
+
+		return p
+	}
+
+	p := insert("with-file", "Index.d.ts", false)
+	insert("without-file", "index.js", false)
+	insert("with-internal-file", "index.d.ts", true)
+
+	ps, err := packages_model.SearchPackagesWithFile(db.DefaultContext, 2, packages_model.TypeNpm, "index.D.ts")
+	assert.NoError(t, err)
+	assert.Len(t, ps, 1)
+	assert.Equal(t, p.ID, ps[0].ID)
+
+	ps, err = packages_model.SearchPackagesWithFile(db.DefaultContext, 2, packages_model.TypeGeneric, "index.d.ts")
+	assert.NoError(t, err)
+	assert.Empty(t, ps)
+
+	ps, err = packages_model.SearchPackagesWithFile(db.DefaultContext, 4, packages_model.TypeNpm, "index.d.ts")
+	assert.NoError(t, err)
+	assert.Empty(t, ps)
+}
+
+func TestSearchPackagesWithProperty(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	insert := func(packageType packages_model.Type, name string, properties map[string]string) *packages_model.Package {
+		p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+			OwnerID:   2,
+			Type:      packageType,
+			Name:      name,
+			LowerName: name,
+		})
+		assert.NoError(t, err)
+
+		for name, value := range properties {
+			_, err = packages_model.InsertProperty(db.DefaultContext, packages_model.PropertyTypePackage, p.ID, name, value)
+			assert.NoError(t, err)
+		}
+		return p
+	}
+
+	p1 := insert(packages_model.TypeGeneric, "with-property-b", map[string]string{"property.test": "a"})
+	p2 := insert(packages_model.TypeNpm, "with-property-a", map[string]string{"property.test": "", "property.other": "b"})
+	insert(packages_model.TypeGeneric, "with-other-property", map[string]string{"property.other": "a"})
+	insert(packages_model.TypeGeneric, "without-property", nil)
+
+	// version properties with the name are ignored
+	p := insert(packages_model.TypeGeneric, "with-version-property", nil)
+	pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+		PackageID:    p.ID,
+		Version:      "1.0.0",
+		LowerVersion: "1.0.0",
+	})
+	assert.NoError(t, err)
+	_, err = packages_model.InsertProperty(db.DefaultContext, packages_model.PropertyTypeVersion, pv.ID, "property.test", "a")
+	assert.NoError(t, err)
+
+	ps, err := packages_model.SearchPackagesWithProperty(db.DefaultContext, 2, "property.test")
+	assert.NoError(t, err)
+	assert.Len(t, ps, 2)
+	assert.Equal(t, p2.ID, ps[0].ID)
+	assert.Equal(t, p1.ID, ps[1].ID)
+
+	ps, err = packages_model.SearchPackagesWithProperty(db.DefaultContext, 2, "property.missing")
+	assert.NoError(t, err)
+	assert.Empty(t, ps)
+
+	ps, err = packages_model.SearchPackagesWithProperty(db.DefaultContext, 4, "property.test")
+	assert.NoError(t, err)
+	assert.Empty(t, ps)
+}
+
+func TestPackageDescriptorPackageCreator(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	insert := func(name string, creatorID int64) *packages_model.PackageVersion {
+		p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+			OwnerID:   2,
+			CreatorID: creatorID,
+			Type:      packages_model.TypeGeneric,
+			Name:      name,
+			LowerName: name,
+		})
+		assert.NoError(t, err)
+
+		pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			CreatorID:    2,
+			Version:      "1.0.0",
+			LowerVersion: "1.0.0",
+		})
+		assert.NoError(t, err)
+		return pv
+	}
+
+	pd, err := packages_model.GetPackageDescriptor(db.DefaultContext, insert("package-creator", 4))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 4, pd.Package.CreatorID)
+	assert.EqualValues(t, 4, pd.PackageCreator.ID)
+	assert.EqualValues(t, 2, pd.Creator.ID)
+
+	// a deleted creator is shown as ghost
+	pd, err = packages_model.GetPackageDescriptor(db.DefaultContext, insert("package-creator-deleted", 9999))
+	assert.NoError(t, err)
+	assert.EqualValues(t, user_model.NewGhostUser().ID, pd.PackageCreator.ID)
+	assert.Equal(t, user_model.NewGhostUser().Name, pd.PackageCreator.Name)
+	assert.EqualValues(t, 2, pd.Creator.ID)
+}
+
+func TestRenamePackage(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	insert := func(name string) *packages_model.Package {
+		p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+			OwnerID:   2,
+			Type:      packages_model.TypeGeneric,
+			Name:      name,
+			LowerName: strings.ToLower(name),
+		})
+		assert.NoError(t, err)
+		return p
+	}
+
+	p := insert("rename-pakcage")
+	other := insert("rename-other")
+
+	pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+		PackageID:    p.ID,
+		Version:      "1.0.0",
+		LowerVersion: "1.0.0",
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, packages_model.RenamePackage(db.DefaultContext, p, "Rename-Package"))
+	assert.Equal(t, "Rename-Package", p.Name)
+
+	p = unittest.AssertExistsAndLoadBean(t, &packages_model.Package{ID: p.ID})
+	assert.Equal(t, "Rename-Package", p.Name)
+	assert.Equal(t, "rename-package", p.LowerName)
+	unittest.AssertExistsAndLoadBean(t, &packages_model.PackageVersion{ID: pv.ID, PackageID: p.ID})
+
+	// the old name resolves to the renamed package
+	unittest.AssertExistsAndLoadBean(t, &packages_model.PackageAlias{OwnerID: 2, Type: packages_model.TypeGeneric, LowerName: "rename-pakcage", PackageID: p.ID})
+
+	byAlias, err := packages_model.GetPackageByName(db.DefaultContext, 2, packages_model.TypeGeneric, "Rename-Pakcage")
+	assert.NoError(t, err)
+	assert.Equal(t, p.ID, byAlias.ID)
+
+	pvByAlias, err := packages_model.GetVersionByNameAndVersion(db.DefaultContext, 2, packages_model.TypeGeneric, "rename-pakcage", "1.0.0")
+	assert.NoError(t, err)
+	assert.Equal(t, pv.ID, pvByAlias.ID)
+
+	// publishing to the old name adds to the renamed package
+	existing, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeGeneric,
+		Name:      "rename-pakcage",
+		LowerName: "rename-pakcage",
+	})
+	assert.ErrorIs(t, err, packages_model.ErrDuplicatePackage)
+	assert.Equal(t, p.ID, existing.ID)
+
+	// changing only the case is no collision and leaves no alias
+	assert.NoError(t, packages_model.RenamePackage(db.DefaultContext, p, "rename-package"))
+	unittest.AssertNotExistsBean(t, &packages_model.PackageAlias{LowerName: "rename-package"})
+
+	assert.ErrorIs(t, packages_model.RenamePackage(db.DefaultContext, p, "Rename-Other"), packages_model.ErrDuplicatePackage)
+
+	p = unittest.AssertExistsAndLoadBean(t, &packages_model.Package{ID: p.ID})
+	assert.Equal(t, "rename-package", p.Name)
+	other = unittest.AssertExistsAndLoadBean(t, &packages_model.Package{ID: other.ID})
+	assert.Equal(t, "rename-other", other.Name)
+
+	// renaming back to the old name removes the alias
+	assert.NoError(t, packages_model.RenamePackage(db.DefaultContext, p, "rename-pakcage"))
+	unittest.AssertNotExistsBean(t, &packages_model.PackageAlias{LowerName: "rename-pakcage"})
+	unittest.AssertExistsAndLoadBean(t, &packages_model.PackageAlias{LowerName: "rename-package", PackageID: p.ID})
+
+	assert.NoError(t, packages_model.DeletePackageByID(db.DefaultContext, p.ID))
+	unittest.AssertNotExistsBean(t, &packages_model.PackageAlias{PackageID: p.ID})
+
+	_, err = packages_model.GetPackageByName(db.DefaultContext, 2, packages_model.TypeGeneric, "rename-package")
+	assert.ErrorIs(t, err, packages_model.ErrPackageNotExist)
+}
+
+func TestRenormalizePackageNames(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	insert := func(name, lowerName string) *packages_model.Package {
+		p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+			OwnerID:   2,
+			Type:      packages_model.TypeGeneric,
+			Name:      name,
+			LowerName: lowerName,
+		})
+		assert.NoError(t, err)
+		return p
+	}
+
+	// seeded with the old (unnormalized) form
+	p := insert("Renormalize-Package", "Renormalize-Package")
+	collision := insert("Renormalize-Other", "Renormalize-Other")
+	insert("renormalize-other", "renormalize-other")
+
+	insertVersion := func(version, lowerVersion string) *packages_model.PackageVersion {
+		pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			Version:      version,
+			LowerVersion: lowerVersion,
+		})
+		assert.NoError(t, err)
+		return pv
+	}
+
+	pv := insertVersion("1.0.0-RC", "1.0.0-RC")
+	insertVersion("1.0.0", "1.0.0")
+
+	updated, err := packages_model.RenormalizePackageNames(db.DefaultContext)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, updated)
+
+	p = unittest.AssertExistsAndLoadBean(t, &packages_model.Package{ID: p.ID})
+	assert.Equal(t, "Renormalize-Package", p.Name)
+	assert.Equal(t, "renormalize-package", p.LowerName)
+	pv = unittest.AssertExistsAndLoadBean(t, &packages_model.PackageVersion{ID: pv.ID})
+	assert.Equal(t, "1.0.0-RC", pv.Version)
+	assert.Equal(t, "1.0.0-rc", pv.LowerVersion)
+
+	// the colliding row is skipped
+	collision = unittest.AssertExistsAndLoadBean(t, &packages_model.Package{ID: collision.ID})
+	assert.Equal(t, "Renormalize-Other", collision.LowerName)
+
+	// nothing left to update
+	updated, err = packages_model.RenormalizePackageNames(db.DefaultContext)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, updated)
+
+	ok, err := packages_model.ExistsPackage(db.DefaultContext, 2, packages_model.TypeGeneric, "RENORMALIZE-PACKAGE")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestStalePackages(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	now := time.Now()
+
+	insert := func(name string, versionAges ...time.Duration) *packages_model.Package {
+		p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+			OwnerID:   2,
+			Type:      packages_model.TypeGeneric,
+			Name:      name,
+			LowerName: name,
+		})
+		assert.NoError(t, err)
+
+		for i, age := range versionAges {
+			version := fmt.Sprintf("1.0.%d", i)
+			pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+				PackageID:    p.ID,
+				Version:      version,
+				LowerVersion: version,
+				IsInternal:   age < 0,
+			})
+			assert.NoError(t, err)
+
+			if age < 0 {
+				age = -age
+			}
+			_, err = db.GetEngine(db.DefaultContext).ID(pv.ID).Cols("created_unix").NoAutoTime().Update(&packages_model.PackageVersion{CreatedUnix: timeutil.TimeStamp(now.Add(-age).Unix())})
+			assert.NoError(t, err)
+		}
+		return p
+	}
+
+	day := 24 * time.Hour
+
+	// negative ages mark internal versions
+	stale := insert("stale-package-old", 100*day, 40*day)
+	fresh := insert("stale-package-fresh", 100*day, 2*day)
+	internalOnly := insert("stale-package-internal", -100*day)
+	freshInternal := insert("stale-package-fresh-internal", 50*day, -1*day)
+
+	ids := func(ps []*packages_model.Package) []int64 {
+		result := make([]int64, 0, len(ps))
+		for _, p := range ps {
+			result = append(result, p.ID)
+		}
+		return result
+	}
+
+	ps, err := packages_model.StalePackages(db.DefaultContext, 2, 30*day, 100)
+	assert.NoError(t, err)
+	assert.Contains(t, ids(ps), stale.ID)
+	assert.Contains(t, ids(ps), freshInternal.ID)
+	assert.NotContains(t, ids(ps), fresh.ID)
+	assert.NotContains(t, ids(ps), internalOnly.ID)
+
+	ps, err = packages_model.StalePackages(db.DefaultContext, 2, 60*day, 100)
+	assert.NoError(t, err)
+	assert.NotContains(t, ids(ps), stale.ID)
+	assert.NotContains(t, ids(ps), freshInternal.ID)
+
+	ps, err = packages_model.StalePackages(db.DefaultContext, 3, 30*day, 100)
+	assert.NoError(t, err)
+	assert.NotContains(t, ids(ps), stale.ID)
+}
+
+func TestCleanupVersionlessPackages(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	insert := func(name string, createdUnix timeutil.TimeStamp, withVersion bool) *packages_model.Package {
+		p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+			OwnerID:   2,
+			Type:      packages_model.TypeGeneric,
+			Name:      name,
+			LowerName: name,
+		})
+		assert.NoError(t, err)
+		_, err = db.GetEngine(db.DefaultContext).ID(p.ID).Cols("created_unix").NoAutoTime().Update(&packages_model.Package{CreatedUnix: createdUnix})
+		assert.NoError(t, err)
+		if withVersion {
+			_, err = packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+				PackageID:    p.ID,
+				Version:      "internal",
+				LowerVersion: "internal",
+				IsInternal:   true,
+			})
+			assert.NoError(t, err)
+		}
+		return p
+	}
+
+	old := timeutil.TimeStamp(time.Now().Add(-48 * time.Hour).Unix())
+
+	versionless := insert("versionless-package", old, false)
+	_, err := packages_model.InsertProperty(db.DefaultContext, packages_model.PropertyTypePackage, versionless.ID, "name", "value")
+	assert.NoError(t, err)
+	recent := insert("recent-versionless-package", timeutil.TimeStampNow(), false)
+	withVersion := insert("versionless-package-with-version", old, true)
+
+	deleted, err := packages_model.CleanupVersionlessPackages(db.DefaultContext, 24*time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	unittest.AssertNotExistsBean(t, &packages_model.Package{ID: versionless.ID})
+	unittest.AssertNotExistsBean(t, &packages_model.PackageProperty{RefType: packages_model.PropertyTypePackage, RefID: versionless.ID})
+	unittest.AssertExistsAndLoadBean(t, &packages_model.Package{ID: recent.ID})
+	unittest.AssertExistsAndLoadBean(t, &packages_model.Package{ID: withVersion.ID})
+
+	// running it again deletes nothing
+	deleted, err = packages_model.CleanupVersionlessPackages(db.DefaultContext, 24*time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, deleted)
+}
+
+func TestOrphanedPackages(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	owner := &user_model.User{
+		Name:      "orphaned-packages-owner",
+		LowerName: "orphaned-packages-owner",
+		Email:     "orphaned-packages-owner@example.com",
+	}
+	assert.NoError(t, db.Insert(db.DefaultContext, owner))
+
+	insert := func(ownerID int64, name string) *packages_model.Package {
+		p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+			OwnerID:   ownerID,
+			Type:      packages_model.TypeGeneric,
+			Name:      name,
+			LowerName: name,
+		})
+		assert.NoError(t, err)
+		return p
+	}
+
+	orphaned := insert(owner.ID, "orphaned-package")
+	kept := insert(2, "not-orphaned-package")
+
+	ids := func() []int64 {
+		ps, err := packages_model.OrphanedPackages(db.DefaultContext, 100)
+		assert.NoError(t, err)
+
+		result := make([]int64, 0, len(ps))
+		for _, p := range ps {
+			result = append(result, p.ID)
+		}
+		return result
+	}
+
+	assert.NotContains(t, ids(), orphaned.ID)
+
+	// remove the owner without cleaning up its packages
+	_, err := db.GetEngine(db.DefaultContext).ID(owner.ID).Delete(&user_model.User{})
+	assert.NoError(t, err)
+
+	found := ids()
+	assert.Contains(t, found, orphaned.ID)
+	assert.NotContains(t, found, kept.ID)
+}
+
+func TestSearchPackagesByNamespace(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	insert := func(packageType packages_model.Type, name string) {
+		_, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+			OwnerID:   2,
+			Type:      packageType,
+			Name:      name,
+			LowerName: strings.ToLower(name),
+		})
+		assert.NoError(t, err)
+	}
+
+	search := func(packageType packages_model.Type, namespace string) []string {
+		ps, err := packages_model.SearchPackagesByNamespace(db.DefaultContext, 2, packageType, namespace)
+		assert.NoError(t, err)
+		names := make([]string, 0, len(ps))
+		for _, p := range ps {
+			names = append(names, p.Name)
+		}
+		return names
+	}
+
+	t.Run("Npm", func(t *testing.T) {
+		insert(packages_model.TypeNpm, "@ns-scope/b")
+		insert(packages_model.TypeNpm, "@ns-scope/a")
+		insert(packages_model.TypeNpm, "@NS-Scope/Upper")
+		insert(packages_model.TypeNpm, "@ns-scope-other/c")
+		insert(packages_model.TypeNpm, "ns-scope")
+		insert(packages_model.TypeComposer, "@ns-scope/composer")
+
+		expected := []string{"@ns-scope/a", "@ns-scope/b", "@NS-Scope/Upper"}
+		assert.Equal(t, expected, search(packages_model.TypeNpm, "@ns-scope/"))
+		assert.Equal(t, expected, search(packages_model.TypeNpm, "@ns-scope"))
+		assert.Equal(t, expected, search(packages_model.TypeNpm, "@NS-SCOPE"))
+		assert.Empty(t, search(packages_model.TypeNpm, "@ns-scope/a"))
+		assert.Empty(t, search(packages_model.TypeNpm, ""))
+	})
+
+	t.Run("Maven", func(t *testing.T) {
+		insertMaven := func(groupID, artifactID string) {
+			name := groupID + "-" + artifactID
+			insert(packages_model.TypeMaven, name)
+
+			p, err := packages_model.GetPackageByName(db.DefaultContext, 2, packages_model.TypeMaven, name)
+			assert.NoError(t, err)
+			_, err = packages_model.InsertProperty(db.DefaultContext, packages_model.PropertyTypePackage, p.ID, maven_module.PropertyGroupID, groupID)
+			assert.NoError(t, err)
+		}
+
+		insertMaven("com.my", "core")
+		insertMaven("com.my", "api-client")
+		insertMaven("com.my.sub", "core")
+		// the name starts with "com.my-" but the groupId is different
+		insertMaven("com.my-company", "core")
+		insertMaven("org.ns_test", "core")
+		insertMaven("org.nsXtest", "core")
+		// packages without a stored groupId are never in a namespace
+		insert(packages_model.TypeMaven, "com.my-legacy")
+
+		expected := []string{"com.my-api-client", "com.my-core"}
+		assert.Equal(t, expected, search(packages_model.TypeMaven, "com.my"))
+		assert.Equal(t, expected, search(packages_model.TypeMaven, "com.my:"))
+		assert.Equal(t, expected, search(packages_model.TypeMaven, "COM.MY"))
+		assert.Equal(t, []string{"com.my-company-core"}, search(packages_model.TypeMaven, "com.my-company:"))
+		assert.Equal(t, []string{"com.my.sub-core"}, search(packages_model.TypeMaven, "com.my.sub"))
+		assert.Equal(t, []string{"org.ns_test-core"}, search(packages_model.TypeMaven, "org.ns_test"))
+		assert.Empty(t, search(packages_model.TypeMaven, "com.my-"))
+		assert.Empty(t, search(packages_model.TypeMaven, "com"))
+		assert.Empty(t, search(packages_model.TypeMaven, ":"))
+	})
+
+	_, err := packages_model.SearchPackagesByNamespace(db.DefaultContext, 2, packages_model.TypeGeneric, "ns")
+	assert.ErrorIs(t, err, packages_model.ErrNamespaceNotSupported)
+}
+
+func TestGetPackageByID(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeGeneric,
+		Name:      "get-package-by-id",
+		LowerName: "get-package-by-id",
+	})
+	assert.NoError(t, err)
+
+	insertVersion := func(version string, created timeutil.TimeStamp, isInternal bool) *packages_model.PackageVersion {
+		pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			Version:      version,
+			LowerVersion: version,
+			IsInternal:   isInternal,
+		})
+		assert.NoError(t, err)
+		_, err = db.GetEngine(db.DefaultContext).ID(pv.ID).Cols("created_unix").NoAutoTime().Update(&packages_model.PackageVersion{CreatedUnix: created})
+		assert.NoError(t, err)
+		return pv
+	}
+
+	v1 := insertVersion("1.0.0", 100, false)
+	v2 := insertVersion("2.0.0", 200, false)
+	insertVersion("internal", 300, true)
+
+	t.Run("WithoutVersions", func(t *testing.T) {
+		pkg, err := packages_model.GetPackageByID(db.DefaultContext, p.ID, false)
+		assert.NoError(t, err)
+		assert.Equal(t, "get-package-by-id", pkg.Name)
+		assert.Nil(t, pkg.Versions)
+	})
+
+	t.Run("WithVersions", func(t *testing.T) {
+		pkg, err := packages_model.GetPackageByID(db.DefaultContext, p.ID, true)
+		assert.NoError(t, err)
+		assert.Equal(t, "get-package-by-id", pkg.Name)
+		assert.Len(t, pkg.Versions, 2)
+		assert.Equal(t, v2.ID, pkg.Versions[0].ID)
+		assert.Equal(t, v1.ID, pkg.Versions[1].ID)
+	})
+
+	t.Run("NotExist", func(t *testing.T) {
+		for _, withVersions := range []bool{false, true} {
+			pkg, err := packages_model.GetPackageByID(db.DefaultContext, p.ID+1000, withVersions)
+			assert.ErrorIs(t, err, packages_model.ErrPackageNotExist)
+			assert.Nil(t, pkg)
+		}
+	})
+}
+
+func TestPackageVisibility(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	owner := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 14})
+	admin := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1})
+	other := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 4})
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   owner.ID,
+		Type:      packages_model.TypeGeneric,
+		Name:      "package-visibility",
+		LowerName: "package-visibility",
+	})
+	assert.NoError(t, err)
+	_, err = packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+		PackageID:    p.ID,
+		Version:      "1.0.0",
+		LowerVersion: "1.0.0",
+	})
+	assert.NoError(t, err)
+
+	assertPrivate := func(t *testing.T, expected bool) {
+		p, err := packages_model.GetPackageByID(db.DefaultContext, p.ID, false)
+		assert.NoError(t, err)
+		isPrivate, err := packages_model.IsPackagePrivate(db.DefaultContext, p)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, isPrivate)
+
+		counts, err := packages_model.CountPublicByType(db.DefaultContext, owner.ID)
+		assert.NoError(t, err)
+		if expected {
+			assert.Empty(t, counts)
+		} else {
+			assert.EqualValues(t, 1, counts[packages_model.TypeGeneric])
+		}
+		counts, err = packages_model.CountByType(db.DefaultContext, owner.ID)
+		assert.NoError(t, err)
+		assert.EqualValues(t, 1, counts[packages_model.TypeGeneric])
+
+		_, total, err := packages_model.SearchVersions(db.DefaultContext, &packages_model.PackageSearchOptions{
+			OwnerID:     owner.ID,
+			HidePrivate: true,
+		})
+		assert.NoError(t, err)
+		if expected {
+			assert.EqualValues(t, 0, total)
+		} else {
+			assert.EqualValues(t, 1, total)
+		}
+	}
+
+	assertVisibleTo := func(t *testing.T, actor *user_model.User, expected bool) {
+		_, total, err := packages_model.SearchVersions(db.DefaultContext, &packages_model.PackageSearchOptions{
+			OwnerID:                 owner.ID,
+			Actor:                   actor,
+			RestrictToVisibleOwners: true,
+		})
+		assert.NoError(t, err)
+		if expected {
+			assert.EqualValues(t, 1, total)
+		} else {
+			assert.EqualValues(t, 0, total)
+		}
+	}
+
+	t.Run("Inherit", func(t *testing.T) {
+		assertPrivate(t, false)
+		assertVisibleTo(t, nil, true)
+		assertVisibleTo(t, other, true)
+	})
+
+	t.Run("PrivateRepository", func(t *testing.T) {
+		assert.NoError(t, packages_model.SetRepositoryLink(db.DefaultContext, p.ID, 2))
+		assertPrivate(t, true)
+		assert.NoError(t, packages_model.SetRepositoryLink(db.DefaultContext, p.ID, 1))
+		assertPrivate(t, false)
+		assert.NoError(t, packages_model.SetRepositoryLink(db.DefaultContext, p.ID, 0))
+	})
+
+	t.Run("Private", func(t *testing.T) {
+		assert.NoError(t, packages_model.SetVisibility(db.DefaultContext, p.ID, packages_model.VisibilityPrivate))
+		assertPrivate(t, true)
+		assertVisibleTo(t, nil, false)
+		assertVisibleTo(t, other, false)
+		assertVisibleTo(t, owner, true)
+		assertVisibleTo(t, admin, true)
+
+		assert.NoError(t, packages_model.SetVisibility(db.DefaultContext, p.ID, packages_model.VisibilityInherit))
+		assertPrivate(t, false)
+	})
+}
+
+func TestParseVisibility(t *testing.T) {
+	for name, expected := range map[string]packages_model.Visibility{
+		"inherit": packages_model.VisibilityInherit,
+		"private": packages_model.VisibilityPrivate,
+		"Private": packages_model.VisibilityPrivate,
+	} {
+		v, ok := packages_model.ParseVisibility(name)
+		assert.True(t, ok, name)
+		assert.Equal(t, expected, v)
+		assert.Equal(t, strings.ToLower(name), v.String())
+	}
+
+	_, ok := packages_model.ParseVisibility("public")
+	assert.False(t, ok)
+}
@@ -0,0 +1,148 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+
+	"code.gitea.io/gitea/models/db"
+
+	"xorm.io/builder"
+)
+
+func init() {
+	db.RegisterModel(new(PackageTypeLimit))
+}
+
+// TypeLimitDefault indicates that the configured default limit applies to a package type
+const TypeLimitDefault = -1
+
+// PackageTypeLimit stores limit overrides for a package type
+type PackageTypeLimit struct {
+	ID             int64 `xorm:"pk autoincr"`
+	Type           Type  `xorm:"UNIQUE NOT NULL"`
+	MaxVersions    int64 `xorm:"NOT NULL DEFAULT -1"`
+	MaxVersionSize int64 `xorm:"NOT NULL DEFAULT -1"`
+	MaxOwnerSize   int64 `xorm:"NOT NULL DEFAULT -1"`
+}
+
+// GetTypeLimit gets the limit overrides of a package type. If no overrides are stored, an empty limit using the configured defaults is returned.
+func GetTypeLimit(ctx context.Context, packageType Type) (*PackageTypeLimit, error) {
+	ptl := &PackageTypeLimit{Type: packageType}
+
+	has, err := db.GetEngine(ctx).Get(ptl)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		ptl.MaxVersions = TypeLimitDefault
+		ptl.MaxVersionSize = TypeLimitDefault
+		ptl.MaxOwnerSize = TypeLimitDefault
+	}
+	return ptl, nil
+}
+
+// SetTypeLimit stores the limit overrides of a package type. Use TypeLimitDefault to apply the configured default.
+func SetTypeLimit(ctx context.Context, ptl *PackageTypeLimit) error {
+	e := db.GetEngine(ctx)
+
+	has, err := e.Where("type = ?", ptl.Type).Exist(&PackageTypeLimit{})
+	if err != nil {
+		return err
+	}
+	if !has {
+		_, err = e.Insert(&PackageTypeLimit{
+			Type:           ptl.Type,
+			MaxVersions:    ptl.MaxVersions,
+			MaxVersionSize: ptl.MaxVersionSize,
+			MaxOwnerSize:   ptl.MaxOwnerSize,
+		})
+		return err
+	}
+
+	_, err = e.Where("type = ?", ptl.Type).Cols("max_versions", "max_version_size", "max_owner_size").Update(ptl)
+	return err
+}
+
+// TypeLimitUsage contains the current usage which is evaluated against the limits of a package type
+type TypeLimitUsage struct {
+	// VersionCount is the number of (non-internal) versions of the package
+	VersionCount int64
+	// VersionSize is the size of all files of the version
+	VersionSize int64
+	// OwnerSize is the size of all blobs referenced by packages of the owner with the package type.
+	// Blobs referenced by multiple files are counted once.
+	OwnerSize int64
+}
+
+// GetTypeLimitUsage gets the current usage of the package version, the package and the owner in a single query
+func GetTypeLimitUsage(ctx context.Context, ownerID int64, packageType Type, packageID, versionID int64) (*TypeLimitUsage, error) {
+	versionCount := builder.
+		Select("COUNT(*)").
+		From("package_version").
+		Where(builder.Eq{
+			"package_version.package_id":  packageID,
+			"package_version.is_internal": false,
+		})
+
+	versionSize := builder.
+		Select("COALESCE(SUM(package_blob.size), 0)").
+		From("package_file").
+		InnerJoin("package_blob", "package_blob.id = package_file.blob_id").
+		Where(builder.Eq{"package_file.version_id": versionID})
+
+	ownerBlobs := builder.
+		Select("DISTINCT package_blob.id, package_blob.size").
+		From("package_blob").
+		InnerJoin("package_file", "package_file.blob_id = package_blob.id").
+		InnerJoin("package_version", "package_version.id = package_file.version_id").
+		InnerJoin("package", "package.id = package_version.package_id").
+		Where(builder.Eq{
+			"package.owner_id": ownerID,
+			"package.type":     packageType,
+		})
+	ownerSize := builder.Select("COALESCE(SUM(size), 0)").From(ownerBlobs, "blobs")
+
+	var args []interface{}
+	query := "SELECT "
+	for i, column := range []struct {
+		Name  string
+		Query *builder.Builder
+	}{
+		{"version_count", versionCount},
+		{"version_size", versionSize},
+		{"owner_size", ownerSize},
+	} {
+		sql, sqlArgs, err := column.Query.ToSQL()
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			query += ", "
+		}
+		query += "(" + sql + ") AS " + column.Name
+		args = append(args, sqlArgs...)
+	}
+
+	usage := &TypeLimitUsage{}
+	if _, err := db.GetEngine(ctx).SQL(query, args...).Get(usage); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// IsBlobReferencedByOwnerAndType tests if a blob is referenced by any file of a package of the owner with the package type
+func IsBlobReferencedByOwnerAndType(ctx context.Context, ownerID int64, packageType Type, blobID int64) (bool, error) {
+	return db.GetEngine(ctx).
+		Table("package_file").
+		Join("INNER", "package_version", "package_version.id = package_file.version_id").
+		Join("INNER", "package", "package.id = package_version.package_id").
+		Where(builder.Eq{
+			"package.owner_id":     ownerID,
+			"package.type":         packageType,
+			"package_file.blob_id": blobID,
+		}).
+		Exist(&PackageFile{})
+}
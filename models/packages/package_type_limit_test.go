@@ -0,0 +1,118 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages_test
+
+import (
+	"testing"
+
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/unittest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetTypeLimit(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	ptl, err := packages_model.GetTypeLimit(db.DefaultContext, packages_model.TypeHelm)
+	assert.NoError(t, err)
+	assert.EqualValues(t, packages_model.TypeLimitDefault, ptl.MaxVersions)
+	assert.EqualValues(t, packages_model.TypeLimitDefault, ptl.MaxVersionSize)
+	assert.EqualValues(t, packages_model.TypeLimitDefault, ptl.MaxOwnerSize)
+
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, packages_model.SetTypeLimit(db.DefaultContext, &packages_model.PackageTypeLimit{
+			Type:           packages_model.TypeHelm,
+			MaxVersions:    10,
+			MaxVersionSize: packages_model.TypeLimitDefault,
+			MaxOwnerSize:   1024,
+		}))
+	}
+
+	ptl, err = packages_model.GetTypeLimit(db.DefaultContext, packages_model.TypeHelm)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 10, ptl.MaxVersions)
+	assert.EqualValues(t, packages_model.TypeLimitDefault, ptl.MaxVersionSize)
+	assert.EqualValues(t, 1024, ptl.MaxOwnerSize)
+
+	ptl, err = packages_model.GetTypeLimit(db.DefaultContext, packages_model.TypeNpm)
+	assert.NoError(t, err)
+	assert.EqualValues(t, packages_model.TypeLimitDefault, ptl.MaxVersions)
+}
+
+func TestGetTypeLimitUsage(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeGeneric,
+		Name:      "type-limit-package",
+		LowerName: "type-limit-package",
+	})
+	assert.NoError(t, err)
+
+	insertVersion := func(version string, isInternal bool) *packages_model.PackageVersion {
+		pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			Version:      version,
+			LowerVersion: version,
+			IsInternal:   isInternal,
+		})
+		assert.NoError(t, err)
+		return pv
+	}
+
+	insertFile := func(pv *packages_model.PackageVersion, pb *packages_model.PackageBlob, name string) {
+		_, err := packages_model.TryInsertFile(db.DefaultContext, &packages_model.PackageFile{
+			VersionID: pv.ID,
+			BlobID:    pb.ID,
+			Name:      name,
+			LowerName: name,
+		})
+		assert.NoError(t, err)
+	}
+
+	usage := func(pv *packages_model.PackageVersion) *packages_model.TypeLimitUsage {
+		u, err := packages_model.GetTypeLimitUsage(db.DefaultContext, p.OwnerID, p.Type, p.ID, pv.ID)
+		assert.NoError(t, err)
+		return u
+	}
+
+	pv1 := insertVersion("1.0.0", false)
+
+	start := usage(pv1)
+	assert.EqualValues(t, 1, start.VersionCount)
+	assert.EqualValues(t, 0, start.VersionSize)
+
+	pbA := insertTestBlob(t, "type-limit-usage-a")
+	pbB := insertTestBlob(t, "type-limit-usage-blob-b")
+
+	insertFile(pv1, pbA, "a.bin")
+	insertFile(pv1, pbB, "b.bin")
+
+	pv2 := insertVersion("2.0.0", false)
+	insertFile(pv2, pbA, "a.bin")
+
+	// internal versions are not counted
+	insertVersion("internal", true)
+
+	u := usage(pv1)
+	assert.EqualValues(t, 2, u.VersionCount)
+	assert.Equal(t, pbA.Size+pbB.Size, u.VersionSize)
+	// blobs shared between versions are counted once
+	assert.Equal(t, start.OwnerSize+pbA.Size+pbB.Size, u.OwnerSize)
+
+	u = usage(pv2)
+	assert.Equal(t, pbA.Size, u.VersionSize)
+
+	has, err := packages_model.IsBlobReferencedByOwnerAndType(db.DefaultContext, p.OwnerID, packages_model.TypeGeneric, pbB.ID)
+	assert.NoError(t, err)
+	assert.True(t, has)
+
+	has, err = packages_model.IsBlobReferencedByOwnerAndType(db.DefaultContext, p.OwnerID, packages_model.TypeNpm, pbB.ID)
+	assert.NoError(t, err)
+	assert.False(t, has)
+}
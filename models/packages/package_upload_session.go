@@ -0,0 +1,116 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/util"
+
+	"xorm.io/builder"
+)
+
+// ErrPackageUploadSessionNotExist indicates a package upload session not exist error
+var ErrPackageUploadSessionNotExist = errors.New("Package upload session does not exist")
+
+func init() {
+	db.RegisterModel(new(PackageUploadSession))
+}
+
+// PackageUploadSession represents a chunked upload of a package file.
+// The received data is stored in a temporary file named after the session id until the upload is completed.
+type PackageUploadSession struct {
+	ID             string             `xorm:"pk"`
+	OwnerID        int64              `xorm:"INDEX NOT NULL"`
+	CreatorID      int64              `xorm:"NOT NULL DEFAULT 0"`
+	Type           Type               `xorm:"INDEX NOT NULL"`
+	PackageName    string             `xorm:"NOT NULL"`
+	PackageVersion string             `xorm:"NOT NULL DEFAULT ''"`
+	Filename       string             `xorm:"NOT NULL DEFAULT ''"`
+	BytesReceived  int64              `xorm:"NOT NULL DEFAULT 0"`
+	HashStateBytes []byte             `xorm:"BLOB"`
+	ExpiresUnix    timeutil.TimeStamp `xorm:"INDEX NOT NULL"`
+	CreatedUnix    timeutil.TimeStamp `xorm:"created NOT NULL"`
+	UpdatedUnix    timeutil.TimeStamp `xorm:"updated NOT NULL"`
+}
+
+// IsExpired returns true if the session can not be continued anymore
+func (pus *PackageUploadSession) IsExpired() bool {
+	return pus.ExpiresUnix < timeutil.TimeStampNow()
+}
+
+// CreateUploadSession inserts an upload session with a new random id
+func CreateUploadSession(ctx context.Context, pus *PackageUploadSession) (*PackageUploadSession, error) {
+	id, err := util.CryptoRandomString(25)
+	if err != nil {
+		return nil, err
+	}
+
+	pus.ID = strings.ToLower(id)
+
+	_, err = db.GetEngine(ctx).Insert(pus)
+	return pus, err
+}
+
+// GetUploadSessionByID gets an upload session by id
+func GetUploadSessionByID(ctx context.Context, id string) (*PackageUploadSession, error) {
+	pus := &PackageUploadSession{}
+
+	has, err := db.GetEngine(ctx).ID(id).Get(pus)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, ErrPackageUploadSessionNotExist
+	}
+	return pus, nil
+}
+
+// GetUploadSessionForFile gets the newest upload session of the file of a package version
+func GetUploadSessionForFile(ctx context.Context, ownerID int64, packageType Type, name, version, filename string) (*PackageUploadSession, error) {
+	pus := &PackageUploadSession{}
+
+	has, err := db.GetEngine(ctx).
+		Where(builder.Eq{
+			"owner_id":        ownerID,
+			"type":            packageType,
+			"package_name":    name,
+			"package_version": version,
+			"filename":        filename,
+		}).
+		Desc("created_unix").
+		Get(pus)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, ErrPackageUploadSessionNotExist
+	}
+	return pus, nil
+}
+
+// UpdateUploadSession updates the upload session
+func UpdateUploadSession(ctx context.Context, pus *PackageUploadSession) error {
+	_, err := db.GetEngine(ctx).ID(pus.ID).Update(pus)
+	return err
+}
+
+// DeleteUploadSessionByID deletes the upload session
+func DeleteUploadSessionByID(ctx context.Context, id string) error {
+	_, err := db.GetEngine(ctx).ID(id).Delete(&PackageUploadSession{})
+	return err
+}
+
+// FindExpiredUploadSessions gets all expired upload sessions
+func FindExpiredUploadSessions(ctx context.Context) ([]*PackageUploadSession, error) {
+	puss := make([]*PackageUploadSession, 0, 10)
+	return puss, db.GetEngine(ctx).
+		Where("expires_unix < ?", timeutil.TimeStampNow()).
+		Find(&puss)
+}
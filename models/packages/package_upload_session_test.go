@@ -0,0 +1,64 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages_test
+
+import (
+	"testing"
+
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/unittest"
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadSession(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	createSession := func(filename string, expires timeutil.TimeStamp) *packages_model.PackageUploadSession {
+		pus, err := packages_model.CreateUploadSession(db.DefaultContext, &packages_model.PackageUploadSession{
+			OwnerID:        2,
+			Type:           packages_model.TypeGeneric,
+			PackageName:    "upload-session",
+			PackageVersion: "1.0.0",
+			Filename:       filename,
+			ExpiresUnix:    expires,
+		})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, pus.ID)
+		return pus
+	}
+
+	active := createSession("active.bin", timeutil.TimeStampNow().Add(3600))
+	expired := createSession("expired.bin", timeutil.TimeStampNow().Add(-3600))
+
+	assert.False(t, active.IsExpired())
+	assert.True(t, expired.IsExpired())
+
+	pus, err := packages_model.GetUploadSessionForFile(db.DefaultContext, 2, packages_model.TypeGeneric, "upload-session", "1.0.0", "active.bin")
+	assert.NoError(t, err)
+	assert.Equal(t, active.ID, pus.ID)
+
+	_, err = packages_model.GetUploadSessionForFile(db.DefaultContext, 2, packages_model.TypeGeneric, "upload-session", "1.0.0", "other.bin")
+	assert.ErrorIs(t, err, packages_model.ErrPackageUploadSessionNotExist)
+
+	active.BytesReceived = 42
+	assert.NoError(t, packages_model.UpdateUploadSession(db.DefaultContext, active))
+
+	pus, err = packages_model.GetUploadSessionByID(db.DefaultContext, active.ID)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 42, pus.BytesReceived)
+
+	puss, err := packages_model.FindExpiredUploadSessions(db.DefaultContext)
+	assert.NoError(t, err)
+	assert.Len(t, puss, 1)
+	assert.Equal(t, expired.ID, puss[0].ID)
+
+	assert.NoError(t, packages_model.DeleteUploadSessionByID(db.DefaultContext, expired.ID))
+
+	_, err = packages_model.GetUploadSessionByID(db.DefaultContext, expired.ID)
+	assert.ErrorIs(t, err, packages_model.ErrPackageUploadSessionNotExist)
+}
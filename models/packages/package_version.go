@@ -7,18 +7,33 @@ package packages
 import (
 	"context"
 	"errors"
-	"strconv"
 	"strings"
+	"time"
 
 	"code.gitea.io/gitea/models/db"
+	user_model "code.gitea.io/gitea/models/user"
+	packages_module "code.gitea.io/gitea/modules/packages"
+	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/timeutil"
 	"code.gitea.io/gitea/modules/util"
 
 	"xorm.io/builder"
+	"xorm.io/xorm/schemas"
 )
 
-// ErrDuplicatePackageVersion indicates a duplicated package version error
-var ErrDuplicatePackageVersion = errors.New("Package version already exists")
+var (
+	// ErrDuplicatePackageVersion indicates a duplicated package version error
+	ErrDuplicatePackageVersion = errors.New("Package version already exists")
+	// ErrPackageTypeMismatch indicates that a version can't be moved into a package of another type
+	ErrPackageTypeMismatch = errors.New("Package types do not match")
+	// ErrNoStableVersion indicates that a package has prereleases only
+	ErrNoStableVersion = errors.New("Package has no stable version")
+	// ErrReleaseNotesTooLong indicates that the release notes of a version exceed MaxReleaseNotesLength
+	ErrReleaseNotesTooLong = errors.New("Release notes are too long")
+)
+
+// MaxReleaseNotesLength is the maximum length in bytes of the release notes of a version
+const MaxReleaseNotesLength = 65535
 
 func init() {
 	db.RegisterModel(new(PackageVersion))
@@ -26,15 +41,36 @@ func init() {
 
 // PackageVersion represents a package version
 type PackageVersion struct {
-	ID            int64              `xorm:"pk autoincr"`
-	PackageID     int64              `xorm:"UNIQUE(s) INDEX NOT NULL"`
-	CreatorID     int64              `xorm:"NOT NULL DEFAULT 0"`
-	Version       string             `xorm:"NOT NULL"`
-	LowerVersion  string             `xorm:"UNIQUE(s) INDEX NOT NULL"`
-	CreatedUnix   timeutil.TimeStamp `xorm:"created INDEX NOT NULL"`
-	IsInternal    bool               `xorm:"INDEX NOT NULL DEFAULT false"`
-	MetadataJSON  string             `xorm:"metadata_json TEXT"`
-	DownloadCount int64              `xorm:"NOT NULL DEFAULT 0"`
+	ID               int64              `xorm:"pk autoincr"`
+	PackageID        int64              `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	CreatorID        int64              `xorm:"NOT NULL DEFAULT 0"`
+	Version          string             `xorm:"NOT NULL"`
+	LowerVersion     string             `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	CreatedUnix      timeutil.TimeStamp `xorm:"created INDEX NOT NULL"`
+	IsInternal       bool               `xorm:"INDEX NOT NULL DEFAULT false"`
+	MetadataJSON     string             `xorm:"metadata_json TEXT"`
+	DownloadCount    int64              `xorm:"NOT NULL DEFAULT 0"`
+	LastDownloadUnix timeutil.TimeStamp `xorm:"INDEX NOT NULL DEFAULT 0"`
+	IsYanked         bool               `xorm:"INDEX NOT NULL DEFAULT false"`
+	YankReason       string             `xorm:"TEXT"`
+	IsPrerelease     bool               `xorm:"INDEX NOT NULL DEFAULT false"`
+	ReleaseNotes     string             `xorm:"TEXT"`
+}
+
+// TableIndices implements xorm's TableIndices interface
+func (pv *PackageVersion) TableIndices() []*schemas.Index {
+	internalIndex := schemas.NewIndex("p_i_c", schemas.IndexType)
+	internalIndex.AddColumn("package_id", "is_internal", "created_unix")
+
+	yankedIndex := schemas.NewIndex("p_y_c", schemas.IndexType)
+	yankedIndex.AddColumn("package_id", "is_yanked", "created_unix")
+
+	return []*schemas.Index{internalIndex, yankedIndex}
+}
+
+// NormalizeVersion returns the form in which versions are stored in LowerVersion and looked up
+func NormalizeVersion(version string) string {
+	return strings.ToLower(version)
 }
 
 // GetOrInsertVersion inserts a version. If the same version exist already ErrDuplicatePackageVersion is returned
@@ -56,21 +92,305 @@ func GetOrInsertVersion(ctx context.Context, pv *PackageVersion) (*PackageVersio
 	if _, err = e.Insert(pv); err != nil {
 		return nil, err
 	}
+	if err := TouchPackage(ctx, pv.PackageID); err != nil {
+		return nil, err
+	}
 	return pv, nil
 }
 
+// CopyVersion copies a version with its properties and files into the package with the same type and name of the new owner.
+// The package is created if it does not exist yet. The blobs are shared between the files, no content gets copied.
+// If the version exists already at the new owner, ErrDuplicatePackageVersion is returned.
+// beforeInsertFile is called with the blob of every file before the file gets added to the new version, an error aborts the copy.
+func CopyVersion(ctx context.Context, pv *PackageVersion, newOwnerID, creatorID int64, beforeInsertFile func(ctx context.Context, p *Package, pv *PackageVersion, pb *PackageBlob) error) (*PackageVersion, error) {
+	p, err := GetPackageByID(ctx, pv.PackageID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	np, err := TryInsertPackage(ctx, &Package{
+		OwnerID:          newOwnerID,
+		CreatorID:        creatorID,
+		Type:             p.Type,
+		Name:             p.Name,
+		LowerName:        p.LowerName,
+		SemverCompatible: p.SemverCompatible,
+	})
+	if err != nil && err != ErrDuplicatePackage {
+		return nil, err
+	}
+	if err == nil {
+		if err := copyProperties(ctx, PropertyTypePackage, p.ID, np.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	npv, err := GetOrInsertVersion(ctx, &PackageVersion{
+		PackageID:    np.ID,
+		CreatorID:    creatorID,
+		Version:      pv.Version,
+		LowerVersion: pv.LowerVersion,
+		IsInternal:   pv.IsInternal,
+		MetadataJSON: pv.MetadataJSON,
+		IsPrerelease: pv.IsPrerelease,
+		ReleaseNotes: pv.ReleaseNotes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := copyProperties(ctx, PropertyTypeVersion, pv.ID, npv.ID); err != nil {
+		return nil, err
+	}
+
+	pfs, err := GetFilesByVersionID(ctx, pv.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, pf := range pfs {
+		if beforeInsertFile != nil {
+			pb, err := GetBlobByID(ctx, pf.BlobID)
+			if err != nil {
+				return nil, err
+			}
+			if err := beforeInsertFile(ctx, np, npv, pb); err != nil {
+				return nil, err
+			}
+		}
+
+		npf, err := TryInsertFile(ctx, &PackageFile{
+			VersionID:    npv.ID,
+			BlobID:       pf.BlobID,
+			Name:         pf.Name,
+			LowerName:    pf.LowerName,
+			CompositeKey: pf.CompositeKey,
+			IsLead:       pf.IsLead,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := copyProperties(ctx, PropertyTypeFile, pf.ID, npf.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return npv, nil
+}
+
+func copyProperties(ctx context.Context, refType PropertyType, fromRefID, toRefID int64) error {
+	pps, err := GetProperties(ctx, refType, fromRefID)
+	if err != nil {
+		return err
+	}
+	for _, pp := range pps {
+		if _, err := InsertProperty(ctx, refType, toRefID, pp.Name, pp.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MoveVersion moves a version with its properties and files into another package of the same type.
+// If the target package has the same version already, ErrDuplicatePackageVersion is returned.
+func MoveVersion(ctx context.Context, versionID, targetPackageID int64) error {
+	return db.WithTx(func(ctx context.Context) error {
+		e := db.GetEngine(ctx)
+
+		pv, err := GetVersionByID(ctx, versionID)
+		if err != nil {
+			return err
+		}
+		if pv.PackageID == targetPackageID {
+			return nil
+		}
+
+		p, err := GetPackageByID(ctx, pv.PackageID, false)
+		if err != nil {
+			return err
+		}
+		target, err := GetPackageByID(ctx, targetPackageID, false)
+		if err != nil {
+			return err
+		}
+		if p.Type != target.Type {
+			return ErrPackageTypeMismatch
+		}
+
+		has, err := e.Exist(&PackageVersion{
+			PackageID:    target.ID,
+			LowerVersion: pv.LowerVersion,
+		})
+		if err != nil {
+			return err
+		}
+		if has {
+			return ErrDuplicatePackageVersion
+		}
+
+		// files and properties reference the version only, so they move with it
+		if _, err := e.ID(pv.ID).Cols("package_id").Update(&PackageVersion{PackageID: target.ID}); err != nil {
+			return err
+		}
+		if err := TouchPackage(ctx, target.ID); err != nil {
+			return err
+		}
+		if err := RecalculatePackageDownloadCount(ctx, p.ID); err != nil {
+			return err
+		}
+		if err := RecalculatePackageDownloadCount(ctx, target.ID); err != nil {
+			return err
+		}
+
+		if p.OwnerID != target.OwnerID {
+			if err := RecalculateQuotaUsedSize(ctx, p.OwnerID); err != nil {
+				return err
+			}
+			return RecalculateQuotaUsedSize(ctx, target.OwnerID)
+		}
+		return nil
+	}, ctx)
+}
+
 // UpdateVersion updates a version
 func UpdateVersion(ctx context.Context, pv *PackageVersion) error {
-	_, err := db.GetEngine(ctx).ID(pv.ID).Update(pv)
+	if _, err := db.GetEngine(ctx).ID(pv.ID).Update(pv); err != nil {
+		return err
+	}
+	if pv.PackageID == 0 {
+		return nil
+	}
+	return TouchPackage(ctx, pv.PackageID)
+}
+
+// SetVersionYanked marks a version as yanked with an optional reason or removes the mark.
+// Yanked versions are hidden from the latest version resolution but stay downloadable.
+func SetVersionYanked(ctx context.Context, versionID int64, yanked bool, reason string) error {
+	if !yanked {
+		reason = ""
+	}
+	_, err := db.GetEngine(ctx).ID(versionID).Cols("is_yanked", "yank_reason").Update(&PackageVersion{IsYanked: yanked, YankReason: reason})
+	return err
+}
+
+// SetVersionReleaseNotes replaces the release notes of a version
+func SetVersionReleaseNotes(ctx context.Context, versionID int64, notes string) error {
+	if len(notes) > MaxReleaseNotesLength {
+		return ErrReleaseNotesTooLong
+	}
+	_, err := db.GetEngine(ctx).ID(versionID).Cols("release_notes").Update(&PackageVersion{ReleaseNotes: notes})
 	return err
 }
 
 // IncrementDownloadCounter increments the download counter of a version
 func IncrementDownloadCounter(ctx context.Context, versionID int64) error {
-	_, err := db.GetEngine(ctx).Exec("UPDATE `package_version` SET `download_count` = `download_count` + 1 WHERE `id` = ?", versionID)
+	return IncrementVersionDownloads(ctx, versionID, 1)
+}
+
+// IncrementVersionDownloads increments the download counter of a version and of its package by delta.
+// The updates are done in single statements so concurrent increments are not lost.
+func IncrementVersionDownloads(ctx context.Context, versionID, delta int64) error {
+	e := db.GetEngine(ctx)
+	if _, err := e.Exec("UPDATE `package_version` SET `download_count` = `download_count` + ? WHERE `id` = ?", delta, versionID); err != nil {
+		return err
+	}
+	_, err := e.Exec("UPDATE `package` SET `download_count` = `download_count` + ? WHERE `id` = (SELECT `package_id` FROM `package_version` WHERE `id` = ? AND `is_internal` = ?)", delta, versionID, false)
+	return err
+}
+
+// RecalculatePackageDownloadCount sets the download count of a package to the sum of the download counts of its non-internal versions
+func RecalculatePackageDownloadCount(ctx context.Context, packageID int64) error {
+	_, err := db.GetEngine(ctx).Exec("UPDATE `package` SET `download_count` = (SELECT COALESCE(SUM(`download_count`), 0) FROM `package_version` WHERE `package_id` = ? AND `is_internal` = ?) WHERE `id` = ?", packageID, false, packageID)
 	return err
 }
 
+// PackageDownloadTotal gets the sum of the download counts of all non-internal versions of a package
+func PackageDownloadTotal(ctx context.Context, packageID int64) (int64, error) {
+	var total int64
+	_, err := db.GetEngine(ctx).
+		Table("package_version").
+		Select("COALESCE(SUM(download_count), 0)").
+		Where(builder.Eq{
+			"package_id":  packageID,
+			"is_internal": false,
+		}).
+		Get(&total)
+	return total, err
+}
+
+// FirstPublishedUnix gets the creation time of the earliest non-internal version of a package, or 0 if there is none
+func FirstPublishedUnix(ctx context.Context, packageID int64) (int64, error) {
+	var first int64
+	_, err := db.GetEngine(ctx).
+		Table("package_version").
+		Select("COALESCE(MIN(created_unix), 0)").
+		Where(builder.Eq{
+			"package_id":  packageID,
+			"is_internal": false,
+		}).
+		Get(&first)
+	return first, err
+}
+
+// CountOwnerVersions counts the versions of all packages of an owner
+func CountOwnerVersions(ctx context.Context, ownerID int64, includeInternal bool) (int64, error) {
+	cond := builder.Eq{"package.owner_id": ownerID}
+	if !includeInternal {
+		cond["package_version.is_internal"] = false
+	}
+
+	return db.GetEngine(ctx).
+		Table("package_version").
+		Join("INNER", "package", "package.id = package_version.package_id").
+		Where(cond).
+		Count(&PackageVersion{})
+}
+
+// CountPackageVersions counts the non-internal versions of a package
+func CountPackageVersions(ctx context.Context, packageID int64) (int64, error) {
+	return db.GetEngine(ctx).
+		Where(builder.Eq{
+			"package_id":  packageID,
+			"is_internal": false,
+		}).
+		Count(&PackageVersion{})
+}
+
+// RecentVersionsForOwner gets the limit most recently created non-internal versions across all packages of an owner, newest first
+func RecentVersionsForOwner(ctx context.Context, ownerID int64, limit int) ([]*PackageVersion, error) {
+	pvs := make([]*PackageVersion, 0, limit)
+	return pvs, db.GetEngine(ctx).
+		Table("package_version").
+		Join("INNER", "package", "package.id = package_version.package_id").
+		Where(builder.Eq{
+			"package.owner_id":            ownerID,
+			"package_version.is_internal": false,
+		}).
+		Desc("package_version.created_unix", "package_version.id").
+		Limit(limit).
+		Find(&pvs)
+}
+
+// LastDownloadUpdateInterval is the minimum time between two updates of the last download timestamp of a version
+const LastDownloadUpdateInterval = 60 * 60
+
+// UpdateVersionLastDownload sets the last download timestamp of a version.
+// To avoid a write on every download the timestamp is only updated if the stored one is older than LastDownloadUpdateInterval seconds.
+// The activity timestamp of the package is updated together with the version.
+func UpdateVersionLastDownload(ctx context.Context, versionID int64, now timeutil.TimeStamp) error {
+	res, err := db.GetEngine(ctx).Exec("UPDATE `package_version` SET `last_download_unix` = ? WHERE `id` = ? AND `last_download_unix` <= ?", now, versionID, now-LastDownloadUpdateInterval)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+
+	pv, err := GetVersionByID(ctx, versionID)
+	if err != nil {
+		return err
+	}
+	return touchPackage(ctx, pv.PackageID, now)
+}
+
 // GetVersionByID gets a version by id
 func GetVersionByID(ctx context.Context, versionID int64) (*PackageVersion, error) {
 	pv := &PackageVersion{}
@@ -90,6 +410,21 @@ func GetVersionByNameAndVersion(ctx context.Context, ownerID int64, packageType
 	return getVersionByNameAndVersion(ctx, ownerID, packageType, name, version, false)
 }
 
+// ExistsVersion checks if a non-internal version exists without loading it.
+// It is meant for existence checks on hot paths which don't need the (possibly large) metadata of the version.
+func ExistsVersion(ctx context.Context, ownerID int64, packageType Type, name, version string) (bool, error) {
+	return db.GetEngine(ctx).
+		Table("package_version").
+		Join("INNER", "package", "package.id = package_version.package_id").
+		Where(builder.Eq{
+			"package.owner_id":              ownerID,
+			"package.type":                  packageType,
+			"package_version.lower_version": NormalizeVersion(version),
+			"package_version.is_internal":   false,
+		}.And(nameCond(NormalizeName(name)))).
+		Exist(&PackageVersion{})
+}
+
 // GetInternalVersionByNameAndVersion gets a version by name and version number
 func GetInternalVersionByNameAndVersion(ctx context.Context, ownerID int64, packageType Type, name, version string) (*PackageVersion, error) {
 	return getVersionByNameAndVersion(ctx, ownerID, packageType, name, version, true)
@@ -143,10 +478,143 @@ func GetVersionsByPackageName(ctx context.Context, ownerID int64, packageType Ty
 	return pvs, err
 }
 
-// DeleteVersionByID deletes a version by id
+// GetLatestStableVersion gets the newest version of a package which is neither a prerelease nor internal.
+// The versions are ordered by semantic version precedence, yanked versions are never considered.
+// ErrNoStableVersion is returned if the package has no such version.
+func GetLatestStableVersion(ctx context.Context, packageID int64) (*PackageVersion, error) {
+	pvs := make([]*PackageVersion, 0, 10)
+	if err := db.GetEngine(ctx).Where(builder.Eq{
+		"package_id":    packageID,
+		"is_internal":   false,
+		"is_prerelease": false,
+		"is_yanked":     false,
+	}).Find(&pvs); err != nil {
+		return nil, err
+	}
+
+	var latest *PackageVersion
+	for _, pv := range pvs {
+		if latest == nil || packages_module.CompareVersions(pv.Version, latest.Version) > 0 {
+			latest = pv
+		}
+	}
+	if latest == nil {
+		return nil, ErrNoStableVersion
+	}
+	return latest, nil
+}
+
+// DeleteVersionByID deletes a version by id and removes its downloads from the download count of the package
 func DeleteVersionByID(ctx context.Context, versionID int64) error {
-	_, err := db.GetEngine(ctx).ID(versionID).Delete(&PackageVersion{})
-	return err
+	pv := &PackageVersion{}
+	has, err := db.GetEngine(ctx).ID(versionID).Cols("package_id").Get(pv)
+	if err != nil {
+		return err
+	}
+	if _, err := db.GetEngine(ctx).ID(versionID).Delete(&PackageVersion{}); err != nil {
+		return err
+	}
+	if !has {
+		return nil
+	}
+	return RecalculatePackageDownloadCount(ctx, pv.PackageID)
+}
+
+// staleInternalVersionsBatchSize is the number of stale internal versions removed in one transaction
+const staleInternalVersionsBatchSize = 100
+
+// CleanupStaleInternalVersions removes internal versions with their files and properties which were not modified since the threshold.
+// Versions with a blob which is referenced by a non-internal version are kept. The blobs of the removed files are left for the blob cleanup.
+// The versions are removed in batches with a transaction per batch, so the tables are not locked for long. It returns the number of removed versions.
+func CleanupStaleInternalVersions(ctx context.Context, olderThan time.Duration) (int, error) {
+	cutoff := time.Now().Add(-olderThan).Unix()
+
+	cond := builder.Eq{"package_version.is_internal": true}.
+		And(builder.Lt{"package_version.created_unix": cutoff}).
+		And(builder.NotExists(
+			builder.Select("package_file.id").
+				From("package_file").
+				Where(builder.Expr("package_file.version_id = package_version.id").And(builder.Gte{"package_file.created_unix": cutoff})),
+		)).
+		And(builder.NotExists(
+			builder.Select("pf.id").
+				From("package_file", "pf").
+				InnerJoin("package_file other_pf", "other_pf.blob_id = pf.blob_id").
+				InnerJoin("package_version other_pv", "other_pv.id = other_pf.version_id").
+				Where(builder.Expr("pf.version_id = package_version.id").And(builder.Eq{"other_pv.is_internal": false})),
+		))
+
+	findStale := func(ctx context.Context, cond builder.Cond) ([]int64, error) {
+		ids := make([]int64, 0, staleInternalVersionsBatchSize)
+		return ids, db.GetEngine(ctx).
+			Table("package_version").
+			Select("package_version.id").
+			Where(cond).
+			OrderBy("package_version.id").
+			Limit(staleInternalVersionsBatchSize).
+			Find(&ids)
+	}
+
+	removed := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return removed, err
+		}
+
+		ids, err := findStale(ctx, cond)
+		if err != nil {
+			return removed, err
+		}
+		if len(ids) == 0 {
+			return removed, nil
+		}
+
+		deleted := 0
+		if err := db.WithTx(func(ctx context.Context) error {
+			// a version may have been modified since it was found
+			staleIDs, err := findStale(ctx, cond.And(builder.In("package_version.id", ids)))
+			if err != nil {
+				return err
+			}
+			for _, id := range staleIDs {
+				if err := deleteVersionWithFiles(ctx, id); err != nil {
+					return err
+				}
+			}
+			deleted = len(staleIDs)
+			return nil
+		}, ctx); err != nil {
+			return removed, err
+		}
+		removed += deleted
+
+		if len(ids) < staleInternalVersionsBatchSize {
+			return removed, nil
+		}
+	}
+}
+
+// deleteVersionWithFiles deletes a version with its files, its release links and the properties of the version and files
+func deleteVersionWithFiles(ctx context.Context, versionID int64) error {
+	pfs, err := GetFilesByVersionID(ctx, versionID)
+	if err != nil {
+		return err
+	}
+	for _, pf := range pfs {
+		if err := DeleteAllProperties(ctx, PropertyTypeFile, pf.ID); err != nil {
+			return err
+		}
+		if err := DeleteFileByID(ctx, pf.ID); err != nil {
+			return err
+		}
+	}
+	if err := DeleteAllProperties(ctx, PropertyTypeVersion, versionID); err != nil {
+		return err
+	}
+	if err := DeleteVersionReleasesByVersionID(ctx, versionID); err != nil {
+		return err
+	}
+	return DeleteVersionByID(ctx, versionID)
 }
 
 // HasVersionFileReferences checks if there are associated files
@@ -156,6 +624,28 @@ func HasVersionFileReferences(ctx context.Context, versionID int64) (bool, error
 	})
 }
 
+// VersionsMissingFile gets the non-internal versions of the package type which have no file with the given name, e.g. a required manifest.
+// Such versions are usually the result of corrupted uploads.
+func VersionsMissingFile(ctx context.Context, packageType Type, requiredFileName string) ([]*PackageVersion, error) {
+	fileCond := builder.
+		Select("package_file.id").
+		From("package_file").
+		Where(builder.Expr("package_file.version_id = package_version.id").And(builder.Eq{
+			"package_file.lower_name": strings.ToLower(requiredFileName),
+		}))
+
+	pvs := make([]*PackageVersion, 0, 10)
+	return pvs, db.GetEngine(ctx).
+		Table("package_version").
+		Join("INNER", "package", "package.id = package_version.package_id").
+		Where(builder.Eq{
+			"package.type":                packageType,
+			"package_version.is_internal": false,
+		}.And(builder.NotExists(fileCond))).
+		OrderBy("package_version.id").
+		Find(&pvs)
+}
+
 // SearchValue describes a value to search
 // If ExactMatch is true, the field must match the value otherwise a LIKE search is performed.
 type SearchValue struct {
@@ -163,20 +653,63 @@ type SearchValue struct {
 	ExactMatch bool
 }
 
+// NumericPropertyOperator compares the value of a numeric property
+type NumericPropertyOperator string
+
+// List of supported operators
+const (
+	NumericPropertyGreaterOrEqual NumericPropertyOperator = ">="
+	NumericPropertyLessOrEqual    NumericPropertyOperator = "<="
+)
+
+// NumericPropertyCondition compares the value of a well-known numeric property
+type NumericPropertyCondition struct {
+	Name     string
+	Operator NumericPropertyOperator
+	Value    int64
+}
+
+func (c *NumericPropertyCondition) toCond() builder.Cond {
+	cond := builder.Expr("package_property.ref_id = package_version.id").
+		And(builder.Eq{
+			"package_property.ref_type":   PropertyTypeVersion,
+			"package_property.name":       c.Name,
+			"package_property.value_type": PropertyValueTypeNumeric,
+		})
+	if c.Operator == NumericPropertyLessOrEqual {
+		cond = cond.And(builder.Lte{"package_property.numeric_value": c.Value})
+	} else {
+		cond = cond.And(builder.Gte{"package_property.numeric_value": c.Value})
+	}
+	return builder.Exists(builder.Select("package_property.id").From("package_property").Where(cond))
+}
+
 // PackageSearchOptions are options for SearchXXX methods
 // Besides IsInternal are all fields optional and are not used if they have their default value (nil, "", 0)
 type PackageSearchOptions struct {
-	OwnerID         int64
-	RepoID          int64
-	Type            Type
-	PackageID       int64
-	Name            SearchValue       // only results with the specific name are found
-	Version         SearchValue       // only results with the specific version are found
-	Properties      map[string]string // only results are found which contain all listed version properties with the specific value
-	IsInternal      util.OptionalBool
-	HasFileWithName string            // only results are found which are associated with a file with the specific name
-	HasFiles        util.OptionalBool // only results are found which have associated files
-	Sort            string
+	OwnerID            int64
+	RepoID             int64
+	Type               Type
+	PackageID          int64
+	Name               SearchValue                 // only results with the specific name are found
+	IncludeDescription bool                        // a non exact name search matches the package description too, name matches are ranked first
+	Version            SearchValue                 // only results with the specific version are found
+	Properties         map[string]string           // only results are found which contain all listed version properties with the specific value
+	NumericProperties  []*NumericPropertyCondition // only results are found which contain all listed numeric version properties matching the condition
+	Keyword            string                      // only results are found which have the keyword in their metadata
+	IsInternal         util.OptionalBool
+	IsYanked           util.OptionalBool
+	IsPrerelease       util.OptionalBool  // SearchLatestVersions finds the latest stable version if set to false
+	HasFileWithName    string             // only results are found which are associated with a file with the specific name
+	HasFiles           util.OptionalBool  // only results are found which have associated files
+	NotDownloadedSince timeutil.TimeStamp // only results are found which were not downloaded since the timestamp (or never)
+	DownloadsBelow     int64              // only results are found which were downloaded less often than the given count
+	HidePrivate        bool               // private packages are excluded from the results
+	Sort               string
+	// RestrictToVisibleOwners limits the results to packages of owners visible to Actor (anonymous if nil).
+	// Private packages are only found if Actor is a site admin, the owner or a member of the owning organization.
+	RestrictToVisibleOwners bool
+	Actor                   *user_model.User
 	db.Paginator
 }
 
@@ -186,6 +719,14 @@ func (opts *PackageSearchOptions) toConds() builder.Cond {
 		cond = builder.Eq{"package_version.is_internal": opts.IsInternal.IsTrue()}
 	}
 
+	if !opts.IsYanked.IsNone() {
+		cond = cond.And(builder.Eq{"package_version.is_yanked": opts.IsYanked.IsTrue()})
+	}
+
+	if !opts.IsPrerelease.IsNone() {
+		cond = cond.And(builder.Eq{"package_version.is_prerelease": opts.IsPrerelease.IsTrue()})
+	}
+
 	if opts.OwnerID != 0 {
 		cond = cond.And(builder.Eq{"package.owner_id": opts.OwnerID})
 	}
@@ -199,38 +740,54 @@ func (opts *PackageSearchOptions) toConds() builder.Cond {
 		cond = cond.And(builder.Eq{"package.id": opts.PackageID})
 	}
 	if opts.Name.Value != "" {
+		lowerName := strings.ToLower(opts.Name.Value)
 		if opts.Name.ExactMatch {
-			cond = cond.And(builder.Eq{"package.lower_name": strings.ToLower(opts.Name.Value)})
+			cond = cond.And(nameCond(NormalizeName(opts.Name.Value)))
+		} else if opts.IncludeDescription {
+			cond = cond.And(builder.Or(
+				builder.Like{"package.lower_name", lowerName},
+				builder.Like{"LOWER(package.description)", lowerName},
+			))
 		} else {
-			cond = cond.And(builder.Like{"package.lower_name", strings.ToLower(opts.Name.Value)})
+			cond = cond.And(builder.Like{"package.lower_name", lowerName})
+		}
+	}
+	if opts.RestrictToVisibleOwners {
+		if visibleCond := user_model.BuildCanSeeUserCondition(opts.Actor); visibleCond != nil {
+			cond = cond.And(builder.In("package.owner_id", builder.Select("`user`.id").From("`user`").Where(visibleCond)))
 		}
+		if privateCond := BuildCanSeePrivatePackageCondition(opts.Actor); privateCond != nil {
+			cond = cond.And(privateCond)
+		}
+	}
+	if opts.HidePrivate {
+		cond = cond.And(builder.Not{PrivatePackageCond()})
 	}
 	if opts.Version.Value != "" {
 		if opts.Version.ExactMatch {
-			cond = cond.And(builder.Eq{"package_version.lower_version": strings.ToLower(opts.Version.Value)})
+			cond = cond.And(builder.Eq{"package_version.lower_version": NormalizeVersion(opts.Version.Value)})
 		} else {
 			cond = cond.And(builder.Like{"package_version.lower_version", strings.ToLower(opts.Version.Value)})
 		}
 	}
 
 	if len(opts.Properties) != 0 {
-		var propsCond builder.Cond = builder.Eq{
-			"package_property.ref_type": PropertyTypeVersion,
-		}
-		propsCond = propsCond.And(builder.Expr("package_property.ref_id = package_version.id"))
+		cond = cond.And(PropertiesCond(PropertyTypeVersion, "package_version.id", opts.Properties))
+	}
+
+	for _, c := range opts.NumericProperties {
+		cond = cond.And(c.toCond())
+	}
 
-		propsCondBlock := builder.NewCond()
-		for name, value := range opts.Properties {
-			propsCondBlock = propsCondBlock.Or(builder.Eq{
-				"package_property.name":  name,
-				"package_property.value": value,
+	if keyword := NormalizeKeyword(opts.Keyword); keyword != "" {
+		keywordCond := builder.Expr("package_property.ref_id = package_version.id").
+			And(builder.Eq{
+				"package_property.ref_type": PropertyTypeVersion,
+				"package_property.name":     PropertyKeyword,
+				"package_property.value":    keyword,
 			})
-		}
-		propsCond = propsCond.And(propsCondBlock)
 
-		cond = cond.And(builder.Eq{
-			strconv.Itoa(len(opts.Properties)): builder.Select("COUNT(*)").Where(propsCond).From("package_property"),
-		})
+		cond = cond.And(builder.Exists(builder.Select("package_property.id").From("package_property").Where(keywordCond)))
 	}
 
 	if opts.HasFileWithName != "" {
@@ -239,6 +796,13 @@ func (opts *PackageSearchOptions) toConds() builder.Cond {
 		cond = cond.And(builder.Exists(builder.Select("package_file.id").From("package_file").Where(fileCond)))
 	}
 
+	if opts.NotDownloadedSince != 0 {
+		cond = cond.And(builder.Lt{"package_version.last_download_unix": opts.NotDownloadedSince})
+	}
+	if opts.DownloadsBelow > 0 {
+		cond = cond.And(builder.Lt{"package_version.download_count": opts.DownloadsBelow})
+	}
+
 	if !opts.HasFiles.IsNone() {
 		filesCond := builder.Exists(builder.Select("package_file.id").From("package_file").Where(builder.Expr("package_file.version_id = package_version.id")))
 
@@ -252,7 +816,23 @@ func (opts *PackageSearchOptions) toConds() builder.Cond {
 	return cond
 }
 
+// SortByDownloads orders the results by the download count of their package, most downloaded first.
+// SortByReverseDownloads orders them least downloaded first. Ties are ordered by package name.
+const (
+	SortByDownloads        = "downloads"
+	SortByReverseDownloads = "reversedownloads"
+)
+
+// SortBySize orders the results by the storage used by their package, largest first.
+// The size of a package is the sum of the sizes of the distinct blobs referenced by its files.
+const SortBySize = "size"
+
 func (opts *PackageSearchOptions) configureOrderBy(e db.Engine) {
+	if opts.IncludeDescription && opts.Name.Value != "" && !opts.Name.ExactMatch {
+		// rank packages matching by name above packages matching only by description
+		e.OrderBy("CASE WHEN package.lower_name LIKE ? THEN 0 ELSE 1 END", "%"+strings.ToLower(opts.Name.Value)+"%")
+	}
+
 	switch opts.Sort {
 	case "alphabetically":
 		e.Asc("package.name")
@@ -264,6 +844,17 @@ func (opts *PackageSearchOptions) configureOrderBy(e db.Engine) {
 		e.Asc("package_version.version")
 	case "oldest":
 		e.Asc("package_version.created_unix")
+	case SortByDownloads:
+		e.Desc("package.download_count")
+		e.Asc("package.name", "package.id")
+		e.Desc("package_version.created_unix")
+	case SortByReverseDownloads:
+		e.Asc("package.download_count", "package.name", "package.id")
+		e.Desc("package_version.created_unix")
+	case SortBySize:
+		e.Join("LEFT", packageSizeSubQuery(), "package_size.package_id = package.id")
+		e.OrderBy("COALESCE(package_size.total_size, 0) DESC")
+		e.Desc("package_version.created_unix")
 	default:
 		e.Desc("package_version.created_unix")
 	}
@@ -287,16 +878,46 @@ func SearchVersions(ctx context.Context, opts *PackageSearchOptions) ([]*Package
 	return pvs, count, err
 }
 
-// SearchLatestVersions gets the latest version of every package matching the search options
+// SearchLatestVersions gets the latest version of every package matching the search options.
+// Yanked versions are never considered as latest version. If IsPrerelease is false, prereleases aren't considered either,
+// so the latest stable version is found and packages with prereleases only are skipped.
 func SearchLatestVersions(ctx context.Context, opts *PackageSearchOptions) ([]*PackageVersion, int64, error) {
 	cond := opts.toConds().
-		And(builder.Expr("pv2.id IS NULL"))
+		And(builder.Eq{"package_version.is_yanked": false})
+
+	candidateCond := builder.Eq{"package_version.is_yanked": false}
+	newerCond := "pv2.is_yanked = ?"
+	newerArgs := []interface{}{false}
+	if opts.IsPrerelease.IsFalse() {
+		candidateCond["package_version.is_prerelease"] = false
+		newerCond += " AND pv2.is_prerelease = ?"
+		newerArgs = append(newerArgs, false)
+	}
 
 	sess := db.GetEngine(ctx).
 		Table("package_version").
-		Join("LEFT", "package_version pv2", "package_version.package_id = pv2.package_id AND (package_version.created_unix < pv2.created_unix OR (package_version.created_unix = pv2.created_unix AND package_version.id < pv2.id))").
-		Join("INNER", "package", "package.id = package_version.package_id").
-		Where(cond)
+		Join("INNER", "package", "package.id = package_version.package_id")
+
+	if supportsWindowFunctions() {
+		// rank the versions of the matching packages only instead of comparing every version with all newer versions
+		candidates := builder.
+			Select("package_version.package_id").
+			From("package_version").
+			InnerJoin("package", "package.id = package_version.package_id").
+			Where(cond)
+
+		ranked := builder.
+			Select("package_version.id, ROW_NUMBER() OVER (PARTITION BY package_version.package_id ORDER BY package_version.created_unix DESC, package_version.id DESC) AS version_rank").
+			From("package_version").
+			Where(candidateCond.And(builder.In("package_version.package_id", candidates)))
+
+		cond = cond.And(builder.In("package_version.id", builder.Select("ranked.id").From(ranked, "ranked").Where(builder.Eq{"ranked.version_rank": 1})))
+	} else {
+		sess = sess.Join("LEFT", "package_version pv2", "package_version.package_id = pv2.package_id AND "+newerCond+" AND (package_version.created_unix < pv2.created_unix OR (package_version.created_unix = pv2.created_unix AND package_version.id < pv2.id))", newerArgs...)
+		cond = cond.And(builder.Expr("pv2.id IS NULL"))
+	}
+
+	sess = sess.Where(cond)
 
 	opts.configureOrderBy(sess)
 
@@ -308,3 +929,9 @@ func SearchLatestVersions(ctx context.Context, opts *PackageSearchOptions) ([]*P
 	count, err := sess.FindAndCount(&pvs)
 	return pvs, count, err
 }
+
+// supportsWindowFunctions checks if the database supports ROW_NUMBER() OVER (...).
+// MySQL is excluded because versions before 8.0 do not support window functions.
+func supportsWindowFunctions() bool {
+	return setting.Database.UsePostgreSQL || setting.Database.UseMSSQL || setting.Database.UseSQLite3
+}
@@ -0,0 +1,153 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"errors"
+	"regexp"
+
+	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/builder"
+)
+
+var (
+	// ErrInvalidVersionPattern indicates that the version pattern of a filter is not a valid regular expression
+	ErrInvalidVersionPattern = errors.New("Version pattern is invalid")
+	// ErrEmptyVersionFilter indicates that a filter without any condition was used to delete versions
+	ErrEmptyVersionFilter = errors.New("Version filter is empty")
+)
+
+// usageBatchSize is the maximum number of ids used in one IN condition
+const usageBatchSize = 500
+
+// VersionFilter selects the non-internal versions of a package for bulk operations.
+// All fields are optional, an empty filter matches all versions.
+type VersionFilter struct {
+	VersionPattern string             // regular expression which must match the whole version, case-insensitive
+	CreatedBefore  timeutil.TimeStamp // only versions created before the timestamp match
+	KeepLatest     int                // number of the newest matching versions which are excluded
+}
+
+// IsEmpty returns true if the filter has no condition and matches all versions
+func (f *VersionFilter) IsEmpty() bool {
+	return f.VersionPattern == "" && f.CreatedBefore == 0 && f.KeepLatest == 0
+}
+
+// FindVersionsByFilter gets the non-internal versions of a package matching the filter, newest first
+func FindVersionsByFilter(ctx context.Context, packageID int64, filter *VersionFilter) ([]*PackageVersion, error) {
+	var pattern *regexp.Regexp
+	if filter.VersionPattern != "" {
+		var err error
+		pattern, err = regexp.Compile(`(?i)\A(?:` + filter.VersionPattern + `)\z`)
+		if err != nil {
+			return nil, ErrInvalidVersionPattern
+		}
+	}
+
+	var cond builder.Cond = builder.Eq{
+		"package_id":  packageID,
+		"is_internal": false,
+	}
+	if filter.CreatedBefore != 0 {
+		cond = cond.And(builder.Lt{"created_unix": filter.CreatedBefore})
+	}
+
+	pvs := make([]*PackageVersion, 0, 10)
+	if err := db.GetEngine(ctx).
+		Where(cond).
+		OrderBy("created_unix DESC, id DESC").
+		Find(&pvs); err != nil {
+		return nil, err
+	}
+
+	matched := make([]*PackageVersion, 0, len(pvs))
+	kept := 0
+	for _, pv := range pvs {
+		if pattern != nil && !pattern.MatchString(pv.Version) {
+			continue
+		}
+		if kept < filter.KeepLatest {
+			kept++
+			continue
+		}
+		matched = append(matched, pv)
+	}
+	return matched, nil
+}
+
+// VersionsUsage describes the files of a set of versions
+type VersionsUsage struct {
+	FileCount int64
+	// FreeableSize is the size of the blobs which are only referenced by files of the versions.
+	// These blobs are not referenced anymore if the versions get deleted.
+	FreeableSize int64
+}
+
+// GetVersionsUsage gets the number of files of the versions and the size of the blobs only used by them
+func GetVersionsUsage(ctx context.Context, versionIDs []int64) (*VersionsUsage, error) {
+	type blobCount struct {
+		BlobID         int64
+		Size           int64
+		ReferenceCount int64
+	}
+
+	usage := &VersionsUsage{}
+
+	references := make(map[int64]int64)
+	for start := 0; start < len(versionIDs); start += usageBatchSize {
+		end := start + usageBatchSize
+		if end > len(versionIDs) {
+			end = len(versionIDs)
+		}
+
+		counts := make([]*blobCount, 0, 10)
+		if err := db.GetEngine(ctx).
+			Table("package_file").
+			Select("blob_id, COUNT(*) AS reference_count").
+			In("version_id", versionIDs[start:end]).
+			GroupBy("blob_id").
+			Find(&counts); err != nil {
+			return nil, err
+		}
+		for _, c := range counts {
+			references[c.BlobID] += c.ReferenceCount
+			usage.FileCount += c.ReferenceCount
+		}
+	}
+
+	blobIDs := make([]int64, 0, len(references))
+	for blobID := range references {
+		blobIDs = append(blobIDs, blobID)
+	}
+
+	for start := 0; start < len(blobIDs); start += usageBatchSize {
+		end := start + usageBatchSize
+		if end > len(blobIDs) {
+			end = len(blobIDs)
+		}
+
+		counts := make([]*blobCount, 0, 10)
+		if err := db.GetEngine(ctx).
+			Table("package_blob").
+			Select("package_blob.id AS blob_id, package_blob.size, COUNT(*) AS reference_count").
+			Join("INNER", "package_file", "package_file.blob_id = package_blob.id").
+			In("package_blob.id", blobIDs[start:end]).
+			GroupBy("package_blob.id, package_blob.size").
+			Find(&counts); err != nil {
+			return nil, err
+		}
+		for _, c := range counts {
+			// the blob has no references outside of the versions
+			if c.ReferenceCount == references[c.BlobID] {
+				usage.FreeableSize += c.Size
+			}
+		}
+	}
+
+	return usage, nil
+}
@@ -0,0 +1,131 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages_test
+
+import (
+	"testing"
+
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/unittest"
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindVersionsByFilter(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeGeneric,
+		Name:      "version-filter",
+		LowerName: "version-filter",
+	})
+	assert.NoError(t, err)
+
+	versions := make(map[string]int64)
+	for _, v := range []struct {
+		Version     string
+		CreatedUnix timeutil.TimeStamp
+		IsInternal  bool
+	}{
+		{"1.0.0", 1000, false},
+		{"1.1.0-Nightly.1", 2000, false},
+		{"1.1.0-nightly.2", 3000, false},
+		{"1.1.0-nightly.3", 4000, false},
+		{"1.1.0", 5000, false},
+		{"1.2.0-nightly.1", 6000, true},
+	} {
+		pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			Version:      v.Version,
+			LowerVersion: v.Version,
+			IsInternal:   v.IsInternal,
+		})
+		assert.NoError(t, err)
+		_, err = db.GetEngine(db.DefaultContext).ID(pv.ID).Cols("created_unix").NoAutoTime().Update(&packages_model.PackageVersion{CreatedUnix: v.CreatedUnix})
+		assert.NoError(t, err)
+		versions[v.Version] = pv.ID
+	}
+
+	find := func(filter *packages_model.VersionFilter) []int64 {
+		pvs, err := packages_model.FindVersionsByFilter(db.DefaultContext, p.ID, filter)
+		assert.NoError(t, err)
+		ids := make([]int64, 0, len(pvs))
+		for _, pv := range pvs {
+			ids = append(ids, pv.ID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []int64{versions["1.1.0"], versions["1.1.0-nightly.3"], versions["1.1.0-nightly.2"], versions["1.1.0-Nightly.1"], versions["1.0.0"]}, find(&packages_model.VersionFilter{}))
+	assert.Equal(t, []int64{versions["1.1.0-nightly.3"], versions["1.1.0-nightly.2"], versions["1.1.0-Nightly.1"]}, find(&packages_model.VersionFilter{VersionPattern: `.*-nightly\..*`}))
+	// the pattern must match the whole version
+	assert.Empty(t, find(&packages_model.VersionFilter{VersionPattern: `nightly`}))
+	assert.Equal(t, []int64{versions["1.1.0-nightly.2"], versions["1.1.0-Nightly.1"], versions["1.0.0"]}, find(&packages_model.VersionFilter{CreatedBefore: 4000}))
+	assert.Equal(t, []int64{versions["1.1.0-Nightly.1"]}, find(&packages_model.VersionFilter{VersionPattern: `.*-nightly\..*`, KeepLatest: 2}))
+	assert.Equal(t, []int64{versions["1.1.0-Nightly.1"]}, find(&packages_model.VersionFilter{VersionPattern: `.*-nightly\..*`, CreatedBefore: 4000, KeepLatest: 1}))
+	assert.Empty(t, find(&packages_model.VersionFilter{KeepLatest: 10}))
+
+	_, err = packages_model.FindVersionsByFilter(db.DefaultContext, p.ID, &packages_model.VersionFilter{VersionPattern: `(`})
+	assert.ErrorIs(t, err, packages_model.ErrInvalidVersionPattern)
+}
+
+func TestGetVersionsUsage(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeGeneric,
+		Name:      "versions-usage",
+		LowerName: "versions-usage",
+	})
+	assert.NoError(t, err)
+
+	insertVersion := func(version string, blobs ...*packages_model.PackageBlob) int64 {
+		pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			Version:      version,
+			LowerVersion: version,
+		})
+		assert.NoError(t, err)
+
+		for i, pb := range blobs {
+			name := version + "-" + string(rune('a'+i))
+			_, err = packages_model.TryInsertFile(db.DefaultContext, &packages_model.PackageFile{
+				VersionID: pv.ID,
+				BlobID:    pb.ID,
+				Name:      name,
+				LowerName: name,
+			})
+			assert.NoError(t, err)
+		}
+		return pv.ID
+	}
+
+	exclusive := insertTestBlob(t, "versions-usage-exclusive")
+	sharedWithin := insertTestBlob(t, "versions-usage-shared-within")
+	sharedOutside := insertTestBlob(t, "versions-usage-shared-outside")
+
+	v1 := insertVersion("1.0.0", exclusive, sharedWithin, sharedOutside)
+	v2 := insertVersion("2.0.0", sharedWithin, sharedWithin)
+	v3 := insertVersion("3.0.0", sharedOutside)
+
+	usage, err := packages_model.GetVersionsUsage(db.DefaultContext, []int64{v1, v2})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 5, usage.FileCount)
+	assert.Equal(t, exclusive.Size+sharedWithin.Size, usage.FreeableSize)
+
+	usage, err = packages_model.GetVersionsUsage(db.DefaultContext, []int64{v1, v2, v3})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 6, usage.FileCount)
+	assert.Equal(t, exclusive.Size+sharedWithin.Size+sharedOutside.Size, usage.FreeableSize)
+
+	usage, err = packages_model.GetVersionsUsage(db.DefaultContext, nil)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, usage.FileCount)
+	assert.EqualValues(t, 0, usage.FreeableSize)
+}
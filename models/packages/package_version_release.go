@@ -0,0 +1,119 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"errors"
+
+	"code.gitea.io/gitea/models/db"
+	repo_model "code.gitea.io/gitea/models/repo"
+
+	"xorm.io/builder"
+)
+
+// ErrReleaseRepositoryMismatch indicates that a release does not belong to the repository the package is linked to
+var ErrReleaseRepositoryMismatch = errors.New("Release does not belong to the linked repository of the package")
+
+func init() {
+	db.RegisterModel(new(PackageVersionRelease))
+}
+
+// PackageVersionRelease links a package version to a release which references it
+type PackageVersionRelease struct {
+	ID        int64 `xorm:"pk autoincr"`
+	VersionID int64 `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	ReleaseID int64 `xorm:"UNIQUE(s) INDEX NOT NULL"`
+}
+
+// LinkVersionToRelease links the version to the release. The release must belong to the repository the package is linked to.
+func LinkVersionToRelease(ctx context.Context, p *Package, versionID int64, release *repo_model.Release) error {
+	if p.RepoID == 0 || release.RepoID != p.RepoID {
+		return ErrReleaseRepositoryMismatch
+	}
+
+	e := db.GetEngine(ctx)
+
+	has, err := e.Exist(&PackageVersionRelease{VersionID: versionID, ReleaseID: release.ID})
+	if err != nil || has {
+		return err
+	}
+
+	_, err = e.Insert(&PackageVersionRelease{VersionID: versionID, ReleaseID: release.ID})
+	return err
+}
+
+// UnlinkVersionFromRelease removes the link between the version and the release
+func UnlinkVersionFromRelease(ctx context.Context, versionID, releaseID int64) error {
+	_, err := db.GetEngine(ctx).Delete(&PackageVersionRelease{VersionID: versionID, ReleaseID: releaseID})
+	return err
+}
+
+// DeleteVersionReleasesByVersionID removes all release links of the version
+func DeleteVersionReleasesByVersionID(ctx context.Context, versionID int64) error {
+	_, err := db.GetEngine(ctx).Where("version_id = ?", versionID).Delete(&PackageVersionRelease{})
+	return err
+}
+
+// DeleteVersionReleasesByReleaseID removes all version links of the release
+func DeleteVersionReleasesByReleaseID(ctx context.Context, releaseID int64) error {
+	_, err := db.GetEngine(ctx).Where("release_id = ?", releaseID).Delete(&PackageVersionRelease{})
+	return err
+}
+
+// protectingReleasesCond matches the links of versions to releases of the repository the package of the version is linked to.
+// Links to releases of other repositories, e.g. after the package was linked to another repository, don't protect a version.
+func protectingReleasesCond() builder.Cond {
+	return builder.In("package_version_release.id",
+		builder.Select("package_version_release.id").
+			From("package_version_release").
+			InnerJoin("`release`", "`release`.id = package_version_release.release_id").
+			InnerJoin("package_version", "package_version.id = package_version_release.version_id").
+			InnerJoin("package", "package.id = package_version.package_id AND package.repo_id = `release`.repo_id"),
+	)
+}
+
+// GetReleasesByVersionID gets the releases of the linked repository which reference the version
+func GetReleasesByVersionID(ctx context.Context, versionID int64) ([]*repo_model.Release, error) {
+	releases := make([]*repo_model.Release, 0, 5)
+	return releases, db.GetEngine(ctx).
+		Where(builder.In("id",
+			builder.Select("package_version_release.release_id").
+				From("package_version_release").
+				Where(builder.Eq{"package_version_release.version_id": versionID}.And(protectingReleasesCond())),
+		)).
+		Desc("created_unix").
+		Find(&releases)
+}
+
+// GetProtectedVersionIDs returns the ids of the given versions which are referenced by a release of the linked repository of their package
+func GetProtectedVersionIDs(ctx context.Context, versionIDs []int64) (map[int64]bool, error) {
+	protected := make(map[int64]bool)
+	if len(versionIDs) == 0 {
+		return protected, nil
+	}
+
+	ids := make([]int64, 0, len(versionIDs))
+	if err := db.GetEngine(ctx).
+		Table("package_version_release").
+		Distinct("version_id").
+		Where(builder.In("version_id", versionIDs).And(protectingReleasesCond())).
+		Find(&ids); err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		protected[id] = true
+	}
+	return protected, nil
+}
+
+// IsVersionProtected checks if the version is referenced by a release of the linked repository of its package
+func IsVersionProtected(ctx context.Context, versionID int64) (bool, error) {
+	protected, err := GetProtectedVersionIDs(ctx, []int64{versionID})
+	if err != nil {
+		return false, err
+	}
+	return protected[versionID], nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages_test
+
+import (
+	"testing"
+
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unittest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPackageVersionRelease(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeGeneric,
+		Name:      "version-release",
+		LowerName: "version-release",
+	})
+	assert.NoError(t, err)
+
+	insertVersion := func(version string) *packages_model.PackageVersion {
+		pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			Version:      version,
+			LowerVersion: version,
+		})
+		assert.NoError(t, err)
+		return pv
+	}
+
+	pv1 := insertVersion("1.0.0")
+	pv2 := insertVersion("2.0.0")
+
+	release := unittest.AssertExistsAndLoadBean(t, &repo_model.Release{ID: 1})
+	otherRelease := unittest.AssertExistsAndLoadBean(t, &repo_model.Release{ID: 2})
+
+	assertProtected := func(t *testing.T, expected ...int64) {
+		protected, err := packages_model.GetProtectedVersionIDs(db.DefaultContext, []int64{pv1.ID, pv2.ID})
+		assert.NoError(t, err)
+		assert.Len(t, protected, len(expected))
+		for _, id := range expected {
+			assert.True(t, protected[id])
+		}
+	}
+
+	t.Run("Unlinked", func(t *testing.T) {
+		// the package must be linked to the repository of the release
+		assert.ErrorIs(t, packages_model.LinkVersionToRelease(db.DefaultContext, p, pv1.ID, release), packages_model.ErrReleaseRepositoryMismatch)
+
+		assert.NoError(t, packages_model.SetRepositoryLink(db.DefaultContext, p.ID, release.RepoID))
+		p.RepoID = release.RepoID
+
+		assert.ErrorIs(t, packages_model.LinkVersionToRelease(db.DefaultContext, p, pv1.ID, otherRelease), packages_model.ErrReleaseRepositoryMismatch)
+		assertProtected(t)
+	})
+
+	t.Run("Linked", func(t *testing.T) {
+		assert.NoError(t, packages_model.LinkVersionToRelease(db.DefaultContext, p, pv1.ID, release))
+		// linking twice is a no-op
+		assert.NoError(t, packages_model.LinkVersionToRelease(db.DefaultContext, p, pv1.ID, release))
+
+		assertProtected(t, pv1.ID)
+
+		protected, err := packages_model.IsVersionProtected(db.DefaultContext, pv1.ID)
+		assert.NoError(t, err)
+		assert.True(t, protected)
+		protected, err = packages_model.IsVersionProtected(db.DefaultContext, pv2.ID)
+		assert.NoError(t, err)
+		assert.False(t, protected)
+
+		releases, err := packages_model.GetReleasesByVersionID(db.DefaultContext, pv1.ID)
+		assert.NoError(t, err)
+		assert.Len(t, releases, 1)
+		assert.Equal(t, release.ID, releases[0].ID)
+	})
+
+	t.Run("RepositoryChanged", func(t *testing.T) {
+		// links to releases of another repository don't protect the version
+		assert.NoError(t, packages_model.SetRepositoryLink(db.DefaultContext, p.ID, otherRelease.RepoID))
+		assertProtected(t)
+
+		releases, err := packages_model.GetReleasesByVersionID(db.DefaultContext, pv1.ID)
+		assert.NoError(t, err)
+		assert.Empty(t, releases)
+
+		assert.NoError(t, packages_model.SetRepositoryLink(db.DefaultContext, p.ID, release.RepoID))
+		assertProtected(t, pv1.ID)
+	})
+
+	t.Run("Unlink", func(t *testing.T) {
+		assert.NoError(t, packages_model.LinkVersionToRelease(db.DefaultContext, p, pv2.ID, release))
+		assertProtected(t, pv1.ID, pv2.ID)
+
+		assert.NoError(t, packages_model.UnlinkVersionFromRelease(db.DefaultContext, pv1.ID, release.ID))
+		assertProtected(t, pv2.ID)
+
+		assert.NoError(t, packages_model.DeleteVersionReleasesByReleaseID(db.DefaultContext, release.ID))
+		assertProtected(t)
+	})
+}
@@ -0,0 +1,1494 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
+	container_module "code.gitea.io/gitea/modules/packages/container"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopyVersion(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeGeneric,
+		Name:      "Copy-Package",
+		LowerName: "copy-package",
+	})
+	assert.NoError(t, err)
+	_, err = packages_model.InsertProperty(db.DefaultContext, packages_model.PropertyTypePackage, p.ID, "package-property", "value")
+	assert.NoError(t, err)
+
+	pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+		PackageID:    p.ID,
+		CreatorID:    2,
+		Version:      "1.0.0-rc.1",
+		LowerVersion: "1.0.0-rc.1",
+		MetadataJSON: `{"key":"value"}`,
+		IsPrerelease: true,
+		ReleaseNotes: "Initial release",
+	})
+	assert.NoError(t, err)
+	_, err = packages_model.InsertProperty(db.DefaultContext, packages_model.PropertyTypeVersion, pv.ID, "version-property", "value")
+	assert.NoError(t, err)
+
+	pb := insertTestBlob(t, "copy-version")
+
+	pf, err := packages_model.TryInsertFile(db.DefaultContext, &packages_model.PackageFile{
+		VersionID: pv.ID,
+		BlobID:    pb.ID,
+		Name:      "File.bin",
+		LowerName: "file.bin",
+		IsLead:    true,
+	})
+	assert.NoError(t, err)
+	_, err = packages_model.InsertProperty(db.DefaultContext, packages_model.PropertyTypeFile, pf.ID, "file-property", "value")
+	assert.NoError(t, err)
+
+	blobCount := unittest.GetCount(t, &packages_model.PackageBlob{})
+
+	npv, err := packages_model.CopyVersion(db.DefaultContext, pv, 3, 1, nil)
+	assert.NoError(t, err)
+	assert.NotEqual(t, pv.ID, npv.ID)
+	assert.EqualValues(t, 1, npv.CreatorID)
+	assert.Equal(t, pv.Version, npv.Version)
+	assert.Equal(t, pv.MetadataJSON, npv.MetadataJSON)
+	assert.True(t, npv.IsPrerelease)
+	assert.Equal(t, "Initial release", npv.ReleaseNotes)
+
+	np, err := packages_model.GetPackageByName(db.DefaultContext, 3, packages_model.TypeGeneric, "copy-package")
+	assert.NoError(t, err)
+	assert.Equal(t, np.ID, npv.PackageID)
+	assert.Equal(t, "Copy-Package", np.Name)
+	assert.EqualValues(t, 1, np.CreatorID)
+
+	pps, err := packages_model.GetProperties(db.DefaultContext, packages_model.PropertyTypePackage, np.ID)
+	assert.NoError(t, err)
+	assert.Len(t, pps, 1)
+	pps, err = packages_model.GetProperties(db.DefaultContext, packages_model.PropertyTypeVersion, npv.ID)
+	assert.NoError(t, err)
+	assert.Len(t, pps, 1)
+
+	pfs, err := packages_model.GetFilesByVersionID(db.DefaultContext, npv.ID)
+	assert.NoError(t, err)
+	assert.Len(t, pfs, 1)
+	assert.Equal(t, pb.ID, pfs[0].BlobID)
+	assert.Equal(t, "File.bin", pfs[0].Name)
+	assert.True(t, pfs[0].IsLead)
+	pps, err = packages_model.GetProperties(db.DefaultContext, packages_model.PropertyTypeFile, pfs[0].ID)
+	assert.NoError(t, err)
+	assert.Len(t, pps, 1)
+
+	// the blob is shared and not duplicated
+	unittest.AssertCount(t, &packages_model.PackageBlob{}, blobCount)
+
+	// copying the same version again fails
+	_, err = packages_model.CopyVersion(db.DefaultContext, pv, 3, 1, nil)
+	assert.ErrorIs(t, err, packages_model.ErrDuplicatePackageVersion)
+
+	// the callback can abort the copy before a file is added
+	errAbort := errors.New("abort")
+	var checked []int64
+	_, err = packages_model.CopyVersion(db.DefaultContext, pv, 4, 1, func(_ context.Context, p *packages_model.Package, _ *packages_model.PackageVersion, pb *packages_model.PackageBlob) error {
+		assert.EqualValues(t, 4, p.OwnerID)
+		checked = append(checked, pb.ID)
+		return errAbort
+	})
+	assert.ErrorIs(t, err, errAbort)
+	assert.Equal(t, []int64{pb.ID}, checked)
+}
+
+func TestIncrementVersionDownloads(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeGeneric,
+		Name:      "download-package",
+		LowerName: "download-package",
+	})
+	assert.NoError(t, err)
+
+	pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+		PackageID:    p.ID,
+		Version:      "1.0.0",
+		LowerVersion: "1.0.0",
+	})
+	assert.NoError(t, err)
+
+	const workers = 10
+	const increments = 5
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(delta int64) {
+			defer wg.Done()
+			for j := 0; j < increments; j++ {
+				assert.NoError(t, packages_model.IncrementVersionDownloads(db.DefaultContext, pv.ID, delta))
+			}
+		}(int64(i + 1))
+	}
+	wg.Wait()
+
+	pv, err = packages_model.GetVersionByID(db.DefaultContext, pv.ID)
+	assert.NoError(t, err)
+	// (1 + 2 + ... + workers) * increments
+	assert.EqualValues(t, workers*(workers+1)/2*increments, pv.DownloadCount)
+}
+
+func TestPackageDownloadTotal(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeGeneric,
+		Name:      "download-total",
+		LowerName: "download-total",
+	})
+	assert.NoError(t, err)
+
+	total, err := packages_model.PackageDownloadTotal(db.DefaultContext, p.ID)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, total)
+
+	for _, v := range []struct {
+		Version       string
+		DownloadCount int64
+		IsInternal    bool
+	}{
+		{"1.0.0", 3, false},
+		{"2.0.0", 5, false},
+		{"3.0.0", 0, false},
+		{"internal", 100, true},
+	} {
+		_, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:     p.ID,
+			Version:       v.Version,
+			LowerVersion:  v.Version,
+			DownloadCount: v.DownloadCount,
+			IsInternal:    v.IsInternal,
+		})
+		assert.NoError(t, err)
+	}
+
+	total, err = packages_model.PackageDownloadTotal(db.DefaultContext, p.ID)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 8, total)
+}
+
+func TestFirstPublishedUnix(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeGeneric,
+		Name:      "first-published",
+		LowerName: "first-published",
+	})
+	assert.NoError(t, err)
+
+	first, err := packages_model.FirstPublishedUnix(db.DefaultContext, p.ID)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, first)
+
+	// the versions are inserted out of order, the internal version is the oldest one
+	for _, v := range []struct {
+		Version     string
+		CreatedUnix timeutil.TimeStamp
+		IsInternal  bool
+	}{
+		{"2.0.0", 3000, false},
+		{"1.0.0", 2000, false},
+		{"3.0.0", 4000, false},
+		{"internal", 1000, true},
+	} {
+		pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			Version:      v.Version,
+			LowerVersion: v.Version,
+			IsInternal:   v.IsInternal,
+		})
+		assert.NoError(t, err)
+		_, err = db.GetEngine(db.DefaultContext).ID(pv.ID).Cols("created_unix").NoAutoTime().Update(&packages_model.PackageVersion{CreatedUnix: v.CreatedUnix})
+		assert.NoError(t, err)
+	}
+
+	first, err = packages_model.FirstPublishedUnix(db.DefaultContext, p.ID)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2000, first)
+}
+
+func TestExistsVersion(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeNpm,
+		Name:      "Exists-Package",
+		LowerName: "exists-package",
+	})
+	assert.NoError(t, err)
+
+	exists, err := packages_model.ExistsPackage(db.DefaultContext, 2, packages_model.TypeNpm, "EXISTS-package")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = packages_model.ExistsPackage(db.DefaultContext, 2, packages_model.TypeGeneric, "exists-package")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = packages_model.ExistsPackage(db.DefaultContext, 3, packages_model.TypeNpm, "exists-package")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	for _, v := range []struct {
+		Version    string
+		IsInternal bool
+	}{
+		{"1.0.0-Beta", false},
+		{"_internal", true},
+	} {
+		_, err = packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			Version:      v.Version,
+			LowerVersion: strings.ToLower(v.Version),
+			IsInternal:   v.IsInternal,
+		})
+		assert.NoError(t, err)
+	}
+
+	cases := []struct {
+		OwnerID int64
+		Type    packages_model.Type
+		Name    string
+		Version string
+		Exists  bool
+	}{
+		{2, packages_model.TypeNpm, "exists-package", "1.0.0-beta", true},
+		{2, packages_model.TypeNpm, "Exists-PACKAGE", "1.0.0-BETA", true},
+		{2, packages_model.TypeNpm, "exists-package", "1.0.0", false},
+		{2, packages_model.TypeNpm, "exists-package", "_internal", false},
+		{2, packages_model.TypeNpm, "exists-other", "1.0.0-beta", false},
+		{2, packages_model.TypeGeneric, "exists-package", "1.0.0-beta", false},
+		{3, packages_model.TypeNpm, "exists-package", "1.0.0-beta", false},
+	}
+
+	for _, c := range cases {
+		exists, err := packages_model.ExistsVersion(db.DefaultContext, c.OwnerID, c.Type, c.Name, c.Version)
+		assert.NoError(t, err)
+		assert.Equal(t, c.Exists, exists, "%d/%s/%s/%s", c.OwnerID, c.Type, c.Name, c.Version)
+
+		// the existence check must agree with loading the version
+		_, err = packages_model.GetVersionByNameAndVersion(db.DefaultContext, c.OwnerID, c.Type, c.Name, c.Version)
+		if c.Exists {
+			assert.NoError(t, err)
+		} else {
+			assert.ErrorIs(t, err, packages_model.ErrPackageNotExist)
+		}
+	}
+}
+
+func insertBenchmarkVersion(b *testing.B) {
+	if err := unittest.PrepareTestDatabase(); err != nil {
+		b.Fatal(err)
+	}
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeNpm,
+		Name:      "exists-benchmark",
+		LowerName: "exists-benchmark",
+	})
+	if err != nil && err != packages_model.ErrDuplicatePackage {
+		b.Fatal(err)
+	}
+	if _, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+		PackageID:    p.ID,
+		Version:      "1.0.0",
+		LowerVersion: "1.0.0",
+		MetadataJSON: `{"readme":"` + strings.Repeat("x", 64*1024) + `"}`,
+	}); err != nil && err != packages_model.ErrDuplicatePackageVersion {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+}
+
+func BenchmarkExistsVersion(b *testing.B) {
+	insertBenchmarkVersion(b)
+
+	for i := 0; i < b.N; i++ {
+		if _, err := packages_model.ExistsVersion(db.DefaultContext, 2, packages_model.TypeNpm, "exists-benchmark", "1.0.0"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetVersionByNameAndVersion(b *testing.B) {
+	insertBenchmarkVersion(b)
+
+	for i := 0; i < b.N; i++ {
+		if _, err := packages_model.GetVersionByNameAndVersion(db.DefaultContext, 2, packages_model.TypeNpm, "exists-benchmark", "1.0.0"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+const (
+	latestBenchmarkPackages = 200
+	latestBenchmarkVersions = 50
+)
+
+// insertLatestBenchmarkVersions generates latestBenchmarkPackages packages with latestBenchmarkVersions versions each.
+// Every tenth version is yanked. The fixtures are only generated once per test database.
+func insertLatestBenchmarkVersions(b *testing.B) {
+	if err := unittest.PrepareTestDatabase(); err != nil {
+		b.Fatal(err)
+	}
+
+	for i := 0; i < latestBenchmarkPackages; i++ {
+		name := fmt.Sprintf("latest-benchmark-%03d", i)
+		p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+			OwnerID:   2,
+			Type:      packages_model.TypeGeneric,
+			Name:      name,
+			LowerName: name,
+		})
+		if err == packages_model.ErrDuplicatePackage {
+			break
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		pvs := make([]*packages_model.PackageVersion, 0, latestBenchmarkVersions)
+		for j := 0; j < latestBenchmarkVersions; j++ {
+			version := fmt.Sprintf("1.0.%d", j)
+			pvs = append(pvs, &packages_model.PackageVersion{
+				PackageID:    p.ID,
+				Version:      version,
+				LowerVersion: version,
+				CreatedUnix:  timeutil.TimeStamp(1600000000 + j),
+				IsYanked:     j%10 == 9,
+			})
+		}
+		if _, err := db.GetEngine(db.DefaultContext).NoAutoTime().Insert(pvs); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ResetTimer()
+}
+
+func BenchmarkSearchLatestVersions(b *testing.B) {
+	insertLatestBenchmarkVersions(b)
+
+	opts := &packages_model.PackageSearchOptions{
+		OwnerID: 2,
+		Type:    packages_model.TypeGeneric,
+		Name:    packages_model.SearchValue{Value: "latest-benchmark-"},
+		Paginator: &db.ListOptions{
+			Page:     1,
+			PageSize: 20,
+		},
+	}
+
+	for i := 0; i < b.N; i++ {
+		pvs, count, err := packages_model.SearchLatestVersions(db.DefaultContext, opts)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if count != latestBenchmarkPackages || len(pvs) != 20 {
+			b.Fatalf("unexpected result: %d versions, %d total", len(pvs), count)
+		}
+	}
+}
+
+func TestUpdateVersionLastDownload(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeGeneric,
+		Name:      "last-download-package",
+		LowerName: "last-download-package",
+	})
+	assert.NoError(t, err)
+
+	pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+		PackageID:    p.ID,
+		Version:      "1.0.0",
+		LowerVersion: "1.0.0",
+	})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, pv.LastDownloadUnix)
+
+	search := func(since timeutil.TimeStamp) int64 {
+		_, count, err := packages_model.SearchVersions(db.DefaultContext, &packages_model.PackageSearchOptions{
+			PackageID:          p.ID,
+			IsInternal:         util.OptionalBoolFalse,
+			NotDownloadedSince: since,
+		})
+		assert.NoError(t, err)
+		return count
+	}
+
+	now := timeutil.TimeStamp(1_600_000_000)
+
+	assert.EqualValues(t, 1, search(now))
+
+	cases := []struct {
+		Now      timeutil.TimeStamp
+		Expected timeutil.TimeStamp
+	}{
+		{now, now},
+		{now + 60, now},
+		{now + packages_model.LastDownloadUpdateInterval - 1, now},
+		{now + packages_model.LastDownloadUpdateInterval, now + packages_model.LastDownloadUpdateInterval},
+		{now + packages_model.LastDownloadUpdateInterval + 60, now + packages_model.LastDownloadUpdateInterval},
+	}
+
+	for _, c := range cases {
+		assert.NoError(t, packages_model.UpdateVersionLastDownload(db.DefaultContext, pv.ID, c.Now))
+
+		pv = unittest.AssertExistsAndLoadBean(t, &packages_model.PackageVersion{ID: pv.ID})
+		assert.Equal(t, c.Expected, pv.LastDownloadUnix, "now %d", c.Now)
+	}
+
+	assert.EqualValues(t, 0, search(now))
+	assert.EqualValues(t, 1, search(now+packages_model.LastDownloadUpdateInterval+1))
+}
+
+func TestSearchVersionsByDownloads(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeGeneric,
+		Name:      "download-criteria-package",
+		LowerName: "download-criteria-package",
+	})
+	assert.NoError(t, err)
+
+	now := timeutil.TimeStamp(1_600_000_000)
+
+	versions := []struct {
+		Version       string
+		DownloadCount int64
+		LastDownload  timeutil.TimeStamp
+	}{
+		{"1.0.0", 0, 0},
+		{"1.1.0", 5, now - 100},
+		{"1.2.0", 100, now - 100},
+		{"1.3.0", 100, now + 100},
+	}
+	for _, v := range versions {
+		pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			Version:      v.Version,
+			LowerVersion: v.Version,
+		})
+		assert.NoError(t, err)
+
+		pv.DownloadCount = v.DownloadCount
+		pv.LastDownloadUnix = v.LastDownload
+		assert.NoError(t, packages_model.UpdateVersion(db.DefaultContext, pv))
+	}
+
+	cases := []struct {
+		DownloadsBelow     int64
+		NotDownloadedSince timeutil.TimeStamp
+		Expected           []string
+	}{
+		{0, 0, []string{"1.0.0", "1.1.0", "1.2.0", "1.3.0"}},
+		{1, 0, []string{"1.0.0"}},
+		{10, 0, []string{"1.0.0", "1.1.0"}},
+		{1000, 0, []string{"1.0.0", "1.1.0", "1.2.0", "1.3.0"}},
+		{0, now, []string{"1.0.0", "1.1.0", "1.2.0"}},
+		{0, now + 200, []string{"1.0.0", "1.1.0", "1.2.0", "1.3.0"}},
+		{10, now, []string{"1.0.0", "1.1.0"}},
+		{1000, now, []string{"1.0.0", "1.1.0", "1.2.0"}},
+		{1, now + 200, []string{"1.0.0"}},
+	}
+
+	for _, c := range cases {
+		pvs, _, err := packages_model.SearchVersions(db.DefaultContext, &packages_model.PackageSearchOptions{
+			PackageID:          p.ID,
+			IsInternal:         util.OptionalBoolFalse,
+			DownloadsBelow:     c.DownloadsBelow,
+			NotDownloadedSince: c.NotDownloadedSince,
+			Sort:               "lowestversion",
+		})
+		assert.NoError(t, err)
+
+		found := make([]string, 0, len(pvs))
+		for _, pv := range pvs {
+			found = append(found, pv.Version)
+		}
+		assert.Equal(t, c.Expected, found, "downloads below %d, not downloaded since %d", c.DownloadsBelow, c.NotDownloadedSince)
+	}
+}
+
+func TestSearchVersionsByProperties(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeGeneric,
+		Name:      "property-search-package",
+		LowerName: "property-search-package",
+	})
+	assert.NoError(t, err)
+
+	versions := []struct {
+		Version    string
+		Properties [][2]string
+	}{
+		{"1.0.0", [][2]string{{"os", "linux"}, {"arch", "amd64"}}},
+		{"1.1.0", [][2]string{{"os", "linux"}, {"arch", "arm64"}}},
+		{"1.2.0", [][2]string{{"os", "Linux"}, {"arch", "amd64"}}},
+		// a property listed multiple times must not affect the matching of the other properties
+		{"1.3.0", [][2]string{{"os", "linux"}, {"os", "linux"}}},
+		{"1.4.0", nil},
+	}
+	for _, v := range versions {
+		pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			Version:      v.Version,
+			LowerVersion: v.Version,
+		})
+		assert.NoError(t, err)
+
+		for _, prop := range v.Properties {
+			_, err := packages_model.InsertProperty(db.DefaultContext, packages_model.PropertyTypeVersion, pv.ID, prop[0], prop[1])
+			assert.NoError(t, err)
+		}
+	}
+
+	cases := []struct {
+		Properties map[string]string
+		Expected   []string
+	}{
+		{nil, []string{"1.0.0", "1.1.0", "1.2.0", "1.3.0", "1.4.0"}},
+		{map[string]string{"os": "linux"}, []string{"1.0.0", "1.1.0", "1.3.0"}},
+		{map[string]string{"os": "linux", "arch": "amd64"}, []string{"1.0.0"}},
+		{map[string]string{"arch": "amd64"}, []string{"1.0.0", "1.2.0"}},
+		// values are compared case sensitive
+		{map[string]string{"os": "Linux"}, []string{"1.2.0"}},
+		{map[string]string{"os": "LINUX"}, []string{}},
+		// all properties must be present
+		{map[string]string{"os": "linux", "libc": "musl"}, []string{}},
+		{map[string]string{"libc": "musl"}, []string{}},
+	}
+
+	for _, c := range cases {
+		pvs, _, err := packages_model.SearchVersions(db.DefaultContext, &packages_model.PackageSearchOptions{
+			PackageID:  p.ID,
+			IsInternal: util.OptionalBoolFalse,
+			Properties: c.Properties,
+			Sort:       "lowestversion",
+		})
+		assert.NoError(t, err)
+
+		found := make([]string, 0, len(pvs))
+		for _, pv := range pvs {
+			found = append(found, pv.Version)
+		}
+		assert.Equal(t, c.Expected, found, "properties %v", c.Properties)
+	}
+}
+
+func TestSearchVersionsByNumericProperties(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeContainer,
+		Name:      "numeric-property-package",
+		LowerName: "numeric-property-package",
+	})
+	assert.NoError(t, err)
+
+	versions := []struct {
+		Version string
+		Size    string
+	}{
+		{"1.0.0", "1024"},
+		{"1.1.0", "1073741824"},
+		{"1.2.0", "2147483648"},
+		{"1.3.0", "invalid"},
+		{"1.4.0", ""},
+	}
+	for _, v := range versions {
+		pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			Version:      v.Version,
+			LowerVersion: v.Version,
+		})
+		assert.NoError(t, err)
+
+		if v.Size == "" {
+			continue
+		}
+		pp, err := packages_model.InsertProperty(db.DefaultContext, packages_model.PropertyTypeVersion, pv.ID, container_module.PropertyImageSize, v.Size)
+		assert.NoError(t, err)
+		if v.Size == "invalid" {
+			assert.Equal(t, packages_model.PropertyValueTypeString, pp.ValueType)
+		} else {
+			assert.Equal(t, packages_model.PropertyValueTypeNumeric, pp.ValueType)
+		}
+	}
+
+	// values of other properties are not interpreted
+	pp, err := packages_model.InsertProperty(db.DefaultContext, packages_model.PropertyTypeVersion, 0, "numeric-property-unknown", "42")
+	assert.NoError(t, err)
+	assert.Equal(t, packages_model.PropertyValueTypeString, pp.ValueType)
+	assert.EqualValues(t, 0, pp.NumericValue)
+
+	size := func(operator packages_model.NumericPropertyOperator, value int64) *packages_model.NumericPropertyCondition {
+		return &packages_model.NumericPropertyCondition{
+			Name:     container_module.PropertyImageSize,
+			Operator: operator,
+			Value:    value,
+		}
+	}
+
+	cases := []struct {
+		Conditions []*packages_model.NumericPropertyCondition
+		Expected   []string
+	}{
+		{nil, []string{"1.0.0", "1.1.0", "1.2.0", "1.3.0", "1.4.0"}},
+		{[]*packages_model.NumericPropertyCondition{size(packages_model.NumericPropertyGreaterOrEqual, 1073741824)}, []string{"1.1.0", "1.2.0"}},
+		{[]*packages_model.NumericPropertyCondition{size(packages_model.NumericPropertyLessOrEqual, 1073741824)}, []string{"1.0.0", "1.1.0"}},
+		{[]*packages_model.NumericPropertyCondition{size(packages_model.NumericPropertyGreaterOrEqual, 0)}, []string{"1.0.0", "1.1.0", "1.2.0"}},
+		{[]*packages_model.NumericPropertyCondition{
+			size(packages_model.NumericPropertyGreaterOrEqual, 2048),
+			size(packages_model.NumericPropertyLessOrEqual, 2147483647),
+		}, []string{"1.1.0"}},
+		{[]*packages_model.NumericPropertyCondition{{
+			Name:     container_module.PropertyImageCreated,
+			Operator: packages_model.NumericPropertyGreaterOrEqual,
+			Value:    0,
+		}}, []string{}},
+	}
+
+	for i, c := range cases {
+		pvs, _, err := packages_model.SearchVersions(db.DefaultContext, &packages_model.PackageSearchOptions{
+			PackageID:         p.ID,
+			IsInternal:        util.OptionalBoolFalse,
+			NumericProperties: c.Conditions,
+			Sort:              "lowestversion",
+		})
+		assert.NoError(t, err)
+
+		found := make([]string, 0, len(pvs))
+		for _, pv := range pvs {
+			found = append(found, pv.Version)
+		}
+		assert.Equal(t, c.Expected, found, "case %d", i)
+	}
+}
+
+func TestSearchVersionsByKeyword(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	insert := func(name string, keywords ...string) *packages_model.PackageVersion {
+		p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+			OwnerID:   2,
+			Type:      packages_model.TypeNpm,
+			Name:      name,
+			LowerName: name,
+		})
+		assert.NoError(t, err)
+
+		pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			Version:      "1.0.0",
+			LowerVersion: "1.0.0",
+		})
+		assert.NoError(t, err)
+
+		for _, keyword := range keywords {
+			_, err := packages_model.InsertProperty(db.DefaultContext, packages_model.PropertyTypeVersion, pv.ID, packages_model.PropertyKeyword, packages_model.NormalizeKeyword(keyword))
+			assert.NoError(t, err)
+		}
+		return pv
+	}
+
+	parser := insert("keyword-search-parser", "JSON", "parser")
+	serializer := insert("keyword-search-serializer", "json", "Serializer")
+	insert("keyword-search-other", "yaml")
+
+	search := func(keyword string) []int64 {
+		pvs, _, err := packages_model.SearchVersions(db.DefaultContext, &packages_model.PackageSearchOptions{
+			OwnerID:    2,
+			Name:       packages_model.SearchValue{Value: "keyword-search-"},
+			Keyword:    keyword,
+			IsInternal: util.OptionalBoolFalse,
+			Sort:       "alphabetically",
+		})
+		assert.NoError(t, err)
+
+		ids := make([]int64, 0, len(pvs))
+		for _, pv := range pvs {
+			ids = append(ids, pv.ID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []int64{parser.ID, serializer.ID}, search("json"))
+	assert.Equal(t, []int64{parser.ID, serializer.ID}, search(" JSON "))
+	assert.Equal(t, []int64{serializer.ID}, search("serializer"))
+	assert.Empty(t, search("xml"))
+	// the keyword must match completely
+	assert.Empty(t, search("pars"))
+	assert.Len(t, search(""), 3)
+}
+
+func TestCountOwnerVersions(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	const ownerID = 5
+
+	count := func(includeInternal bool) int64 {
+		c, err := packages_model.CountOwnerVersions(db.DefaultContext, ownerID, includeInternal)
+		assert.NoError(t, err)
+		return c
+	}
+
+	startPublic := count(false)
+	startAll := count(true)
+
+	for i, versions := range []int{3, 2, 1} {
+		name := fmt.Sprintf("count-owner-versions-%d", i)
+		p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+			OwnerID:   ownerID,
+			Type:      packages_model.TypeGeneric,
+			Name:      name,
+			LowerName: name,
+		})
+		assert.NoError(t, err)
+
+		for j := 0; j < versions; j++ {
+			version := fmt.Sprintf("1.0.%d", j)
+			_, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+				PackageID:    p.ID,
+				Version:      version,
+				LowerVersion: version,
+			})
+			assert.NoError(t, err)
+		}
+	}
+
+	internal, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   ownerID,
+		Type:      packages_model.TypeContainer,
+		Name:      "count-owner-versions-internal",
+		LowerName: "count-owner-versions-internal",
+	})
+	assert.NoError(t, err)
+	_, err = packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+		PackageID:    internal.ID,
+		Version:      "_upload",
+		LowerVersion: "_upload",
+		IsInternal:   true,
+	})
+	assert.NoError(t, err)
+
+	// versions of other owners are not counted
+	other, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeGeneric,
+		Name:      "count-owner-versions-other",
+		LowerName: "count-owner-versions-other",
+	})
+	assert.NoError(t, err)
+	_, err = packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+		PackageID:    other.ID,
+		Version:      "1.0.0",
+		LowerVersion: "1.0.0",
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, startPublic+6, count(false))
+	assert.Equal(t, startAll+7, count(true))
+}
+
+func TestSearchVersionsByDescription(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	insert := func(ownerID int64, name, description string) *packages_model.PackageVersion {
+		p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+			OwnerID:   ownerID,
+			Type:      packages_model.TypeNpm,
+			Name:      name,
+			LowerName: name,
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, packages_model.SetDescription(db.DefaultContext, p.ID, description))
+
+		pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			Version:      "1.0.0",
+			LowerVersion: "1.0.0",
+		})
+		assert.NoError(t, err)
+		return pv
+	}
+
+	byName := insert(2, "description-search-quasar", "")
+	byDescription := insert(2, "description-search-alpha", "A QUASAR toolkit")
+	insert(2, "description-search-other", "something else")
+	private := insert(23, "description-search-private", "quasar for members")
+
+	search := func(includeDescription bool, actor *user_model.User) []int64 {
+		pvs, _, err := packages_model.SearchLatestVersions(db.DefaultContext, &packages_model.PackageSearchOptions{
+			Name:                    packages_model.SearchValue{Value: "Quasar"},
+			IncludeDescription:      includeDescription,
+			IsInternal:              util.OptionalBoolFalse,
+			RestrictToVisibleOwners: true,
+			Actor:                   actor,
+			Sort:                    "alphabetically",
+		})
+		assert.NoError(t, err)
+
+		ids := make([]int64, 0, len(pvs))
+		for _, pv := range pvs {
+			ids = append(ids, pv.ID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []int64{byName.ID}, search(false, nil))
+	// name matches are ranked above description matches
+	assert.Equal(t, []int64{byName.ID, byDescription.ID}, search(true, nil))
+
+	admin := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1})
+	assert.Equal(t, []int64{byName.ID, byDescription.ID, private.ID}, search(true, admin))
+}
+
+func TestSetDescriptionTruncates(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeNpm,
+		Name:      "description-truncate",
+		LowerName: "description-truncate",
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, packages_model.SetDescription(db.DefaultContext, p.ID, strings.Repeat("ä", packages_model.MaxDescriptionLength+10)))
+
+	p, err = packages_model.GetPackageByID(db.DefaultContext, p.ID, false)
+	assert.NoError(t, err)
+	assert.Len(t, []rune(p.Description), packages_model.MaxDescriptionLength)
+}
+
+func TestMoveVersion(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	insertPackage := func(name string, packageType packages_model.Type) *packages_model.Package {
+		p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+			OwnerID:   2,
+			Type:      packageType,
+			Name:      name,
+			LowerName: name,
+		})
+		assert.NoError(t, err)
+		return p
+	}
+	insertVersion := func(packageID int64, version string) *packages_model.PackageVersion {
+		pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    packageID,
+			Version:      version,
+			LowerVersion: version,
+		})
+		assert.NoError(t, err)
+		return pv
+	}
+
+	source := insertPackage("move-version-source", packages_model.TypeNpm)
+	target := insertPackage("move-version-target", packages_model.TypeNpm)
+	other := insertPackage("move-version-other", packages_model.TypeNuGet)
+
+	t.Run("Move", func(t *testing.T) {
+		pv := insertVersion(source.ID, "1.0.0")
+		pb := insertTestBlob(t, "move-version")
+		pf, err := packages_model.TryInsertFile(db.DefaultContext, &packages_model.PackageFile{
+			VersionID: pv.ID,
+			BlobID:    pb.ID,
+			Name:      "file.tgz",
+			LowerName: "file.tgz",
+		})
+		assert.NoError(t, err)
+
+		assert.NoError(t, packages_model.MoveVersion(db.DefaultContext, pv.ID, target.ID))
+
+		pv, err = packages_model.GetVersionByID(db.DefaultContext, pv.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, target.ID, pv.PackageID)
+
+		pfs, err := packages_model.GetFilesByVersionID(db.DefaultContext, pv.ID)
+		assert.NoError(t, err)
+		assert.Len(t, pfs, 1)
+		assert.Equal(t, pf.ID, pfs[0].ID)
+	})
+
+	t.Run("Collision", func(t *testing.T) {
+		pv := insertVersion(source.ID, "2.0.0")
+		insertVersion(target.ID, "2.0.0")
+
+		assert.ErrorIs(t, packages_model.MoveVersion(db.DefaultContext, pv.ID, target.ID), packages_model.ErrDuplicatePackageVersion)
+
+		pv, err := packages_model.GetVersionByID(db.DefaultContext, pv.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, source.ID, pv.PackageID)
+	})
+
+	t.Run("TypeMismatch", func(t *testing.T) {
+		pv := insertVersion(source.ID, "3.0.0")
+
+		assert.ErrorIs(t, packages_model.MoveVersion(db.DefaultContext, pv.ID, other.ID), packages_model.ErrPackageTypeMismatch)
+	})
+}
+
+func TestSearchVersionsYanked(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeNpm,
+		Name:      "yank-test",
+		LowerName: "yank-test",
+	})
+	assert.NoError(t, err)
+
+	insert := func(version string, createdUnix timeutil.TimeStamp) *packages_model.PackageVersion {
+		pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			Version:      version,
+			LowerVersion: version,
+		})
+		assert.NoError(t, err)
+		_, err = db.GetEngine(db.DefaultContext).ID(pv.ID).Cols("created_unix").NoAutoTime().Update(&packages_model.PackageVersion{CreatedUnix: createdUnix})
+		assert.NoError(t, err)
+		return pv
+	}
+
+	pv1 := insert("1.0.0", 1000)
+	pv2 := insert("2.0.0", 2000)
+
+	latest := func() []int64 {
+		pvs, _, err := packages_model.SearchLatestVersions(db.DefaultContext, &packages_model.PackageSearchOptions{
+			PackageID:  p.ID,
+			IsInternal: util.OptionalBoolFalse,
+		})
+		assert.NoError(t, err)
+
+		ids := make([]int64, 0, len(pvs))
+		for _, pv := range pvs {
+			ids = append(ids, pv.ID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []int64{pv2.ID}, latest())
+
+	assert.NoError(t, packages_model.SetVersionYanked(db.DefaultContext, pv2.ID, true, "broken build"))
+
+	pv, err := packages_model.GetVersionByID(db.DefaultContext, pv2.ID)
+	assert.NoError(t, err)
+	assert.True(t, pv.IsYanked)
+	assert.Equal(t, "broken build", pv.YankReason)
+
+	// the newest version which is not yanked is the latest version
+	assert.Equal(t, []int64{pv1.ID}, latest())
+
+	pvs, _, err := packages_model.SearchVersions(db.DefaultContext, &packages_model.PackageSearchOptions{
+		PackageID: p.ID,
+		IsYanked:  util.OptionalBoolTrue,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, pvs, 1)
+	assert.Equal(t, pv2.ID, pvs[0].ID)
+
+	assert.NoError(t, packages_model.SetVersionYanked(db.DefaultContext, pv1.ID, true, ""))
+	assert.Empty(t, latest())
+
+	assert.NoError(t, packages_model.SetVersionYanked(db.DefaultContext, pv2.ID, false, "ignored"))
+
+	pv, err = packages_model.GetVersionByID(db.DefaultContext, pv2.ID)
+	assert.NoError(t, err)
+	assert.False(t, pv.IsYanked)
+	assert.Empty(t, pv.YankReason)
+	assert.Equal(t, []int64{pv2.ID}, latest())
+}
+
+func TestSetVersionReleaseNotes(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeGeneric,
+		Name:      "release-notes-test",
+		LowerName: "release-notes-test",
+	})
+	assert.NoError(t, err)
+
+	pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+		PackageID:    p.ID,
+		Version:      "1.0.0",
+		LowerVersion: "1.0.0",
+		ReleaseNotes: "First",
+	})
+	assert.NoError(t, err)
+
+	releaseNotes := func() string {
+		pv, err := packages_model.GetVersionByID(db.DefaultContext, pv.ID)
+		assert.NoError(t, err)
+		return pv.ReleaseNotes
+	}
+
+	assert.Equal(t, "First", releaseNotes())
+
+	assert.NoError(t, packages_model.SetVersionReleaseNotes(db.DefaultContext, pv.ID, "# Changes\n\n* Fixed a bug"))
+	assert.Equal(t, "# Changes\n\n* Fixed a bug", releaseNotes())
+
+	err = packages_model.SetVersionReleaseNotes(db.DefaultContext, pv.ID, strings.Repeat("a", packages_model.MaxReleaseNotesLength+1))
+	assert.ErrorIs(t, err, packages_model.ErrReleaseNotesTooLong)
+	assert.Equal(t, "# Changes\n\n* Fixed a bug", releaseNotes())
+
+	assert.NoError(t, packages_model.SetVersionReleaseNotes(db.DefaultContext, pv.ID, ""))
+	assert.Empty(t, releaseNotes())
+}
+
+func TestSearchLatestVersionsBySize(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	insert := func(name string, blobs ...*packages_model.PackageBlob) *packages_model.Package {
+		p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+			OwnerID:   2,
+			Type:      packages_model.TypeGeneric,
+			Name:      name,
+			LowerName: name,
+		})
+		assert.NoError(t, err)
+
+		// every blob is stored in a separate version
+		for i, pb := range blobs {
+			version := fmt.Sprintf("1.0.%d", i)
+			pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+				PackageID:    p.ID,
+				Version:      version,
+				LowerVersion: version,
+			})
+			assert.NoError(t, err)
+
+			_, err = packages_model.TryInsertFile(db.DefaultContext, &packages_model.PackageFile{
+				VersionID: pv.ID,
+				BlobID:    pb.ID,
+				Name:      "file.bin",
+				LowerName: "file.bin",
+			})
+			assert.NoError(t, err)
+		}
+		return p
+	}
+
+	shared := insertTestBlob(t, "size-sort-"+strings.Repeat("s", 100))
+	first := insertTestBlob(t, "size-sort-"+strings.Repeat("a", 60))
+	second := insertTestBlob(t, "size-sort-"+strings.Repeat("b", 60))
+	small := insertTestBlob(t, "size-sort-small")
+
+	// the shared blob is counted once, so this package is smaller than the one with two distinct blobs
+	sharedPackage := insert("size-sort-shared", shared, shared)
+	distinctPackage := insert("size-sort-distinct", first, second)
+	smallPackage := insert("size-sort-small", small)
+
+	pvs, _, err := packages_model.SearchLatestVersions(db.DefaultContext, &packages_model.PackageSearchOptions{
+		Name:       packages_model.SearchValue{Value: "size-sort-"},
+		IsInternal: util.OptionalBoolFalse,
+		Sort:       packages_model.SortBySize,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, pvs, 3)
+
+	packageIDs := make([]int64, 0, len(pvs))
+	for _, pv := range pvs {
+		packageIDs = append(packageIDs, pv.PackageID)
+	}
+	assert.Equal(t, []int64{distinctPackage.ID, sharedPackage.ID, smallPackage.ID}, packageIDs)
+}
+
+func TestSearchLatestVersionsByDownloads(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	insert := func(name string, downloads ...int64) (*packages_model.Package, []*packages_model.PackageVersion) {
+		p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+			OwnerID:   2,
+			Type:      packages_model.TypeGeneric,
+			Name:      name,
+			LowerName: name,
+		})
+		assert.NoError(t, err)
+
+		pvs := make([]*packages_model.PackageVersion, 0, len(downloads))
+		for i, count := range downloads {
+			version := fmt.Sprintf("1.0.%d", i)
+			pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+				PackageID:    p.ID,
+				Version:      version,
+				LowerVersion: version,
+			})
+			assert.NoError(t, err)
+			assert.NoError(t, packages_model.IncrementVersionDownloads(db.DefaultContext, pv.ID, count))
+			pvs = append(pvs, pv)
+		}
+		return p, pvs
+	}
+
+	search := func(sort string) []int64 {
+		pvs, _, err := packages_model.SearchLatestVersions(db.DefaultContext, &packages_model.PackageSearchOptions{
+			Name:       packages_model.SearchValue{Value: "download-sort-"},
+			IsInternal: util.OptionalBoolFalse,
+			Sort:       sort,
+		})
+		assert.NoError(t, err)
+
+		packageIDs := make([]int64, 0, len(pvs))
+		for _, pv := range pvs {
+			packageIDs = append(packageIDs, pv.PackageID)
+		}
+		return packageIDs
+	}
+
+	// the downloads of all versions are summed up
+	many, manyVersions := insert("download-sort-many", 3, 5)
+	few, _ := insert("download-sort-few", 2)
+	// ties are ordered by name
+	tieB, _ := insert("download-sort-tie-b", 4)
+	tieA, _ := insert("download-sort-tie-a", 4)
+
+	p, err := packages_model.GetPackageByID(db.DefaultContext, many.ID, false)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 8, p.DownloadCount)
+
+	assert.Equal(t, []int64{many.ID, tieA.ID, tieB.ID, few.ID}, search(packages_model.SortByDownloads))
+	assert.Equal(t, []int64{few.ID, tieA.ID, tieB.ID, many.ID}, search(packages_model.SortByReverseDownloads))
+
+	// the downloads of deleted versions are removed
+	assert.NoError(t, packages_model.DeleteVersionByID(db.DefaultContext, manyVersions[1].ID))
+
+	p, err = packages_model.GetPackageByID(db.DefaultContext, many.ID, false)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3, p.DownloadCount)
+
+	assert.Equal(t, []int64{tieA.ID, tieB.ID, many.ID, few.ID}, search(packages_model.SortByDownloads))
+}
+
+func TestSearchLatestVersionsSkipsPrereleases(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	insert := func(name string, versions ...string) *packages_model.Package {
+		p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+			OwnerID:   2,
+			Type:      packages_model.TypeNpm,
+			Name:      name,
+			LowerName: name,
+		})
+		assert.NoError(t, err)
+
+		for _, version := range versions {
+			_, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+				PackageID:    p.ID,
+				Version:      version,
+				LowerVersion: version,
+				IsPrerelease: strings.Contains(version, "-"),
+			})
+			assert.NoError(t, err)
+		}
+		return p
+	}
+
+	search := func(isPrerelease util.OptionalBool) map[int64]string {
+		pvs, _, err := packages_model.SearchLatestVersions(db.DefaultContext, &packages_model.PackageSearchOptions{
+			Name:         packages_model.SearchValue{Value: "prerelease-latest-"},
+			IsInternal:   util.OptionalBoolFalse,
+			IsPrerelease: isPrerelease,
+		})
+		assert.NoError(t, err)
+
+		latest := make(map[int64]string, len(pvs))
+		for _, pv := range pvs {
+			latest[pv.PackageID] = pv.Version
+		}
+		return latest
+	}
+
+	mixed := insert("prerelease-latest-mixed", "1.2.5", "1.3.0-rc.1")
+	only := insert("prerelease-latest-only", "2.0.0-beta.1")
+
+	assert.Equal(t, map[int64]string{mixed.ID: "1.3.0-rc.1", only.ID: "2.0.0-beta.1"}, search(util.OptionalBoolNone))
+	assert.Equal(t, map[int64]string{mixed.ID: "1.2.5"}, search(util.OptionalBoolFalse))
+}
+
+func TestGetLatestStableVersion(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	insert := func(name string, versions ...string) *packages_model.Package {
+		p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+			OwnerID:   2,
+			Type:      packages_model.TypeNpm,
+			Name:      name,
+			LowerName: name,
+		})
+		assert.NoError(t, err)
+
+		for _, version := range versions {
+			_, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+				PackageID:    p.ID,
+				Version:      version,
+				LowerVersion: version,
+				IsPrerelease: strings.Contains(version, "-"),
+				IsInternal:   strings.HasSuffix(version, "+internal"),
+			})
+			assert.NoError(t, err)
+		}
+		return p
+	}
+
+	t.Run("PrereleaseOnly", func(t *testing.T) {
+		p := insert("latest-stable-prerelease-only", "2.0.0-beta.1")
+
+		pv, err := packages_model.GetLatestStableVersion(db.DefaultContext, p.ID)
+		assert.ErrorIs(t, err, packages_model.ErrNoStableVersion)
+		assert.Nil(t, pv)
+	})
+
+	t.Run("Mixed", func(t *testing.T) {
+		// versions are ordered by semver precedence instead of lexically or by creation time
+		p := insert("latest-stable-mixed", "1.10.0", "1.9.0", "1.11.0-rc.1", "2.0.0+internal")
+
+		pv, err := packages_model.GetLatestStableVersion(db.DefaultContext, p.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "1.10.0", pv.Version)
+	})
+}
+
+func TestCleanupStaleInternalVersions(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeContainer,
+		Name:      "stale-internal-test",
+		LowerName: "stale-internal-test",
+	})
+	assert.NoError(t, err)
+
+	old := timeutil.TimeStamp(time.Now().Add(-2 * time.Hour).Unix())
+
+	insertVersion := func(version string, isInternal bool, createdUnix timeutil.TimeStamp) *packages_model.PackageVersion {
+		pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			Version:      version,
+			LowerVersion: version,
+			IsInternal:   isInternal,
+		})
+		assert.NoError(t, err)
+		_, err = db.GetEngine(db.DefaultContext).ID(pv.ID).Cols("created_unix").NoAutoTime().Update(&packages_model.PackageVersion{CreatedUnix: createdUnix})
+		assert.NoError(t, err)
+		return pv
+	}
+	insertFile := func(pv *packages_model.PackageVersion, pb *packages_model.PackageBlob, createdUnix timeutil.TimeStamp) *packages_model.PackageFile {
+		pf, err := packages_model.TryInsertFile(db.DefaultContext, &packages_model.PackageFile{
+			VersionID: pv.ID,
+			BlobID:    pb.ID,
+			Name:      pb.HashSHA256,
+			LowerName: pb.HashSHA256,
+		})
+		assert.NoError(t, err)
+		_, err = db.GetEngine(db.DefaultContext).ID(pf.ID).Cols("created_unix").NoAutoTime().Update(&packages_model.PackageFile{CreatedUnix: createdUnix})
+		assert.NoError(t, err)
+		return pf
+	}
+
+	// stale, the file is removed too
+	stale := insertVersion("stale", true, old)
+	staleFile := insertFile(stale, insertTestBlob(t, "stale-internal-stale"), old)
+	_, err = packages_model.InsertProperty(db.DefaultContext, packages_model.PropertyTypeFile, staleFile.ID, "name", "value")
+	assert.NoError(t, err)
+	// stale without files
+	empty := insertVersion("empty", true, old)
+	// a file was added recently
+	active := insertVersion("active", true, old)
+	insertFile(active, insertTestBlob(t, "stale-internal-active"), timeutil.TimeStampNow())
+	// the blob is used by a published version
+	shared := insertVersion("shared", true, old)
+	sharedBlob := insertTestBlob(t, "stale-internal-shared")
+	insertFile(shared, sharedBlob, old)
+	insertFile(insertVersion("published", false, old), sharedBlob, old)
+	// created recently
+	recent := insertVersion("recent", true, timeutil.TimeStampNow())
+
+	removed, err := packages_model.CleanupStaleInternalVersions(db.DefaultContext, time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, removed)
+
+	for _, pv := range []*packages_model.PackageVersion{stale, empty} {
+		unittest.AssertNotExistsBean(t, &packages_model.PackageVersion{ID: pv.ID})
+	}
+	unittest.AssertNotExistsBean(t, &packages_model.PackageFile{ID: staleFile.ID})
+	unittest.AssertNotExistsBean(t, &packages_model.PackageProperty{RefType: packages_model.PropertyTypeFile, RefID: staleFile.ID})
+	for _, pv := range []*packages_model.PackageVersion{active, shared, recent} {
+		unittest.AssertExistsAndLoadBean(t, &packages_model.PackageVersion{ID: pv.ID})
+	}
+
+	// running the cleanup again doesn't remove anything
+	removed, err = packages_model.CleanupStaleInternalVersions(db.DefaultContext, time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, removed)
+}
+
+func TestRecentVersionsForOwner(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	const ownerID = 8
+
+	// use timestamps in the future so versions created by other tests are older
+	base := timeutil.TimeStampNow() + 1000
+
+	createPackage := func(name string) *packages_model.Package {
+		p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+			OwnerID:   ownerID,
+			Type:      packages_model.TypeGeneric,
+			Name:      name,
+			LowerName: name,
+		})
+		assert.NoError(t, err)
+		return p
+	}
+	createVersion := func(p *packages_model.Package, version string, isInternal bool, createdUnix timeutil.TimeStamp) *packages_model.PackageVersion {
+		pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			Version:      version,
+			LowerVersion: version,
+			IsInternal:   isInternal,
+		})
+		assert.NoError(t, err)
+		_, err = db.GetEngine(db.DefaultContext).ID(pv.ID).Cols("created_unix").NoAutoTime().Update(&packages_model.PackageVersion{CreatedUnix: createdUnix})
+		assert.NoError(t, err)
+		return pv
+	}
+
+	p1 := createPackage("recent-versions-1")
+	p2 := createPackage("recent-versions-2")
+
+	a1 := createVersion(p1, "1.0.0", false, base+1)
+	b1 := createVersion(p2, "1.0.0", false, base+2)
+	a2 := createVersion(p1, "1.1.0", false, base+3)
+	createVersion(p2, "_internal", true, base+4)
+	b2 := createVersion(p2, "1.1.0", false, base+5)
+
+	// versions of other owners are ignored
+	other, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeGeneric,
+		Name:      "recent-versions-other",
+		LowerName: "recent-versions-other",
+	})
+	assert.NoError(t, err)
+	pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+		PackageID:    other.ID,
+		Version:      "1.0.0",
+		LowerVersion: "1.0.0",
+	})
+	assert.NoError(t, err)
+	_, err = db.GetEngine(db.DefaultContext).ID(pv.ID).Cols("created_unix").NoAutoTime().Update(&packages_model.PackageVersion{CreatedUnix: base + 6})
+	assert.NoError(t, err)
+
+	ids := func(limit int) []int64 {
+		pvs, err := packages_model.RecentVersionsForOwner(db.DefaultContext, ownerID, limit)
+		assert.NoError(t, err)
+		ids := make([]int64, 0, len(pvs))
+		for _, pv := range pvs {
+			ids = append(ids, pv.ID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []int64{b2.ID, a2.ID, b1.ID, a1.ID}, ids(4))
+	assert.Equal(t, []int64{b2.ID, a2.ID}, ids(2))
+}
+
+func TestVersionsMissingFile(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	p, err := packages_model.TryInsertPackage(db.DefaultContext, &packages_model.Package{
+		OwnerID:   2,
+		Type:      packages_model.TypeHelm,
+		Name:      "missing-file-test",
+		LowerName: "missing-file-test",
+	})
+	assert.NoError(t, err)
+
+	insertVersion := func(version string, isInternal bool, fileNames ...string) *packages_model.PackageVersion {
+		pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			Version:      version,
+			LowerVersion: version,
+			IsInternal:   isInternal,
+		})
+		assert.NoError(t, err)
+		for _, fileName := range fileNames {
+			_, err := packages_model.TryInsertFile(db.DefaultContext, &packages_model.PackageFile{
+				VersionID: pv.ID,
+				BlobID:    insertTestBlob(t, "missing-file-"+version+"-"+fileName).ID,
+				Name:      fileName,
+				LowerName: strings.ToLower(fileName),
+			})
+			assert.NoError(t, err)
+		}
+		return pv
+	}
+
+	complete := insertVersion("1.0.0", false, "Chart.yaml", "chart.tgz")
+	missing := insertVersion("1.1.0", false, "chart.tgz")
+	empty := insertVersion("1.2.0", false)
+	internal := insertVersion("1.3.0", true, "chart.tgz")
+
+	pvs, err := packages_model.VersionsMissingFile(db.DefaultContext, packages_model.TypeHelm, "chart.yaml")
+	assert.NoError(t, err)
+
+	ids := make(map[int64]bool)
+	for _, pv := range pvs {
+		ids[pv.ID] = true
+	}
+	assert.False(t, ids[complete.ID])
+	assert.True(t, ids[missing.ID])
+	assert.True(t, ids[empty.ID])
+	assert.False(t, ids[internal.ID])
+
+	// other package types are not checked
+	pvs, err = packages_model.VersionsMissingFile(db.DefaultContext, packages_model.TypeGeneric, "chart.yaml")
+	assert.NoError(t, err)
+	for _, pv := range pvs {
+		assert.NotEqual(t, p.ID, pv.PackageID)
+	}
+}
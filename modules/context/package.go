@@ -20,9 +20,10 @@ import (
 
 // Package contains owner, access mode and optional the package descriptor
 type Package struct {
-	Owner      *user_model.User
-	AccessMode perm.AccessMode
-	Descriptor *packages_model.PackageDescriptor
+	Owner          *user_model.User
+	AccessMode     perm.AccessMode
+	CanReadPrivate bool // the doer can read the private packages of the owner
+	Descriptor     *packages_model.PackageDescriptor
 }
 
 // PackageAssignment returns a middleware to handle Context.Package assignment
@@ -49,55 +50,38 @@ func PackageAssignmentAPI() func(ctx *APIContext) {
 	}
 }
 
+// PackageTypeAssignment returns a middleware which sets the access mode of Context.Package to the access mode for the package type.
+// It is used by the package registries which have no type parameter in their routes.
+func PackageTypeAssignment(packageType packages_model.Type) func(ctx *Context) {
+	return func(ctx *Context) {
+		accessMode, err := DeterminePackageTypeAccessMode(ctx, ctx.Doer, ctx.Package.Owner, packageType)
+		if err != nil {
+			ctx.ServerError("DeterminePackageTypeAccessMode", err)
+			return
+		}
+		ctx.Package.AccessMode = accessMode
+	}
+}
+
 func packageAssignment(ctx *Context, errCb func(int, string, interface{})) {
 	ctx.Package = &Package{
 		Owner: ctx.ContextUser,
 	}
 
-	if ctx.Package.Owner.IsOrganization() {
-		org := organization.OrgFromUser(ctx.Package.Owner)
+	packageType := ctx.Params("type")
 
-		// 1. Get user max authorize level for the org (may be none, if user is not member of the org)
-		if ctx.Doer != nil {
-			var err error
-			ctx.Package.AccessMode, err = org.GetOrgUserMaxAuthorizeLevel(ctx.Doer.ID)
-			if err != nil {
-				errCb(http.StatusInternalServerError, "GetOrgUserMaxAuthorizeLevel", err)
-				return
-			}
-			// If access mode is less than write check every team for more permissions
-			if ctx.Package.AccessMode < perm.AccessModeWrite {
-				teams, err := organization.GetUserOrgTeams(ctx, org.ID, ctx.Doer.ID)
-				if err != nil {
-					errCb(http.StatusInternalServerError, "GetUserOrgTeams", err)
-					return
-				}
-				for _, t := range teams {
-					perm := t.UnitAccessModeCtx(ctx, unit.TypePackages)
-					if ctx.Package.AccessMode < perm {
-						ctx.Package.AccessMode = perm
-					}
-				}
-			}
-		}
-		// 2. If authorize level is none, check if org is visible to user
-		if ctx.Package.AccessMode == perm.AccessModeNone && organization.HasOrgOrUserVisible(ctx, ctx.Package.Owner, ctx.Doer) {
-			ctx.Package.AccessMode = perm.AccessModeRead
-		}
-	} else {
-		if ctx.Doer != nil && !ctx.Doer.IsGhost() {
-			// 1. Check if user is package owner
-			if ctx.Doer.ID == ctx.Package.Owner.ID {
-				ctx.Package.AccessMode = perm.AccessModeOwner
-			} else if ctx.Package.Owner.Visibility == structs.VisibleTypePublic || ctx.Package.Owner.Visibility == structs.VisibleTypeLimited { // 2. Check if package owner is public or limited
-				ctx.Package.AccessMode = perm.AccessModeRead
-			}
-		} else if ctx.Package.Owner.Visibility == structs.VisibleTypePublic { // 3. Check if package owner is public
-			ctx.Package.AccessMode = perm.AccessModeRead
-		}
+	var err error
+	ctx.Package.AccessMode, err = DeterminePackageTypeAccessMode(ctx, ctx.Doer, ctx.Package.Owner, packages_model.Type(packageType))
+	if err != nil {
+		errCb(http.StatusInternalServerError, "DeterminePackageTypeAccessMode", err)
+		return
+	}
+	ctx.Package.CanReadPrivate, err = CanReadPrivatePackages(ctx, ctx.Doer, ctx.Package.Owner)
+	if err != nil {
+		errCb(http.StatusInternalServerError, "CanReadPrivatePackages", err)
+		return
 	}
 
-	packageType := ctx.Params("type")
 	name := ctx.Params("name")
 	version := ctx.Params("version")
 	if packageType != "" && name != "" && version != "" {
@@ -111,14 +95,136 @@ func packageAssignment(ctx *Context, errCb func(int, string, interface{})) {
 			return
 		}
 
-		ctx.Package.Descriptor, err = packages_model.GetPackageDescriptor(ctx, pv)
+		pd, err := packages_model.GetPackageDescriptor(ctx, pv)
 		if err != nil {
 			errCb(http.StatusInternalServerError, "GetPackageDescriptor", err)
 			return
 		}
+		if pd.IsPrivate() && !ctx.Package.CanReadPrivate {
+			errCb(http.StatusNotFound, "GetPackageDescriptor", packages_model.ErrPackageNotExist)
+			return
+		}
+		ctx.Package.Descriptor = pd
+	} else if packageType != "" && name != "" && !ctx.Package.CanReadPrivate {
+		p, err := packages_model.GetPackageByName(ctx, ctx.Package.Owner.ID, packages_model.Type(packageType), name)
+		if err != nil {
+			if err != packages_model.ErrPackageNotExist {
+				errCb(http.StatusInternalServerError, "GetPackageByName", err)
+			}
+			return
+		}
+		private, err := packages_model.IsPackagePrivate(ctx, p)
+		if err != nil {
+			errCb(http.StatusInternalServerError, "IsPackagePrivate", err)
+			return
+		}
+		if private {
+			errCb(http.StatusNotFound, "GetPackageByName", packages_model.ErrPackageNotExist)
+			return
+		}
 	}
 }
 
+// DeterminePackageAccessMode returns the access mode of the doer for the packages of the owner
+func DeterminePackageAccessMode(ctx *Context, doer, owner *user_model.User) (perm.AccessMode, error) {
+	return DeterminePackageTypeAccessMode(ctx, doer, owner, "")
+}
+
+// DeterminePackageTypeAccessMode returns the access mode of the doer for the packages of the given type of the owner.
+// Teams of an organization can have a different access mode for a package type than for the packages unit.
+// An empty package type returns the access mode for all packages of the owner.
+func DeterminePackageTypeAccessMode(ctx *Context, doer, owner *user_model.User, packageType packages_model.Type) (perm.AccessMode, error) {
+	// 1. Get the access mode granted by ownership or membership
+	accessMode, err := determineMemberPackageAccessMode(ctx, doer, owner, packageType)
+	if err != nil || accessMode != perm.AccessModeNone {
+		return accessMode, err
+	}
+
+	if owner.IsOrganization() {
+		// 2. If authorize level is none, check if org is visible to user
+		if organization.HasOrgOrUserVisible(ctx, owner, doer) {
+			accessMode = perm.AccessModeRead
+		}
+	} else {
+		if doer != nil && !doer.IsGhost() {
+			// 2. Check if package owner is public or limited
+			if owner.Visibility == structs.VisibleTypePublic || owner.Visibility == structs.VisibleTypeLimited {
+				accessMode = perm.AccessModeRead
+			}
+		} else if owner.Visibility == structs.VisibleTypePublic { // 3. Check if package owner is public
+			accessMode = perm.AccessModeRead
+		}
+	}
+
+	return accessMode, nil
+}
+
+// CanReadPrivatePackages checks if the doer can read the private packages of the owner.
+// This requires read access by ownership or membership, the visibility of the owner is not taken into account.
+func CanReadPrivatePackages(ctx *Context, doer, owner *user_model.User) (bool, error) {
+	if doer != nil && doer.IsAdmin {
+		return true, nil
+	}
+
+	accessMode, err := determineMemberPackageAccessMode(ctx, doer, owner, "")
+	if err != nil {
+		return false, err
+	}
+	return accessMode >= perm.AccessModeRead, nil
+}
+
+// determineMemberPackageAccessMode returns the access mode of the doer for the packages of the owner which is granted by ownership or membership
+func determineMemberPackageAccessMode(ctx *Context, doer, owner *user_model.User, packageType packages_model.Type) (perm.AccessMode, error) {
+	accessMode := perm.AccessModeNone
+
+	if doer == nil || doer.IsGhost() {
+		return accessMode, nil
+	}
+
+	if owner.IsOrganization() && packageType != "" {
+		// The access mode of a team for the package type replaces its access mode for the packages unit
+		teams, err := organization.GetUserOrgTeams(ctx, owner.ID, doer.ID)
+		if err != nil {
+			return accessMode, err
+		}
+		for _, t := range teams {
+			perm, err := t.PackageTypeAccessMode(ctx, string(packageType))
+			if err != nil {
+				return accessMode, err
+			}
+			if accessMode < perm {
+				accessMode = perm
+			}
+		}
+	} else if owner.IsOrganization() {
+		org := organization.OrgFromUser(owner)
+
+		// Get user max authorize level for the org (may be none, if user is not member of the org)
+		var err error
+		accessMode, err = org.GetOrgUserMaxAuthorizeLevel(doer.ID)
+		if err != nil {
+			return accessMode, err
+		}
+		// If access mode is less than write check every team for more permissions
+		if accessMode < perm.AccessModeWrite {
+			teams, err := organization.GetUserOrgTeams(ctx, org.ID, doer.ID)
+			if err != nil {
+				return accessMode, err
+			}
+			for _, t := range teams {
+				perm := t.UnitAccessModeCtx(ctx, unit.TypePackages)
+				if accessMode < perm {
+					accessMode = perm
+				}
+			}
+		}
+	} else if doer.ID == owner.ID { // Check if user is package owner
+		accessMode = perm.AccessModeOwner
+	}
+
+	return accessMode, nil
+}
+
 // PackageContexter initializes a package context for a request.
 func PackageContexter(ctx gocontext.Context) func(next http.Handler) http.Handler {
 	_, rnd := templates.HTMLRenderer(ctx)
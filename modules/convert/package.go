@@ -6,6 +6,7 @@ package convert
 
 import (
 	"context"
+	"time"
 
 	"code.gitea.io/gitea/models/packages"
 	access_model "code.gitea.io/gitea/models/perm/access"
@@ -27,27 +28,80 @@ func ToPackage(ctx context.Context, pd *packages.PackageDescriptor, doer *user_m
 		}
 	}
 
+	var lastDownload *time.Time
+	if pd.Version.LastDownloadUnix != 0 {
+		t := pd.Version.LastDownloadUnix.AsTime()
+		lastDownload = &t
+	}
+
 	return &api.Package{
-		ID:         pd.Version.ID,
-		Owner:      ToUser(pd.Owner, doer),
-		Repository: repo,
-		Creator:    ToUser(pd.Creator, doer),
-		Type:       string(pd.Package.Type),
-		Name:       pd.Package.Name,
-		Version:    pd.Version.Version,
-		CreatedAt:  pd.Version.CreatedUnix.AsTime(),
+		ID:             pd.Version.ID,
+		Owner:          ToUser(pd.Owner, doer),
+		Repository:     repo,
+		Creator:        ToUser(pd.Creator, doer),
+		PackageCreator: ToUser(pd.PackageCreator, doer),
+		Type:           string(pd.Package.Type),
+		Name:           pd.Package.Name,
+		Version:        pd.Version.Version,
+		CreatedAt:      pd.Version.CreatedUnix.AsTime(),
+		LastDownloadAt: lastDownload,
+		Visibility:     pd.Package.Visibility.String(),
+		Private:        pd.IsPrivate(),
+		ReleaseNotes:   pd.Version.ReleaseNotes,
 	}, nil
 }
 
 // ToPackageFile converts packages.PackageFileDescriptor to api.PackageFile
 func ToPackageFile(pfd *packages.PackageFileDescriptor) *api.PackageFile {
 	return &api.PackageFile{
-		ID:         pfd.File.ID,
-		Size:       pfd.Blob.Size,
-		Name:       pfd.File.Name,
-		HashMD5:    pfd.Blob.HashMD5,
-		HashSHA1:   pfd.Blob.HashSHA1,
-		HashSHA256: pfd.Blob.HashSHA256,
-		HashSHA512: pfd.Blob.HashSHA512,
+		ID:            pfd.File.ID,
+		Size:          pfd.Blob.Size,
+		Name:          pfd.File.Name,
+		HashMD5:       pfd.Blob.HashMD5,
+		HashSHA1:      pfd.Blob.HashSHA1,
+		HashSHA256:    pfd.Blob.HashSHA256,
+		HashSHA512:    pfd.Blob.HashSHA512,
+		DownloadCount: pfd.File.DownloadCount,
+	}
+}
+
+// ToPackageSizeSummary converts packages.PackageSizeSummary to api.PackageSizeSummary
+func ToPackageSizeSummary(s *packages.PackageSizeSummary) *api.PackageSizeSummary {
+	return &api.PackageSizeSummary{
+		Type:         string(s.Type),
+		LogicalSize:  s.LogicalSize,
+		PhysicalSize: s.PhysicalSize,
+		SavedSize:    s.SavedSize(),
+		UpdatedAt:    s.UpdatedUnix.AsTime(),
+	}
+}
+
+// ToPackageInventoryEntry converts packages.InventoryEntry to api.PackageInventoryEntry
+func ToPackageInventoryEntry(e *packages.InventoryEntry) *api.PackageInventoryEntry {
+	return &api.PackageInventoryEntry{
+		ID:              e.ID,
+		OwnerID:         e.OwnerID,
+		Type:            string(e.Type),
+		Name:            e.Name,
+		RepoID:          e.RepoID,
+		TotalSize:       e.TotalSize,
+		LastPublishedAt: e.LastPublishUnix.AsTime(),
+	}
+}
+
+// ToPackageAudit converts packages.PackageAudit to api.PackageAudit
+func ToPackageAudit(pa *packages.PackageAudit) *api.PackageAudit {
+	return &api.PackageAudit{
+		ID:          pa.ID,
+		OwnerID:     pa.OwnerID,
+		ActorID:     pa.ActorID,
+		Action:      string(pa.Action),
+		PackageID:   pa.PackageID,
+		PackageType: string(pa.PackageType),
+		PackageName: pa.PackageName,
+		VersionID:   pa.VersionID,
+		Version:     pa.Version,
+		Details:     pa.Details,
+		CreatedAt:   pa.CreatedUnix.AsTime(),
 	}
 }
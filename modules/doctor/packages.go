@@ -0,0 +1,156 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package doctor
+
+import (
+	"context"
+
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/storage"
+	packages_service "code.gitea.io/gitea/services/packages"
+)
+
+func rebuildPackageKeywords(ctx context.Context, logger log.Logger, autofix bool) error {
+	if !autofix {
+		logger.Info("Run with --fix to extract the keywords of all package versions again")
+		return nil
+	}
+
+	count, err := packages_service.RebuildKeywords(ctx)
+	if err != nil {
+		logger.Critical("Error: %v whilst extracting package keywords", err)
+		return err
+	}
+	logger.Info("Keywords of %d package versions extracted", count)
+	return nil
+}
+
+func checkPackageBlobs(ctx context.Context, logger log.Logger, autofix bool) error {
+	var total, missing, corrupt int
+	if err := db.IterateObjects(ctx, func(pb *packages_model.PackageBlob) error {
+		total++
+		switch err := packages_service.VerifyBlob(storage.Packages, pb, true); err {
+		case nil:
+		case packages_service.ErrBlobMissing:
+			missing++
+			logger.Warn("Package blob %d (%s) is missing in the storage", pb.ID, pb.HashSHA256)
+		case packages_service.ErrBlobCorrupt:
+			corrupt++
+			logger.Warn("Package blob %d (%s) does not match its size or hash", pb.ID, pb.HashSHA256)
+		default:
+			return err
+		}
+		return nil
+	}); err != nil {
+		logger.Critical("Error: %v whilst checking package blobs", err)
+		return err
+	}
+
+	if missing > 0 || corrupt > 0 {
+		logger.Warn("Checked %d package blobs, %d missing and %d corrupt. Use `gitea restore-packages` to restore them from a dump.", total, missing, corrupt)
+	} else {
+		logger.Info("Checked %d package blobs", total)
+	}
+	return nil
+}
+
+func checkPackageBlobReferenceCounts(ctx context.Context, logger log.Logger, autofix bool) error {
+	count, err := packages_model.CountBlobsWithWrongReferenceCount(ctx)
+	if err != nil {
+		logger.Critical("Error: %v whilst counting package blobs with wrong reference counts", err)
+		return err
+	}
+	if count == 0 {
+		logger.Info("All package blob reference counts are correct")
+		return nil
+	}
+
+	if !autofix {
+		logger.Warn("%d package blobs have a wrong reference count", count)
+		return nil
+	}
+
+	fixed, err := packages_model.FixBlobReferenceCounts(ctx)
+	if err != nil {
+		logger.Critical("Error: %v whilst fixing package blob reference counts", err)
+		return err
+	}
+	logger.Info("%d package blob reference counts fixed", fixed)
+	return nil
+}
+
+func renormalizePackageNames(ctx context.Context, logger log.Logger, autofix bool) error {
+	if !autofix {
+		logger.Info("Run with --fix to normalize the names and versions of all packages again")
+		return nil
+	}
+
+	updated, err := packages_model.RenormalizePackageNames(ctx)
+	if err != nil {
+		logger.Critical("Error: %v whilst normalizing package names", err)
+		return err
+	}
+	logger.Info("Names and versions of %d packages and package versions normalized", updated)
+	return nil
+}
+
+func checkPackageMetadata(ctx context.Context, logger log.Logger, autofix bool) error {
+	var invalid int
+	if err := packages_model.IterateVersionsWithInvalidMetadata(ctx, func(p *packages_model.Package, pv *packages_model.PackageVersion, err error) error {
+		invalid++
+		logger.Warn("Metadata of package %s version %s (%d) is invalid: %v", p.Name, pv.Version, pv.ID, err)
+		return nil
+	}); err != nil {
+		logger.Critical("Error: %v whilst checking package metadata", err)
+		return err
+	}
+
+	if invalid > 0 {
+		logger.Warn("%d package versions have invalid metadata. Delete and publish them again to fix it.", invalid)
+	} else {
+		logger.Info("The metadata of all package versions is valid")
+	}
+	return nil
+}
+
+func init() {
+	Register(&Check{
+		Title:     "Extract the keywords of package versions again",
+		Name:      "rebuild-package-keywords",
+		IsDefault: false,
+		Run:       rebuildPackageKeywords,
+		Priority:  8,
+	})
+	Register(&Check{
+		Title:     "Check that the content of all package blobs is present and intact",
+		Name:      "check-package-blobs",
+		IsDefault: false,
+		Run:       checkPackageBlobs,
+		Priority:  8,
+	})
+	Register(&Check{
+		Title:     "Check that the reference counts of package blobs match their files",
+		Name:      "check-package-blob-reference-counts",
+		IsDefault: false,
+		Run:       checkPackageBlobReferenceCounts,
+		Priority:  8,
+	})
+	Register(&Check{
+		Title:     "Normalize the names and versions of packages again",
+		Name:      "renormalize-package-names",
+		IsDefault: false,
+		Run:       renormalizePackageNames,
+		Priority:  8,
+	})
+	Register(&Check{
+		Title:     "Check that the metadata of package versions matches their package type",
+		Name:      "check-package-metadata",
+		IsDefault: false,
+		Run:       checkPackageMetadata,
+		Priority:  8,
+	})
+}
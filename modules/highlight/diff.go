@@ -0,0 +1,226 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package highlight
+
+import (
+	"errors"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/lexers"
+)
+
+var errLineCountMismatch = errors.New("highlighted line count does not match the hunk")
+
+var hunkHeaderRegex = regexp.MustCompile(`^@@ -\d+(?:,(\d+))? \+\d+(?:,(\d+))? @@`)
+
+// diffMarker returns the HTML of the marker of a hunk line, using the classes of the chroma diff lexer
+func diffMarker(marker byte) string {
+	switch marker {
+	case '+':
+		return `<span class="` + classPrefix + `gi">+</span>`
+	case '-':
+		return `<span class="` + classPrefix + `gd">-</span>`
+	default:
+		return " "
+	}
+}
+
+// diffSegment is a run of consecutive diff lines which are highlighted together.
+// If lexer is nil, the lines are highlighted as diff.
+type diffSegment struct {
+	lexer chroma.Lexer
+	lines []string
+}
+
+// HighlightDiff returns a slice of chroma syntax highlighted HTML lines of a unified diff.
+// The content of the hunks is highlighted in the language of the changed file which is detected from the file header.
+// The diff markers of the hunk lines are preserved. If the language can't be detected, the diff is highlighted as plain diff.
+func HighlightDiff(code string) ([]string, error) {
+	NewContext()
+
+	if len(code) > sizeLimit {
+		return PlainText([]byte(code)), nil
+	}
+
+	var segments []*diffSegment
+	addLine := func(lexer chroma.Lexer, line string) {
+		if len(segments) == 0 || segments[len(segments)-1].lexer != lexer {
+			segments = append(segments, &diffSegment{lexer: lexer})
+		}
+		segment := segments[len(segments)-1]
+		segment.lines = append(segment.lines, line)
+	}
+
+	var oldName string
+	var lexer chroma.Lexer
+	var oldRemaining, newRemaining int
+	for _, line := range strings.SplitAfter(code, "\n") {
+		if line == "" {
+			continue
+		}
+
+		if oldRemaining > 0 || newRemaining > 0 {
+			switch line[0] {
+			case '+':
+				newRemaining--
+			case '-':
+				oldRemaining--
+			case ' ', '\r', '\n':
+				// a blank context line may have lost its marker
+				oldRemaining--
+				newRemaining--
+			case '\\':
+				// "\ No newline at end of file"
+				addLine(nil, line)
+				continue
+			default:
+				// malformed hunk
+				oldRemaining, newRemaining = 0, 0
+				addLine(nil, line)
+				continue
+			}
+			addLine(lexer, line)
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "--- "):
+			oldName = diffFileName(line[4:], "a/")
+		case strings.HasPrefix(line, "+++ "):
+			name := diffFileName(line[4:], "b/")
+			if name == "/dev/null" {
+				name = oldName
+			}
+			lexer = diffLexer(name)
+		case strings.HasPrefix(line, "@@ "):
+			if m := hunkHeaderRegex.FindStringSubmatch(line); m != nil {
+				oldRemaining, newRemaining = hunkLineCount(m[1]), hunkLineCount(m[2])
+			}
+		}
+		addLine(nil, line)
+	}
+
+	fallback := lexers.Get("diff")
+
+	result := make([]string, 0, len(code)/40)
+	for _, segment := range segments {
+		var lines []string
+		var err error
+		if segment.lexer != nil {
+			lines, err = highlightHunkLines(segment.lexer, segment.lines)
+		}
+		if segment.lexer == nil || err != nil {
+			lines, err = linesFromLexer(fallback, strings.Join(segment.lines, ""))
+			if err != nil {
+				return nil, err
+			}
+		}
+		result = append(result, lines...)
+	}
+	return result, nil
+}
+
+// diffFileName extracts the file name from a "---" or "+++" file header
+func diffFileName(header, prefix string) string {
+	header = strings.TrimRight(header, "\r\n")
+	if i := strings.IndexByte(header, '\t'); i != -1 {
+		header = header[:i]
+	}
+	if unquoted, err := strconv.Unquote(header); err == nil {
+		header = unquoted
+	}
+	return strings.TrimPrefix(header, prefix)
+}
+
+func hunkLineCount(s string) int {
+	if s == "" {
+		return 1
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// diffLexer returns the lexer for the file name or nil if the language can't be detected
+func diffLexer(fileName string) chroma.Lexer {
+	if fileName == "" || fileName == "/dev/null" {
+		return nil
+	}
+	if val, ok := highlightMapping[filepath.Ext(fileName)]; ok {
+		if lexer := lexers.Get(val); lexer != nil {
+			return lexer
+		}
+	}
+	return lexers.Match(path.Base(fileName))
+}
+
+// hunkLineContent returns the content of a hunk line without its marker.
+// Tools which strip trailing whitespace turn a blank context line into an empty line without marker.
+func hunkLineContent(line string) string {
+	if line[0] == '\r' || line[0] == '\n' {
+		return line
+	}
+	return line[1:]
+}
+
+// highlightHunkLines highlights the content of hunk lines without their markers.
+// The old and the new side of the hunk are highlighted separately, so constructs spanning multiple lines are highlighted correctly.
+func highlightHunkLines(lexer chroma.Lexer, lines []string) ([]string, error) {
+	var oldCode, newCode strings.Builder
+	for _, line := range lines {
+		content := hunkLineContent(line)
+		switch line[0] {
+		case '-':
+			oldCode.WriteString(content)
+		case '+':
+			newCode.WriteString(content)
+		default:
+			oldCode.WriteString(content)
+			newCode.WriteString(content)
+		}
+	}
+
+	oldLines, err := linesFromLexer(lexer, oldCode.String())
+	if err != nil {
+		return nil, err
+	}
+	newLines, err := linesFromLexer(lexer, newCode.String())
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]string, 0, len(lines))
+	var oldIndex, newIndex int
+	for _, line := range lines {
+		var highlighted string
+		switch line[0] {
+		case '-':
+			if oldIndex >= len(oldLines) {
+				return nil, errLineCountMismatch
+			}
+			highlighted = oldLines[oldIndex]
+			oldIndex++
+		case '+':
+			if newIndex >= len(newLines) {
+				return nil, errLineCountMismatch
+			}
+			highlighted = newLines[newIndex]
+			newIndex++
+		default:
+			if oldIndex >= len(oldLines) || newIndex >= len(newLines) {
+				return nil, errLineCountMismatch
+			}
+			highlighted = newLines[newIndex]
+			oldIndex++
+			newIndex++
+		}
+		result = append(result, diffMarker(line[0])+highlighted)
+	}
+	return result, nil
+}
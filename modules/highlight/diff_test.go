@@ -0,0 +1,92 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package highlight
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHighlightDiff(t *testing.T) {
+	code := `diff --git a/main.go b/main.go
+index 1234567..89abcde 100644
+--- a/main.go
++++ b/main.go
+@@ -1,4 +1,5 @@
+ package main
+ 
+-var s = "old"
++var s = ` + "`new\n+multi`" + `
+ func main() {}
+`
+
+	out, err := HighlightDiff(code)
+	assert.NoError(t, err)
+	assert.Len(t, out, 11)
+
+	// file headers are highlighted as diff
+	assert.Contains(t, out[0], `diff --git a/main.go b/main.go`)
+	assert.Contains(t, out[3], `<span class="gi">+++ b/main.go`)
+	assert.Contains(t, out[4], `<span class="gu">@@ -1,4 +1,5 @@`)
+
+	// hunk content is highlighted as Go with preserved markers
+	assert.Equal(t, ` <span class="kn">package</span> <span class="nx">main</span>`+"\n", out[5])
+	assert.Equal(t, ` `+"\n", out[6])
+	assert.True(t, strings.HasPrefix(out[7], `<span class="gd">-</span><span class="kd">var</span>`), out[7])
+	assert.Contains(t, out[7], `<span class="s">&#34;old&#34;</span>`)
+	assert.True(t, strings.HasPrefix(out[8], `<span class="gi">+</span><span class="kd">var</span>`), out[8])
+	// the raw string spanning multiple lines is highlighted as string on both lines
+	assert.Contains(t, out[8], `<span class="s">new`+"\n</span>")
+	assert.True(t, strings.HasPrefix(out[9], `<span class="gi">+</span><span class="s">multi</span>`), out[9])
+	assert.Equal(t, ` <span class="kd">func</span> <span class="nf">main</span><span class="p">(</span><span class="p">)</span> <span class="p">{</span><span class="p">}</span>`+"\n", out[10])
+}
+
+func TestHighlightDiffBlankContextLine(t *testing.T) {
+	// the blank context line has lost its " " marker
+	code := "--- a/main.go\n" +
+		"+++ b/main.go\n" +
+		"@@ -1,4 +1,4 @@\n" +
+		" package main\n" +
+		"\n" +
+		"-var a = 1\n" +
+		"+var a = 2\n" +
+		" func main() {}\n"
+
+	out, err := HighlightDiff(code)
+	assert.NoError(t, err)
+	assert.Len(t, out, 8)
+
+	assert.Equal(t, ` <span class="kn">package</span> <span class="nx">main</span>`+"\n", out[3])
+	assert.Equal(t, ` `+"\n", out[4])
+	assert.True(t, strings.HasPrefix(out[5], `<span class="gd">-</span><span class="kd">var</span>`), out[5])
+	assert.True(t, strings.HasPrefix(out[6], `<span class="gi">+</span><span class="kd">var</span>`), out[6])
+	// the line after the blank line still belongs to the hunk
+	assert.True(t, strings.HasPrefix(out[7], ` <span class="kd">func</span>`), out[7])
+
+	t.Run("CRLF", func(t *testing.T) {
+		out, err := HighlightDiff(strings.ReplaceAll(code, "\n", "\r\n"))
+		assert.NoError(t, err)
+		assert.Len(t, out, 8)
+		assert.True(t, strings.HasPrefix(out[5], `<span class="gd">-</span><span class="kd">var</span>`), out[5])
+		assert.True(t, strings.HasPrefix(out[7], ` <span class="kd">func</span>`), out[7])
+	})
+}
+
+func TestHighlightDiffFallback(t *testing.T) {
+	code := `--- a/unknown.zzz-no-lexer
++++ b/unknown.zzz-no-lexer
+@@ -1 +1 @@
+-old
++new
+`
+
+	out, err := HighlightDiff(code)
+	assert.NoError(t, err)
+	assert.Len(t, out, 5)
+	assert.Contains(t, out[3], `<span class="gd">-old`)
+	assert.Contains(t, out[4], `<span class="gi">+new`)
+}
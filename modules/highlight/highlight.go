@@ -8,10 +8,15 @@ package highlight
 import (
 	"bufio"
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	gohtml "html"
 	"io"
+	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
 
@@ -20,33 +25,132 @@ import (
 	"code.gitea.io/gitea/modules/setting"
 
 	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/formatters"
 	"github.com/alecthomas/chroma/formatters/html"
 	"github.com/alecthomas/chroma/lexers"
 	"github.com/alecthomas/chroma/styles"
 	lru "github.com/hashicorp/golang-lru"
+	"github.com/microcosm-cc/bluemonday"
 )
 
 // don't index files larger than this many bytes for performance purposes
 const sizeLimit = 1024 * 1024
 
+// SectionHeaderClass is the additional class of the section header tokens of INI and TOML files, see FileOptions.MarkSections
+const SectionHeaderClass = "section-header"
+
+// sectionStart and sectionEnd enclose the value of a section header token until the formatted HTML is post-processed.
+// They are private use characters, so the chroma class mapping which is shared by all callers needn't be changed.
+const (
+	sectionStart = '\uE000'
+	sectionEnd   = '\uE001'
+)
+
+var (
+	sectionTokenPattern = regexp.MustCompile(`<span class="([^"]*)">\x{E000}([^\x{E000}\x{E001}]*)\x{E001}</span>`)
+	sectionTextPattern  = regexp.MustCompile(`\x{E000}([^\x{E000}\x{E001}]*)\x{E001}`)
+)
+
 var (
+	// Built-in mapping for extensions which are not detected reliably, custom user mapping takes precedence
+	defaultHighlightMapping = map[string]string{
+		".ASM":          "nasm",
+		".R":            "r",
+		".Rmd":          "markdown", // chroma has no R Markdown lexer
+		".Rprofile":     "r",
+		".S":            "gas",
+		".asm":          "nasm",
+		".dockerignore": "bash",
+		".gql":          "graphql",
+		".graphql":      "graphql",
+		".graphqls":     "graphql",
+		".hcl":          "hcl",
+		".jl":           "julia",
+		".nix":          "nix",
+		".proto":        "protobuf",
+		".r":            "r",
+		".rmd":          "markdown",
+		".s":            "gas",
+		".sol":          "solidity",
+		".toml":         "toml",
+		".v":            "verilog",
+		".vhd":          "vhdl",
+		".vhdl":         "vhdl",
+		".zig":          "zig",
+	}
+
 	// For custom user mapping
 	highlightMapping = map[string]string{}
 
+	// classPrefix is prepended to all CSS classes generated by chroma
+	classPrefix string
+
 	once sync.Once
 
 	cache *lru.TwoQueueCache
+
+	// outputCache holds the HTML generated by Code, it is nil if the output cache is disabled
+	outputCache *lru.TwoQueueCache
+
+	// maxAverageLineLength is the number of bytes per line above which code is not highlighted, 0 disables the limit
+	maxAverageLineLength int
+
+	// sanitizer is the allowlist of CodeSanitized
+	sanitizer     *bluemonday.Policy
+	sanitizerOnce sync.Once
+
+	// tabWidth is the tab width passed to the chroma HTML formatter, 8 is the default of chroma
+	tabWidth = 8
+
+	// getCodeLanguage detects the language of a file by its content, it is replaced in tests
+	getCodeLanguage = analyze.GetCodeLanguage
 )
 
+// outputCacheKey identifies an entry of the output cache. The content hash is part of the key, so all entries of a content can be invalidated.
+type outputCacheKey struct {
+	contentHash string
+	fileName    string
+	language    string
+}
+
 // NewContext loads custom highlight map from local config
 func NewContext() {
 	once.Do(func() {
+		applied := make([]string, 0, len(defaultHighlightMapping))
+		for ext, language := range defaultHighlightMapping {
+			// languages unknown to the bundled chroma version are left to the lexer matching by file name
+			if lexers.Get(language) == nil {
+				continue
+			}
+			highlightMapping[ext] = language
+			applied = append(applied, ext)
+		}
 		if setting.Cfg != nil {
 			keys := setting.Cfg.Section("highlight.mapping").Keys()
 			for i := range keys {
 				highlightMapping[keys[i].Name()] = keys[i].Value()
 			}
+			classPrefix = setting.Cfg.Section("highlight").Key("CLASS_PREFIX").MustString("")
+			if size := setting.Cfg.Section("highlight").Key("OUTPUT_CACHE_SIZE").MustInt(0); size > 0 {
+				c, err := lru.New2Q(size)
+				if err != nil {
+					panic(fmt.Sprintf("failed to initialize output cache for highlighter: %s", err))
+				}
+				outputCache = c
+			}
+			maxAverageLineLength = setting.Cfg.Section("highlight").Key("MAX_AVERAGE_LINE_LENGTH").MustInt(0)
+			tabWidth = setting.Cfg.Section("highlight").Key("TAB_WIDTH").MustInt(8)
 		}
+		sort.Strings(applied)
+		defaults := make([]string, 0, len(applied))
+		for _, ext := range applied {
+			// skip the defaults which are overridden by a custom mapping
+			if language := highlightMapping[ext]; language == defaultHighlightMapping[ext] {
+				defaults = append(defaults, ext+"="+language)
+			}
+		}
+		log.Debug("Applied default highlight mappings: %s", strings.Join(defaults, ", "))
+
 		// The size 512 is simply a conservative rule of thumb
 		c, err := lru.New2Q(512)
 		if err != nil {
@@ -56,8 +160,49 @@ func NewContext() {
 	})
 }
 
+// exceedsAverageLineLength checks if the code has so few lines for its size that it is likely minified.
+// Highlighting such code is slow and the result is unreadable anyway.
+func exceedsAverageLineLength(size, newlines int) bool {
+	if maxAverageLineLength <= 0 {
+		return false
+	}
+	return size/(newlines+1) > maxAverageLineLength
+}
+
+// ContentHash returns the hash of code which identifies its entries in the output cache
+func ContentHash(code string) string {
+	hash := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(hash[:])
+}
+
+// InvalidateHighlightCache removes all entries of the content with the hash from the output cache,
+// so the content gets highlighted again, e.g. after the style or a lexer mapping was changed.
+// It is a no-op if the output cache is disabled.
+func InvalidateHighlightCache(contentHash string) {
+	NewContext()
+
+	if outputCache == nil {
+		return
+	}
+	for _, key := range outputCache.Keys() {
+		if k, ok := key.(outputCacheKey); ok && k.contentHash == contentHash {
+			outputCache.Remove(key)
+		}
+	}
+}
+
 // Code returns a HTML version of code string with chroma syntax highlighting classes
 func Code(fileName, language, code string) string {
+	return highlightCode(fileName, language, code, true)
+}
+
+// CodeUncached is like Code but neither reads nor fills the lexer and the output cache.
+// It is meant for bulk jobs which highlight every file once, so they don't evict the cache entries used by the web UI.
+func CodeUncached(fileName, language, code string) string {
+	return highlightCode(fileName, language, code, false)
+}
+
+func highlightCode(fileName, language, code string, useCache bool) string {
 	NewContext()
 
 	// diff view newline will be passed as empty, change to literal '\n' so it can be copied
@@ -66,10 +211,75 @@ func Code(fileName, language, code string) string {
 		return "\n"
 	}
 
+	if len(code) > sizeLimit || exceedsAverageLineLength(len(code), strings.Count(code, "\n")) {
+		return code
+	}
+
+	if !useCache || outputCache == nil {
+		return CodeFromLexer(codeLexer(fileName, language, useCache), code)
+	}
+
+	key := outputCacheKey{contentHash: ContentHash(code), fileName: fileName, language: language}
+	if output, ok := outputCache.Get(key); ok {
+		return output.(string)
+	}
+	output := CodeFromLexer(codeLexer(fileName, language, true), code)
+	outputCache.Add(key, output)
+	return output
+}
+
+// CodeSanitized is like Code but passes the output through a strict allowlist which only keeps span elements with a class attribute.
+// It is meant for untrusted contexts like notification mails, so they don't depend on the HTML generated by chroma being safe.
+func CodeSanitized(fileName, language, code string) string {
+	return sanitizeCode(Code(fileName, language, code))
+}
+
+func sanitizeCode(output string) string {
+	sanitizerOnce.Do(func() {
+		sanitizer = bluemonday.NewPolicy()
+		sanitizer.AllowAttrs("class").Matching(regexp.MustCompile(`^[\w\- ]+$`)).OnElements("span")
+	})
+	return sanitizer.Sanitize(output)
+}
+
+// CodeANSI returns code with ANSI escape sequences for syntax highlighting in a 256-colour terminal.
+// The lexer is resolved the same way as in Code. Code larger than the highlight size limit is returned as is.
+func CodeANSI(fileName, language, code string) string {
+	NewContext()
+
 	if len(code) > sizeLimit {
 		return code
 	}
 
+	iterator, err := codeLexer(fileName, language, true).Tokenise(nil, code)
+	if err != nil {
+		log.Error("Can't tokenize code: %v", err)
+		return code
+	}
+
+	buf := strings.Builder{}
+	if err := formatters.TTY256.Format(&buf, styles.Monokai, iterator); err != nil {
+		log.Error("Can't format code: %v", err)
+		return code
+	}
+	return buf.String()
+}
+
+// Tokenize returns the chroma token stream of code. The lexer is resolved the same way as in Code.
+// Code larger than the highlight size limit is tokenized as plain text.
+func Tokenize(fileName, language, code string) (chroma.Iterator, error) {
+	NewContext()
+
+	lexer := lexers.Fallback
+	if len(code) <= sizeLimit {
+		lexer = codeLexer(fileName, language, true)
+	}
+	return lexer.Tokenise(nil, code)
+}
+
+// codeLexer returns the lexer used by Code for the language or the file name.
+// Lexers matched by the file name are cached if useCache is set.
+func codeLexer(fileName, language string, useCache bool) chroma.Lexer {
 	var lexer chroma.Lexer
 
 	if len(language) > 0 {
@@ -90,7 +300,7 @@ func Code(fileName, language, code string) string {
 		}
 	}
 
-	if lexer == nil {
+	if lexer == nil && useCache {
 		if l, ok := cache.Get(fileName); ok {
 			lexer = l.(chroma.Lexer)
 		}
@@ -101,17 +311,33 @@ func Code(fileName, language, code string) string {
 		if lexer == nil {
 			lexer = lexers.Fallback
 		}
-		cache.Add(fileName, lexer)
+		if useCache {
+			cache.Add(fileName, lexer)
+		}
 	}
-	return CodeFromLexer(lexer, code)
+	return lexer
 }
 
-// CodeFromLexer returns a HTML version of code string with chroma syntax highlighting classes
-func CodeFromLexer(lexer chroma.Lexer, code string) string {
-	formatter := html.New(html.WithClasses(true),
+func newFormatter() *html.Formatter {
+	return html.New(html.WithClasses(true),
+		html.ClassPrefix(classPrefix),
 		html.WithLineNumbers(false),
 		html.PreventSurroundingPre(true),
+		html.TabWidth(tabWidth),
 	)
+}
+
+// CodeFromLexer returns a HTML version of code string with chroma syntax highlighting classes.
+// If chroma panics, the escaped code is returned without highlighting.
+func CodeFromLexer(lexer chroma.Lexer, code string) (output string) {
+	defer func() {
+		if err := recover(); err != nil {
+			log.Error("PANIC whilst highlighting code: %v\nStacktrace: %s", err, log.Stack(2))
+			output = gohtml.EscapeString(code)
+		}
+	}()
+
+	formatter := newFormatter()
 
 	htmlbuf := bytes.Buffer{}
 	htmlw := bufio.NewWriter(&htmlbuf)
@@ -134,22 +360,36 @@ func CodeFromLexer(lexer chroma.Lexer, code string) string {
 	return strings.TrimSuffix(htmlbuf.String(), "\n")
 }
 
+// FileOptions are the options for FileWithOptions
+type FileOptions struct {
+	// LineNumbers wraps every line into a span carrying its 1-based line number as data-line-number attribute
+	LineNumbers bool
+	// MarkSections adds SectionHeaderClass to the tokens of section headers like [section] in INI and TOML files
+	MarkSections bool
+	// PreserveIndentation encodes the leading whitespace of every line as &nbsp; runs, so the indentation
+	// is not collapsed and gets announced consistently by assistive technology. A tab counts as indentTabWidth spaces.
+	PreserveIndentation bool
+	// SkipContentAnalysis resolves the lexer by the language, the highlight mapping and the file name only.
+	// The content analysis is slow for large files, so latency sensitive callers can skip it.
+	SkipContentAnalysis bool
+}
+
+// indentTabWidth is the number of &nbsp; a leading tab is encoded as, see FileOptions.PreserveIndentation
+const indentTabWidth = 4
+
 // File returns a slice of chroma syntax highlighted HTML lines of code
 func File(fileName, language string, code []byte) ([]string, error) {
+	return FileWithOptions(fileName, language, code, FileOptions{})
+}
+
+// FileWithOptions returns a slice of chroma syntax highlighted HTML lines of code using the given options
+func FileWithOptions(fileName, language string, code []byte, opts FileOptions) ([]string, error) {
 	NewContext()
 
-	if len(code) > sizeLimit {
-		return PlainText(code), nil
+	if len(code) > sizeLimit || exceedsAverageLineLength(len(code), bytes.Count(code, []byte{'\n'})) {
+		return opts.apply(PlainText(code)), nil
 	}
 
-	formatter := html.New(html.WithClasses(true),
-		html.WithLineNumbers(false),
-		html.PreventSurroundingPre(true),
-	)
-
-	htmlBuf := bytes.Buffer{}
-	htmlWriter := bufio.NewWriter(&htmlBuf)
-
 	var lexer chroma.Lexer
 
 	// provided language overrides everything
@@ -163,24 +403,153 @@ func File(fileName, language string, code []byte) ([]string, error) {
 		}
 	}
 
-	if lexer == nil {
-		guessLanguage := analyze.GetCodeLanguage(fileName, code)
+	if lexer == nil && !opts.SkipContentAnalysis {
+		lexer = lexers.Get(getCodeLanguage(fileName, code))
+	}
 
-		lexer = lexers.Get(guessLanguage)
+	if lexer == nil {
+		lexer = lexers.Match(fileName)
 		if lexer == nil {
-			lexer = lexers.Match(fileName)
-			if lexer == nil {
-				lexer = lexers.Fallback
-			}
+			lexer = lexers.Fallback
+		}
+	}
+
+	lines, err := fileLines(lexer, code, opts.MarkSections)
+	if err != nil {
+		return nil, err
+	}
+	return opts.apply(lines), nil
+}
+
+// FileLineMap returns the chroma syntax highlighted HTML of the requested 1-based lines of code, e.g. for rendering only the visible lines.
+// The code is tokenized as a whole, so the lines of tokens spanning several lines like block comments are highlighted correctly.
+// Requested lines which are out of range are omitted.
+func FileLineMap(fileName, language string, code []byte, lines []int) (map[int]string, error) {
+	highlighted, err := File(fileName, language, code)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[int]string, len(lines))
+	for _, line := range lines {
+		if line >= 1 && line <= len(highlighted) {
+			m[line] = highlighted[line-1]
 		}
 	}
+	return m, nil
+}
+
+// fileLines returns the HTML lines of code highlighted by the lexer.
+// If chroma panics, the plain text lines are returned.
+func fileLines(lexer chroma.Lexer, code []byte, markSections bool) (lines []string, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			log.Error("PANIC whilst highlighting code: %v\nStacktrace: %s", recovered, log.Stack(2))
+			lines, err = PlainText(code), nil
+		}
+	}()
 
 	iterator, err := lexer.Tokenise(nil, string(code))
 	if err != nil {
 		return nil, fmt.Errorf("can't tokenize code: %w", err)
 	}
 
-	err = formatter.Format(htmlWriter, styles.GitHub, iterator)
+	// code containing the markers is highlighted without marking the section headers
+	marked := false
+	if markSections && !bytes.ContainsRune(code, sectionStart) && !bytes.ContainsRune(code, sectionEnd) {
+		switch lexer.Config().Name {
+		case "INI", "TOML":
+			iterator = markSectionHeaders(iterator)
+			marked = true
+		}
+	}
+
+	lines, err = linesFromIterator(iterator)
+	if err != nil || !marked {
+		return lines, err
+	}
+	for i, line := range lines {
+		lines[i] = addSectionHeaderClass(line)
+	}
+	return lines, nil
+}
+
+// addSectionHeaderClass replaces the markers of the section header tokens in a formatted line with SectionHeaderClass.
+func addSectionHeaderClass(line string) string {
+	line = sectionTokenPattern.ReplaceAllStringFunc(line, func(token string) string {
+		m := sectionTokenPattern.FindStringSubmatch(token)
+		return `<span class="` + m[1] + " " + classPrefix + SectionHeaderClass + `">` + m[2] + "</span>"
+	})
+	// tokens without class are not wrapped by the formatter
+	return sectionTextPattern.ReplaceAllStringFunc(line, func(token string) string {
+		m := sectionTextPattern.FindStringSubmatch(token)
+		return `<span class="` + classPrefix + SectionHeaderClass + `">` + m[1] + "</span>"
+	})
+}
+
+// markSectionHeaders encloses the values of the tokens of section headers with sectionStart and sectionEnd, see addSectionHeaderClass.
+// A section header starts with a "[" at the beginning of a line, outside of a multi-line TOML array or table, and ends with the matching "]".
+func markSectionHeaders(iterator chroma.Iterator) chroma.Iterator {
+	lineStart := true
+	nesting := 0 // of TOML arrays and inline tables
+	depth := 0   // of the brackets of the current section header
+	return func() chroma.Token {
+		token := iterator()
+		if token == chroma.EOF {
+			return token
+		}
+
+		mark := false
+		if depth == 0 && lineStart && nesting == 0 && strings.HasPrefix(token.Value, "[") && !strings.ContainsRune(token.Value, '\n') {
+			depth = strings.Count(token.Value, "[") - strings.Count(token.Value, "]")
+			mark = true
+		} else if depth > 0 {
+			if strings.ContainsRune(token.Value, '\n') {
+				// unterminated section header
+				depth = 0
+			} else {
+				if token.Type == chroma.Punctuation {
+					depth += strings.Count(token.Value, "[") - strings.Count(token.Value, "]")
+				}
+				mark = true
+			}
+		} else if token.Type == chroma.Punctuation {
+			nesting += strings.Count(token.Value, "[") + strings.Count(token.Value, "{") - strings.Count(token.Value, "]") - strings.Count(token.Value, "}")
+			if nesting < 0 {
+				nesting = 0
+			}
+		}
+
+		if i := strings.LastIndexByte(token.Value, '\n'); i != -1 {
+			lineStart = strings.TrimSpace(token.Value[i+1:]) == ""
+		} else if strings.TrimSpace(token.Value) != "" {
+			lineStart = false
+		}
+
+		if mark {
+			token.Value = string(sectionStart) + token.Value + string(sectionEnd)
+		}
+		return token
+	}
+}
+
+// linesFromLexer returns a slice of HTML lines of code highlighted by the lexer
+func linesFromLexer(lexer chroma.Lexer, code string) ([]string, error) {
+	iterator, err := lexer.Tokenise(nil, code)
+	if err != nil {
+		return nil, fmt.Errorf("can't tokenize code: %w", err)
+	}
+	return linesFromIterator(iterator)
+}
+
+// linesFromIterator returns a slice of HTML lines of the highlighted tokens
+func linesFromIterator(iterator chroma.Iterator) ([]string, error) {
+	formatter := newFormatter()
+
+	htmlBuf := bytes.Buffer{}
+	htmlWriter := bufio.NewWriter(&htmlBuf)
+
+	err := formatter.Format(htmlWriter, styles.GitHub, iterator)
 	if err != nil {
 		return nil, fmt.Errorf("can't format code: %w", err)
 	}
@@ -189,7 +558,7 @@ func File(fileName, language string, code []byte) ([]string, error) {
 
 	// at the moment, Chroma generates stable output `<span class="line"><span class="cl">...\n</span></span>` for each line
 	htmlStr := htmlBuf.String()
-	lines := strings.Split(htmlStr, `<span class="line"><span class="cl">`)
+	lines := strings.Split(htmlStr, fmt.Sprintf(`<span class="%sline"><span class="%scl">`, classPrefix, classPrefix))
 	m := make([]string, 0, len(lines))
 	for i := 1; i < len(lines); i++ {
 		line := lines[i]
@@ -199,12 +568,71 @@ func File(fileName, language string, code []byte) ([]string, error) {
 	return m, nil
 }
 
+// HighlightArchiveEntry returns a slice of chroma syntax highlighted HTML lines of a file contained in an archive.
+// The lexer is chosen by the name of the entry only, the language of the outer archive or package is ignored.
+func HighlightArchiveEntry(entryName string, content []byte) ([]string, error) {
+	return File(path.Base(entryName), "", content)
+}
+
+func (opts FileOptions) apply(lines []string) []string {
+	if opts.PreserveIndentation {
+		for i, line := range lines {
+			lines[i] = encodeIndentation(line)
+		}
+	}
+	if opts.LineNumbers {
+		for i, line := range lines {
+			lines[i] = fmt.Sprintf(`<span data-line-number="%d">%s</span>`, i+1, line)
+		}
+	}
+	return lines
+}
+
+// encodeIndentation replaces the leading spaces and tabs of a HTML line with &nbsp; runs.
+// Tags before and between the whitespace are kept, so the whitespace tokens of highlighted lines are handled too.
+func encodeIndentation(line string) string {
+	var sb strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; c {
+		case '<':
+			end := strings.IndexByte(line[i:], '>')
+			if end == -1 {
+				return sb.String() + line[i:]
+			}
+			sb.WriteString(line[i : i+end+1])
+			i += end
+		case ' ':
+			sb.WriteString("&nbsp;")
+		case '\t':
+			sb.WriteString(strings.Repeat("&nbsp;", indentTabWidth))
+		default:
+			return sb.String() + line[i:]
+		}
+	}
+	return sb.String()
+}
+
+// PlainTextWithOptions returns non-highlighted HTML for code using the given options
+func PlainTextWithOptions(code []byte, opts FileOptions) []string {
+	return opts.apply(PlainText(code))
+}
+
 // PlainText returns non-highlighted HTML for code
 func PlainText(code []byte) []string {
-	r := bufio.NewReader(bytes.NewReader(code))
 	m := make([]string, 0, bytes.Count(code, []byte{'\n'})+1)
+	PlainTextLines(bytes.NewReader(code), func(line string) bool {
+		m = append(m, line)
+		return true
+	})
+	return m
+}
+
+// PlainTextLines reads the code line by line and passes every escaped line to yield.
+// Reading stops if yield returns false, so large files can be streamed without holding all lines in memory.
+func PlainTextLines(r io.Reader, yield func(line string) bool) {
+	br := bufio.NewReader(r)
 	for {
-		content, err := r.ReadString('\n')
+		content, err := br.ReadString('\n')
 		if err != nil && err != io.EOF {
 			log.Error("failed to read string from buffer: %v", err)
 			break
@@ -212,8 +640,68 @@ func PlainText(code []byte) []string {
 		if content == "" && err == io.EOF {
 			break
 		}
-		s := gohtml.EscapeString(content)
-		m = append(m, s)
+		if !yield(gohtml.EscapeString(content)) {
+			break
+		}
 	}
-	return m
+}
+
+// logLevelPattern matches the severity of a log line: an upper case level word (e.g. "ERROR"),
+// a bracketed single letter level as written by Gitea (e.g. "[E]") or a key value level (e.g. "level=error" or "\"level\":\"error\"")
+var logLevelPattern = regexp.MustCompile(`\b(FATAL|PANIC|CRITICAL|CRIT|ERROR|ERR|WARNING|WARN|INFO|NOTICE|DEBUG|TRACE)\b|\[([FCEWIDT])\]|(?i:\blevel"?\s*[=:]\s*"?(fatal|panic|critical|crit|error|err|warning|warn|info|notice|debug|trace)\b)`)
+
+// logSeverityClasses maps the upper case log levels to the classes of the highlighted lines
+var logSeverityClasses = map[string]string{
+	"FATAL":    "log-error",
+	"PANIC":    "log-error",
+	"CRITICAL": "log-error",
+	"CRIT":     "log-error",
+	"ERROR":    "log-error",
+	"ERR":      "log-error",
+	"F":        "log-error",
+	"C":        "log-error",
+	"E":        "log-error",
+	"WARNING":  "log-warning",
+	"WARN":     "log-warning",
+	"W":        "log-warning",
+	"INFO":     "log-info",
+	"NOTICE":   "log-info",
+	"I":        "log-info",
+	"DEBUG":    "log-debug",
+	"TRACE":    "log-debug",
+	"D":        "log-debug",
+	"T":        "log-debug",
+}
+
+// HighlightLog returns escaped HTML lines of a log file. Lines with a recognizable log level are wrapped in a span
+// with the class log-error, log-warning, log-info or log-debug, the first level found in a line wins.
+// Other lines are returned as plain text. Unlike File no chroma lexer is used.
+func HighlightLog(code string) []string {
+	lines := make([]string, 0, strings.Count(code, "\n")+1)
+	for _, line := range strings.SplitAfter(code, "\n") {
+		if line == "" {
+			continue
+		}
+		content := strings.TrimSuffix(line, "\n")
+		escaped := gohtml.EscapeString(content)
+		if class := logLineClass(content); class != "" {
+			escaped = `<span class="` + class + `">` + escaped + `</span>`
+		}
+		lines = append(lines, escaped+line[len(content):])
+	}
+	return lines
+}
+
+// logLineClass returns the severity class of a log line or "" if the line has no recognizable level
+func logLineClass(line string) string {
+	match := logLevelPattern.FindStringSubmatch(line)
+	if match == nil {
+		return ""
+	}
+	for _, level := range match[1:] {
+		if level != "" {
+			return logSeverityClasses[strings.ToUpper(level)]
+		}
+	}
+	return ""
 }
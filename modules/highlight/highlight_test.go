@@ -5,9 +5,15 @@
 package highlight
 
 import (
+	"bytes"
+	"regexp"
 	"strings"
 	"testing"
 
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/alecthomas/chroma/styles"
+	lru "github.com/hashicorp/golang-lru"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -151,3 +157,562 @@ c=2`),
 		})
 	}
 }
+
+func TestFileWithLineNumbers(t *testing.T) {
+	code := []byte("a=1\nb=2\n")
+
+	out, err := File("test.py", "", code)
+	assert.NoError(t, err)
+	assert.NotContains(t, strings.Join(out, ""), "data-line-number")
+
+	out, err = FileWithOptions("test.py", "", code, FileOptions{LineNumbers: true})
+	assert.NoError(t, err)
+	assert.Len(t, out, 2)
+	assert.EqualValues(t, `<span data-line-number="1"><span class="n">a</span><span class="o">=</span><span class="mi">1</span>`+"\n</span>", out[0])
+	assert.EqualValues(t, `<span data-line-number="2"><span class="n">b</span><span class="o">=</span><span class="mi">2</span>`+"\n</span>", out[1])
+}
+
+func TestPreserveIndentation(t *testing.T) {
+	code := []byte("if a:\n  b = '<x>'\n\tc = 1\n")
+
+	out := PlainText(code)
+	assert.EqualValues(t, "  b = &#39;&lt;x&gt;&#39;\n", out[1])
+
+	out = PlainTextWithOptions(code, FileOptions{PreserveIndentation: true})
+	assert.Len(t, out, 3)
+	assert.EqualValues(t, "if a:\n", out[0])
+	assert.EqualValues(t, "&nbsp;&nbsp;b = &#39;&lt;x&gt;&#39;\n", out[1])
+	assert.EqualValues(t, "&nbsp;&nbsp;&nbsp;&nbsp;c = 1\n", out[2])
+
+	out, err := FileWithOptions("test.py", "", code, FileOptions{PreserveIndentation: true, LineNumbers: true})
+	assert.NoError(t, err)
+	assert.Len(t, out, 3)
+	assert.True(t, strings.HasPrefix(out[1], `<span data-line-number="2">&nbsp;&nbsp;<span class="n">b</span> <span class="o">=</span>`), out[1])
+	assert.True(t, strings.HasPrefix(out[2], `<span data-line-number="3">&nbsp;&nbsp;&nbsp;&nbsp;<span class="n">c</span> <span class="o">=</span>`), out[2])
+}
+
+func TestFileLineMap(t *testing.T) {
+	code := []byte("package main\n/* a\nb\nc */\nvar x = 1\n")
+
+	all, err := File("main.go", "", code)
+	assert.NoError(t, err)
+	assert.Len(t, all, 5)
+
+	m, err := FileLineMap("main.go", "", code, []int{5, 1, 3, 0, 6, 3})
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]string{
+		1: all[0],
+		3: all[2],
+		5: all[4],
+	}, m)
+
+	// the middle line of the block comment is still highlighted as comment
+	assert.EqualValues(t, `<span class="cm">b`+"\n</span>", m[3])
+
+	m, err = FileLineMap("main.go", "", code, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, m)
+}
+
+func TestSkipContentAnalysis(t *testing.T) {
+	analyzed := 0
+	defer func(f func(string, []byte) string) {
+		getCodeLanguage = f
+	}(getCodeLanguage)
+	getCodeLanguage = func(fileName string, code []byte) string {
+		analyzed++
+		return "python"
+	}
+
+	code := []byte("package main\n")
+
+	out, err := File("main.go", "", code)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, analyzed)
+	assert.EqualValues(t, []string{`<span class="n">package</span> <span class="n">main</span>` + "\n"}, out)
+
+	out, err = FileWithOptions("main.go", "", code, FileOptions{SkipContentAnalysis: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, analyzed)
+	assert.EqualValues(t, []string{`<span class="kn">package</span> <span class="nx">main</span>` + "\n"}, out)
+
+	// a mapped extension never needs the content analysis
+	_, err = FileWithOptions("test.toml", "", []byte("a = 1\n"), FileOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, analyzed)
+}
+
+func BenchmarkFileContentAnalysis(b *testing.B) {
+	code := bytes.Repeat([]byte("func a(b int) int {\n\treturn b * 2\n}\n\n"), sizeLimit/64)
+
+	for _, skip := range []bool{false, true} {
+		name := "Analysis"
+		if skip {
+			name = "SkipContentAnalysis"
+		}
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := FileWithOptions("main", "", code, FileOptions{SkipContentAnalysis: skip}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestHighlightArchiveEntry(t *testing.T) {
+	code := []byte("const a: number = 1\n")
+
+	expected, err := File("index.ts", "typescript", code)
+	assert.NoError(t, err)
+
+	for _, entryName := range []string{"index.ts", "package/index.ts", "package/lib/index.ts"} {
+		out, err := HighlightArchiveEntry(entryName, code)
+		assert.NoError(t, err)
+		assert.EqualValues(t, expected, out, entryName)
+	}
+
+	out, err := HighlightArchiveEntry("package/index.ts", code)
+	assert.NoError(t, err)
+	assert.Len(t, out, 1)
+	assert.Contains(t, out[0], `<span class="kt">number</span>`)
+}
+
+func TestPlainTextLines(t *testing.T) {
+	codes := []string{
+		"",
+		"<>",
+		"a=1",
+		"a=1\n",
+		"a=1\n\n",
+		"def:\n    a=1\n\nb=''\n    \nc=2",
+	}
+
+	for _, code := range codes {
+		streamed := make([]string, 0)
+		PlainTextLines(strings.NewReader(code), func(line string) bool {
+			streamed = append(streamed, line)
+			return true
+		})
+		assert.EqualValues(t, PlainText([]byte(code)), streamed, code)
+	}
+
+	streamed := make([]string, 0)
+	PlainTextLines(strings.NewReader("a\nb\nc\n"), func(line string) bool {
+		streamed = append(streamed, line)
+		return len(streamed) < 2
+	})
+	assert.EqualValues(t, []string{"a\n", "b\n"}, streamed)
+}
+
+func TestDefaultHighlightMapping(t *testing.T) {
+	for ext, language := range defaultHighlightMapping {
+		lexer := lexers.Get(language)
+		assert.NotNil(t, lexer, ext)
+		assert.NotEqual(t, lexers.Fallback, lexer, ext)
+	}
+
+	tests := []struct {
+		name string
+		code string
+	}{
+		{"message.proto", `syntax = "proto3";`},
+		{"Cargo.toml", `name = "gitea"`},
+		{"main.hcl", `variable "name" {}`},
+		{".dockerignore", "# comment"},
+	}
+
+	for _, tt := range tests {
+		out, err := File(tt.name, "", []byte(tt.code))
+		assert.NoError(t, err)
+		assert.Len(t, out, 1)
+		assert.Contains(t, out[0], `<span class="`, tt.name)
+	}
+}
+
+func TestModernLanguageMapping(t *testing.T) {
+	NewContext()
+
+	for _, tt := range []struct {
+		fileName string
+		language string
+	}{
+		{"schema.graphql", "graphql"},
+		{"build.zig", "zig"},
+		{"default.nix", "nix"},
+	} {
+		lexer := codeLexer(tt.fileName, "", false)
+		if lexers.Get(tt.language) == nil {
+			// not supported by the bundled chroma version, highlighting must still work
+			assert.NotNil(t, lexer, tt.fileName)
+			assert.NotEmpty(t, Code(tt.fileName, "", "x"), tt.fileName)
+			continue
+		}
+		assert.NotEqual(t, lexers.Fallback, lexer, tt.fileName)
+		assert.Equal(t, lexers.Get(tt.language).Config().Name, lexer.Config().Name, tt.fileName)
+	}
+}
+
+func TestAssemblyAndHardwareLanguageMapping(t *testing.T) {
+	NewContext()
+
+	// the extensions are claimed by several lexers (e.g. Coq for .v, TASM for .asm, ArmAsm for .s)
+	for _, tt := range []struct {
+		fileName string
+		language string
+	}{
+		{"boot.asm", "nasm"},
+		{"BOOT.ASM", "nasm"},
+		{"start.s", "gas"},
+		{"start.S", "gas"},
+		{"counter.v", "verilog"},
+		{"counter.vhd", "vhdl"},
+		{"counter.vhdl", "vhdl"},
+	} {
+		lexer := codeLexer(tt.fileName, "", false)
+		if lexers.Get(tt.language) == nil {
+			// not supported by the bundled chroma version, the content is rendered as plain text
+			assert.NotNil(t, lexer, tt.fileName)
+			assert.NotEmpty(t, Code(tt.fileName, "", "x"), tt.fileName)
+			continue
+		}
+		assert.Equal(t, lexers.Get(tt.language).Config().Name, lexer.Config().Name, tt.fileName)
+	}
+
+	// a custom mapping takes precedence over the defaults
+	defer func(language string) {
+		highlightMapping[".v"] = language
+	}(highlightMapping[".v"])
+	highlightMapping[".v"] = "coq"
+	assert.Equal(t, lexers.Get("coq").Config().Name, codeLexer("proof.v", "", false).Config().Name)
+}
+
+func TestDataScienceLanguageMapping(t *testing.T) {
+	NewContext()
+
+	for _, tt := range []struct {
+		fileName string
+		language string
+	}{
+		{"analysis.R", "r"},
+		{"analysis.r", "r"},
+		{".Rprofile", "r"},
+		{"solver.jl", "julia"},
+		{"report.Rmd", "markdown"},
+		{"report.rmd", "markdown"},
+	} {
+		lexer := codeLexer(tt.fileName, "", false)
+		if lexers.Get(tt.language) == nil {
+			// not supported by the bundled chroma version, highlighting must still work
+			assert.NotNil(t, lexer, tt.fileName)
+			assert.NotEmpty(t, Code(tt.fileName, "", "x"), tt.fileName)
+			continue
+		}
+		assert.Equal(t, lexers.Get(tt.language).Config().Name, lexer.Config().Name, tt.fileName)
+	}
+
+	// .S stays GNU assembler although the R lexer claims it too
+	assert.Equal(t, lexers.Get("gas").Config().Name, codeLexer("start.S", "", false).Config().Name)
+
+	// a custom mapping takes precedence over the defaults
+	defer func(language string) {
+		highlightMapping[".r"] = language
+	}(highlightMapping[".r"])
+	highlightMapping[".r"] = "rebol"
+	assert.Equal(t, lexers.Get("rebol").Config().Name, codeLexer("script.r", "", false).Config().Name)
+}
+
+func TestTokenize(t *testing.T) {
+	iterator, err := Tokenize("main.go", "", "package main\n")
+	assert.NoError(t, err)
+
+	var tokens []chroma.Token
+	for _, token := range iterator.Tokens() {
+		if token.Type == chroma.Text && strings.TrimSpace(token.Value) == "" {
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+	assert.Equal(t, []chroma.Token{
+		{Type: chroma.KeywordNamespace, Value: "package"},
+		{Type: chroma.NameOther, Value: "main"},
+	}, tokens)
+
+	// the language overrides the file name
+	iterator, err = Tokenize("main.txt", "go", "func f() {}")
+	assert.NoError(t, err)
+	types := make([]chroma.TokenType, 0, 8)
+	for _, token := range iterator.Tokens() {
+		types = append(types, token.Type)
+	}
+	assert.Equal(t, []chroma.TokenType{
+		chroma.KeywordDeclaration,
+		chroma.Text,
+		chroma.NameFunction,
+		chroma.Punctuation,
+		chroma.Punctuation,
+		chroma.Text,
+		chroma.Punctuation,
+		chroma.Punctuation,
+	}, types)
+}
+
+func TestClassPrefix(t *testing.T) {
+	NewContext()
+	defer func(prefix string) {
+		classPrefix = prefix
+	}(classPrefix)
+	classPrefix = "hl-"
+
+	out, err := File("test.go", "", []byte("package main\n\nfunc main() {}\n"))
+	assert.NoError(t, err)
+	assert.Len(t, out, 3)
+	assert.Equal(t, `<span class="hl-kn">package</span> <span class="hl-nx">main</span>`+"\n", out[0])
+	for _, line := range out {
+		assert.NotContains(t, line, `class="line"`)
+		assert.NotContains(t, line, `class="cl"`)
+		assert.NotContains(t, line, `hl-line`)
+	}
+
+	code := Code("test.go", "", "func f()")
+	assert.True(t, strings.HasPrefix(code, `<span class="hl-line"><span class="hl-cl"><span class="hl-kd">func</span>`), code)
+}
+
+func TestCodeUncached(t *testing.T) {
+	NewContext()
+
+	const fileName = "uncached-test.go"
+	cache.Remove(fileName)
+
+	code := CodeUncached(fileName, "", "package main")
+	assert.Equal(t, Code(fileName, "", "package main"), code)
+	assert.Contains(t, code, `<span class="kn">package</span>`)
+
+	cache.Remove(fileName)
+	CodeUncached(fileName, "", "package main")
+	assert.False(t, cache.Contains(fileName))
+
+	// the cache is still used by Code
+	Code(fileName, "", "package main")
+	assert.True(t, cache.Contains(fileName))
+}
+
+func TestCodeSanitized(t *testing.T) {
+	NewContext()
+
+	out := CodeSanitized("main.go", "", "package main\n\nfunc main() {}\n")
+	assert.Contains(t, out, `<span class="`)
+
+	tags := regexp.MustCompile(`<[^>]*>`).FindAllString(out, -1)
+	assert.NotEmpty(t, tags)
+	for _, tag := range tags {
+		assert.Regexp(t, `^(<span class="[\w\- ]+">|<span>|</span>)$`, tag)
+	}
+
+	// everything but span elements and their classes is removed
+	assert.Equal(t,
+		`<span class="k">func</span>link`,
+		sanitizeCode(`<span class="k" style="color:red" onclick="alert(1)">func</span><a href="javascript:alert(1)">link</a><script>alert(1)</script>`),
+	)
+	out = sanitizeCode(`<span class="k&quot; onclick=&quot;alert(1)">x</span>`)
+	assert.NotContains(t, out, "class")
+	assert.NotContains(t, out, "onclick")
+	assert.Contains(t, out, "x")
+}
+
+func TestCodeANSI(t *testing.T) {
+	code := CodeANSI("test.go", "", "package main\n\nfunc main() {}\n")
+	assert.Contains(t, code, "\x1b[")
+	assert.Contains(t, code, "package")
+	assert.NotContains(t, code, "<span")
+
+	large := strings.Repeat("a", sizeLimit+1)
+	assert.Equal(t, large, CodeANSI("test.go", "", large))
+}
+
+func TestMaxAverageLineLength(t *testing.T) {
+	NewContext()
+	defer func(limit int) {
+		maxAverageLineLength = limit
+	}(maxAverageLineLength)
+
+	minified := "var a=1;" + strings.Repeat("a=a+1;", 100*1024)
+	formatted := "var a = 1;\n" + strings.Repeat("a = a + 1;\n", 10*1024)
+
+	// the limit is disabled by default
+	maxAverageLineLength = 0
+	lines, err := File("test.js", "", []byte(minified))
+	assert.NoError(t, err)
+	assert.Contains(t, lines[0], `<span class="`)
+
+	maxAverageLineLength = 1000
+
+	lines, err = File("test.js", "", []byte(minified))
+	assert.NoError(t, err)
+	assert.Len(t, lines, 1)
+	assert.NotContains(t, lines[0], `<span class="`)
+	assert.Equal(t, minified, Code("test.js", "", minified))
+
+	// the line count matters, not the size
+	lines, err = File("test.js", "", []byte(formatted))
+	assert.NoError(t, err)
+	assert.Len(t, lines, 10*1024+1)
+	assert.Contains(t, lines[0], `<span class="`)
+	assert.Contains(t, Code("test.js", "", formatted), `<span class="`)
+}
+
+func TestTabWidth(t *testing.T) {
+	NewContext()
+	defer func(width int) {
+		tabWidth = width
+	}(tabWidth)
+
+	writeCSS := func() string {
+		var buf bytes.Buffer
+		assert.NoError(t, newFormatter().WriteCSS(&buf, styles.GitHub))
+		return buf.String()
+	}
+
+	// chroma omits the tab size for its default width
+	tabWidth = 8
+	assert.NotContains(t, writeCSS(), "tab-size")
+
+	tabWidth = 4
+	assert.Contains(t, writeCSS(), "tab-size: 4")
+}
+
+// panickingLexer panics either when tokenizing or when the tokens are iterated
+type panickingLexer struct {
+	lazy bool
+}
+
+func (l *panickingLexer) Config() *chroma.Config {
+	return &chroma.Config{Name: "panicking"}
+}
+
+func (l *panickingLexer) Tokenise(*chroma.TokeniseOptions, string) (chroma.Iterator, error) {
+	if !l.lazy {
+		panic("tokenise")
+	}
+	return func() chroma.Token {
+		panic("iterate")
+	}, nil
+}
+
+func TestHighlightRecoversFromPanic(t *testing.T) {
+	NewContext()
+
+	const code = "<a>\nb"
+	for _, lexer := range []chroma.Lexer{&panickingLexer{}, &panickingLexer{lazy: true}} {
+		assert.Equal(t, "&lt;a&gt;\nb", CodeFromLexer(lexer, code))
+
+		lines, err := fileLines(lexer, []byte(code), false)
+		assert.NoError(t, err)
+		assert.Equal(t, PlainText([]byte(code)), lines)
+	}
+}
+
+func TestInvalidateHighlightCache(t *testing.T) {
+	NewContext()
+
+	// no-op if the output cache is disabled
+	InvalidateHighlightCache(ContentHash("package main"))
+
+	c, err := lru.New2Q(16)
+	assert.NoError(t, err)
+	outputCache = c
+	defer func() {
+		outputCache = nil
+	}()
+
+	const fileName = "output-cache-test.go"
+	code := "package main"
+	other := "package other"
+
+	expected := Code(fileName, "", code)
+	Code(fileName, "", other)
+	assert.Equal(t, 2, outputCache.Len())
+
+	// replace the cached entries to detect if the code is highlighted again
+	for _, key := range outputCache.Keys() {
+		outputCache.Add(key, "stale")
+	}
+	assert.Equal(t, "stale", Code(fileName, "", code))
+
+	InvalidateHighlightCache(ContentHash(code))
+	assert.Equal(t, expected, Code(fileName, "", code))
+	assert.Equal(t, "stale", Code(fileName, "", other))
+
+	// the output cache is not used by CodeUncached
+	assert.NotEqual(t, "stale", CodeUncached(fileName, "", other))
+}
+
+func TestFileMarkSections(t *testing.T) {
+	standardTypes := len(chroma.StandardTypes)
+	ini := []byte("; comment\n[section]\nkey = [value]\n")
+
+	out, err := FileWithOptions("test.ini", "", ini, FileOptions{MarkSections: true})
+	assert.NoError(t, err)
+	assert.Len(t, out, 3)
+	assert.NotContains(t, out[0], SectionHeaderClass)
+	assert.Equal(t, `<span class="k section-header">[section]</span>`+"\n", out[1])
+	assert.NotContains(t, out[2], SectionHeaderClass)
+
+	// without the option, the output is unchanged
+	out, err = File("test.ini", "", ini)
+	assert.NoError(t, err)
+	assert.Equal(t, `<span class="k">[section]</span>`+"\n", out[1])
+
+	toml := []byte("[table.sub]\nvalues = [\n  [1, 2],\n]\n\n[[array]]\nkey = \"value\"\n")
+
+	out, err = FileWithOptions("test.toml", "", toml, FileOptions{MarkSections: true})
+	assert.NoError(t, err)
+	assert.Len(t, out, 7)
+	assert.Equal(t, `<span class="p section-header">[</span><span class="nx section-header">table</span><span class="p section-header">.</span><span class="nx section-header">sub</span><span class="p section-header">]</span>`+"\n", out[0])
+	assert.NotContains(t, out[1], SectionHeaderClass)
+	assert.NotContains(t, out[2], SectionHeaderClass, "nested arrays are not section headers")
+	assert.NotContains(t, out[3], SectionHeaderClass)
+	assert.Equal(t, `<span class="p section-header">[</span><span class="p section-header">[</span><span class="nx section-header">array</span><span class="p section-header">]</span><span class="p section-header">]</span>`+"\n", out[5])
+	assert.NotContains(t, out[6], SectionHeaderClass)
+
+	// other languages are not affected
+	out, err = FileWithOptions("test.py", "", []byte("[1, 2]\n"), FileOptions{MarkSections: true})
+	assert.NoError(t, err)
+	assert.NotContains(t, out[0], SectionHeaderClass)
+
+	// code containing the markers is not marked
+	out, err = FileWithOptions("test.ini", "", []byte("[section]\nkey = \uE000\n"), FileOptions{MarkSections: true})
+	assert.NoError(t, err)
+	assert.Equal(t, `<span class="k">[section]</span>`+"\n", out[0])
+
+	// the class mapping shared with other chroma users is not changed
+	assert.Len(t, chroma.StandardTypes, standardTypes)
+}
+
+func TestHighlightLog(t *testing.T) {
+	code := "2022/10/01 12:00:00 [I] Starting server\n" +
+		"2022/10/01 12:00:01 [W] Slow query <select>\n" +
+		"ERROR: failed to connect\n" +
+		"time=2022-10-01T12:00:02Z level=debug msg=\"retrying\"\n" +
+		"{\"level\":\"Warn\",\"msg\":\"disk almost full\"}\n" +
+		"\tat main.go:42\n" +
+		"INFO request failed with ERROR\n" +
+		"FATAL error\n" +
+		"an informational message without level\n" +
+		"WARNING: last line without newline"
+
+	assert.Equal(t, []string{
+		`<span class="log-info">2022/10/01 12:00:00 [I] Starting server</span>` + "\n",
+		`<span class="log-warning">2022/10/01 12:00:01 [W] Slow query &lt;select&gt;</span>` + "\n",
+		`<span class="log-error">ERROR: failed to connect</span>` + "\n",
+		`<span class="log-debug">time=2022-10-01T12:00:02Z level=debug msg=&#34;retrying&#34;</span>` + "\n",
+		`<span class="log-warning">{&#34;level&#34;:&#34;Warn&#34;,&#34;msg&#34;:&#34;disk almost full&#34;}</span>` + "\n",
+		"\tat main.go:42\n",
+		`<span class="log-info">INFO request failed with ERROR</span>` + "\n",
+		`<span class="log-error">FATAL error</span>` + "\n",
+		"an informational message without level\n",
+		`<span class="log-warning">WARNING: last line without newline</span>`,
+	}, HighlightLog(code))
+
+	assert.Empty(t, HighlightLog(""))
+}
@@ -182,3 +182,7 @@ func (*NullNotifier) NotifyPackageCreate(doer *user_model.User, pd *packages_mod
 // NotifyPackageDelete places a place holder function
 func (*NullNotifier) NotifyPackageDelete(doer *user_model.User, pd *packages_model.PackageDescriptor) {
 }
+
+// NotifyPackageTransfer places a place holder function
+func (*NullNotifier) NotifyPackageTransfer(doer, oldOwner *user_model.User, p *packages_model.Package) {
+}
@@ -323,3 +323,10 @@ func NotifyPackageDelete(doer *user_model.User, pd *packages_model.PackageDescri
 		notifier.NotifyPackageDelete(doer, pd)
 	}
 }
+
+// NotifyPackageTransfer notifies the ownership transfer of a package to notifiers
+func NotifyPackageTransfer(doer, oldOwner *user_model.User, p *packages_model.Package) {
+	for _, notifier := range notifiers {
+		notifier.NotifyPackageTransfer(doer, oldOwner, p)
+	}
+}
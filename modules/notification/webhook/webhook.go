@@ -868,11 +868,6 @@ func (m *webhookNotifier) NotifyPackageDelete(doer *user_model.User, pd *package
 }
 
 func notifyPackage(sender *user_model.User, pd *packages_model.PackageDescriptor, action api.HookPackageAction) {
-	if pd.Repository == nil {
-		// TODO https://github.com/go-gitea/gitea/pull/17940
-		return
-	}
-
 	ctx, _, finished := process.GetManager().AddContext(graceful.GetManager().HammerContext(), fmt.Sprintf("webhook.notifyPackage Package: %s[%d]", pd.Package.Name, pd.Package.ID))
 	defer finished()
 
@@ -882,11 +877,28 @@ func notifyPackage(sender *user_model.User, pd *packages_model.PackageDescriptor
 		return
 	}
 
-	if err := webhook_services.PrepareWebhooks(pd.Repository, webhook.HookEventPackage, &api.PackagePayload{
+	files := make([]*api.PackageFile, 0, len(pd.Files))
+	for _, pfd := range pd.Files {
+		files = append(files, convert.ToPackageFile(pfd))
+	}
+
+	payload := &api.PackagePayload{
 		Action:  action,
 		Package: apiPackage,
+		Files:   files,
 		Sender:  convert.ToUser(sender, nil),
-	}); err != nil {
-		log.Error("PrepareWebhooks: %v", err)
+	}
+	if pd.Repository != nil {
+		payload.Repository = convert.ToRepo(pd.Repository, perm.AccessModeOwner)
+	}
+	if pd.Owner.IsOrganization() {
+		payload.Organization = convert.ToUser(pd.Owner, nil)
+	}
+
+	if err := webhook_services.PrepareWebhooksForSource(webhook_services.EventSource{
+		Repository: pd.Repository,
+		Owner:      pd.Owner,
+	}, webhook.HookEventPackage, payload); err != nil {
+		log.Error("PrepareWebhooksForSource: %v", err)
 	}
 }
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	"code.gitea.io/gitea/modules/json"
 	"code.gitea.io/gitea/modules/packages/container/helm"
@@ -21,9 +22,14 @@ const (
 	PropertyMediaType         = "container.mediatype"
 	PropertyManifestTagged    = "container.manifest.tagged"
 	PropertyManifestReference = "container.manifest.reference"
+	PropertyImageSize         = "container.image.size"
+	PropertyImageCreated      = "container.image.created"
 
 	DefaultPlatform = "linux/amd64"
 
+	// AnnotationReleaseNotes is the manifest annotation which contains the release notes of an image
+	AnnotationReleaseNotes = "io.gitea.release-notes"
+
 	labelLicenses      = "org.opencontainers.image.licenses"
 	labelURL           = "org.opencontainers.image.url"
 	labelSource        = "org.opencontainers.image.source"
@@ -63,6 +69,7 @@ type Metadata struct {
 	Labels           map[string]string `json:"labels,omitempty"`
 	ImageLayers      []string          `json:"layer_creation,omitempty"`
 	MultiArch        map[string]string `json:"multiarch,omitempty"`
+	Created          *time.Time        `json:"created,omitempty"`
 }
 
 // ParseImageConfig parses the metadata of an image config
@@ -108,6 +115,7 @@ func parseOCIImageConfig(r io.Reader) (*Metadata, error) {
 		Description:      image.Config.Labels[labelDescription],
 		Labels:           image.Config.Labels,
 		ImageLayers:      imageLayers,
+		Created:          image.Created,
 	}
 
 	if authors, ok := image.Config.Labels[labelAuthors]; ok {
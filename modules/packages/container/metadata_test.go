@@ -22,7 +22,7 @@ func TestParseImageConfig(t *testing.T) {
 	repositoryURL := "https://gitea.com/gitea"
 	documentationURL := "https://docs.gitea.io"
 
-	configOCI := `{"config": {"labels": {"` + labelAuthors + `": "` + author + `", "` + labelLicenses + `": "` + license + `", "` + labelURL + `": "` + projectURL + `", "` + labelSource + `": "` + repositoryURL + `", "` + labelDocumentation + `": "` + documentationURL + `", "` + labelDescription + `": "` + description + `"}}, "history": [{"created_by": "do it 1"}, {"created_by": "dummy #(nop) do it 2"}]}`
+	configOCI := `{"config": {"labels": {"` + labelAuthors + `": "` + author + `", "` + labelLicenses + `": "` + license + `", "` + labelURL + `": "` + projectURL + `", "` + labelSource + `": "` + repositoryURL + `", "` + labelDocumentation + `": "` + documentationURL + `", "` + labelDescription + `": "` + description + `"}}, "history": [{"created_by": "do it 1"}, {"created_by": "dummy #(nop) do it 2"}], "created": "2022-08-01T10:00:00Z"}`
 
 	metadata, err := ParseImageConfig(oci.MediaType(oci.MediaTypeImageManifest), strings.NewReader(configOCI))
 	assert.NoError(t, err)
@@ -35,6 +35,8 @@ func TestParseImageConfig(t *testing.T) {
 	assert.Equal(t, repositoryURL, metadata.RepositoryURL)
 	assert.Equal(t, documentationURL, metadata.DocumentationURL)
 	assert.Equal(t, []string{"do it 1", "do it 2"}, metadata.ImageLayers)
+	assert.NotNil(t, metadata.Created)
+	assert.EqualValues(t, 1659348000, metadata.Created.Unix())
 	assert.Equal(
 		t,
 		map[string]string{
@@ -11,6 +11,9 @@ import (
 	"code.gitea.io/gitea/modules/validation"
 )
 
+// PropertyGroupID is the name of the package property which stores the groupId of a Maven package
+const PropertyGroupID = "maven.group_id"
+
 // Metadata represents the metadata of a Maven package
 type Metadata struct {
 	GroupID      string        `json:"group_id,omitempty"`
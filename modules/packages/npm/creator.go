@@ -17,6 +17,7 @@ import (
 	"time"
 
 	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/packages"
 	"code.gitea.io/gitea/modules/validation"
 
 	"github.com/hashicorp/go-version"
@@ -39,12 +40,13 @@ var nameMatch = regexp.MustCompile(`\A((@[^\s\/~'!\(\)\*]+?)[\/])?([^_.][^\s\/~'
 
 // Package represents a npm package
 type Package struct {
-	Name     string
-	Version  string
-	DistTags []string
-	Metadata Metadata
-	Filename string
-	Data     []byte
+	Name         string
+	Version      string
+	DistTags     []string
+	Metadata     Metadata
+	ReleaseNotes string
+	Filename     string
+	Data         []byte
 }
 
 // PackageMetadata https://github.com/npm/registry/blob/master/docs/REGISTRY-API.md#package
@@ -82,6 +84,8 @@ type PackageMetadataVersion struct {
 	PeerDependencies     map[string]string   `json:"peerDependencies,omitempty"`
 	OptionalDependencies map[string]string   `json:"optionalDependencies,omitempty"`
 	Readme               string              `json:"readme,omitempty"`
+	Deprecated           string              `json:"deprecated,omitempty"`
+	ReleaseNotes         string              `json:"releaseNotes,omitempty"`
 	Dist                 PackageDistribution `json:"dist"`
 	Maintainers          []User              `json:"maintainers,omitempty"`
 }
@@ -176,6 +180,10 @@ func ParsePackage(r io.Reader) (*Package, error) {
 			meta.Homepage = ""
 		}
 
+		if len(meta.Readme) > packages.MaxReadmeSize {
+			meta.Readme = ""
+		}
+
 		p := &Package{
 			Name:     meta.Name,
 			Version:  v.String(),
@@ -187,6 +195,7 @@ func ParsePackage(r io.Reader) (*Package, error) {
 				Author:                  meta.Author.Name,
 				License:                 meta.License,
 				ProjectURL:              meta.Homepage,
+				RepositoryURL:           meta.Repository.URL,
 				Keywords:                meta.Keywords,
 				Dependencies:            meta.Dependencies,
 				DevelopmentDependencies: meta.DevDependencies,
@@ -194,6 +203,7 @@ func ParsePackage(r io.Reader) (*Package, error) {
 				OptionalDependencies:    meta.OptionalDependencies,
 				Readme:                  meta.Readme,
 			},
+			ReleaseNotes: meta.ReleaseNotes,
 		}
 
 		for tag := range upload.DistTags {
@@ -12,6 +12,7 @@ import (
 	"testing"
 
 	"code.gitea.io/gitea/modules/json"
+	"code.gitea.io/gitea/modules/packages"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -232,6 +233,7 @@ func TestParsePackage(t *testing.T) {
 						Author:      User{Name: packageAuthor},
 						License:     "MIT",
 						Homepage:    "https://gitea.io/",
+						Repository:  Repository{Type: "git", URL: "git+https://gitea.com/gitea/test.git"},
 						Readme:      packageDescription,
 						Dependencies: map[string]string{
 							"package": "1.2.0",
@@ -266,7 +268,38 @@ func TestParsePackage(t *testing.T) {
 		assert.Equal(t, packageAuthor, p.Metadata.Author)
 		assert.Equal(t, "MIT", p.Metadata.License)
 		assert.Equal(t, "https://gitea.io/", p.Metadata.ProjectURL)
+		assert.Equal(t, "git+https://gitea.com/gitea/test.git", p.Metadata.RepositoryURL)
 		assert.Contains(t, p.Metadata.Dependencies, "package")
 		assert.Equal(t, "1.2.0", p.Metadata.Dependencies["package"])
 	})
+
+	t.Run("ValidWithLargeReadme", func(t *testing.T) {
+		filename := fmt.Sprintf("%s-%s.tgz", packageFullName, packageVersion)
+		b, _ := json.Marshal(packageUpload{
+			PackageMetadata: PackageMetadata{
+				ID:   packageFullName,
+				Name: packageFullName,
+				Versions: map[string]*PackageMetadataVersion{
+					packageVersion: {
+						Name:    packageFullName,
+						Version: packageVersion,
+						Readme:  strings.Repeat("a", packages.MaxReadmeSize+1),
+						Dist: PackageDistribution{
+							Integrity: integrity,
+						},
+					},
+				},
+			},
+			Attachments: map[string]*PackageAttachment{
+				filename: {
+					Data: data,
+				},
+			},
+		})
+
+		p, err := ParsePackage(bytes.NewReader(b))
+		assert.NotNil(t, p)
+		assert.NoError(t, err)
+		assert.Empty(t, p.Metadata.Readme)
+	})
 }
@@ -9,10 +9,12 @@ import (
 	"encoding/xml"
 	"errors"
 	"io"
+	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
 
+	"code.gitea.io/gitea/modules/packages"
 	"code.gitea.io/gitea/modules/validation"
 
 	"github.com/hashicorp/go-version"
@@ -51,6 +53,8 @@ type Package struct {
 	ID          string
 	Version     string
 	Metadata    *Metadata
+
+	readmeFile string // path of the README in the package archive
 }
 
 // Metadata represents the metadata of a Nuget package
@@ -60,7 +64,9 @@ type Metadata struct {
 	Authors       string                  `json:"authors,omitempty"`
 	ProjectURL    string                  `json:"project_url,omitempty"`
 	RepositoryURL string                  `json:"repository_url,omitempty"`
+	Tags          []string                `json:"tags,omitempty"`
 	Dependencies  map[string][]Dependency `json:"dependencies,omitempty"`
+	Readme        string                  `json:"readme,omitempty"`
 }
 
 // Dependency represents a dependency of a Nuget package
@@ -78,6 +84,8 @@ type nuspecPackage struct {
 		ProjectURL               string `xml:"projectUrl"`
 		Description              string `xml:"description"`
 		ReleaseNotes             string `xml:"releaseNotes"`
+		Tags                     string `xml:"tags"`
+		Readme                   string `xml:"readme"`
 		PackageTypes             struct {
 			PackageType []struct {
 				Name string `xml:"name,attr"`
@@ -120,12 +128,48 @@ func ParsePackageMetaData(r io.ReaderAt, size int64) (*Package, error) {
 			}
 			defer f.Close()
 
-			return ParseNuspecMetaData(f)
+			p, err := ParseNuspecMetaData(f)
+			if err != nil {
+				return nil, err
+			}
+			if p.readmeFile != "" {
+				if p.Metadata.Readme, err = readReadme(archive, p.readmeFile); err != nil {
+					return nil, err
+				}
+			}
+			return p, nil
 		}
 	}
 	return nil, ErrMissingNuspecFile
 }
 
+// readReadme reads the README referenced by the Nuspec file.
+// A missing or too large README is ignored.
+func readReadme(archive *zip.Reader, name string) (string, error) {
+	name = strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(name, "\\", "/")), "/")
+
+	for _, file := range archive.File {
+		if !strings.EqualFold(file.Name, name) {
+			continue
+		}
+		if file.UncompressedSize64 > packages.MaxReadmeSize {
+			return "", nil
+		}
+		f, err := file.Open()
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+
+		data, err := io.ReadAll(io.LimitReader(f, packages.MaxReadmeSize))
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+	return "", nil
+}
+
 // ParseNuspecMetaData parses a Nuspec file to retrieve the metadata of a Nuget package
 func ParseNuspecMetaData(r io.Reader) (*Package, error) {
 	var p nuspecPackage
@@ -160,6 +204,7 @@ func ParseNuspecMetaData(r io.Reader) (*Package, error) {
 		Authors:       p.Metadata.Authors,
 		ProjectURL:    p.Metadata.ProjectURL,
 		RepositoryURL: p.Metadata.Repository.URL,
+		Tags:          strings.Fields(p.Metadata.Tags),
 		Dependencies:  make(map[string][]Dependency),
 	}
 
@@ -183,5 +228,6 @@ func ParseNuspecMetaData(r io.Reader) (*Package, error) {
 		ID:          p.Metadata.ID,
 		Version:     v.String(),
 		Metadata:    m,
+		readmeFile:  p.Metadata.Readme,
 	}, nil
 }
@@ -36,6 +36,8 @@ const nuspecContent = `<?xml version="1.0" encoding="utf-8"?>
     <projectUrl>` + projectURL + `</projectUrl>
     <description>` + description + `</description>
     <releaseNotes>` + releaseNotes + `</releaseNotes>
+    <tags>gitea  json
+      serializer</tags>
     <repository url="` + repositoryURL + `" />
     <dependencies>
       <group targetFramework="` + targetFramework + `">
@@ -124,6 +126,30 @@ func TestParsePackageMetaData(t *testing.T) {
 		np, err := ParsePackageMetaData(bytes.NewReader(data), int64(len(data)))
 		assert.NoError(t, err)
 		assert.NotNil(t, np)
+		assert.Empty(t, np.Metadata.Readme)
+	})
+
+	t.Run("ValidWithReadme", func(t *testing.T) {
+		nuspec := strings.Replace(nuspecContent, "<tags>", `<readme>docs\README.md</readme><tags>`, 1)
+
+		for name, expected := range map[string]string{
+			"docs/README.md":  "# Readme",
+			"other/README.md": "",
+		} {
+			var buf bytes.Buffer
+			archive := zip.NewWriter(&buf)
+			w, _ := archive.Create("package.nuspec")
+			w.Write([]byte(nuspec))
+			w, _ = archive.Create(name)
+			w.Write([]byte("# Readme"))
+			archive.Close()
+			data := buf.Bytes()
+
+			np, err := ParsePackageMetaData(bytes.NewReader(data), int64(len(data)))
+			assert.NoError(t, err)
+			assert.NotNil(t, np)
+			assert.Equal(t, expected, np.Metadata.Readme)
+		}
 	})
 }
 
@@ -141,6 +167,7 @@ func TestParseNuspecMetaData(t *testing.T) {
 		assert.Equal(t, description, np.Metadata.Description)
 		assert.Equal(t, releaseNotes, np.Metadata.ReleaseNotes)
 		assert.Equal(t, repositoryURL, np.Metadata.RepositoryURL)
+		assert.Equal(t, []string{"gitea", "json", "serializer"}, np.Metadata.Tags)
 		assert.Len(t, np.Metadata.Dependencies, 1)
 		assert.Contains(t, np.Metadata.Dependencies, targetFramework)
 		deps := np.Metadata.Dependencies[targetFramework]
@@ -158,6 +185,7 @@ func TestParseNuspecMetaData(t *testing.T) {
 		assert.Equal(t, id, np.ID)
 		assert.Equal(t, semver, np.Version)
 		assert.Equal(t, description, np.Metadata.Description)
+		assert.Empty(t, np.Metadata.Tags)
 		assert.Empty(t, np.Metadata.Dependencies)
 	})
 }
@@ -12,6 +12,7 @@ import (
 	"regexp"
 	"strings"
 
+	"code.gitea.io/gitea/modules/packages"
 	"code.gitea.io/gitea/modules/validation"
 
 	"github.com/hashicorp/go-version"
@@ -89,7 +90,7 @@ func ParsePackage(r io.Reader) (*Package, error) {
 			if err != nil {
 				return nil, err
 			}
-		} else if strings.ToLower(hd.Name) == "readme.md" {
+		} else if strings.ToLower(hd.Name) == "readme.md" && hd.Size <= packages.MaxReadmeSize {
 			data, err := io.ReadAll(tr)
 			if err != nil {
 				return nil, err
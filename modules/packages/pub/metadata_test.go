@@ -12,6 +12,8 @@ import (
 	"strings"
 	"testing"
 
+	"code.gitea.io/gitea/modules/packages"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -102,6 +104,15 @@ func TestParsePackage(t *testing.T) {
 		assert.NotNil(t, pp)
 		assert.Equal(t, "readme", pp.Metadata.Readme)
 	})
+
+	t.Run("ValidWithLargeReadme", func(t *testing.T) {
+		data := createArchive(map[string][]byte{"pubspec.yaml": []byte(pubspecContent), "README.md": bytes.Repeat([]byte{'a'}, packages.MaxReadmeSize+1)})
+
+		pp, err := ParsePackage(data)
+		assert.NoError(t, err)
+		assert.NotNil(t, pp)
+		assert.Empty(t, pp.Metadata.Readme)
+	})
 }
 
 func TestParsePubspecMetadata(t *testing.T) {
@@ -0,0 +1,9 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages
+
+// MaxReadmeSize is the maximum size of a README which gets stored in the metadata of a package version.
+// Larger READMEs are dropped to keep the metadata small.
+const MaxReadmeSize = 512 * 1024
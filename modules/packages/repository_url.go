@@ -0,0 +1,46 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"net/url"
+	"strings"
+)
+
+// ParseRepositoryURL checks if rawURL points to a repository of the instance reachable at appURL
+// and returns the owner and repository name. Scheme, host and path must match exactly, only an
+// optional "git+" scheme prefix, a ".git" suffix and a trailing slash are accepted.
+func ParseRepositoryURL(appURL, rawURL string) (string, string, bool) {
+	base, err := url.Parse(appURL)
+	if err != nil || base.Host == "" {
+		return "", "", false
+	}
+
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", "", false
+	}
+	if u.User != nil || u.RawQuery != "" || u.Fragment != "" || u.Opaque != "" {
+		return "", "", false
+	}
+	if strings.TrimPrefix(u.Scheme, "git+") != base.Scheme || !strings.EqualFold(u.Host, base.Host) {
+		return "", "", false
+	}
+
+	prefix := strings.TrimSuffix(base.Path, "/") + "/"
+	if !strings.HasPrefix(u.Path, prefix) {
+		return "", "", false
+	}
+
+	parts := strings.Split(strings.TrimSuffix(strings.TrimPrefix(u.Path, prefix), "/"), "/")
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	owner, repo := parts[0], strings.TrimSuffix(parts[1], ".git")
+	if owner == "" || repo == "" || strings.HasPrefix(owner, ".") || strings.HasPrefix(repo, ".") {
+		return "", "", false
+	}
+	return owner, repo, true
+}
@@ -0,0 +1,59 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRepositoryURL(t *testing.T) {
+	cases := []struct {
+		AppURL string
+		URL    string
+		Owner  string
+		Repo   string
+	}{
+		{"https://gitea.example.com/", "https://gitea.example.com/owner/repo", "owner", "repo"},
+		{"https://gitea.example.com/", "https://gitea.example.com/owner/repo.git", "owner", "repo"},
+		{"https://gitea.example.com/", "https://gitea.example.com/owner/repo/", "owner", "repo"},
+		{"https://gitea.example.com/", "git+https://gitea.example.com/owner/repo.git", "owner", "repo"},
+		{"https://gitea.example.com/", "https://GITEA.example.com/Owner/Repo", "Owner", "Repo"},
+		{"https://gitea.example.com/", " https://gitea.example.com/owner/repo ", "owner", "repo"},
+		{"http://localhost:3000/", "http://localhost:3000/owner/repo", "owner", "repo"},
+		{"https://example.com/gitea/", "https://example.com/gitea/owner/repo", "owner", "repo"},
+		// near misses
+		{"https://gitea.example.com/", "", "", ""},
+		{"https://gitea.example.com/", "http://gitea.example.com/owner/repo", "", ""},
+		{"https://gitea.example.com/", "ssh://gitea.example.com/owner/repo", "", ""},
+		{"https://gitea.example.com/", "git+ssh://gitea.example.com/owner/repo", "", ""},
+		{"https://gitea.example.com/", "https://gitea.example.com:8443/owner/repo", "", ""},
+		{"https://gitea.example.com/", "https://gitea.example.com.evil.com/owner/repo", "", ""},
+		{"https://gitea.example.com/", "https://evilgitea.example.com/owner/repo", "", ""},
+		{"https://gitea.example.com/", "https://example.com/owner/repo", "", ""},
+		{"https://gitea.example.com/", "https://gitea.example.com@evil.com/owner/repo", "", ""},
+		{"https://gitea.example.com/", "https://user@gitea.example.com/owner/repo", "", ""},
+		{"https://gitea.example.com/", "https://gitea.example.com/owner", "", ""},
+		{"https://gitea.example.com/", "https://gitea.example.com/owner/", "", ""},
+		{"https://gitea.example.com/", "https://gitea.example.com//repo", "", ""},
+		{"https://gitea.example.com/", "https://gitea.example.com/owner/repo/src/branch/main", "", ""},
+		{"https://gitea.example.com/", "https://gitea.example.com/owner/repo?tab=readme", "", ""},
+		{"https://gitea.example.com/", "https://gitea.example.com/owner/repo#readme", "", ""},
+		{"https://gitea.example.com/", "https://gitea.example.com/owner/.git", "", ""},
+		{"https://gitea.example.com/", "https://gitea.example.com/../owner/repo", "", ""},
+		{"https://gitea.example.com/", "gitea.example.com/owner/repo", "", ""},
+		{"https://gitea.example.com/", "github:owner/repo", "", ""},
+		{"https://example.com/gitea/", "https://example.com/owner/repo", "", ""},
+		{"https://example.com/gitea/", "https://example.com/gitea-other/owner/repo", "", ""},
+	}
+
+	for _, c := range cases {
+		owner, repo, ok := ParseRepositoryURL(c.AppURL, c.URL)
+		assert.Equal(t, c.Owner != "", ok, "%s", c.URL)
+		assert.Equal(t, c.Owner, owner, "%s", c.URL)
+		assert.Equal(t, c.Repo, repo, "%s", c.URL)
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+)
+
+// pep440PrereleasePattern matches the pre-release and development release segments of PEP 440 versions, e.g. 1.0a1, 1.0rc2 or 1.0.dev3
+var pep440PrereleasePattern = regexp.MustCompile(`(?i)[0-9][._-]?(a|alpha|b|beta|c|rc|pre|preview|dev)[._-]?[0-9]*([._+-]|$)`)
+
+// IsPrerelease checks if the version is a prerelease by the rules of the ecosystem of the package type.
+// Semantic versions are prereleases if they have a prerelease part, RubyGems versions if they contain a letter
+// and PyPI versions if they have a PEP 440 pre-release or development release segment.
+// Versions of package types without a prerelease concept are never prereleases.
+func IsPrerelease(packageType, v string) bool {
+	switch packageType {
+	case "composer", "helm", "npm", "nuget", "pub", "vagrant":
+		sv, err := version.NewSemver(v)
+		return err == nil && sv.Prerelease() != ""
+	case "rubygems":
+		return strings.IndexFunc(v, func(r rune) bool {
+			return ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z')
+		}) != -1
+	case "pypi":
+		if i := strings.IndexByte(v, '+'); i != -1 {
+			v = v[:i]
+		}
+		return pep440PrereleasePattern.MatchString(v)
+	}
+	return false
+}
+
+// CompareVersions compares two versions and returns -1, 0 or 1 if a is lower, equal or greater than b.
+// Semantic versions are compared with semver precedence: prereleases are lower than the release and build metadata is ignored.
+// If one of the versions is not a semantic version, both are compared lexically.
+func CompareVersions(a, b string) int {
+	va, errA := version.NewSemver(a)
+	vb, errB := version.NewSemver(b)
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b)
+	}
+	return va.Compare(vb)
+}
@@ -0,0 +1,87 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+)
+
+// Keywords which can be used instead of a range expression
+const (
+	VersionRangeStable     = "stable"
+	VersionRangePrerelease = "prerelease"
+)
+
+// VersionRange matches semantic versions against a range expression
+type VersionRange struct {
+	keyword     string
+	constraints version.Constraints
+}
+
+// ParseVersionRange parses a range expression. Supported are the keywords "stable" and "prerelease",
+// a tilde range like "~1.2" which matches all versions with the same minor version (or the same major version if only the major version is given)
+// and comma separated constraints like ">=1.0.0, <2.0.0". Constraints without a prerelease never match prerelease versions.
+func ParseVersionRange(expr string) (*VersionRange, error) {
+	expr = strings.TrimSpace(expr)
+
+	switch strings.ToLower(expr) {
+	case VersionRangeStable, VersionRangePrerelease:
+		return &VersionRange{keyword: strings.ToLower(expr)}, nil
+	}
+
+	if strings.HasPrefix(expr, "~") && !strings.HasPrefix(expr, "~>") {
+		var err error
+		expr, err = tildeToConstraints(strings.TrimSpace(expr[1:]))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	constraints, err := version.NewConstraint(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &VersionRange{constraints: constraints}, nil
+}
+
+func tildeToConstraints(v string) (string, error) {
+	lower, err := version.NewVersion(v)
+	if err != nil {
+		return "", err
+	}
+
+	segments := lower.Segments()
+	core := v
+	if i := strings.IndexAny(core, "-+"); i != -1 {
+		core = core[:i]
+	}
+
+	var upper string
+	if strings.Count(core, ".") == 0 {
+		upper = fmt.Sprintf("%d.0.0", segments[0]+1)
+	} else {
+		upper = fmt.Sprintf("%d.%d.0", segments[0], segments[1]+1)
+	}
+	return fmt.Sprintf(">= %s, < %s", lower.String(), upper), nil
+}
+
+// Match tests if the version is in the range. Versions which are not valid semantic versions never match.
+func (r *VersionRange) Match(v string) bool {
+	sv, err := version.NewSemver(v)
+	if err != nil {
+		return false
+	}
+
+	switch r.keyword {
+	case VersionRangeStable:
+		return sv.Prerelease() == ""
+	case VersionRangePrerelease:
+		return sv.Prerelease() != ""
+	}
+	return r.constraints.Check(sv)
+}
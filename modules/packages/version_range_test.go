@@ -0,0 +1,71 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseVersionRange(t *testing.T) {
+	for _, expr := range []string{"stable", "Prerelease", ">=1.0.0", ">= 1.0.0, < 2.0.0", "~1.2", "~ 1", "~1.2.3-beta", "~> 1.2", "1.0.0"} {
+		_, err := ParseVersionRange(expr)
+		assert.NoError(t, err, expr)
+	}
+
+	for _, expr := range []string{"", "latest", ">=", "~", "~abc", ">=1.0.0,"} {
+		_, err := ParseVersionRange(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestVersionRangeMatch(t *testing.T) {
+	cases := []struct {
+		Range    string
+		Version  string
+		Expected bool
+	}{
+		{"stable", "1.0.0", true},
+		{"stable", "1.0.0+build.5", true},
+		{"stable", "1.0.0-alpha", false},
+		{"stable", "1.0.0-alpha+build.5", false},
+		{"stable", "not-a-version", false},
+		{"prerelease", "1.0.0", false},
+		{"prerelease", "1.0.0-rc.1", true},
+		{"prerelease", "1.0.0-rc.1+build.5", true},
+		{"prerelease", "latest", false},
+		{">=1.0.0", "0.9.9", false},
+		{">=1.0.0", "1.0.0", true},
+		{">=1.0.0", "1.0.0+build.5", true},
+		{">=1.0.0", "2.3.4", true},
+		{">=1.0.0", "2.0.0-beta", false},
+		{">=1.0.0-alpha", "1.0.0-beta", true},
+		{">=1.0.0-alpha", "1.1.0-beta", false},
+		{">=1.0.0, <2.0.0", "1.9.9", true},
+		{">=1.0.0, <2.0.0", "2.0.0", false},
+		{"~1.2", "1.2.0", true},
+		{"~1.2", "1.2.9", true},
+		{"~1.2", "1.2.9+build.5", true},
+		{"~1.2", "1.3.0", false},
+		{"~1.2", "1.1.9", false},
+		{"~1.2", "1.2.5-rc.1", false},
+		{"~1.2.3", "1.2.2", false},
+		{"~1.2.3", "1.2.3", true},
+		{"~1.2.3", "1.2.10", true},
+		{"~1", "1.9.0", true},
+		{"~1", "2.0.0", false},
+		{"~1", "0.9.0", false},
+		{">=1.0.0", "v1.0.0", true},
+		{">=1.0.0", "release-1", false},
+		{">=1.0.0", "", false},
+	}
+
+	for _, c := range cases {
+		r, err := ParseVersionRange(c.Range)
+		assert.NoError(t, err)
+		assert.Equal(t, c.Expected, r.Match(c.Version), "%s matches %s", c.Range, c.Version)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		A        string
+		B        string
+		Expected int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"1.0.0", "1.0.1", -1},
+		{"1.10.0", "1.9.0", 1},
+		{"1.0.0-alpha", "1.0.0", -1},
+		{"1.0.0", "1.0.0-alpha", 1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-beta", -1},
+		{"1.0.0-beta.2", "1.0.0-beta.11", -1},
+		{"1.0.0-rc.1", "1.0.0", -1},
+		{"1.0.0+build.1", "1.0.0", 0},
+		{"1.0.0+build.1", "1.0.0+build.2", 0},
+		{"1.0.0-alpha+build.1", "1.0.0-alpha", 0},
+		{"1.0.0+build.1", "1.0.1", -1},
+		{"abc", "abd", -1},
+		{"latest", "1.0.0", 1},
+		{"1.0.0", "latest", -1},
+		{"nightly", "nightly", 0},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.Expected, CompareVersions(c.A, c.B), "%s <=> %s", c.A, c.B)
+	}
+}
+
+func TestIsPrerelease(t *testing.T) {
+	cases := []struct {
+		Type       string
+		Version    string
+		Prerelease bool
+	}{
+		{"npm", "1.2.5", false},
+		{"npm", "1.3.0-rc.1", true},
+		{"npm", "1.3.0+build.1", false},
+		{"nuget", "1.0.0-beta", true},
+		{"helm", "0.1.0-alpha.2", true},
+		{"composer", "dev-main", false},
+		{"rubygems", "1.0.0", false},
+		{"rubygems", "1.0.0.pre", true},
+		{"rubygems", "2.0.0.rc1", true},
+		{"pypi", "1.0", false},
+		{"pypi", "1.0.post1", false},
+		{"pypi", "1.0a1", true},
+		{"pypi", "1.0.0rc2", true},
+		{"pypi", "1.0.dev3", true},
+		{"pypi", "1.0+local.beta", false},
+		{"maven", "1.0.0-SNAPSHOT", false},
+		{"generic", "1.0.0-rc.1", false},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.Prerelease, IsPrerelease(c.Type, c.Version), "%s %s", c.Type, c.Version)
+	}
+}
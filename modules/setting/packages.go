@@ -8,22 +8,51 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"code.gitea.io/gitea/modules/log"
+
+	"github.com/dustin/go-humanize"
+	ini "gopkg.in/ini.v1"
 )
 
 // Package registry settings
 var (
 	Packages = struct {
 		Storage
-		Enabled           bool
-		ChunkedUploadPath string
-		RegistryHost      string
+		Enabled                 bool
+		ChunkedUploadPath       string
+		UploadSessionTimeout    time.Duration `ini:"-"`
+		RegistryHost            string
+		DefaultOwnerQuota       int64                        `ini:"-"`
+		TypeLimits              map[string]PackageTypeLimits `ini:"-"`
+		ImmutableMavenSnapshots bool
 	}{
-		Enabled: true,
+		Enabled:              true,
+		UploadSessionTimeout: 24 * time.Hour,
+		DefaultOwnerQuota:    -1,
+		TypeLimits:           map[string]PackageTypeLimits{},
 	}
 )
 
+// PackageTypeLimits contains the default limits of a package type. -1 means no limit
+type PackageTypeLimits struct {
+	MaxVersions    int64
+	MaxVersionSize int64
+	MaxOwnerSize   int64
+	// PruneVersions deletes the oldest versions of a package instead of rejecting a new version which exceeds MaxVersions
+	PruneVersions bool
+}
+
+// GetTypeLimits returns the default limits of a package type
+func GetTypeLimits(packageType string) PackageTypeLimits {
+	if limits, ok := Packages.TypeLimits[packageType]; ok {
+		return limits
+	}
+	return PackageTypeLimits{MaxVersions: -1, MaxVersionSize: -1, MaxOwnerSize: -1}
+}
+
 func newPackages() {
 	sec := Cfg.Section("packages")
 	if err := sec.MapTo(&Packages); err != nil {
@@ -40,7 +69,53 @@ func newPackages() {
 		Packages.ChunkedUploadPath = filepath.ToSlash(filepath.Join(AppDataPath, Packages.ChunkedUploadPath))
 	}
 
+	Packages.UploadSessionTimeout = sec.Key("UPLOAD_SESSION_TIMEOUT").MustDuration(24 * time.Hour)
+
+	Packages.DefaultOwnerQuota = mustBytes(sec, "DEFAULT_OWNER_QUOTA")
+
+	Packages.TypeLimits = map[string]PackageTypeLimits{}
+	for _, key := range sec.Keys() {
+		var packageType string
+		var limits PackageTypeLimits
+		switch name := key.Name(); {
+		case strings.HasPrefix(name, "LIMIT_VERSIONS_"):
+			packageType = strings.ToLower(strings.TrimPrefix(name, "LIMIT_VERSIONS_"))
+			limits = GetTypeLimits(packageType)
+			limits.MaxVersions = key.MustInt64(-1)
+		case strings.HasPrefix(name, "LIMIT_VERSION_SIZE_"):
+			packageType = strings.ToLower(strings.TrimPrefix(name, "LIMIT_VERSION_SIZE_"))
+			limits = GetTypeLimits(packageType)
+			limits.MaxVersionSize = mustBytes(sec, name)
+		case strings.HasPrefix(name, "LIMIT_OWNER_SIZE_"):
+			packageType = strings.ToLower(strings.TrimPrefix(name, "LIMIT_OWNER_SIZE_"))
+			limits = GetTypeLimits(packageType)
+			limits.MaxOwnerSize = mustBytes(sec, name)
+		case strings.HasPrefix(name, "PRUNE_VERSIONS_"):
+			packageType = strings.ToLower(strings.TrimPrefix(name, "PRUNE_VERSIONS_"))
+			limits = GetTypeLimits(packageType)
+			limits.PruneVersions = key.MustBool(false)
+		default:
+			continue
+		}
+		Packages.TypeLimits[packageType] = limits
+	}
+
 	if err := os.MkdirAll(Packages.ChunkedUploadPath, os.ModePerm); err != nil {
 		log.Error("Unable to create chunked upload directory: %s (%v)", Packages.ChunkedUploadPath, err)
 	}
 }
+
+func mustBytes(section *ini.Section, key string) int64 {
+	const noLimit = "-1"
+
+	value := section.Key(key).MustString(noLimit)
+	if value == noLimit {
+		return -1
+	}
+	bytes, err := humanize.ParseBytes(value)
+	if err != nil {
+		log.Error("Failed to parse %s.%s: %v", section.Name(), key, err)
+		return -1
+	}
+	return int64(bytes)
+}
@@ -442,6 +442,7 @@ type PackagePayload struct {
 	Action       HookPackageAction `json:"action"`
 	Repository   *Repository       `json:"repository"`
 	Package      *Package          `json:"package"`
+	Files        []*PackageFile    `json:"files"`
 	Organization *User             `json:"organization"`
 	Sender       *User             `json:"sender"`
 }
@@ -10,24 +10,167 @@ import (
 
 // Package represents a package
 type Package struct {
-	ID         int64       `json:"id"`
-	Owner      *User       `json:"owner"`
-	Repository *Repository `json:"repository"`
-	Creator    *User       `json:"creator"`
-	Type       string      `json:"type"`
-	Name       string      `json:"name"`
-	Version    string      `json:"version"`
+	ID             int64       `json:"id"`
+	Owner          *User       `json:"owner"`
+	Repository     *Repository `json:"repository"`
+	Creator        *User       `json:"creator"`
+	PackageCreator *User       `json:"package_creator"`
+	Type           string      `json:"type"`
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
 	// swagger:strfmt date-time
 	CreatedAt time.Time `json:"created_at"`
+	// swagger:strfmt date-time
+	LastDownloadAt *time.Time `json:"last_download_at"`
+	// Visibility is the visibility setting of the package, either inherit or private
+	Visibility string `json:"visibility"`
+	// Private is set if the package is private by its visibility setting or because the linked repository is private
+	Private bool `json:"private"`
+	// Readme is the raw README of the package version. It is only set when a single package version is requested.
+	Readme string `json:"readme,omitempty"`
+	// ReleaseNotes are the raw markdown release notes of the package version
+	ReleaseNotes string `json:"release_notes,omitempty"`
 }
 
 // PackageFile represents a package file
 type PackageFile struct {
-	ID         int64 `json:"id"`
-	Size       int64
-	Name       string `json:"name"`
-	HashMD5    string `json:"md5"`
-	HashSHA1   string `json:"sha1"`
-	HashSHA256 string `json:"sha256"`
-	HashSHA512 string `json:"sha512"`
+	ID            int64 `json:"id"`
+	Size          int64
+	Name          string `json:"name"`
+	HashMD5       string `json:"md5"`
+	HashSHA1      string `json:"sha1"`
+	HashSHA256    string `json:"sha256"`
+	HashSHA512    string `json:"sha512"`
+	DownloadCount int64  `json:"download_count"`
+}
+
+// PackageSizeSummary represents the storage used by the packages of a type.
+// Package files share blobs with identical content, so the physical size can be smaller than the logical size.
+type PackageSizeSummary struct {
+	// Type of the packages, empty for the summary of all package types
+	Type string `json:"type"`
+	// LogicalSize is the sum of the sizes of all package files
+	LogicalSize int64 `json:"logical_size"`
+	// PhysicalSize is the sum of the sizes of the stored blobs
+	PhysicalSize int64 `json:"physical_size"`
+	// SavedSize is the size saved by sharing blobs
+	SavedSize int64 `json:"saved_size"`
+	// swagger:strfmt date-time
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PackageTypeCount represents the number of packages of a type
+type PackageTypeCount struct {
+	Type  string `json:"type"`
+	Count int64  `json:"count"`
+}
+
+// PackageTypeStats represents the package statistics of an owner for a package type
+type PackageTypeStats struct {
+	Type         string `json:"type"`
+	PackageCount int64  `json:"package_count"`
+	VersionCount int64  `json:"version_count"`
+	FileCount    int64  `json:"file_count"`
+	// LogicalSize is the sum of the sizes of all files
+	LogicalSize int64 `json:"logical_size"`
+	// PhysicalSize is the sum of the sizes of the distinct blobs
+	PhysicalSize int64 `json:"physical_size"`
+}
+
+// OwnerPackageStats represents the package statistics of an owner
+type OwnerPackageStats struct {
+	PackageCount int64 `json:"package_count"`
+	VersionCount int64 `json:"version_count"`
+	FileCount    int64 `json:"file_count"`
+	// LogicalSize is the sum of the sizes of all files
+	LogicalSize int64 `json:"logical_size"`
+	// PhysicalSize is the sum of the sizes of the distinct blobs, it counts towards the quota
+	PhysicalSize int64 `json:"physical_size"`
+	// SizeLimit is the package storage quota of the owner, -1 if the storage is unlimited
+	SizeLimit int64               `json:"size_limit"`
+	Types     []*PackageTypeStats `json:"types"`
+}
+
+// PackageInventoryEntry represents a package with the storage used by its files
+type PackageInventoryEntry struct {
+	ID      int64  `json:"id"`
+	OwnerID int64  `json:"owner_id"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	RepoID  int64  `json:"repository_id"`
+	// TotalSize is the sum of the sizes of the distinct blobs referenced by the files of the package
+	TotalSize int64 `json:"total_size"`
+	// swagger:strfmt date-time
+	LastPublishedAt time.Time `json:"last_published_at"`
+}
+
+// TransferPackageOption options when transferring a package's ownership
+// swagger:model
+type TransferPackageOption struct {
+	// required: true
+	NewOwner string `json:"new_owner" binding:"Required"`
+}
+
+// SetPackageVisibilityOption options when changing the visibility of a package
+// swagger:model
+type SetPackageVisibilityOption struct {
+	// required: true
+	// enum: inherit,private
+	Visibility string `json:"visibility" binding:"Required;In(inherit,private)"`
+}
+
+// EditPackageReleaseNotesOption options when changing the release notes of a package version
+// swagger:model
+type EditPackageReleaseNotesOption struct {
+	// An empty value removes the release notes
+	ReleaseNotes string `json:"release_notes"`
+}
+
+// CopyPackageOption options when copying a package version to another owner
+// swagger:model
+type CopyPackageOption struct {
+	// required: true
+	TargetOwner string `json:"target_owner" binding:"Required"`
+}
+
+// BulkDeletePackageVersionsOption options when deleting the versions of a package which match a filter
+// swagger:model
+type BulkDeletePackageVersionsOption struct {
+	// VersionPattern is a regular expression which must match the whole version, case-insensitive
+	VersionPattern string `json:"version_pattern"`
+	// CreatedBefore deletes only versions created before the time
+	// swagger:strfmt date-time
+	CreatedBefore *time.Time `json:"created_before"`
+	// KeepLatest is the number of the newest matching versions which are kept
+	KeepLatest int `json:"keep_latest"`
+	// DryRun lists the matching versions without deleting them.
+	// Versions can only be deleted if at least one of the other fields is set.
+	DryRun bool `json:"dry_run"`
+}
+
+// PackageBulkDeleteResult represents the versions deleted by a bulk deletion
+type PackageBulkDeleteResult struct {
+	Versions  []string `json:"versions"`
+	FileCount int64    `json:"file_count"`
+	// FreedSize is the size of the blobs which are not referenced anymore after the deletion
+	FreedSize int64 `json:"freed_size"`
+	DryRun    bool  `json:"dry_run"`
+}
+
+// PackageAudit represents an entry of the package audit log
+type PackageAudit struct {
+	ID      int64 `json:"id"`
+	OwnerID int64 `json:"owner_id"`
+	// ActorID is the id of the user who executed the operation, 0 for operations executed by the system
+	ActorID int64 `json:"actor_id"`
+	// Action is one of publish, delete_version, delete_package, link_repository, transfer or rename
+	Action      string `json:"action"`
+	PackageID   int64  `json:"package_id"`
+	PackageType string `json:"package_type"`
+	PackageName string `json:"package_name"`
+	VersionID   int64  `json:"version_id"`
+	Version     string `json:"version"`
+	Details     string `json:"details"`
+	// swagger:strfmt date-time
+	CreatedAt time.Time `json:"created_at"`
 }
@@ -10,6 +10,7 @@ import (
 	"regexp"
 	"strings"
 
+	packages_model "code.gitea.io/gitea/models/packages"
 	"code.gitea.io/gitea/models/perm"
 	"code.gitea.io/gitea/modules/context"
 	"code.gitea.io/gitea/modules/setting"
@@ -69,7 +70,7 @@ func Routes(ctx gocontext.Context) *web.Route {
 			r.Get("/p2/{vendorname}/{projectname}.json", composer.PackageMetadata)
 			r.Get("/files/{package}/{version}/{filename}", composer.DownloadPackageFile)
 			r.Put("", reqPackageAccess(perm.AccessModeWrite), composer.UploadPackage)
-		})
+		}, context.PackageTypeAssignment(packages_model.TypeComposer))
 		r.Group("/conan", func() {
 			r.Group("/v1", func() {
 				r.Get("/ping", conan.Ping)
@@ -157,7 +158,7 @@ func Routes(ctx gocontext.Context) *web.Route {
 					}, conan.ExtractPathParameters)
 				})
 			})
-		})
+		}, context.PackageTypeAssignment(packages_model.TypeConan))
 		r.Group("/generic", func() {
 			r.Group("/{packagename}/{packageversion}", func() {
 				r.Delete("", reqPackageAccess(perm.AccessModeWrite), generic.DeletePackage)
@@ -165,20 +166,21 @@ func Routes(ctx gocontext.Context) *web.Route {
 					r.Get("", generic.DownloadPackageFile)
 					r.Group("", func() {
 						r.Put("", generic.UploadPackage)
+						r.Patch("", generic.UploadPackageChunk)
 						r.Delete("", generic.DeletePackageFile)
 					}, reqPackageAccess(perm.AccessModeWrite))
 				})
 			})
-		})
+		}, context.PackageTypeAssignment(packages_model.TypeGeneric))
 		r.Group("/helm", func() {
 			r.Get("/index.yaml", helm.Index)
 			r.Get("/{filename}", helm.DownloadPackageFile)
 			r.Post("/api/charts", reqPackageAccess(perm.AccessModeWrite), helm.UploadPackage)
-		})
+		}, context.PackageTypeAssignment(packages_model.TypeHelm))
 		r.Group("/maven", func() {
 			r.Put("/*", reqPackageAccess(perm.AccessModeWrite), maven.UploadPackageFile)
 			r.Get("/*", maven.DownloadPackageFile)
-		})
+		}, context.PackageTypeAssignment(packages_model.TypeMaven))
 		r.Group("/nuget", func() {
 			r.Get("/index.json", nuget.ServiceIndex)
 			r.Get("/query", nuget.SearchService)
@@ -196,7 +198,7 @@ func Routes(ctx gocontext.Context) *web.Route {
 				r.Delete("/{id}/{version}", nuget.DeletePackage)
 			}, reqPackageAccess(perm.AccessModeWrite))
 			r.Get("/symbols/{filename}/{guid:[0-9a-f]{32}}FFFFFFFF/{filename2}", nuget.DownloadSymbolFile)
-		})
+		}, context.PackageTypeAssignment(packages_model.TypeNuGet))
 		r.Group("/npm", func() {
 			r.Group("/@{scope}/{id}", func() {
 				r.Get("", npm.PackageMetadata)
@@ -236,7 +238,7 @@ func Routes(ctx gocontext.Context) *web.Route {
 					r.Delete("", npm.DeletePackageTag)
 				}, reqPackageAccess(perm.AccessModeWrite))
 			})
-		})
+		}, context.PackageTypeAssignment(packages_model.TypeNpm))
 		r.Group("/pub", func() {
 			r.Group("/api/packages", func() {
 				r.Group("/versions/new", func() {
@@ -250,12 +252,12 @@ func Routes(ctx gocontext.Context) *web.Route {
 					r.Get("/{version}", pub.PackageVersionMetadata)
 				})
 			})
-		})
+		}, context.PackageTypeAssignment(packages_model.TypePub))
 		r.Group("/pypi", func() {
 			r.Post("/", reqPackageAccess(perm.AccessModeWrite), pypi.UploadPackageFile)
 			r.Get("/files/{id}/{version}/{filename}", pypi.DownloadPackageFile)
 			r.Get("/simple/{id}", pypi.PackageMetadata)
-		})
+		}, context.PackageTypeAssignment(packages_model.TypePyPI))
 		r.Group("/rubygems", func() {
 			r.Get("/specs.4.8.gz", rubygems.EnumeratePackages)
 			r.Get("/latest_specs.4.8.gz", rubygems.EnumeratePackagesLatest)
@@ -266,7 +268,7 @@ func Routes(ctx gocontext.Context) *web.Route {
 				r.Post("/", rubygems.UploadPackageFile)
 				r.Delete("/yank", rubygems.DeletePackage)
 			}, reqPackageAccess(perm.AccessModeWrite))
-		})
+		}, context.PackageTypeAssignment(packages_model.TypeRubyGems))
 		r.Group("/vagrant", func() {
 			r.Group("/authenticate", func() {
 				r.Get("", vagrant.CheckAuthenticate)
@@ -279,7 +281,7 @@ func Routes(ctx gocontext.Context) *web.Route {
 					r.Put("", reqPackageAccess(perm.AccessModeWrite), vagrant.UploadPackageFile)
 				})
 			})
-		})
+		}, context.PackageTypeAssignment(packages_model.TypeVagrant))
 	}, context_service.UserAssignmentWeb(), context.PackageAssignment(), reqPackageAccess(perm.AccessModeRead))
 
 	return r
@@ -446,7 +448,7 @@ func ContainerRoutes(ctx gocontext.Context) *web.Route {
 
 			ctx.Status(http.StatusNotFound)
 		})
-	}, container.ReqContainerAccess, context_service.UserAssignmentWeb(), context.PackageAssignment(), reqPackageAccess(perm.AccessModeRead))
+	}, container.ReqContainerAccess, context_service.UserAssignmentWeb(), context.PackageAssignment(), context.PackageTypeAssignment(packages_model.TypeContainer), reqPackageAccess(perm.AccessModeRead))
 
 	return r
 }
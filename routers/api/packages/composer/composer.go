@@ -63,11 +63,12 @@ func SearchPackages(ctx *context.Context) {
 	}
 
 	opts := &packages_model.PackageSearchOptions{
-		OwnerID:    ctx.Package.Owner.ID,
-		Type:       packages_model.TypeComposer,
-		Name:       packages_model.SearchValue{Value: ctx.FormTrim("q")},
-		IsInternal: util.OptionalBoolFalse,
-		Paginator:  &paginator,
+		OwnerID:     ctx.Package.Owner.ID,
+		Type:        packages_model.TypeComposer,
+		Name:        packages_model.SearchValue{Value: ctx.FormTrim("q")},
+		IsInternal:  util.OptionalBoolFalse,
+		HidePrivate: !ctx.Package.CanReadPrivate,
+		Paginator:   &paginator,
 	}
 	if ctx.FormTrim("type") != "" {
 		opts.Properties = map[string]string{
@@ -100,7 +101,7 @@ func SearchPackages(ctx *context.Context) {
 		nextLink = u.String()
 	}
 
-	pds, err := packages_model.GetPackageDescriptors(ctx, pvs)
+	pds, err := packages_model.GetPackageDescriptors(ctx, pvs, packages_model.DescriptorLoadOptions{})
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
@@ -122,7 +123,14 @@ func EnumeratePackages(ctx *context.Context) {
 
 	names := make([]string, 0, len(ps))
 	for _, p := range ps {
-		names = append(names, p.Name)
+		visible, err := helper.IsPackageVisible(ctx, p)
+		if err != nil {
+			apiError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		if visible {
+			names = append(names, p.Name)
+		}
 	}
 
 	ctx.JSON(http.StatusOK, map[string][]string{
@@ -141,12 +149,17 @@ func PackageMetadata(ctx *context.Context) {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
+	pvs, err = helper.FilterVisibleVersions(ctx, pvs)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
 	if len(pvs) == 0 {
 		apiError(ctx, http.StatusNotFound, packages_model.ErrPackageNotExist)
 		return
 	}
 
-	pds, err := packages_model.GetPackageDescriptors(ctx, pvs)
+	pds, err := packages_model.GetPackageDescriptors(ctx, pvs, packages_model.DescriptorLoadOptions{})
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
@@ -162,6 +175,15 @@ func PackageMetadata(ctx *context.Context) {
 
 // DownloadPackageFile serves the content of a package
 func DownloadPackageFile(ctx *context.Context) {
+	if err := helper.CheckPackageVisible(ctx, packages_model.TypeComposer, ctx.Params("package")); err != nil {
+		if err == packages_model.ErrPackageNotExist {
+			apiError(ctx, http.StatusNotFound, err)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
 	s, pf, err := packages_service.GetFileStreamByPackageNameAndVersion(
 		ctx,
 		&packages_service.PackageInfo{
@@ -240,6 +262,14 @@ func UploadPackage(ctx *context.Context) {
 		},
 	)
 	if err != nil {
+		if packages_service.IsErrQuotaExceeded(err) || packages_service.IsErrTypeLimitExceeded(err) {
+			apiError(ctx, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+		if packages_model.IsErrInvalidMetadata(err) {
+			apiError(ctx, http.StatusBadRequest, err)
+			return
+		}
 		if err == packages_model.ErrDuplicatePackageVersion {
 			apiError(ctx, http.StatusBadRequest, err)
 			return
@@ -84,6 +84,15 @@ func ExtractPathParameters(ctx *context.Context) {
 		return
 	}
 
+	if err := helper.CheckPackageVisible(ctx, packages_model.TypeConan, rref.Name); err != nil {
+		if err == packages_model.ErrPackageNotExist {
+			apiError(ctx, http.StatusNotFound, err)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
 	ctx.Data[recipeReferenceKey] = rref
 
 	reference := ctx.Params("package_reference")
@@ -389,6 +398,14 @@ func uploadFile(ctx *context.Context, fileFilter stringSet, fileKey string) {
 					apiError(ctx, http.StatusInternalServerError, err)
 					return
 				}
+				if err := packages_service.SetVersionKeywords(ctx, pv.ID, metadata); err != nil {
+					apiError(ctx, http.StatusInternalServerError, err)
+					return
+				}
+				if err := packages_service.SetPackageDescription(ctx, pv.PackageID, metadata); err != nil {
+					apiError(ctx, http.StatusInternalServerError, err)
+					return
+				}
 			} else {
 				pci.Metadata = metadata
 			}
@@ -418,10 +435,22 @@ func uploadFile(ctx *context.Context, fileFilter stringSet, fileKey string) {
 		pfci,
 	)
 	if err != nil {
+		if packages_service.IsErrQuotaExceeded(err) || packages_service.IsErrTypeLimitExceeded(err) {
+			apiError(ctx, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+		if packages_model.IsErrInvalidMetadata(err) {
+			apiError(ctx, http.StatusBadRequest, err)
+			return
+		}
 		if err == packages_model.ErrDuplicatePackageFile {
 			apiError(ctx, http.StatusBadRequest, err)
 			return
 		}
+		if err == packages_service.ErrVersionImmutable {
+			apiError(ctx, http.StatusConflict, err)
+			return
+		}
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
@@ -485,6 +514,8 @@ func DeleteRecipeV1(ctx *context.Context) {
 	if err := deleteRecipeOrPackage(ctx, rref, true, nil, false); err != nil {
 		if err == packages_model.ErrPackageNotExist || err == conan_model.ErrPackageReferenceNotExist {
 			apiError(ctx, http.StatusNotFound, err)
+		} else if err == packages_service.ErrVersionImmutable || err == packages_service.ErrVersionProtected {
+			apiError(ctx, http.StatusConflict, err)
 		} else {
 			apiError(ctx, http.StatusInternalServerError, err)
 		}
@@ -500,6 +531,8 @@ func DeleteRecipeV2(ctx *context.Context) {
 	if err := deleteRecipeOrPackage(ctx, rref, rref.Revision == "", nil, false); err != nil {
 		if err == packages_model.ErrPackageNotExist || err == conan_model.ErrPackageReferenceNotExist {
 			apiError(ctx, http.StatusNotFound, err)
+		} else if err == packages_service.ErrVersionImmutable || err == packages_service.ErrVersionProtected {
+			apiError(ctx, http.StatusConflict, err)
 		} else {
 			apiError(ctx, http.StatusInternalServerError, err)
 		}
@@ -547,6 +580,8 @@ func DeletePackageV1(ctx *context.Context) {
 			if err := deleteRecipeOrPackage(ctx, currentRref, true, pref, true); err != nil {
 				if err == packages_model.ErrPackageNotExist || err == conan_model.ErrPackageReferenceNotExist {
 					apiError(ctx, http.StatusNotFound, err)
+				} else if err == packages_service.ErrVersionImmutable || err == packages_service.ErrVersionProtected {
+					apiError(ctx, http.StatusConflict, err)
 				} else {
 					apiError(ctx, http.StatusInternalServerError, err)
 				}
@@ -566,6 +601,8 @@ func DeletePackageV2(ctx *context.Context) {
 		if err := deleteRecipeOrPackage(ctx, rref, false, pref, pref.Revision == ""); err != nil {
 			if err == packages_model.ErrPackageNotExist || err == conan_model.ErrPackageReferenceNotExist {
 				apiError(ctx, http.StatusNotFound, err)
+			} else if err == packages_service.ErrVersionImmutable || err == packages_service.ErrVersionProtected {
+				apiError(ctx, http.StatusConflict, err)
 			} else {
 				apiError(ctx, http.StatusInternalServerError, err)
 			}
@@ -591,6 +628,8 @@ func DeletePackageV2(ctx *context.Context) {
 		if err := deleteRecipeOrPackage(ctx, rref, false, pref, true); err != nil {
 			if err == packages_model.ErrPackageNotExist || err == conan_model.ErrPackageReferenceNotExist {
 				apiError(ctx, http.StatusNotFound, err)
+			} else if err == packages_service.ErrVersionImmutable || err == packages_service.ErrVersionProtected {
+				apiError(ctx, http.StatusConflict, err)
 			} else {
 				apiError(ctx, http.StatusInternalServerError, err)
 			}
@@ -618,6 +657,10 @@ func deleteRecipeOrPackage(apictx *context.Context, rref *conan_module.RecipeRef
 		return err
 	}
 
+	if err := packages_service.CheckVersionDeletable(ctx, apictx.Doer, pd.Package, pv, apictx.FormBool("force")); err != nil {
+		return err
+	}
+
 	filter := map[string]string{
 		conan_module.PropertyRecipeUser:    rref.User,
 		conan_module.PropertyRecipeChannel: rref.Channel,
@@ -667,6 +710,10 @@ func deleteRecipeOrPackage(apictx *context.Context, rref *conan_module.RecipeRef
 		if err := packages_model.DeleteVersionByID(ctx, pv.ID); err != nil {
 			return err
 		}
+
+		if err := packages_service.InsertAuditEntry(ctx, apictx.Doer, packages_model.AuditActionDeleteVersion, pd.Package, pv, ""); err != nil {
+			return err
+		}
 	}
 
 	if err := committer.Commit(); err != nil {
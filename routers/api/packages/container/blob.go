@@ -13,6 +13,7 @@ import (
 	"code.gitea.io/gitea/models/db"
 	packages_model "code.gitea.io/gitea/models/packages"
 	container_model "code.gitea.io/gitea/models/packages/container"
+	user_model "code.gitea.io/gitea/models/user"
 	"code.gitea.io/gitea/modules/log"
 	packages_module "code.gitea.io/gitea/modules/packages"
 	container_module "code.gitea.io/gitea/modules/packages/container"
@@ -21,10 +22,10 @@ import (
 
 // saveAsPackageBlob creates a package blob from an upload
 // The uploaded blob gets stored in a special upload version to link them to the package/image
-func saveAsPackageBlob(hsr packages_module.HashedSizeReader, pi *packages_service.PackageInfo) (*packages_model.PackageBlob, error) {
+func saveAsPackageBlob(hsr packages_module.HashedSizeReader, pi *packages_service.PackageInfo, doer *user_model.User) (*packages_model.PackageBlob, error) {
 	pb := packages_service.NewPackageBlob(hsr)
 
-	exists := false
+	blobCreated := false
 
 	contentStore := packages_module.NewContentStore()
 
@@ -32,6 +33,7 @@ func saveAsPackageBlob(hsr packages_module.HashedSizeReader, pi *packages_servic
 		created := true
 		p := &packages_model.Package{
 			OwnerID:   pi.Owner.ID,
+			CreatorID: doer.ID,
 			Type:      packages_model.TypeContainer,
 			Name:      strings.ToLower(pi.Name),
 			LowerName: strings.ToLower(pi.Name),
@@ -68,18 +70,25 @@ func saveAsPackageBlob(hsr packages_module.HashedSizeReader, pi *packages_servic
 			}
 		}
 
-		pb, exists, err = packages_model.GetOrInsertBlob(ctx, pb)
+		pb, blobCreated, err = packages_model.GetOrInsertBlob(ctx, pb)
 		if err != nil {
 			log.Error("Error inserting package blob: %v", err)
 			return err
 		}
-		if !exists {
+		if blobCreated {
 			if err := contentStore.Save(packages_module.BlobHash256Key(pb.HashSHA256), hsr, hsr.Size()); err != nil {
 				log.Error("Error saving package blob in content store: %v", err)
 				return err
 			}
 		}
 
+		if err := packages_service.CheckQuota(ctx, pi.Owner.ID, pb); err != nil {
+			return err
+		}
+		if err := packages_service.CheckTypeLimits(ctx, p, pv, false, pb); err != nil {
+			return err
+		}
+
 		filename := strings.ToLower(fmt.Sprintf("sha256_%s", pb.HashSHA256))
 
 		pf := &packages_model.PackageFile{
@@ -105,7 +114,7 @@ func saveAsPackageBlob(hsr packages_module.HashedSizeReader, pi *packages_servic
 		return nil
 	})
 	if err != nil {
-		if !exists {
+		if blobCreated {
 			if err := contentStore.Delete(packages_module.BlobHash256Key(pb.HashSHA256)); err != nil {
 				log.Error("Error deleting package blob from content store: %v", err)
 			}
@@ -26,7 +26,6 @@ import (
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/routers/api/packages/helper"
 	packages_service "code.gitea.io/gitea/services/packages"
-	container_service "code.gitea.io/gitea/services/packages/container"
 )
 
 // maximum size of a container manifest
@@ -117,10 +116,20 @@ func ReqContainerAccess(ctx *context.Context) {
 	}
 }
 
-// VerifyImageName is a middleware which checks if the image name is allowed
+// VerifyImageName is a middleware which checks if the image name is allowed and the image is visible to the doer
 func VerifyImageName(ctx *context.Context) {
-	if !imageNamePattern.MatchString(ctx.Params("image")) {
+	image := ctx.Params("image")
+	if !imageNamePattern.MatchString(image) {
 		apiErrorDefined(ctx, errNameInvalid)
+		return
+	}
+
+	if err := helper.CheckPackageVisible(ctx, packages_model.TypeContainer, image); err != nil {
+		if err == packages_model.ErrPackageNotExist {
+			apiErrorDefined(ctx, errNameUnknown)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
 	}
 }
 
@@ -221,7 +230,15 @@ func InitiateUploadBlob(ctx *context.Context) {
 			return
 		}
 
-		if _, err := saveAsPackageBlob(buf, &packages_service.PackageInfo{Owner: ctx.Package.Owner, Name: image}); err != nil {
+		if _, err := saveAsPackageBlob(buf, &packages_service.PackageInfo{Owner: ctx.Package.Owner, Name: image}, ctx.Doer); err != nil {
+			if packages_service.IsErrQuotaExceeded(err) || packages_service.IsErrTypeLimitExceeded(err) {
+				apiError(ctx, http.StatusRequestEntityTooLarge, err)
+				return
+			}
+			if packages_model.IsErrInvalidMetadata(err) {
+				apiError(ctx, http.StatusBadRequest, err)
+				return
+			}
 			apiError(ctx, http.StatusInternalServerError, err)
 			return
 		}
@@ -234,7 +251,7 @@ func InitiateUploadBlob(ctx *context.Context) {
 		return
 	}
 
-	upload, err := packages_model.CreateBlobUpload(ctx)
+	upload, err := packages_service.CreateUploadSession(ctx, &packages_service.PackageInfo{Owner: ctx.Package.Owner, PackageType: packages_model.TypeContainer, Name: image}, "", ctx.Doer)
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
@@ -252,9 +269,9 @@ func InitiateUploadBlob(ctx *context.Context) {
 func UploadBlob(ctx *context.Context) {
 	image := ctx.Params("image")
 
-	uploader, err := container_service.NewBlobUploader(ctx, ctx.Params("uuid"))
+	uploader, err := openUploadSession(ctx, image)
 	if err != nil {
-		if err == packages_model.ErrPackageBlobUploadNotExist {
+		if err == packages_model.ErrPackageUploadSessionNotExist {
 			apiErrorDefined(ctx, errBlobUploadUnknown)
 		} else {
 			apiError(ctx, http.StatusInternalServerError, err)
@@ -280,7 +297,7 @@ func UploadBlob(ctx *context.Context) {
 		return
 	}
 
-	if err := uploader.Append(ctx, ctx.Req.Body); err != nil {
+	if err := uploader.Append(ctx, uploader.Size(), ctx.Req.Body); err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
@@ -303,24 +320,19 @@ func EndUploadBlob(ctx *context.Context) {
 		return
 	}
 
-	uploader, err := container_service.NewBlobUploader(ctx, ctx.Params("uuid"))
+	uploader, err := openUploadSession(ctx, image)
 	if err != nil {
-		if err == packages_model.ErrPackageBlobUploadNotExist {
+		if err == packages_model.ErrPackageUploadSessionNotExist {
 			apiErrorDefined(ctx, errBlobUploadUnknown)
 		} else {
 			apiError(ctx, http.StatusInternalServerError, err)
 		}
 		return
 	}
-	close := true
-	defer func() {
-		if close {
-			uploader.Close()
-		}
-	}()
+	defer uploader.Close()
 
 	if ctx.Req.Body != nil {
-		if err := uploader.Append(ctx, ctx.Req.Body); err != nil {
+		if err := uploader.Append(ctx, uploader.Size(), ctx.Req.Body); err != nil {
 			apiError(ctx, http.StatusInternalServerError, err)
 			return
 		}
@@ -331,18 +343,18 @@ func EndUploadBlob(ctx *context.Context) {
 		return
 	}
 
-	if _, err := saveAsPackageBlob(uploader, &packages_service.PackageInfo{Owner: ctx.Package.Owner, Name: image}); err != nil {
-		apiError(ctx, http.StatusInternalServerError, err)
-		return
-	}
-
-	if err := uploader.Close(); err != nil {
-		apiError(ctx, http.StatusInternalServerError, err)
-		return
-	}
-	close = false
-
-	if err := container_service.RemoveBlobUploadByID(ctx, uploader.ID); err != nil {
+	if err := uploader.Complete(ctx, func(hsr packages_module.HashedSizeReader) error {
+		_, err := saveAsPackageBlob(hsr, &packages_service.PackageInfo{Owner: ctx.Package.Owner, Name: image}, ctx.Doer)
+		return err
+	}); err != nil {
+		if packages_service.IsErrQuotaExceeded(err) || packages_service.IsErrTypeLimitExceeded(err) {
+			apiError(ctx, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+		if packages_model.IsErrInvalidMetadata(err) {
+			apiError(ctx, http.StatusBadRequest, err)
+			return
+		}
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
@@ -354,6 +366,19 @@ func EndUploadBlob(ctx *context.Context) {
 	})
 }
 
+// openUploadSession opens the upload session of the request which must belong to the image
+func openUploadSession(ctx *context.Context, image string) (*packages_service.UploadSession, error) {
+	uploader, err := packages_service.OpenUploadSession(ctx, ctx.Params("uuid"))
+	if err != nil {
+		return nil, err
+	}
+	if uploader.OwnerID != ctx.Package.Owner.ID || uploader.Type != packages_model.TypeContainer || uploader.PackageName != image {
+		uploader.Close()
+		return nil, packages_model.ErrPackageUploadSessionNotExist
+	}
+	return uploader, nil
+}
+
 func getBlobFromContext(ctx *context.Context) (*packages_model.PackageFileDescriptor, error) {
 	digest := ctx.Params("digest")
 
@@ -474,6 +499,12 @@ func UploadManifest(ctx *context.Context) {
 			apiErrorDefined(ctx, namedError)
 		} else if errors.Is(err, container_model.ErrContainerBlobNotExist) {
 			apiErrorDefined(ctx, errBlobUnknown)
+		} else if packages_service.IsErrTypeLimitExceeded(err) {
+			apiError(ctx, http.StatusRequestEntityTooLarge, err)
+		} else if err == packages_service.ErrVersionImmutable {
+			apiErrorDefined(ctx, errDenied)
+		} else if packages_model.IsErrInvalidMetadata(err) || err == packages_model.ErrReleaseNotesTooLong {
+			apiErrorDefined(ctx, errManifestInvalid.WithMessage(err.Error()))
 		} else {
 			apiError(ctx, http.StatusInternalServerError, err)
 		}
@@ -587,7 +618,11 @@ func DeleteManifest(ctx *context.Context) {
 	}
 
 	for _, pv := range pvs {
-		if err := packages_service.RemovePackageVersion(ctx.Doer, pv); err != nil {
+		if err := packages_service.RemovePackageVersion(ctx.Doer, pv, false); err != nil {
+			if err == packages_service.ErrVersionImmutable || err == packages_service.ErrVersionProtected {
+				apiErrorDefined(ctx, errDenied)
+				return
+			}
 			apiError(ctx, http.StatusInternalServerError, err)
 			return
 		}
@@ -602,12 +637,11 @@ func DeleteManifest(ctx *context.Context) {
 func GetTagList(ctx *context.Context) {
 	image := ctx.Params("image")
 
-	if _, err := packages_model.GetPackageByName(ctx, ctx.Package.Owner.ID, packages_model.TypeContainer, image); err != nil {
-		if err == packages_model.ErrPackageNotExist {
-			apiErrorDefined(ctx, errNameUnknown)
-		} else {
-			apiError(ctx, http.StatusInternalServerError, err)
-		}
+	if exists, err := packages_model.ExistsPackage(ctx, ctx.Package.Owner.ID, packages_model.TypeContainer, image); err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	} else if !exists {
+		apiErrorDefined(ctx, errNameUnknown)
 		return
 	}
 
@@ -8,6 +8,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 
 	"code.gitea.io/gitea/models/db"
@@ -16,6 +17,7 @@ import (
 	user_model "code.gitea.io/gitea/models/user"
 	"code.gitea.io/gitea/modules/json"
 	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/notification"
 	packages_module "code.gitea.io/gitea/modules/packages"
 	container_module "code.gitea.io/gitea/modules/packages/container"
 	"code.gitea.io/gitea/modules/packages/container/oci"
@@ -31,6 +33,8 @@ type manifestCreationInfo struct {
 	Reference  string
 	IsTagged   bool
 	Properties map[string]string
+	// ReleaseNotes are read from the AnnotationReleaseNotes annotation of the manifest
+	ReleaseNotes string
 }
 
 func processManifest(mci *manifestCreationInfo, buf *packages_module.HashedBuffer) (string, error) {
@@ -72,6 +76,7 @@ func processImageManifest(mci *manifestCreationInfo, buf *packages_module.Hashed
 		if err := json.NewDecoder(buf).Decode(&manifest); err != nil {
 			return err
 		}
+		mci.ReleaseNotes = manifest.Annotations[container_module.AnnotationReleaseNotes]
 
 		if _, err := buf.Seek(0, io.SeekStart); err != nil {
 			return err
@@ -146,6 +151,10 @@ func processImageManifest(mci *manifestCreationInfo, buf *packages_module.Hashed
 			}
 		}
 
+		if err := setImageProperties(ctx, pv, metadata, blobReferences); err != nil {
+			return err
+		}
+
 		pb, created, digest, err := createManifestBlob(ctx, mci, pv, buf)
 		removeBlob := false
 		defer func() {
@@ -166,6 +175,10 @@ func processImageManifest(mci *manifestCreationInfo, buf *packages_module.Hashed
 			return err
 		}
 
+		if err := notifyPackageCreate(mci.Creator, pv); err != nil {
+			return err
+		}
+
 		manifestDigest = digest
 
 		return nil
@@ -185,6 +198,7 @@ func processImageManifestIndex(mci *manifestCreationInfo, buf *packages_module.H
 		if err := json.NewDecoder(buf).Decode(&index); err != nil {
 			return err
 		}
+		mci.ReleaseNotes = index.Annotations[container_module.AnnotationReleaseNotes]
 
 		if _, err := buf.Seek(0, io.SeekStart); err != nil {
 			return err
@@ -255,6 +269,10 @@ func processImageManifestIndex(mci *manifestCreationInfo, buf *packages_module.H
 			return err
 		}
 
+		if err := notifyPackageCreate(mci.Creator, pv); err != nil {
+			return err
+		}
+
 		manifestDigest = digest
 
 		return nil
@@ -266,10 +284,22 @@ func processImageManifestIndex(mci *manifestCreationInfo, buf *packages_module.H
 	return manifestDigest, nil
 }
 
+func notifyPackageCreate(doer *user_model.User, pv *packages_model.PackageVersion) error {
+	pd, err := packages_model.GetPackageDescriptor(db.DefaultContext, pv)
+	if err != nil {
+		return err
+	}
+
+	notification.NotifyPackageCreate(doer, pd)
+
+	return nil
+}
+
 func createPackageAndVersion(ctx context.Context, mci *manifestCreationInfo, metadata *container_module.Metadata) (*packages_model.PackageVersion, error) {
 	created := true
 	p := &packages_model.Package{
 		OwnerID:   mci.Owner.ID,
+		CreatorID: mci.Creator.ID,
 		Type:      packages_model.TypeContainer,
 		Name:      strings.ToLower(mci.Image),
 		LowerName: strings.ToLower(mci.Image),
@@ -293,21 +323,36 @@ func createPackageAndVersion(ctx context.Context, mci *manifestCreationInfo, met
 
 	metadata.IsTagged = mci.IsTagged
 
+	if len(mci.ReleaseNotes) > packages_model.MaxReleaseNotesLength {
+		return nil, packages_model.ErrReleaseNotesTooLong
+	}
+
 	metadataJSON, err := json.Marshal(metadata)
 	if err != nil {
 		return nil, err
 	}
+	normalizedMetadataJSON, err := packages_model.NormalizeMetadata(packages_model.TypeContainer, string(metadataJSON))
+	if err != nil {
+		return nil, err
+	}
 
 	_pv := &packages_model.PackageVersion{
 		PackageID:    p.ID,
 		CreatorID:    mci.Creator.ID,
 		Version:      strings.ToLower(mci.Reference),
 		LowerVersion: strings.ToLower(mci.Reference),
-		MetadataJSON: string(metadataJSON),
+		MetadataJSON: normalizedMetadataJSON,
+		ReleaseNotes: mci.ReleaseNotes,
 	}
 	var pv *packages_model.PackageVersion
+	isNewVersion := true
 	if pv, err = packages_model.GetOrInsertVersion(ctx, _pv); err != nil {
 		if err == packages_model.ErrDuplicatePackageVersion {
+			isNewVersion = false
+			// a digest reference always points to the same manifest, only tags can be overwritten
+			if mci.IsTagged && packages_service.IsVersionImmutable(p, pv) {
+				return nil, packages_service.ErrVersionImmutable
+			}
 			if err := packages_service.DeletePackageVersionAndReferences(ctx, pv); err != nil {
 				return nil, err
 			}
@@ -325,7 +370,22 @@ func createPackageAndVersion(ctx context.Context, mci *manifestCreationInfo, met
 		}
 	}
 
+	if err := packages_service.CheckTypeLimits(ctx, p, pv, isNewVersion, nil); err != nil {
+		return nil, err
+	}
+
+	if isNewVersion {
+		if err := packages_service.InsertAuditEntry(ctx, mci.Creator, packages_model.AuditActionPublish, p, pv, ""); err != nil {
+			return nil, err
+		}
+	}
+
 	if mci.IsTagged {
+		// untagged manifests are usually the platform specific parts of a tagged image index
+		if err := packages_service.SetPackageDescription(ctx, p.ID, metadata); err != nil {
+			log.Error("Error setting package description: %v", err)
+			return nil, err
+		}
 		if _, err := packages_model.InsertProperty(ctx, packages_model.PropertyTypeVersion, pv.ID, container_module.PropertyManifestTagged, ""); err != nil {
 			log.Error("Error setting package version property: %v", err)
 			return nil, err
@@ -341,6 +401,34 @@ func createPackageAndVersion(ctx context.Context, mci *manifestCreationInfo, met
 	return pv, nil
 }
 
+// setImageProperties stores the size and the creation time of an image as version properties, so they can be used in range queries
+func setImageProperties(ctx context.Context, pv *packages_model.PackageVersion, metadata *container_module.Metadata, refs []*blobReference) error {
+	var size int64
+	seen := make(map[oci.Digest]bool, len(refs))
+	for _, ref := range refs {
+		// the same filesystem layer may be referenced multiple times
+		if seen[ref.Digest] {
+			continue
+		}
+		seen[ref.Digest] = true
+		size += ref.ExpectedSize
+	}
+
+	props := map[string]string{
+		container_module.PropertyImageSize: strconv.FormatInt(size, 10),
+	}
+	if metadata.Created != nil {
+		props[container_module.PropertyImageCreated] = strconv.FormatInt(metadata.Created.Unix(), 10)
+	}
+	for name, value := range props {
+		if _, err := packages_model.InsertProperty(ctx, packages_model.PropertyTypeVersion, pv.ID, name, value); err != nil {
+			log.Error("Error setting package version property: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
 type blobReference struct {
 	Digest       oci.Digest
 	MediaType    oci.MediaType
@@ -398,12 +486,12 @@ func createFileFromBlobReference(ctx context.Context, pv, uploadVersion *package
 }
 
 func createManifestBlob(ctx context.Context, mci *manifestCreationInfo, pv *packages_model.PackageVersion, buf *packages_module.HashedBuffer) (*packages_model.PackageBlob, bool, string, error) {
-	pb, exists, err := packages_model.GetOrInsertBlob(ctx, packages_service.NewPackageBlob(buf))
+	pb, created, err := packages_model.GetOrInsertBlob(ctx, packages_service.NewPackageBlob(buf))
 	if err != nil {
 		log.Error("Error inserting package blob: %v", err)
 		return nil, false, "", err
 	}
-	if !exists {
+	if created {
 		contentStore := packages_module.NewContentStore()
 		if err := contentStore.Save(packages_module.BlobHash256Key(pb.HashSHA256), buf, buf.Size()); err != nil {
 			log.Error("Error saving package blob in content store: %v", err)
@@ -421,5 +509,5 @@ func createManifestBlob(ctx context.Context, mci *manifestCreationInfo, pv *pack
 		IsLead:       true,
 	})
 
-	return pb, !exists, manifestDigest, err
+	return pb, created, manifestDigest, err
 }
@@ -6,8 +6,12 @@ package generic
 
 import (
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 
 	packages_model "code.gitea.io/gitea/models/packages"
@@ -31,6 +35,15 @@ func apiError(ctx *context.Context, status int, obj interface{}) {
 
 // DownloadPackageFile serves the specific generic package.
 func DownloadPackageFile(ctx *context.Context) {
+	if err := helper.CheckPackageVisible(ctx, packages_model.TypeGeneric, ctx.Params("packagename")); err != nil {
+		if err == packages_model.ErrPackageNotExist {
+			apiError(ctx, http.StatusNotFound, err)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
 	s, pf, err := packages_service.GetFileStreamByPackageNameAndVersion(
 		ctx,
 		&packages_service.PackageInfo{
@@ -56,20 +69,34 @@ func DownloadPackageFile(ctx *context.Context) {
 	ctx.ServeContent(pf.Name, s, pf.CreatedUnix.AsLocalTime())
 }
 
-// UploadPackage uploads the specific generic package.
-// Duplicated packages get rejected.
-func UploadPackage(ctx *context.Context) {
+// packageInfoFromUploadRequest validates the package name, version and filename of an upload request
+func packageInfoFromUploadRequest(ctx *context.Context) (*packages_service.PackageInfo, string, error) {
 	packageName := ctx.Params("packagename")
 	filename := ctx.Params("filename")
 
 	if !packageNameRegex.MatchString(packageName) || !filenameRegex.MatchString(filename) {
-		apiError(ctx, http.StatusBadRequest, errors.New("Invalid package name or filename"))
-		return
+		return nil, "", errors.New("Invalid package name or filename")
 	}
 
 	packageVersion := ctx.Params("packageversion")
 	if packageVersion != strings.TrimSpace(packageVersion) {
-		apiError(ctx, http.StatusBadRequest, errors.New("Invalid package version"))
+		return nil, "", errors.New("Invalid package version")
+	}
+
+	return &packages_service.PackageInfo{
+		Owner:       ctx.Package.Owner,
+		PackageType: packages_model.TypeGeneric,
+		Name:        packageName,
+		Version:     packageVersion,
+	}, filename, nil
+}
+
+// UploadPackage uploads the specific generic package.
+// Duplicated packages get rejected.
+func UploadPackage(ctx *context.Context) {
+	pi, filename, err := packageInfoFromUploadRequest(ctx)
+	if err != nil {
+		apiError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
@@ -90,34 +117,174 @@ func UploadPackage(ctx *context.Context) {
 	}
 	defer buf.Close()
 
+	if err := createPackageFile(ctx, pi, filename, buf); err != nil {
+		handleCreatePackageFileError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusCreated)
+}
+
+// UploadPackageChunk uploads a chunk of the specific generic package file.
+// The chunk is described by the Content-Range header (e.g. "bytes 0-1023/4096") and must start at the end of the already received data.
+// A chunk starting at 0 (re)starts the upload. The file is created when the last chunk is received.
+func UploadPackageChunk(ctx *context.Context) {
+	pi, filename, err := packageInfoFromUploadRequest(ctx)
+	if err != nil {
+		apiError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	start, end, total, err := parseContentRange(ctx.Req.Header.Get("Content-Range"))
+	if err != nil {
+		apiError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	pus, err := packages_model.GetUploadSessionForFile(ctx, pi.Owner.ID, pi.PackageType, pi.Name, pi.Version, filename)
+	if err != nil && err != packages_model.ErrPackageUploadSessionNotExist {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if start == 0 {
+		if pus != nil {
+			if err := packages_service.AbortUploadSession(ctx, pus.ID); err != nil {
+				apiError(ctx, http.StatusInternalServerError, err)
+				return
+			}
+		}
+		pus, err = packages_service.CreateUploadSession(ctx, pi, filename, ctx.Doer)
+		if err != nil {
+			apiError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+	} else if pus == nil {
+		apiError(ctx, http.StatusRequestedRangeNotSatisfiable, packages_model.ErrPackageUploadSessionNotExist)
+		return
+	}
+
+	session, err := packages_service.OpenUploadSession(ctx, pus.ID)
+	if err != nil {
+		if err == packages_model.ErrPackageUploadSessionNotExist {
+			apiError(ctx, http.StatusRequestedRangeNotSatisfiable, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	defer session.Close()
+
+	if err := session.Append(ctx, start, io.LimitReader(ctx.Req.Body, end-start+1)); err != nil {
+		if err == packages_service.ErrUploadOffsetMismatch {
+			setReceivedRangeHeader(ctx, session.Size())
+			apiError(ctx, http.StatusRequestedRangeNotSatisfiable, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if session.Size() != end+1 {
+		setReceivedRangeHeader(ctx, session.Size())
+		apiError(ctx, http.StatusBadRequest, errors.New("Content-Range does not match the size of the chunk"))
+		return
+	}
+
+	if total == -1 || session.Size() < total {
+		setReceivedRangeHeader(ctx, session.Size())
+		ctx.Status(http.StatusAccepted)
+		return
+	}
+
+	if err := session.Complete(ctx, func(hsr packages_module.HashedSizeReader) error {
+		return createPackageFile(ctx, pi, filename, hsr)
+	}); err != nil {
+		handleCreatePackageFileError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusCreated)
+}
+
+// parseContentRange parses a Content-Range header of the form "bytes start-end/total".
+// total is -1 if the complete size is unknown ("bytes start-end/*").
+func parseContentRange(contentRange string) (start, end, total int64, err error) {
+	errInvalid := errors.New("Invalid or missing Content-Range header")
+
+	if !strings.HasPrefix(contentRange, "bytes ") {
+		return 0, 0, 0, errInvalid
+	}
+	rangeSpec, totalSpec, ok := strings.Cut(strings.TrimPrefix(contentRange, "bytes "), "/")
+	if !ok {
+		return 0, 0, 0, errInvalid
+	}
+	startSpec, endSpec, ok := strings.Cut(rangeSpec, "-")
+	if !ok {
+		return 0, 0, 0, errInvalid
+	}
+	if start, err = strconv.ParseInt(startSpec, 10, 64); err != nil || start < 0 {
+		return 0, 0, 0, errInvalid
+	}
+	if end, err = strconv.ParseInt(endSpec, 10, 64); err != nil || end < start {
+		return 0, 0, 0, errInvalid
+	}
+	if totalSpec == "*" {
+		return start, end, -1, nil
+	}
+	if total, err = strconv.ParseInt(totalSpec, 10, 64); err != nil || total <= end {
+		return 0, 0, 0, errInvalid
+	}
+	return start, end, total, nil
+}
+
+// setReceivedRangeHeader tells the client which bytes were received so far
+func setReceivedRangeHeader(ctx *context.Context, size int64) {
+	if size > 0 {
+		ctx.Resp.Header().Set("Range", fmt.Sprintf("bytes=0-%d", size-1))
+	}
+}
+
+// errInvalidReleaseNotes indicates that the release notes header is not properly percent-encoded
+var errInvalidReleaseNotes = errors.New("Invalid X-Release-Notes header")
+
+// createPackageFile adds the file to the version. If the version gets created,
+// the percent-encoded X-Release-Notes header of the request is stored as its release notes.
+func createPackageFile(ctx *context.Context, pi *packages_service.PackageInfo, filename string, data packages_module.HashedSizeReader) error {
+	releaseNotes, err := url.PathUnescape(ctx.Req.Header.Get("X-Release-Notes"))
+	if err != nil {
+		return errInvalidReleaseNotes
+	}
+
 	_, _, err = packages_service.CreatePackageOrAddFileToExisting(
 		&packages_service.PackageCreationInfo{
-			PackageInfo: packages_service.PackageInfo{
-				Owner:       ctx.Package.Owner,
-				PackageType: packages_model.TypeGeneric,
-				Name:        packageName,
-				Version:     packageVersion,
-			},
-			Creator: ctx.Doer,
+			PackageInfo:  *pi,
+			Creator:      ctx.Doer,
+			ReleaseNotes: releaseNotes,
 		},
 		&packages_service.PackageFileCreationInfo{
 			PackageFileInfo: packages_service.PackageFileInfo{
 				Filename: filename,
 			},
-			Data:   buf,
+			Data:   data,
 			IsLead: true,
 		},
 	)
-	if err != nil {
-		if err == packages_model.ErrDuplicatePackageFile {
-			apiError(ctx, http.StatusConflict, err)
-			return
-		}
-		apiError(ctx, http.StatusInternalServerError, err)
+	return err
+}
+
+func handleCreatePackageFileError(ctx *context.Context, err error) {
+	if packages_service.IsErrQuotaExceeded(err) || packages_service.IsErrTypeLimitExceeded(err) {
+		apiError(ctx, http.StatusRequestEntityTooLarge, err)
 		return
 	}
-
-	ctx.Status(http.StatusCreated)
+	if packages_model.IsErrInvalidMetadata(err) || err == packages_model.ErrReleaseNotesTooLong || err == errInvalidReleaseNotes {
+		apiError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if err == packages_model.ErrDuplicatePackageFile {
+		apiError(ctx, http.StatusConflict, err)
+		return
+	}
+	apiError(ctx, http.StatusInternalServerError, err)
 }
 
 // DeletePackage deletes the specific generic package.
@@ -136,6 +303,10 @@ func DeletePackage(ctx *context.Context) {
 			apiError(ctx, http.StatusNotFound, err)
 			return
 		}
+		if err == packages_service.ErrVersionImmutable || err == packages_service.ErrVersionProtected {
+			apiError(ctx, http.StatusConflict, err)
+			return
+		}
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
@@ -167,6 +338,16 @@ func DeletePackageFile(ctx *context.Context) {
 		return
 	}
 
+	p, err := packages_model.GetPackageByID(ctx, pv.PackageID, false)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if packages_service.IsVersionImmutable(p, pv) && !ctx.IsUserSiteAdmin() {
+		apiError(ctx, http.StatusConflict, packages_service.ErrVersionImmutable)
+		return
+	}
+
 	pfs, err := packages_model.GetFilesByVersionID(ctx, pv.ID)
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
@@ -174,7 +355,11 @@ func DeletePackageFile(ctx *context.Context) {
 	}
 
 	if len(pfs) == 1 {
-		if err := packages_service.RemovePackageVersion(ctx.Doer, pv); err != nil {
+		if err := packages_service.RemovePackageVersion(ctx.Doer, pv, false); err != nil {
+			if err == packages_service.ErrVersionProtected {
+				apiError(ctx, http.StatusConflict, err)
+				return
+			}
 			apiError(ctx, http.StatusInternalServerError, err)
 			return
 		}
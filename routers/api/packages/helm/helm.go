@@ -40,9 +40,10 @@ func apiError(ctx *context.Context, status int, obj interface{}) {
 // Index generates the Helm charts index
 func Index(ctx *context.Context) {
 	pvs, _, err := packages_model.SearchVersions(ctx, &packages_model.PackageSearchOptions{
-		OwnerID:    ctx.Package.Owner.ID,
-		Type:       packages_model.TypeHelm,
-		IsInternal: util.OptionalBoolFalse,
+		OwnerID:     ctx.Package.Owner.ID,
+		Type:        packages_model.TypeHelm,
+		IsInternal:  util.OptionalBoolFalse,
+		HidePrivate: !ctx.Package.CanReadPrivate,
 	})
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
@@ -111,6 +112,7 @@ func DownloadPackageFile(ctx *context.Context) {
 		},
 		HasFileWithName: filename,
 		IsInternal:      util.OptionalBoolFalse,
+		HidePrivate:     !ctx.Package.CanReadPrivate,
 	})
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
@@ -192,10 +194,22 @@ func UploadPackage(ctx *context.Context) {
 		},
 	)
 	if err != nil {
+		if packages_service.IsErrQuotaExceeded(err) || packages_service.IsErrTypeLimitExceeded(err) {
+			apiError(ctx, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+		if packages_model.IsErrInvalidMetadata(err) {
+			apiError(ctx, http.StatusBadRequest, err)
+			return
+		}
 		if err == packages_model.ErrDuplicatePackageVersion {
 			apiError(ctx, http.StatusConflict, err)
 			return
 		}
+		if err == packages_service.ErrVersionImmutable {
+			apiError(ctx, http.StatusConflict, err)
+			return
+		}
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"net/http"
 
+	packages_model "code.gitea.io/gitea/models/packages"
 	"code.gitea.io/gitea/modules/context"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/setting"
@@ -36,3 +37,65 @@ func LogAndProcessError(ctx *context.Context, status int, obj interface{}, cb fu
 		cb(message)
 	}
 }
+
+// IsPackageVisible checks if the package is visible to the doer.
+// Private packages are only visible if the doer can read the private packages of the owner.
+func IsPackageVisible(ctx *context.Context, p *packages_model.Package) (bool, error) {
+	if ctx.Package.CanReadPrivate {
+		return true, nil
+	}
+	private, err := packages_model.IsPackagePrivate(ctx, p)
+	return !private, err
+}
+
+// CheckPackageVisible returns packages_model.ErrPackageNotExist if the package with the given name is not visible to the doer.
+// A package which does not exist passes the check, the caller has to handle this case.
+func CheckPackageVisible(ctx *context.Context, packageType packages_model.Type, name string) error {
+	if ctx.Package.CanReadPrivate {
+		return nil
+	}
+
+	p, err := packages_model.GetPackageByName(ctx, ctx.Package.Owner.ID, packageType, name)
+	if err != nil {
+		if err == packages_model.ErrPackageNotExist {
+			return nil
+		}
+		return err
+	}
+
+	visible, err := IsPackageVisible(ctx, p)
+	if err != nil {
+		return err
+	}
+	if !visible {
+		return packages_model.ErrPackageNotExist
+	}
+	return nil
+}
+
+// FilterVisibleVersions removes the versions of the packages which are not visible to the doer
+func FilterVisibleVersions(ctx *context.Context, pvs []*packages_model.PackageVersion) ([]*packages_model.PackageVersion, error) {
+	if ctx.Package.CanReadPrivate {
+		return pvs, nil
+	}
+
+	visibleByPackage := make(map[int64]bool)
+	filtered := make([]*packages_model.PackageVersion, 0, len(pvs))
+	for _, pv := range pvs {
+		visible, has := visibleByPackage[pv.PackageID]
+		if !has {
+			p, err := packages_model.GetPackageByID(ctx, pv.PackageID, false)
+			if err != nil {
+				return nil, err
+			}
+			if visible, err = IsPackageVisible(ctx, p); err != nil {
+				return nil, err
+			}
+			visibleByPackage[pv.PackageID] = visible
+		}
+		if visible {
+			filtered = append(filtered, pv)
+		}
+	}
+	return filtered, nil
+}
@@ -31,16 +31,20 @@ func createMetadataResponse(pds []*packages_model.PackageDescriptor) *MetadataRe
 
 	var release *packages_model.PackageDescriptor
 
+	// yanked versions are listed, but they are not resolved as latest version or release
+	latest := pds[len(pds)-1]
+
 	versions := make([]string, 0, len(pds))
 	for _, pd := range pds {
-		if !strings.HasSuffix(pd.Version.Version, "-SNAPSHOT") {
-			release = pd
+		if !pd.Version.IsYanked {
+			latest = pd
+			if !strings.HasSuffix(pd.Version.Version, "-SNAPSHOT") {
+				release = pd
+			}
 		}
 		versions = append(versions, pd.Version.Version)
 	}
 
-	latest := pds[len(pds)-1]
-
 	metadata := latest.Metadata.(*maven_module.Metadata)
 
 	resp := &MetadataResponse{
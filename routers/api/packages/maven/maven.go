@@ -24,6 +24,7 @@ import (
 	"code.gitea.io/gitea/modules/log"
 	packages_module "code.gitea.io/gitea/modules/packages"
 	maven_module "code.gitea.io/gitea/modules/packages/maven"
+	"code.gitea.io/gitea/modules/timeutil"
 	"code.gitea.io/gitea/routers/api/packages/helper"
 	packages_service "code.gitea.io/gitea/services/packages"
 )
@@ -55,6 +56,15 @@ func DownloadPackageFile(ctx *context.Context) {
 		return
 	}
 
+	if err := helper.CheckPackageVisible(ctx, packages_model.TypeMaven, params.GroupID+"-"+params.ArtifactID); err != nil {
+		if err == packages_model.ErrPackageNotExist {
+			apiError(ctx, http.StatusNotFound, err)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
 	if params.IsMeta && params.Version == "" {
 		serveMavenMetadata(ctx, params)
 	} else {
@@ -76,7 +86,7 @@ func serveMavenMetadata(ctx *context.Context, params parameters) {
 		return
 	}
 
-	pds, err := packages_model.GetPackageDescriptors(ctx, pvs)
+	pds, err := packages_model.GetPackageDescriptors(ctx, pvs, packages_model.DescriptorLoadOptions{})
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
@@ -175,6 +185,12 @@ func servePackageFile(ctx *context.Context, params parameters) {
 		if err := packages_model.IncrementDownloadCounter(ctx, pv.ID); err != nil {
 			log.Error("Error incrementing download counter: %v", err)
 		}
+		if err := packages_model.UpdateVersionLastDownload(ctx, pv.ID, timeutil.TimeStampNow()); err != nil {
+			log.Error("Error updating last download timestamp: %v", err)
+		}
+	}
+	if err := packages_model.IncrementFileDownloadCounter(ctx, pf.ID); err != nil {
+		log.Error("Error incrementing file download counter: %v", err)
 	}
 
 	ctx.ServeContent(pf.Name, s, pf.CreatedUnix.AsLocalTime())
@@ -214,6 +230,9 @@ func UploadPackageFile(ctx *context.Context) {
 		},
 		SemverCompatible: false,
 		Creator:          ctx.Doer,
+		PackageProperties: map[string]string{
+			maven_module.PropertyGroupID: params.GroupID,
+		},
 	}
 
 	ext := filepath.Ext(params.Filename)
@@ -312,10 +331,22 @@ func UploadPackageFile(ctx *context.Context) {
 		pfci,
 	)
 	if err != nil {
+		if packages_service.IsErrQuotaExceeded(err) || packages_service.IsErrTypeLimitExceeded(err) {
+			apiError(ctx, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+		if packages_model.IsErrInvalidMetadata(err) {
+			apiError(ctx, http.StatusBadRequest, err)
+			return
+		}
 		if err == packages_model.ErrDuplicatePackageFile {
 			apiError(ctx, http.StatusBadRequest, err)
 			return
 		}
+		if err == packages_service.ErrVersionImmutable {
+			apiError(ctx, http.StatusConflict, err)
+			return
+		}
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
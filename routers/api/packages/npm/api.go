@@ -54,6 +54,15 @@ func createPackageMetadataVersion(registryURL string, pd *packages_model.Package
 
 	metadata := pd.Metadata.(*npm_module.Metadata)
 
+	// npm shows the deprecation message on install, so it must not be empty
+	var deprecated string
+	if pd.Version.IsYanked {
+		deprecated = pd.Version.YankReason
+		if deprecated == "" {
+			deprecated = "This version is deprecated"
+		}
+	}
+
 	return &npm_module.PackageMetadataVersion{
 		ID:                   fmt.Sprintf("%s@%s", pd.Package.Name, pd.Version.Version),
 		Name:                 pd.Package.Name,
@@ -67,6 +76,7 @@ func createPackageMetadataVersion(registryURL string, pd *packages_model.Package
 		PeerDependencies:     metadata.PeerDependencies,
 		OptionalDependencies: metadata.OptionalDependencies,
 		Readme:               metadata.Readme,
+		Deprecated:           deprecated,
 		Dist: npm_module.PackageDistribution{
 			Shasum:    pd.Files[0].Blob.HashSHA1,
 			Integrity: "sha512-" + base64.StdEncoding.EncodeToString(hashBytes),
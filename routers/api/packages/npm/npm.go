@@ -56,12 +56,17 @@ func PackageMetadata(ctx *context.Context) {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
+	pvs, err = helper.FilterVisibleVersions(ctx, pvs)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
 	if len(pvs) == 0 {
 		apiError(ctx, http.StatusNotFound, err)
 		return
 	}
 
-	pds, err := packages_model.GetPackageDescriptors(ctx, pvs)
+	pds, err := packages_model.GetPackageDescriptors(ctx, pvs, packages_model.DescriptorLoadOptions{})
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
@@ -81,6 +86,15 @@ func DownloadPackageFile(ctx *context.Context) {
 	packageVersion := ctx.Params("version")
 	filename := ctx.Params("filename")
 
+	if err := helper.CheckPackageVisible(ctx, packages_model.TypeNpm, packageName); err != nil {
+		if err == packages_model.ErrPackageNotExist {
+			apiError(ctx, http.StatusNotFound, err)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
 	s, pf, err := packages_service.GetFileStreamByPackageNameAndVersion(
 		ctx,
 		&packages_service.PackageInfo{
@@ -114,6 +128,17 @@ func UploadPackage(ctx *context.Context) {
 		return
 	}
 
+	// reject duplicates before the package data gets buffered and hashed
+	exists, err := packages_model.ExistsVersion(ctx, ctx.Package.Owner.ID, packages_model.TypeNpm, npmPackage.Name, npmPackage.Version)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if exists {
+		apiError(ctx, http.StatusBadRequest, packages_model.ErrDuplicatePackageVersion)
+		return
+	}
+
 	buf, err := packages_module.CreateHashedBufferFromReader(bytes.NewReader(npmPackage.Data), 32*1024*1024)
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
@@ -132,6 +157,7 @@ func UploadPackage(ctx *context.Context) {
 			SemverCompatible: true,
 			Creator:          ctx.Doer,
 			Metadata:         npmPackage.Metadata,
+			ReleaseNotes:     npmPackage.ReleaseNotes,
 		},
 		&packages_service.PackageFileCreationInfo{
 			PackageFileInfo: packages_service.PackageFileInfo{
@@ -142,6 +168,14 @@ func UploadPackage(ctx *context.Context) {
 		},
 	)
 	if err != nil {
+		if packages_service.IsErrQuotaExceeded(err) || packages_service.IsErrTypeLimitExceeded(err) {
+			apiError(ctx, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+		if packages_model.IsErrInvalidMetadata(err) || err == packages_model.ErrReleaseNotesTooLong {
+			apiError(ctx, http.StatusBadRequest, err)
+			return
+		}
 		if err == packages_model.ErrDuplicatePackageVersion {
 			apiError(ctx, http.StatusBadRequest, err)
 			return
@@ -151,15 +185,15 @@ func UploadPackage(ctx *context.Context) {
 	}
 
 	for _, tag := range npmPackage.DistTags {
-		if err := setPackageTag(tag, pv, false); err != nil {
-			if err == errInvalidTagName {
-				apiError(ctx, http.StatusBadRequest, err)
-				return
-			}
-			apiError(ctx, http.StatusInternalServerError, err)
+		if !isValidTagName(tag) {
+			apiError(ctx, http.StatusBadRequest, errInvalidTagName)
 			return
 		}
 	}
+	if err := packages_model.SetTags(ctx, pv.PackageID, pv.ID, npmPackage.DistTags); err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
 
 	ctx.Status(http.StatusCreated)
 }
@@ -189,6 +223,10 @@ func DeletePackageVersion(ctx *context.Context) {
 			apiError(ctx, http.StatusNotFound, err)
 			return
 		}
+		if err == packages_service.ErrVersionImmutable || err == packages_service.ErrVersionProtected {
+			apiError(ctx, http.StatusConflict, err)
+			return
+		}
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
@@ -212,7 +250,11 @@ func DeletePackage(ctx *context.Context) {
 	}
 
 	for _, pv := range pvs {
-		if err := packages_service.RemovePackageVersion(ctx.Doer, pv); err != nil {
+		if err := packages_service.RemovePackageVersion(ctx.Doer, pv, false); err != nil {
+			if err == packages_service.ErrVersionImmutable || err == packages_service.ErrVersionProtected {
+				apiError(ctx, http.StatusConflict, err)
+				return
+			}
 			apiError(ctx, http.StatusInternalServerError, err)
 			return
 		}
@@ -230,6 +272,11 @@ func ListPackageTags(ctx *context.Context) {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
+	pvs, err = helper.FilterVisibleVersions(ctx, pvs)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
 
 	tags := make(map[string]string)
 	for _, pv := range pvs {
@@ -299,12 +346,17 @@ func DeletePackageTag(ctx *context.Context) {
 	}
 }
 
-func setPackageTag(tag string, pv *packages_model.PackageVersion, deleteOnly bool) error {
+// isValidTagName checks if the tag is not empty and can't be confused with a version
+func isValidTagName(tag string) bool {
 	if tag == "" {
-		return errInvalidTagName
+		return false
 	}
 	_, err := version.NewVersion(tag)
-	if err == nil {
+	return err != nil
+}
+
+func setPackageTag(tag string, pv *packages_model.PackageVersion, deleteOnly bool) error {
+	if !isValidTagName(tag) {
 		return errInvalidTagName
 	}
 
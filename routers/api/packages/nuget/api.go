@@ -80,6 +80,7 @@ type CatalogEntry struct {
 	Authors                  string                    `json:"authors"`
 	RequireLicenseAcceptance bool                      `json:"requireLicenseAcceptance"`
 	ProjectURL               string                    `json:"projectURL"`
+	Listed                   bool                      `json:"listed"`
 	DependencyGroups         []*PackageDependencyGroup `json:"dependencyGroups"`
 }
 
@@ -136,6 +137,7 @@ func createRegistrationIndexPageItem(l *linkBuilder, pd *packages_model.PackageD
 			ReleaseNotes:      metadata.ReleaseNotes,
 			Authors:           metadata.Authors,
 			ProjectURL:        metadata.ProjectURL,
+			Listed:            !pd.Version.IsYanked,
 			DependencyGroups:  createDependencyGroups(pd),
 		},
 	}
@@ -175,7 +177,7 @@ type RegistrationLeafResponse struct {
 func createRegistrationLeafResponse(l *linkBuilder, pd *packages_model.PackageDescriptor) *RegistrationLeafResponse {
 	return &RegistrationLeafResponse{
 		Type:                 []string{"Package", "http://schema.nuget.org/catalog#Permalink"},
-		Listed:               true,
+		Listed:               !pd.Version.IsYanked,
 		Published:            pd.Version.CreatedUnix.AsLocalTime(),
 		RegistrationLeafURL:  l.GetRegistrationLeafURL(pd.Package.Name, pd.Version.Version),
 		PackageContentURL:    l.GetPackageDownloadURL(pd.Package.Name, pd.Version.Version),
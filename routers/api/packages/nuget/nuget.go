@@ -40,10 +40,12 @@ func ServiceIndex(ctx *context.Context) {
 // SearchService https://docs.microsoft.com/en-us/nuget/api/search-query-service-resource#search-for-packages
 func SearchService(ctx *context.Context) {
 	pvs, count, err := packages_model.SearchVersions(ctx, &packages_model.PackageSearchOptions{
-		OwnerID:    ctx.Package.Owner.ID,
-		Type:       packages_model.TypeNuGet,
-		Name:       packages_model.SearchValue{Value: ctx.FormTrim("q")},
-		IsInternal: util.OptionalBoolFalse,
+		OwnerID:     ctx.Package.Owner.ID,
+		Type:        packages_model.TypeNuGet,
+		Name:        packages_model.SearchValue{Value: ctx.FormTrim("q")},
+		IsInternal:  util.OptionalBoolFalse,
+		IsYanked:    util.OptionalBoolFalse,
+		HidePrivate: !ctx.Package.CanReadPrivate,
 		Paginator: db.NewAbsoluteListOptions(
 			ctx.FormInt("skip"),
 			ctx.FormInt("take"),
@@ -54,7 +56,7 @@ func SearchService(ctx *context.Context) {
 		return
 	}
 
-	pds, err := packages_model.GetPackageDescriptors(ctx, pvs)
+	pds, err := packages_model.GetPackageDescriptors(ctx, pvs, packages_model.DescriptorLoadOptions{})
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
@@ -78,12 +80,17 @@ func RegistrationIndex(ctx *context.Context) {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
+	pvs, err = helper.FilterVisibleVersions(ctx, pvs)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
 	if len(pvs) == 0 {
 		apiError(ctx, http.StatusNotFound, err)
 		return
 	}
 
-	pds, err := packages_model.GetPackageDescriptors(ctx, pvs)
+	pds, err := packages_model.GetPackageDescriptors(ctx, pvs, packages_model.DescriptorLoadOptions{})
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
@@ -117,6 +124,10 @@ func RegistrationLeaf(ctx *context.Context) {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
+	if pd.IsPrivate() && !ctx.Package.CanReadPrivate {
+		apiError(ctx, http.StatusNotFound, packages_model.ErrPackageNotExist)
+		return
+	}
 
 	resp := createRegistrationLeafResponse(
 		&linkBuilder{setting.AppURL + "api/packages/" + ctx.Package.Owner.Name + "/nuget"},
@@ -135,12 +146,17 @@ func EnumeratePackageVersions(ctx *context.Context) {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
+	pvs, err = helper.FilterVisibleVersions(ctx, pvs)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
 	if len(pvs) == 0 {
 		apiError(ctx, http.StatusNotFound, err)
 		return
 	}
 
-	pds, err := packages_model.GetPackageDescriptors(ctx, pvs)
+	pds, err := packages_model.GetPackageDescriptors(ctx, pvs, packages_model.DescriptorLoadOptions{})
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
@@ -157,6 +173,15 @@ func DownloadPackageFile(ctx *context.Context) {
 	packageVersion := ctx.Params("version")
 	filename := ctx.Params("filename")
 
+	if err := helper.CheckPackageVisible(ctx, packages_model.TypeNuGet, packageName); err != nil {
+		if err == packages_model.ErrPackageNotExist {
+			apiError(ctx, http.StatusNotFound, err)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
 	s, pf, err := packages_service.GetFileStreamByPackageNameAndVersion(
 		ctx,
 		&packages_service.PackageInfo{
@@ -216,6 +241,14 @@ func UploadPackage(ctx *context.Context) {
 		},
 	)
 	if err != nil {
+		if packages_service.IsErrQuotaExceeded(err) || packages_service.IsErrTypeLimitExceeded(err) {
+			apiError(ctx, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+		if packages_model.IsErrInvalidMetadata(err) {
+			apiError(ctx, http.StatusBadRequest, err)
+			return
+		}
 		if err == packages_model.ErrDuplicatePackageVersion {
 			apiError(ctx, http.StatusConflict, err)
 			return
@@ -270,6 +303,14 @@ func UploadSymbolPackage(ctx *context.Context) {
 		},
 	)
 	if err != nil {
+		if packages_service.IsErrQuotaExceeded(err) || packages_service.IsErrTypeLimitExceeded(err) {
+			apiError(ctx, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+		if packages_model.IsErrInvalidMetadata(err) {
+			apiError(ctx, http.StatusBadRequest, err)
+			return
+		}
 		switch err {
 		case packages_model.ErrPackageNotExist:
 			apiError(ctx, http.StatusNotFound, err)
@@ -297,6 +338,14 @@ func UploadSymbolPackage(ctx *context.Context) {
 			},
 		)
 		if err != nil {
+			if packages_service.IsErrQuotaExceeded(err) || packages_service.IsErrTypeLimitExceeded(err) {
+				apiError(ctx, http.StatusRequestEntityTooLarge, err)
+				return
+			}
+			if packages_model.IsErrInvalidMetadata(err) {
+				apiError(ctx, http.StatusBadRequest, err)
+				return
+			}
 			switch err {
 			case packages_model.ErrDuplicatePackageFile:
 				apiError(ctx, http.StatusConflict, err)
@@ -368,6 +417,7 @@ func DownloadSymbolFile(ctx *context.Context) {
 		Properties: map[string]string{
 			nuget_module.PropertySymbolID: strings.ToLower(guid),
 		},
+		HidePrivate: !ctx.Package.CanReadPrivate,
 	})
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
@@ -412,6 +462,10 @@ func DeletePackage(ctx *context.Context) {
 			apiError(ctx, http.StatusNotFound, err)
 			return
 		}
+		if err == packages_service.ErrVersionImmutable || err == packages_service.ErrVersionProtected {
+			apiError(ctx, http.StatusConflict, err)
+			return
+		}
 		apiError(ctx, http.StatusInternalServerError, err)
 	}
 
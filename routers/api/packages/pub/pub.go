@@ -62,6 +62,7 @@ type versionMetadata struct {
 	Version    string      `json:"version"`
 	ArchiveURL string      `json:"archive_url"`
 	Published  time.Time   `json:"published"`
+	Retracted  bool        `json:"retracted,omitempty"`
 	Pubspec    interface{} `json:"pubspec,omitempty"`
 }
 
@@ -70,6 +71,7 @@ func packageDescriptorToMetadata(baseURL string, pd *packages_model.PackageDescr
 		Version:    pd.Version.Version,
 		ArchiveURL: fmt.Sprintf("%s/files/%s.tar.gz", baseURL, url.PathEscape(pd.Version.Version)),
 		Published:  pd.Version.CreatedUnix.AsLocalTime(),
+		Retracted:  pd.Version.IsYanked,
 		Pubspec:    pd.Metadata.(*pub_module.Metadata).Pubspec,
 	}
 }
@@ -87,12 +89,17 @@ func EnumeratePackageVersions(ctx *context.Context) {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
+	pvs, err = helper.FilterVisibleVersions(ctx, pvs)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
 	if len(pvs) == 0 {
 		apiError(ctx, http.StatusNotFound, err)
 		return
 	}
 
-	pds, err := packages_model.GetPackageDescriptors(ctx, pvs)
+	pds, err := packages_model.GetPackageDescriptors(ctx, pvs, packages_model.DescriptorLoadOptions{})
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
@@ -104,6 +111,15 @@ func EnumeratePackageVersions(ctx *context.Context) {
 
 	baseURL := fmt.Sprintf("%s/%s", baseURL(ctx), url.PathEscape(pds[0].Package.Name))
 
+	// retracted versions are not resolved as latest version
+	latest := pds[0]
+	for _, pd := range pds {
+		if !pd.Version.IsYanked {
+			latest = pd
+			break
+		}
+	}
+
 	versions := make([]*versionMetadata, 0, len(pds))
 	for _, pd := range pds {
 		versions = append(versions, packageDescriptorToMetadata(baseURL, pd))
@@ -111,7 +127,7 @@ func EnumeratePackageVersions(ctx *context.Context) {
 
 	jsonResponse(ctx, http.StatusOK, &packageVersions{
 		Name:     pds[0].Package.Name,
-		Latest:   packageDescriptorToMetadata(baseURL, pds[0]),
+		Latest:   packageDescriptorToMetadata(baseURL, latest),
 		Versions: versions,
 	})
 }
@@ -136,6 +152,10 @@ func PackageVersionMetadata(ctx *context.Context) {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
+	if pd.IsPrivate() && !ctx.Package.CanReadPrivate {
+		apiError(ctx, http.StatusNotFound, packages_model.ErrPackageNotExist)
+		return
+	}
 
 	jsonResponse(ctx, http.StatusOK, packageDescriptorToMetadata(
 		fmt.Sprintf("%s/%s", baseURL(ctx), url.PathEscape(pd.Package.Name)),
@@ -204,6 +224,14 @@ func UploadPackageFile(ctx *context.Context) {
 		},
 	)
 	if err != nil {
+		if packages_service.IsErrQuotaExceeded(err) || packages_service.IsErrTypeLimitExceeded(err) {
+			apiError(ctx, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+		if packages_model.IsErrInvalidMetadata(err) {
+			apiError(ctx, http.StatusBadRequest, err)
+			return
+		}
 		if err == packages_model.ErrDuplicatePackageVersion {
 			apiError(ctx, http.StatusBadRequest, err)
 			return
@@ -221,15 +249,15 @@ func FinalizePackage(ctx *context.Context) {
 	packageName := ctx.Params("id")
 	packageVersion := ctx.Params("version")
 
-	_, err := packages_model.GetVersionByNameAndVersion(ctx, ctx.Package.Owner.ID, packages_model.TypePub, packageName, packageVersion)
+	exists, err := packages_model.ExistsVersion(ctx, ctx.Package.Owner.ID, packages_model.TypePub, packageName, packageVersion)
 	if err != nil {
-		if err == packages_model.ErrPackageNotExist {
-			apiError(ctx, http.StatusNotFound, err)
-			return
-		}
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
+	if !exists {
+		apiError(ctx, http.StatusNotFound, packages_model.ErrPackageNotExist)
+		return
+	}
 
 	type Success struct {
 		Message string `json:"message"`
@@ -261,6 +289,10 @@ func DownloadPackageFile(ctx *context.Context) {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
+	if pd.IsPrivate() && !ctx.Package.CanReadPrivate {
+		apiError(ctx, http.StatusNotFound, packages_model.ErrPackageNotExist)
+		return
+	}
 
 	pf := pd.Files[0].File
 
@@ -43,12 +43,17 @@ func PackageMetadata(ctx *context.Context) {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
+	pvs, err = helper.FilterVisibleVersions(ctx, pvs)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
 	if len(pvs) == 0 {
 		apiError(ctx, http.StatusNotFound, err)
 		return
 	}
 
-	pds, err := packages_model.GetPackageDescriptors(ctx, pvs)
+	pds, err := packages_model.GetPackageDescriptors(ctx, pvs, packages_model.DescriptorLoadOptions{})
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
@@ -66,6 +71,15 @@ func DownloadPackageFile(ctx *context.Context) {
 	packageVersion := ctx.Params("version")
 	filename := ctx.Params("filename")
 
+	if err := helper.CheckPackageVisible(ctx, packages_model.TypePyPI, packageName); err != nil {
+		if err == packages_model.ErrPackageNotExist {
+			apiError(ctx, http.StatusNotFound, err)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
 	s, pf, err := packages_service.GetFileStreamByPackageNameAndVersion(
 		ctx,
 		&packages_service.PackageInfo{
@@ -119,7 +133,8 @@ func UploadPackageFile(ctx *context.Context) {
 		return
 	}
 
-	packageName := normalizer.Replace(ctx.Req.FormValue("name"))
+	rawName := ctx.Req.FormValue("name")
+	packageName := normalizer.Replace(rawName)
 	packageVersion := ctx.Req.FormValue("version")
 	if !nameMatcher.MatchString(packageName) || !versionMatcher.MatchString(packageVersion) {
 		apiError(ctx, http.StatusBadRequest, "invalid name or version")
@@ -141,6 +156,8 @@ func UploadPackageFile(ctx *context.Context) {
 			},
 			SemverCompatible: true,
 			Creator:          ctx.Doer,
+			// the name as published resolves outside of the registry api which normalizes the requested names
+			Aliases: []string{rawName},
 			Metadata: &pypi_module.Metadata{
 				Author:          ctx.Req.FormValue("author"),
 				Description:     ctx.Req.FormValue("description"),
@@ -160,6 +177,14 @@ func UploadPackageFile(ctx *context.Context) {
 		},
 	)
 	if err != nil {
+		if packages_service.IsErrQuotaExceeded(err) || packages_service.IsErrTypeLimitExceeded(err) {
+			apiError(ctx, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+		if packages_model.IsErrInvalidMetadata(err) {
+			apiError(ctx, http.StatusBadRequest, err)
+			return
+		}
 		if err == packages_model.ErrDuplicatePackageFile {
 			apiError(ctx, http.StatusBadRequest, err)
 			return
@@ -34,16 +34,30 @@ func EnumeratePackages(ctx *context.Context) {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
 	}
+	packages, err = helper.FilterVisibleVersions(ctx, packages)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	// yanked gems are removed from the index but can still be downloaded
+	pvs := make([]*packages_model.PackageVersion, 0, len(packages))
+	for _, pv := range packages {
+		if !pv.IsYanked {
+			pvs = append(pvs, pv)
+		}
+	}
 
-	enumeratePackages(ctx, "specs.4.8", packages)
+	enumeratePackages(ctx, "specs.4.8", pvs)
 }
 
 // EnumeratePackagesLatest serves the list of the latest version of every package
 func EnumeratePackagesLatest(ctx *context.Context) {
 	pvs, _, err := packages_model.SearchLatestVersions(ctx, &packages_model.PackageSearchOptions{
-		OwnerID:    ctx.Package.Owner.ID,
-		Type:       packages_model.TypeRubyGems,
-		IsInternal: util.OptionalBoolFalse,
+		OwnerID:     ctx.Package.Owner.ID,
+		Type:        packages_model.TypeRubyGems,
+		IsInternal:  util.OptionalBoolFalse,
+		HidePrivate: !ctx.Package.CanReadPrivate,
 	})
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
@@ -59,7 +73,7 @@ func EnumeratePackagesPreRelease(ctx *context.Context) {
 }
 
 func enumeratePackages(ctx *context.Context, filename string, pvs []*packages_model.PackageVersion) {
-	pds, err := packages_model.GetPackageDescriptors(ctx, pvs)
+	pds, err := packages_model.GetPackageDescriptors(ctx, pvs, packages_model.DescriptorLoadOptions{})
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
 		return
@@ -247,6 +261,14 @@ func UploadPackageFile(ctx *context.Context) {
 		},
 	)
 	if err != nil {
+		if packages_service.IsErrQuotaExceeded(err) || packages_service.IsErrTypeLimitExceeded(err) {
+			apiError(ctx, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+		if packages_model.IsErrInvalidMetadata(err) {
+			apiError(ctx, http.StatusBadRequest, err)
+			return
+		}
 		if err == packages_model.ErrDuplicatePackageVersion {
 			apiError(ctx, http.StatusBadRequest, err)
 			return
@@ -282,6 +304,10 @@ func DeletePackage(ctx *context.Context) {
 			apiError(ctx, http.StatusNotFound, err)
 			return
 		}
+		if err == packages_service.ErrVersionImmutable || err == packages_service.ErrVersionProtected {
+			apiError(ctx, http.StatusConflict, err)
+			return
+		}
 		apiError(ctx, http.StatusInternalServerError, err)
 	}
 }
@@ -292,6 +318,7 @@ func getVersionsByFilename(ctx *context.Context, filename string) ([]*packages_m
 		Type:            packages_model.TypeRubyGems,
 		HasFileWithName: filename,
 		IsInternal:      util.OptionalBoolFalse,
+		HidePrivate:     !ctx.Package.CanReadPrivate,
 	})
 	return pvs, err
 }
@@ -0,0 +1,266 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"net/http"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/context"
+	"code.gitea.io/gitea/modules/convert"
+	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/routers/api/v1/utils"
+)
+
+// ListPackageSizeSummaries api for getting the storage statistics of packages
+func ListPackageSizeSummaries(ctx *context.APIContext) {
+	// swagger:operation GET /admin/packages/sizes admin adminListPackageSizeSummaries
+	// ---
+	// summary: List the storage used by packages per package type
+	// produces:
+	// - application/json
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/PackageSizeSummaryList"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+
+	summaries, err := packages_model.GetSizeSummaries(ctx)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "GetSizeSummaries", err)
+		return
+	}
+
+	res := make([]*api.PackageSizeSummary, 0, len(summaries))
+	for _, s := range summaries {
+		res = append(res, convert.ToPackageSizeSummary(s))
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// ListPackageAudits api for getting the package audit log
+func ListPackageAudits(ctx *context.APIContext) {
+	// swagger:operation GET /admin/packages/audit admin adminListPackageAudits
+	// ---
+	// summary: List the package audit log, newest entries first
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: query
+	//   description: only entries of packages of this owner
+	//   type: string
+	// - name: package_id
+	//   in: query
+	//   description: only entries of this package
+	//   type: integer
+	//   format: int64
+	// - name: actor
+	//   in: query
+	//   description: only entries of operations executed by this user
+	//   type: string
+	// - name: action
+	//   in: query
+	//   description: only entries of this action
+	//   type: string
+	//   enum: [publish, delete_version, delete_package, link_repository, transfer, rename]
+	// - name: since
+	//   in: query
+	//   description: Only show entries created after the given time. This is a timestamp in RFC 3339 format
+	//   type: string
+	//   format: date-time
+	// - name: before
+	//   in: query
+	//   description: Only show entries created before the given time. This is a timestamp in RFC 3339 format
+	//   type: string
+	//   format: date-time
+	// - name: page
+	//   in: query
+	//   description: page number of results to return (1-based)
+	//   type: integer
+	// - name: limit
+	//   in: query
+	//   description: page size of results
+	//   type: integer
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/PackageAuditList"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	before, since, err := context.GetQueryBeforeSince(ctx.Context)
+	if err != nil {
+		ctx.Error(http.StatusUnprocessableEntity, "GetQueryBeforeSince", err)
+		return
+	}
+
+	listOptions := utils.GetListOptions(ctx)
+
+	opts := &packages_model.AuditSearchOptions{
+		PackageID:     ctx.FormInt64("package_id"),
+		Action:        packages_model.AuditAction(ctx.FormTrim("action")),
+		CreatedAfter:  timeutil.TimeStamp(since),
+		CreatedBefore: timeutil.TimeStamp(before),
+		Paginator:     &listOptions,
+	}
+
+	for _, filter := range []struct {
+		Name string
+		ID   *int64
+	}{
+		{"owner", &opts.OwnerID},
+		{"actor", &opts.ActorID},
+	} {
+		name := ctx.FormTrim(filter.Name)
+		if name == "" {
+			continue
+		}
+		u, err := user_model.GetUserByName(ctx, name)
+		if err != nil {
+			if user_model.IsErrUserNotExist(err) {
+				ctx.Error(http.StatusUnprocessableEntity, "GetUserByName", err)
+			} else {
+				ctx.Error(http.StatusInternalServerError, "GetUserByName", err)
+			}
+			return
+		}
+		*filter.ID = u.ID
+	}
+
+	pas, count, err := packages_model.SearchAudits(ctx, opts)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "SearchAudits", err)
+		return
+	}
+
+	res := make([]*api.PackageAudit, 0, len(pas))
+	for _, pa := range pas {
+		res = append(res, convert.ToPackageAudit(pa))
+	}
+
+	ctx.SetLinkHeader(int(count), listOptions.PageSize)
+	ctx.SetTotalCountHeader(count)
+	ctx.JSON(http.StatusOK, res)
+}
+
+// ListPackageInventory api for getting all packages with their used storage
+func ListPackageInventory(ctx *context.APIContext) {
+	// swagger:operation GET /admin/packages/inventory admin adminListPackageInventory
+	// ---
+	// summary: List all packages with the storage used by their files
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: type
+	//   in: query
+	//   description: package type filter
+	//   type: string
+	//   enum: [composer, conan, container, generic, helm, maven, npm, nuget, pub, pypi, rubygems, vagrant]
+	// - name: owner
+	//   in: query
+	//   description: only packages of this owner
+	//   type: string
+	// - name: owner_active
+	//   in: query
+	//   description: only packages of active (true) or deactivated (false) owners
+	//   type: boolean
+	// - name: q
+	//   in: query
+	//   description: name filter
+	//   type: string
+	// - name: min_size
+	//   in: query
+	//   description: only packages using at least this many bytes
+	//   type: integer
+	//   format: int64
+	// - name: max_size
+	//   in: query
+	//   description: only packages using at most this many bytes
+	//   type: integer
+	//   format: int64
+	// - name: since
+	//   in: query
+	//   description: Only show packages with a version published after the given time. This is a timestamp in RFC 3339 format
+	//   type: string
+	//   format: date-time
+	// - name: before
+	//   in: query
+	//   description: Only show packages without a version published after the given time. This is a timestamp in RFC 3339 format
+	//   type: string
+	//   format: date-time
+	// - name: sort
+	//   in: query
+	//   description: sort order, defaults to the latest published packages first
+	//   type: string
+	//   enum: [size, smallest, newest, oldest, alphabetically, reversealphabetically]
+	// - name: page
+	//   in: query
+	//   description: page number of results to return (1-based)
+	//   type: integer
+	// - name: limit
+	//   in: query
+	//   description: page size of results
+	//   type: integer
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/PackageInventoryEntryList"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	before, since, err := context.GetQueryBeforeSince(ctx.Context)
+	if err != nil {
+		ctx.Error(http.StatusUnprocessableEntity, "GetQueryBeforeSince", err)
+		return
+	}
+
+	listOptions := utils.GetListOptions(ctx)
+
+	opts := &packages_model.InventorySearchOptions{
+		Type:            packages_model.Type(ctx.FormTrim("type")),
+		OwnerIsActive:   ctx.FormOptionalBool("owner_active"),
+		Name:            ctx.FormTrim("q"),
+		MinSize:         ctx.FormInt64("min_size"),
+		MaxSize:         ctx.FormInt64("max_size"),
+		PublishedAfter:  timeutil.TimeStamp(since),
+		PublishedBefore: timeutil.TimeStamp(before),
+		Sort:            ctx.FormTrim("sort"),
+		Paginator:       &listOptions,
+	}
+
+	if name := ctx.FormTrim("owner"); name != "" {
+		owner, err := user_model.GetUserByName(ctx, name)
+		if err != nil {
+			if user_model.IsErrUserNotExist(err) {
+				ctx.Error(http.StatusUnprocessableEntity, "GetUserByName", err)
+			} else {
+				ctx.Error(http.StatusInternalServerError, "GetUserByName", err)
+			}
+			return
+		}
+		opts.OwnerID = owner.ID
+	}
+
+	entries, count, err := packages_model.SearchInventory(ctx, opts)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "SearchInventory", err)
+		return
+	}
+
+	res := make([]*api.PackageInventoryEntry, 0, len(entries))
+	for _, e := range entries {
+		res = append(res, convert.ToPackageInventoryEntry(e))
+	}
+
+	ctx.SetLinkHeader(int(count), listOptions.PageSize)
+	ctx.SetTotalCountHeader(count)
+	ctx.JSON(http.StatusOK, res)
+}
@@ -1077,8 +1077,18 @@ func Routes(ctx gocontext.Context) *web.Route {
 				m.Get("", packages.GetPackage)
 				m.Delete("", reqPackageAccess(perm.AccessModeWrite), packages.DeletePackage)
 				m.Get("/files", packages.ListPackageFiles)
+				m.Post("/copy", reqToken(), bind(api.CopyPackageOption{}), packages.CopyPackage)
+				m.Combo("/releases/{id}", reqToken(), reqPackageAccess(perm.AccessModeWrite)).
+					Put(packages.LinkPackageRelease).
+					Delete(packages.UnlinkPackageRelease)
+				m.Put("/release-notes", reqToken(), reqPackageAccess(perm.AccessModeWrite), bind(api.EditPackageReleaseNotesOption{}), packages.EditPackageReleaseNotes)
 			})
+			m.Post("/{type}/{name}/-/transfer", reqToken(), reqPackageAccess(perm.AccessModeOwner), bind(api.TransferPackageOption{}), packages.TransferPackage)
+			m.Post("/{type}/{name}/-/visibility", reqToken(), reqPackageAccess(perm.AccessModeOwner), bind(api.SetPackageVisibilityOption{}), packages.SetPackageVisibility)
+			m.Post("/{type}/{name}/-/bulk-delete", reqToken(), reqPackageAccess(perm.AccessModeWrite), bind(api.BulkDeletePackageVersionsOption{}), packages.BulkDeletePackageVersions)
 			m.Get("/", packages.ListPackages)
+			m.Get("/-/types", packages.ListPackageTypeCounts)
+			m.Get("/-/stats", reqToken(), reqPackageAccess(perm.AccessModeAdmin), packages.GetOwnerPackageStats)
 		}, context_service.UserAssignmentAPI(), context.PackageAssignmentAPI(), reqPackageAccess(perm.AccessModeRead))
 
 		// Organizations
@@ -1152,6 +1162,9 @@ func Routes(ctx gocontext.Context) *web.Route {
 				m.Post("/{task}", admin.PostCronTask)
 			})
 			m.Get("/orgs", admin.GetAllOrgs)
+			m.Get("/packages/sizes", admin.ListPackageSizeSummaries)
+			m.Get("/packages/audit", admin.ListPackageAudits)
+			m.Get("/packages/inventory", admin.ListPackageInventory)
 			m.Group("/users", func() {
 				m.Get("", admin.GetAllUsers)
 				m.Post("", bind(api.CreateUserOption{}), admin.CreateUser)
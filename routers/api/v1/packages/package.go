@@ -5,13 +5,21 @@
 package packages
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/models/perm"
+	repo_model "code.gitea.io/gitea/models/repo"
+	user_model "code.gitea.io/gitea/models/user"
 	"code.gitea.io/gitea/modules/context"
 	"code.gitea.io/gitea/modules/convert"
 	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/timeutil"
 	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/modules/web"
 	"code.gitea.io/gitea/routers/api/v1/utils"
 	packages_service "code.gitea.io/gitea/services/packages"
 )
@@ -46,28 +54,58 @@ func ListPackages(ctx *context.APIContext) {
 	//   in: query
 	//   description: name filter
 	//   type: string
+	// - name: keyword
+	//   in: query
+	//   description: keyword filter, matches the keywords and tags of the package metadata
+	//   type: string
+	// - name: property
+	//   in: query
+	//   description: "version property filter in the form key=value, only versions with all listed properties are returned. Numeric properties can be compared with key>=value and key<=value"
+	//   type: array
+	//   collectionFormat: multi
+	//   items:
+	//     type: string
+	// - name: sort
+	//   in: query
+	//   description: "sort order, downloads and reversedownloads order by the download count of the package"
+	//   type: string
+	//   enum: [newest, oldest, alphabetically, reversealphabetically, downloads, reversedownloads]
 	// responses:
 	//   "200":
 	//     "$ref": "#/responses/PackageList"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
 
 	listOptions := utils.GetListOptions(ctx)
 
 	packageType := ctx.FormTrim("type")
 	query := ctx.FormTrim("q")
+	keyword := ctx.FormTrim("keyword")
+
+	properties, numericProperties, err := parsePropertyFilters(ctx.FormStrings("property"))
+	if err != nil {
+		ctx.Error(http.StatusUnprocessableEntity, "", err)
+		return
+	}
 
 	pvs, count, err := packages.SearchVersions(ctx, &packages.PackageSearchOptions{
-		OwnerID:    ctx.Package.Owner.ID,
-		Type:       packages.Type(packageType),
-		Name:       packages.SearchValue{Value: query},
-		IsInternal: util.OptionalBoolFalse,
-		Paginator:  &listOptions,
+		OwnerID:           ctx.Package.Owner.ID,
+		Type:              packages.Type(packageType),
+		Name:              packages.SearchValue{Value: query},
+		Keyword:           keyword,
+		Properties:        properties,
+		NumericProperties: numericProperties,
+		IsInternal:        util.OptionalBoolFalse,
+		HidePrivate:       !ctx.Package.CanReadPrivate,
+		Sort:              ctx.FormTrim("sort"),
+		Paginator:         &listOptions,
 	})
 	if err != nil {
 		ctx.Error(http.StatusInternalServerError, "SearchVersions", err)
 		return
 	}
 
-	pds, err := packages.GetPackageDescriptors(ctx, pvs)
+	pds, err := packages.GetPackageDescriptors(ctx, pvs, packages.DescriptorLoadOptions{SkipMetadata: true})
 	if err != nil {
 		ctx.Error(http.StatusInternalServerError, "GetPackageDescriptors", err)
 		return
@@ -88,6 +126,132 @@ func ListPackages(ctx *context.APIContext) {
 	ctx.JSON(http.StatusOK, apiPackages)
 }
 
+// ListPackageTypeCounts gets the number of packages per type of an owner
+func ListPackageTypeCounts(ctx *context.APIContext) {
+	// swagger:operation GET /packages/{owner}/-/types package listPackageTypeCounts
+	// ---
+	// summary: Gets the number of packages per package type of an owner
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the packages
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/PackageTypeCountList"
+
+	countByType := packages.CountPublicByType
+	if ctx.Package.CanReadPrivate {
+		countByType = packages.CountByType
+	}
+	counts, err := countByType(ctx, ctx.Package.Owner.ID)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "CountByType", err)
+		return
+	}
+
+	apiCounts := make([]*api.PackageTypeCount, 0, len(counts))
+	for _, pt := range packages.TypeList {
+		if count, has := counts[pt]; has {
+			apiCounts = append(apiCounts, &api.PackageTypeCount{
+				Type:  string(pt),
+				Count: count,
+			})
+		}
+	}
+
+	ctx.JSON(http.StatusOK, apiCounts)
+}
+
+// GetOwnerPackageStats gets the package statistics of an owner
+func GetOwnerPackageStats(ctx *context.APIContext) {
+	// swagger:operation GET /packages/{owner}/-/stats package getOwnerPackageStats
+	// ---
+	// summary: Gets the package statistics of an owner
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the packages
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/OwnerPackageStats"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+
+	stats, err := packages.GetOwnerStats(ctx, ctx.Package.Owner.ID)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "GetOwnerStats", err)
+		return
+	}
+	pq, err := packages.GetQuotaByOwnerID(ctx, ctx.Package.Owner.ID)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "GetQuotaByOwnerID", err)
+		return
+	}
+
+	apiStats := &api.OwnerPackageStats{
+		PackageCount: stats.PackageCount,
+		VersionCount: stats.VersionCount,
+		FileCount:    stats.FileCount,
+		LogicalSize:  stats.LogicalSize,
+		PhysicalSize: stats.PhysicalSize,
+		SizeLimit:    packages_service.QuotaSizeLimit(pq),
+		Types:        make([]*api.PackageTypeStats, 0, len(stats.ByType)),
+	}
+	for _, ts := range stats.ByType {
+		apiStats.Types = append(apiStats.Types, &api.PackageTypeStats{
+			Type:         string(ts.Type),
+			PackageCount: ts.PackageCount,
+			VersionCount: ts.VersionCount,
+			FileCount:    ts.FileCount,
+			LogicalSize:  ts.LogicalSize,
+			PhysicalSize: ts.PhysicalSize,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, apiStats)
+}
+
+// parsePropertyFilters parses filters in the form key=value, key>=value and key<=value
+func parsePropertyFilters(filters []string) (map[string]string, []*packages.NumericPropertyCondition, error) {
+	properties := make(map[string]string)
+	var numericProperties []*packages.NumericPropertyCondition
+	for _, filter := range filters {
+		i := strings.IndexByte(filter, '=')
+		if i < 1 {
+			return nil, nil, fmt.Errorf("invalid property filter %q, expected key=value", filter)
+		}
+
+		operator := packages.NumericPropertyOperator(filter[i-1 : i+1])
+		if operator != packages.NumericPropertyGreaterOrEqual && operator != packages.NumericPropertyLessOrEqual {
+			properties[filter[:i]] = filter[i+1:]
+			continue
+		}
+
+		name := filter[:i-1]
+		if !packages.IsNumericProperty(name) {
+			return nil, nil, fmt.Errorf("property %q can't be compared, it is not numeric", name)
+		}
+		value, err := strconv.ParseInt(filter[i+1:], 10, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid value of property filter %q, expected a number", filter)
+		}
+		numericProperties = append(numericProperties, &packages.NumericPropertyCondition{
+			Name:     name,
+			Operator: operator,
+			Value:    value,
+		})
+	}
+	return properties, numericProperties, nil
+}
+
 // GetPackage gets a package
 func GetPackage(ctx *context.APIContext) {
 	// swagger:operation GET /packages/{owner}/{type}/{name}/{version} package getPackage
@@ -127,6 +291,7 @@ func GetPackage(ctx *context.APIContext) {
 		ctx.Error(http.StatusInternalServerError, "Error converting package for api", err)
 		return
 	}
+	apiPackage.Readme = packages_service.ExtractReadme(ctx.Package.Descriptor.Metadata)
 
 	ctx.JSON(http.StatusOK, apiPackage)
 }
@@ -157,13 +322,23 @@ func DeletePackage(ctx *context.APIContext) {
 	//   description: version of the package
 	//   type: string
 	//   required: true
+	// - name: force
+	//   in: query
+	//   description: delete the version even if it is referenced by a release, requires site administrator rights
+	//   type: boolean
 	// responses:
 	//   "204":
 	//     "$ref": "#/responses/empty"
 	//   "404":
 	//     "$ref": "#/responses/notFound"
+	//   "409":
+	//     "$ref": "#/responses/conflict"
 
-	err := packages_service.RemovePackageVersion(ctx.Doer, ctx.Package.Descriptor.Version)
+	err := packages_service.RemovePackageVersion(ctx.Doer, ctx.Package.Descriptor.Version, ctx.FormBool("force"))
+	if err == packages_service.ErrVersionImmutable || err == packages_service.ErrVersionProtected {
+		ctx.Error(http.StatusConflict, "", err)
+		return
+	}
 	if err != nil {
 		ctx.Error(http.StatusInternalServerError, "RemovePackageVersion", err)
 		return
@@ -171,6 +346,269 @@ func DeletePackage(ctx *context.APIContext) {
 	ctx.Status(http.StatusNoContent)
 }
 
+// LinkPackageRelease marks a package version as referenced by a release
+func LinkPackageRelease(ctx *context.APIContext) {
+	// swagger:operation PUT /packages/{owner}/{type}/{name}/{version}/releases/{id} package linkPackageRelease
+	// ---
+	// summary: Mark a package version as referenced by a release of the linked repository, which protects it from deletion
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the package
+	//   type: string
+	//   required: true
+	// - name: type
+	//   in: path
+	//   description: type of the package
+	//   type: string
+	//   required: true
+	// - name: name
+	//   in: path
+	//   description: name of the package
+	//   type: string
+	//   required: true
+	// - name: version
+	//   in: path
+	//   description: version of the package
+	//   type: string
+	//   required: true
+	// - name: id
+	//   in: path
+	//   description: id of the release
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	pd := ctx.Package.Descriptor
+
+	release, err := repo_model.GetReleaseByID(ctx, ctx.ParamsInt64(":id"))
+	if err != nil {
+		if repo_model.IsErrReleaseNotExist(err) {
+			ctx.NotFound()
+		} else {
+			ctx.Error(http.StatusInternalServerError, "GetReleaseByID", err)
+		}
+		return
+	}
+
+	if err := packages.LinkVersionToRelease(ctx, pd.Package, pd.Version.ID, release); err != nil {
+		if err == packages.ErrReleaseRepositoryMismatch {
+			ctx.Error(http.StatusUnprocessableEntity, "", err)
+		} else {
+			ctx.Error(http.StatusInternalServerError, "LinkVersionToRelease", err)
+		}
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// UnlinkPackageRelease removes the reference of a release to a package version
+func UnlinkPackageRelease(ctx *context.APIContext) {
+	// swagger:operation DELETE /packages/{owner}/{type}/{name}/{version}/releases/{id} package unlinkPackageRelease
+	// ---
+	// summary: Remove the reference of a release to a package version
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the package
+	//   type: string
+	//   required: true
+	// - name: type
+	//   in: path
+	//   description: type of the package
+	//   type: string
+	//   required: true
+	// - name: name
+	//   in: path
+	//   description: name of the package
+	//   type: string
+	//   required: true
+	// - name: version
+	//   in: path
+	//   description: version of the package
+	//   type: string
+	//   required: true
+	// - name: id
+	//   in: path
+	//   description: id of the release
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	if err := packages.UnlinkVersionFromRelease(ctx, ctx.Package.Descriptor.Version.ID, ctx.ParamsInt64(":id")); err != nil {
+		ctx.Error(http.StatusInternalServerError, "UnlinkVersionFromRelease", err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// EditPackageReleaseNotes changes the release notes of a package version
+func EditPackageReleaseNotes(ctx *context.APIContext) {
+	// swagger:operation PUT /packages/{owner}/{type}/{name}/{version}/release-notes package editPackageReleaseNotes
+	// ---
+	// summary: Change the release notes of a package version
+	// consumes:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the package
+	//   type: string
+	//   required: true
+	// - name: type
+	//   in: path
+	//   description: type of the package
+	//   type: string
+	//   required: true
+	// - name: name
+	//   in: path
+	//   description: name of the package
+	//   type: string
+	//   required: true
+	// - name: version
+	//   in: path
+	//   description: version of the package
+	//   type: string
+	//   required: true
+	// - name: body
+	//   in: body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/EditPackageReleaseNotesOption"
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	opts := web.GetForm(ctx).(*api.EditPackageReleaseNotesOption)
+
+	if err := packages_service.SetPackageVersionReleaseNotes(ctx.Doer, ctx.Package.Descriptor.Version, opts.ReleaseNotes); err != nil {
+		if err == packages.ErrReleaseNotesTooLong {
+			ctx.Error(http.StatusUnprocessableEntity, "", err)
+		} else {
+			ctx.Error(http.StatusInternalServerError, "SetPackageVersionReleaseNotes", err)
+		}
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// CopyPackage copies a package version to another owner
+func CopyPackage(ctx *context.APIContext) {
+	// swagger:operation POST /packages/{owner}/{type}/{name}/{version}/copy package copyPackage
+	// ---
+	// summary: Copy a package version with its files to another owner
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the package
+	//   type: string
+	//   required: true
+	// - name: type
+	//   in: path
+	//   description: type of the package
+	//   type: string
+	//   required: true
+	// - name: name
+	//   in: path
+	//   description: name of the package
+	//   type: string
+	//   required: true
+	// - name: version
+	//   in: path
+	//   description: version of the package
+	//   type: string
+	//   required: true
+	// - name: body
+	//   in: body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/CopyPackageOption"
+	// responses:
+	//   "201":
+	//     "$ref": "#/responses/Package"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "409":
+	//     "$ref": "#/responses/conflict"
+	//   "413":
+	//     "$ref": "#/responses/error"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	opts := web.GetForm(ctx).(*api.CopyPackageOption)
+
+	targetOwner, err := user_model.GetUserByName(ctx, opts.TargetOwner)
+	if err != nil {
+		if user_model.IsErrUserNotExist(err) {
+			ctx.Error(http.StatusNotFound, "", "The target owner does not exist or cannot be found")
+		} else {
+			ctx.Error(http.StatusInternalServerError, "GetUserByName", err)
+		}
+		return
+	}
+
+	accessMode, err := context.DeterminePackageTypeAccessMode(ctx.Context, ctx.Doer, targetOwner, ctx.Package.Descriptor.Package.Type)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "DeterminePackageTypeAccessMode", err)
+		return
+	}
+	if accessMode < perm.AccessModeWrite && !ctx.IsUserSiteAdmin() {
+		ctx.Error(http.StatusForbidden, "", "user should have write permission for the packages of the target owner")
+		return
+	}
+
+	pv, err := packages_service.CopyPackageVersion(ctx.Doer, ctx.Package.Descriptor.Version, targetOwner)
+	if err != nil {
+		if packages_service.IsErrQuotaExceeded(err) || packages_service.IsErrTypeLimitExceeded(err) {
+			ctx.Error(http.StatusRequestEntityTooLarge, "", err)
+			return
+		}
+		switch err {
+		case packages.ErrDuplicatePackageVersion:
+			ctx.Error(http.StatusConflict, "", err)
+		case packages_service.ErrCopyNotSupported:
+			ctx.Error(http.StatusUnprocessableEntity, "", err)
+		default:
+			ctx.Error(http.StatusInternalServerError, "CopyPackageVersion", err)
+		}
+		return
+	}
+
+	pd, err := packages.GetPackageDescriptor(ctx, pv)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "GetPackageDescriptor", err)
+		return
+	}
+
+	apiPackage, err := convert.ToPackage(ctx, pd, ctx.Doer)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "Error converting package for api", err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, apiPackage)
+}
+
 // ListPackageFiles gets all files of a package
 func ListPackageFiles(ctx *context.APIContext) {
 	// swagger:operation GET /packages/{owner}/{type}/{name}/{version}/files package listPackageFiles
@@ -212,3 +650,244 @@ func ListPackageFiles(ctx *context.APIContext) {
 
 	ctx.JSON(http.StatusOK, apiPackageFiles)
 }
+
+// TransferPackage transfers a package to a new owner
+func TransferPackage(ctx *context.APIContext) {
+	// swagger:operation POST /packages/{owner}/{type}/{name}/-/transfer package transferPackage
+	// ---
+	// summary: Transfer a package to a new owner
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the package
+	//   type: string
+	//   required: true
+	// - name: type
+	//   in: path
+	//   description: type of the package
+	//   type: string
+	//   required: true
+	// - name: name
+	//   in: path
+	//   description: name of the package
+	//   type: string
+	//   required: true
+	// - name: body
+	//   in: body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/TransferPackageOption"
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "409":
+	//     "$ref": "#/responses/conflict"
+	//   "413":
+	//     "$ref": "#/responses/error"
+
+	opts := web.GetForm(ctx).(*api.TransferPackageOption)
+
+	p, err := packages.GetPackageByName(ctx, ctx.Package.Owner.ID, packages.Type(ctx.Params("type")), ctx.Params("name"))
+	if err != nil {
+		if err == packages.ErrPackageNotExist {
+			ctx.NotFound()
+		} else {
+			ctx.Error(http.StatusInternalServerError, "GetPackageByName", err)
+		}
+		return
+	}
+
+	newOwner, err := user_model.GetUserByName(ctx, opts.NewOwner)
+	if err != nil {
+		if user_model.IsErrUserNotExist(err) {
+			ctx.Error(http.StatusNotFound, "", "The new owner does not exist or cannot be found")
+		} else {
+			ctx.Error(http.StatusInternalServerError, "GetUserByName", err)
+		}
+		return
+	}
+
+	canAdministrate, err := packages_service.CanAdministrateOwnerPackages(ctx, ctx.Doer, newOwner)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "CanAdministrateOwnerPackages", err)
+		return
+	}
+	if !canAdministrate {
+		ctx.Error(http.StatusForbidden, "", "user should be an administrator of the new owner")
+		return
+	}
+
+	if err := packages_service.TransferPackage(ctx.Doer, p, newOwner); err != nil {
+		if err == packages.ErrDuplicatePackage {
+			ctx.Error(http.StatusConflict, "", err)
+		} else if packages_service.IsErrQuotaExceeded(err) || packages_service.IsErrTypeLimitExceeded(err) {
+			ctx.Error(http.StatusRequestEntityTooLarge, "", err)
+		} else {
+			ctx.Error(http.StatusInternalServerError, "TransferPackage", err)
+		}
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// SetPackageVisibility changes the visibility of a package
+func SetPackageVisibility(ctx *context.APIContext) {
+	// swagger:operation POST /packages/{owner}/{type}/{name}/-/visibility package setPackageVisibility
+	// ---
+	// summary: Change the visibility of a package
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the package
+	//   type: string
+	//   required: true
+	// - name: type
+	//   in: path
+	//   description: type of the package
+	//   type: string
+	//   required: true
+	// - name: name
+	//   in: path
+	//   description: name of the package
+	//   type: string
+	//   required: true
+	// - name: body
+	//   in: body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/SetPackageVisibilityOption"
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	opts := web.GetForm(ctx).(*api.SetPackageVisibilityOption)
+
+	visibility, ok := packages.ParseVisibility(opts.Visibility)
+	if !ok {
+		ctx.Error(http.StatusUnprocessableEntity, "", "visibility must be inherit or private")
+		return
+	}
+
+	p, err := packages.GetPackageByName(ctx, ctx.Package.Owner.ID, packages.Type(ctx.Params("type")), ctx.Params("name"))
+	if err != nil {
+		if err == packages.ErrPackageNotExist {
+			ctx.NotFound()
+		} else {
+			ctx.Error(http.StatusInternalServerError, "GetPackageByName", err)
+		}
+		return
+	}
+
+	if err := packages_service.SetPackageVisibility(ctx.Doer, p, visibility); err != nil {
+		ctx.Error(http.StatusInternalServerError, "SetPackageVisibility", err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// BulkDeletePackageVersions deletes the versions of a package which match a filter
+func BulkDeletePackageVersions(ctx *context.APIContext) {
+	// swagger:operation POST /packages/{owner}/{type}/{name}/-/bulk-delete package bulkDeletePackageVersions
+	// ---
+	// summary: Delete the versions of a package which match a filter
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the package
+	//   type: string
+	//   required: true
+	// - name: type
+	//   in: path
+	//   description: type of the package
+	//   type: string
+	//   required: true
+	// - name: name
+	//   in: path
+	//   description: name of the package
+	//   type: string
+	//   required: true
+	// - name: body
+	//   in: body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/BulkDeletePackageVersionsOption"
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/PackageBulkDeleteResult"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "409":
+	//     "$ref": "#/responses/conflict"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	opts := web.GetForm(ctx).(*api.BulkDeletePackageVersionsOption)
+
+	if opts.KeepLatest < 0 {
+		ctx.Error(http.StatusUnprocessableEntity, "", "keep_latest must not be negative")
+		return
+	}
+
+	p, err := packages.GetPackageByName(ctx, ctx.Package.Owner.ID, packages.Type(ctx.Params("type")), ctx.Params("name"))
+	if err != nil {
+		if err == packages.ErrPackageNotExist {
+			ctx.NotFound()
+		} else {
+			ctx.Error(http.StatusInternalServerError, "GetPackageByName", err)
+		}
+		return
+	}
+
+	filter := &packages.VersionFilter{
+		VersionPattern: opts.VersionPattern,
+		KeepLatest:     opts.KeepLatest,
+	}
+	if opts.CreatedBefore != nil {
+		filter.CreatedBefore = timeutil.TimeStamp(opts.CreatedBefore.Unix())
+	}
+
+	result, err := packages_service.DeleteVersionsByFilter(ctx, ctx.Doer, ctx.Package.Owner.ID, p.ID, filter, opts.DryRun)
+	if err != nil {
+		switch err {
+		case packages.ErrInvalidVersionPattern, packages.ErrEmptyVersionFilter:
+			ctx.Error(http.StatusUnprocessableEntity, "", err)
+		case packages_service.ErrVersionImmutable, packages_service.ErrVersionProtected:
+			ctx.Error(http.StatusConflict, "", err)
+		default:
+			ctx.Error(http.StatusInternalServerError, "DeleteVersionsByFilter", err)
+		}
+		return
+	}
+
+	versions := make([]string, 0, len(result.Versions))
+	for _, pv := range result.Versions {
+		versions = append(versions, pv.Version)
+	}
+
+	ctx.JSON(http.StatusOK, &api.PackageBulkDeleteResult{
+		Versions:  versions,
+		FileCount: result.FileCount,
+		FreedSize: result.FreedSize,
+		DryRun:    result.DryRun,
+	})
+}
@@ -172,4 +172,19 @@ type swaggerParameterBodies struct {
 
 	// in:body
 	CreatePushMirrorOption api.CreatePushMirrorOption
+
+	// in:body
+	TransferPackageOption api.TransferPackageOption
+
+	// in:body
+	SetPackageVisibilityOption api.SetPackageVisibilityOption
+
+	// in:body
+	CopyPackageOption api.CopyPackageOption
+
+	// in:body
+	EditPackageReleaseNotesOption api.EditPackageReleaseNotesOption
+
+	// in:body
+	BulkDeletePackageVersionsOption api.BulkDeletePackageVersionsOption
 }
@@ -22,9 +22,51 @@ type swaggerResponsePackageList struct {
 	Body []api.Package `json:"body"`
 }
 
+// PackageTypeCountList
+// swagger:response PackageTypeCountList
+type swaggerResponsePackageTypeCountList struct {
+	// in:body
+	Body []api.PackageTypeCount `json:"body"`
+}
+
+// OwnerPackageStats
+// swagger:response OwnerPackageStats
+type swaggerResponseOwnerPackageStats struct {
+	// in:body
+	Body api.OwnerPackageStats `json:"body"`
+}
+
 // PackageFileList
 // swagger:response PackageFileList
 type swaggerResponsePackageFileList struct {
 	// in:body
 	Body []api.PackageFile `json:"body"`
 }
+
+// PackageSizeSummaryList
+// swagger:response PackageSizeSummaryList
+type swaggerResponsePackageSizeSummaryList struct {
+	// in:body
+	Body []api.PackageSizeSummary `json:"body"`
+}
+
+// PackageAuditList
+// swagger:response PackageAuditList
+type swaggerResponsePackageAuditList struct {
+	// in:body
+	Body []api.PackageAudit `json:"body"`
+}
+
+// PackageBulkDeleteResult
+// swagger:response PackageBulkDeleteResult
+type swaggerResponsePackageBulkDeleteResult struct {
+	// in:body
+	Body api.PackageBulkDeleteResult `json:"body"`
+}
+
+// PackageInventoryEntryList
+// swagger:response PackageInventoryEntryList
+type swaggerResponsePackageInventoryEntryList struct {
+	// in:body
+	Body []api.PackageInventoryEntry `json:"body"`
+}
@@ -5,22 +5,35 @@
 package admin
 
 import (
+	goctx "context"
 	"net/http"
 	"net/url"
+	"strconv"
+	"time"
 
 	"code.gitea.io/gitea/models/db"
 	packages_model "code.gitea.io/gitea/models/packages"
+	user_model "code.gitea.io/gitea/models/user"
 	"code.gitea.io/gitea/modules/base"
 	"code.gitea.io/gitea/modules/context"
 	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/timeutil"
 	"code.gitea.io/gitea/modules/util"
+	"code.gitea.io/gitea/services/cron"
 	packages_service "code.gitea.io/gitea/services/packages"
+
+	"github.com/dustin/go-humanize"
 )
 
 const (
-	tplPackagesList base.TplName = "admin/packages/list"
+	tplPackagesList      base.TplName = "admin/packages/list"
+	tplPackagesLimits    base.TplName = "admin/packages/limits"
+	tplPackagesInventory base.TplName = "admin/packages/inventory"
 )
 
+// cleanupTimeBudget limits the duration of a cleanup started by an administrator
+const cleanupTimeBudget = time.Minute
+
 // Packages shows all packages
 func Packages(ctx *context.Context) {
 	page := ctx.FormInt("page")
@@ -46,15 +59,21 @@ func Packages(ctx *context.Context) {
 		return
 	}
 
-	pds, err := packages_model.GetPackageDescriptors(ctx, pvs)
+	pds, err := packages_model.GetPackageDescriptors(ctx, pvs, packages_model.DescriptorLoadOptions{SkipMetadata: true})
 	if err != nil {
 		ctx.ServerError("GetPackageDescriptors", err)
 		return
 	}
 
-	totalBlobSize, err := packages_model.GetTotalBlobSize()
+	stats, err := packages_model.InstancePackageStats(ctx)
+	if err != nil {
+		ctx.ServerError("InstancePackageStats", err)
+		return
+	}
+
+	sizeSummaries, err := packages_model.GetSizeSummaries(ctx)
 	if err != nil {
-		ctx.ServerError("GetTotalBlobSize", err)
+		ctx.ServerError("GetSizeSummaries", err)
 		return
 	}
 
@@ -66,7 +85,10 @@ func Packages(ctx *context.Context) {
 	ctx.Data["SortType"] = sort
 	ctx.Data["PackageDescriptors"] = pds
 	ctx.Data["Total"] = total
-	ctx.Data["TotalBlobSize"] = totalBlobSize
+	ctx.Data["TotalBlobSize"] = stats.TotalBlobSize
+	ctx.Data["OrphanedBlobs"] = stats.OrphanedBlobCount
+	ctx.Data["OrphanedBlobsSize"] = stats.OrphanedBlobSize
+	ctx.Data["SizeSummaries"] = sizeSummaries
 
 	pager := context.NewPagination(int(total), setting.UI.PackagesPagingNum, page, 5)
 	pager.AddParamString("q", query)
@@ -77,6 +99,120 @@ func Packages(ctx *context.Context) {
 	ctx.HTML(http.StatusOK, tplPackagesList)
 }
 
+// PackageInventory shows all packages with their used storage
+func PackageInventory(ctx *context.Context) {
+	page := ctx.FormInt("page")
+	if page <= 1 {
+		page = 1
+	}
+
+	opts := &packages_model.InventorySearchOptions{
+		Type: packages_model.Type(ctx.FormTrim("type")),
+		Name: ctx.FormTrim("q"),
+		Sort: ctx.FormTrim("sort"),
+		Paginator: &db.ListOptions{
+			PageSize: setting.UI.PackagesPagingNum,
+			Page:     page,
+		},
+	}
+
+	invalid := false
+	if ownerName := ctx.FormTrim("owner"); ownerName != "" {
+		owner, err := user_model.GetUserByName(ctx, ownerName)
+		if err != nil && !user_model.IsErrUserNotExist(err) {
+			ctx.ServerError("GetUserByName", err)
+			return
+		}
+		if owner != nil {
+			opts.OwnerID = owner.ID
+		} else {
+			// an unknown owner has no packages
+			opts.OwnerID = -1
+		}
+	}
+	switch ctx.FormTrim("owner_state") {
+	case "active":
+		opts.OwnerIsActive = util.OptionalBoolTrue
+	case "inactive":
+		opts.OwnerIsActive = util.OptionalBoolFalse
+	}
+	for _, filter := range []struct {
+		Name string
+		Size *int64
+	}{
+		{"min_size", &opts.MinSize},
+		{"max_size", &opts.MaxSize},
+	} {
+		if value := ctx.FormTrim(filter.Name); value != "" {
+			size, err := humanize.ParseBytes(value)
+			if err != nil {
+				invalid = true
+				continue
+			}
+			*filter.Size = int64(size)
+		}
+	}
+	for _, filter := range []struct {
+		Name string
+		Time *timeutil.TimeStamp
+	}{
+		{"published_after", &opts.PublishedAfter},
+		{"published_before", &opts.PublishedBefore},
+	} {
+		if value := ctx.FormTrim(filter.Name); value != "" {
+			t, err := time.ParseInLocation("2006-01-02", value, setting.DefaultUILocation)
+			if err != nil {
+				invalid = true
+				continue
+			}
+			*filter.Time = timeutil.TimeStamp(t.Unix())
+		}
+	}
+	if invalid {
+		ctx.Flash.Error(ctx.Tr("admin.packages.inventory.invalid_filter"), true)
+	}
+
+	entries, total, err := packages_model.SearchInventory(ctx, opts)
+	if err != nil {
+		ctx.ServerError("SearchInventory", err)
+		return
+	}
+
+	ownerIDs := make([]int64, 0, len(entries))
+	for _, e := range entries {
+		ownerIDs = append(ownerIDs, e.OwnerID)
+	}
+	owners, err := user_model.GetUsersByIDs(ownerIDs)
+	if err != nil {
+		ctx.ServerError("GetUsersByIDs", err)
+		return
+	}
+	ownerMap := make(map[int64]*user_model.User, len(owners))
+	for _, owner := range owners {
+		ownerMap[owner.ID] = owner
+	}
+
+	ctx.Data["Title"] = ctx.Tr("admin.packages.inventory")
+	ctx.Data["PageIsAdmin"] = true
+	ctx.Data["PageIsAdminPackages"] = true
+	ctx.Data["PackageTypes"] = packages_model.TypeList
+	ctx.Data["Entries"] = entries
+	ctx.Data["Owners"] = ownerMap
+	ctx.Data["Total"] = total
+	ctx.Data["SortType"] = opts.Sort
+
+	filters := make(map[string]string)
+	pager := context.NewPagination(int(total), setting.UI.PackagesPagingNum, page, 5)
+	for _, key := range []string{"q", "type", "owner", "owner_state", "min_size", "max_size", "published_after", "published_before", "sort"} {
+		filters[key] = ctx.FormTrim(key)
+		pager.AddParamString(key, filters[key])
+	}
+	ctx.Data["Filters"] = filters
+	ctx.Data["Page"] = pager
+
+	ctx.HTML(http.StatusOK, tplPackagesInventory)
+}
+
 // DeletePackageVersion deletes a package version
 func DeletePackageVersion(ctx *context.Context) {
 	pv, err := packages_model.GetVersionByID(db.DefaultContext, ctx.FormInt64("id"))
@@ -85,13 +221,147 @@ func DeletePackageVersion(ctx *context.Context) {
 		return
 	}
 
-	if err := packages_service.RemovePackageVersion(ctx.Doer, pv); err != nil {
-		ctx.ServerError("RemovePackageVersion", err)
-		return
+	if err := packages_service.RemovePackageVersion(ctx.Doer, pv, false); err != nil {
+		if err != packages_service.ErrVersionProtected {
+			ctx.ServerError("RemovePackageVersion", err)
+			return
+		}
+		ctx.Flash.Error(ctx.Tr("packages.settings.delete.protected"))
+	} else {
+		ctx.Flash.Success(ctx.Tr("packages.settings.delete.success"))
 	}
 
-	ctx.Flash.Success(ctx.Tr("packages.settings.delete.success"))
 	ctx.JSON(http.StatusOK, map[string]interface{}{
 		"redirect": setting.AppSubURL + "/admin/packages?page=" + url.QueryEscape(ctx.FormString("page")) + "&q=" + url.QueryEscape(ctx.FormString("q")) + "&type=" + url.QueryEscape(ctx.FormString("type")),
 	})
 }
+
+// CleanupPackages runs the package cleanup synchronously and reports the reclaimed blobs
+func CleanupPackages(ctx *context.Context) {
+	olderThan := 24 * time.Hour
+	if task := cron.GetTask("cleanup_packages"); task != nil {
+		if config, ok := task.GetConfig().(*cron.OlderThanConfig); ok {
+			olderThan = config.OlderThan
+		}
+	}
+
+	cleanupCtx, cancel := goctx.WithTimeout(ctx, cleanupTimeBudget)
+	defer cancel()
+
+	result, err := packages_service.CleanupWithResult(cleanupCtx, olderThan, ctx.FormBool("force"))
+	if err != nil {
+		ctx.ServerError("CleanupWithResult", err)
+		return
+	}
+
+	if result.Completed {
+		ctx.Flash.Success(ctx.Tr("admin.packages.cleanup.success", result.DeletedBlobs, base.FileSize(result.DeletedBlobsSize)))
+	} else {
+		ctx.Flash.Warning(ctx.Tr("admin.packages.cleanup.incomplete", result.DeletedBlobs, base.FileSize(result.DeletedBlobsSize)))
+	}
+	ctx.Redirect(setting.AppSubURL + "/admin/packages")
+}
+
+type packageTypeLimitRow struct {
+	Type                    packages_model.Type
+	MaxVersions             string
+	MaxVersionSize          string
+	MaxOwnerSize            string
+	EffectiveMaxVersions    string
+	EffectiveMaxVersionSize string
+	EffectiveMaxOwnerSize   string
+}
+
+// PackageTypeLimits shows the limits of all package types
+func PackageTypeLimits(ctx *context.Context) {
+	formatLimit := func(limit int64, isSize bool) string {
+		if limit < 0 {
+			return ctx.Tr("admin.packages.limits.unlimited")
+		}
+		if isSize {
+			return base.FileSize(limit)
+		}
+		return strconv.FormatInt(limit, 10)
+	}
+
+	rows := make([]*packageTypeLimitRow, 0, len(packages_model.TypeList))
+	for _, pt := range packages_model.TypeList {
+		ptl, err := packages_model.GetTypeLimit(ctx, pt)
+		if err != nil {
+			ctx.ServerError("GetTypeLimit", err)
+			return
+		}
+		effective, err := packages_service.GetEffectiveTypeLimit(ctx, pt)
+		if err != nil {
+			ctx.ServerError("GetEffectiveTypeLimit", err)
+			return
+		}
+
+		row := &packageTypeLimitRow{
+			Type:                    pt,
+			EffectiveMaxVersions:    formatLimit(effective.MaxVersions, false),
+			EffectiveMaxVersionSize: formatLimit(effective.MaxVersionSize, true),
+			EffectiveMaxOwnerSize:   formatLimit(effective.MaxOwnerSize, true),
+		}
+		if ptl.MaxVersions != packages_model.TypeLimitDefault {
+			row.MaxVersions = strconv.FormatInt(ptl.MaxVersions, 10)
+		}
+		if ptl.MaxVersionSize != packages_model.TypeLimitDefault {
+			row.MaxVersionSize = base.FileSize(ptl.MaxVersionSize)
+		}
+		if ptl.MaxOwnerSize != packages_model.TypeLimitDefault {
+			row.MaxOwnerSize = base.FileSize(ptl.MaxOwnerSize)
+		}
+		rows = append(rows, row)
+	}
+
+	ctx.Data["Title"] = ctx.Tr("admin.packages.limits")
+	ctx.Data["PageIsAdmin"] = true
+	ctx.Data["PageIsAdminPackages"] = true
+	ctx.Data["TypeLimits"] = rows
+
+	ctx.HTML(http.StatusOK, tplPackagesLimits)
+}
+
+// PackageTypeLimitsPost updates the limits of all package types. Empty values reset a limit to the configured default.
+func PackageTypeLimitsPost(ctx *context.Context) {
+	parseValue := func(key string, isSize bool) (int64, bool) {
+		value := ctx.FormTrim(key)
+		if value == "" {
+			return packages_model.TypeLimitDefault, true
+		}
+		if isSize {
+			size, err := humanize.ParseBytes(value)
+			return int64(size), err == nil
+		}
+		count, err := strconv.ParseInt(value, 10, 64)
+		return count, err == nil && count >= 0
+	}
+
+	limits := make([]*packages_model.PackageTypeLimit, 0, len(packages_model.TypeList))
+	for _, pt := range packages_model.TypeList {
+		maxVersions, ok1 := parseValue("max_versions_"+string(pt), false)
+		maxVersionSize, ok2 := parseValue("max_version_size_"+string(pt), true)
+		maxOwnerSize, ok3 := parseValue("max_owner_size_"+string(pt), true)
+		if !ok1 || !ok2 || !ok3 {
+			ctx.Flash.Error(ctx.Tr("admin.packages.limits.invalid", pt.Name()))
+			ctx.Redirect(setting.AppSubURL + "/admin/packages/limits")
+			return
+		}
+
+		limits = append(limits, &packages_model.PackageTypeLimit{
+			Type:           pt,
+			MaxVersions:    maxVersions,
+			MaxVersionSize: maxVersionSize,
+			MaxOwnerSize:   maxOwnerSize,
+		})
+	}
+
+	if err := packages_service.SetTypeLimits(limits); err != nil {
+		ctx.ServerError("SetTypeLimits", err)
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("admin.packages.limits.update_success"))
+	ctx.Redirect(setting.AppSubURL + "/admin/packages/limits")
+}
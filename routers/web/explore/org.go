@@ -25,6 +25,7 @@ func Organizations(ctx *context.Context) {
 	ctx.Data["PageIsExplore"] = true
 	ctx.Data["PageIsExploreOrganizations"] = true
 	ctx.Data["IsRepoIndexerEnabled"] = setting.Indexer.RepoIndexerEnabled
+	ctx.Data["IsPackageEnabled"] = setting.Packages.Enabled
 
 	visibleTypes := []structs.VisibleType{structs.VisibleTypePublic}
 	if ctx.Doer != nil {
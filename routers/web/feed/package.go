@@ -0,0 +1,101 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package feed
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"time"
+
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/modules/context"
+	"code.gitea.io/gitea/modules/httpcache"
+	"code.gitea.io/gitea/modules/markup"
+	"code.gitea.io/gitea/modules/markup/markdown"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/util"
+
+	"github.com/gorilla/feeds"
+)
+
+// ShowPackageFeed shows the latest versions of a package as RSS / Atom feed
+func ShowPackageFeed(ctx *context.Context, p *packages_model.Package, formatType string) {
+	pvs, _, err := packages_model.SearchVersions(ctx, &packages_model.PackageSearchOptions{
+		Paginator:  db.NewAbsoluteListOptions(0, setting.UI.FeedPagingNum),
+		PackageID:  p.ID,
+		IsInternal: util.OptionalBoolFalse,
+	})
+	if err != nil {
+		ctx.ServerError("SearchVersions", err)
+		return
+	}
+
+	pds, err := packages_model.GetPackageDescriptors(ctx, pvs, packages_model.DescriptorLoadOptions{SkipMetadata: true})
+	if err != nil {
+		ctx.ServerError("GetPackageDescriptors", err)
+		return
+	}
+
+	// the feed changes only if a version is published, the newest version is removed or release notes are edited
+	var lastModified time.Time
+	var newestID int64
+	if len(pvs) > 0 {
+		lastModified = pvs[0].CreatedUnix.AsTime()
+		newestID = pvs[0].ID
+	}
+	notesHash := fnv.New32a()
+	for _, pv := range pvs {
+		_, _ = notesHash.Write([]byte(pv.ReleaseNotes))
+		_, _ = notesHash.Write([]byte{0})
+	}
+	etag := fmt.Sprintf(`"%s-%d-%d-%d-%x"`, formatType, p.ID, newestID, lastModified.Unix(), notesHash.Sum32())
+	if httpcache.HandleGenericETagTimeCache(ctx.Req, ctx.Resp, etag, lastModified) {
+		return
+	}
+
+	packageLink := (&packages_model.PackageDescriptor{
+		Package: p,
+		Owner:   ctx.Package.Owner,
+	}).PackageWebLink()
+
+	feed := &feeds.Feed{
+		Title:   ctx.Tr("home.feed_of", ctx.Package.Owner.Name+"/"+p.Name),
+		Link:    &feeds.Link{Href: packageLink},
+		Created: time.Now(),
+		Updated: lastModified,
+	}
+
+	feed.Items = make([]*feeds.Item, 0, len(pds))
+	for _, pd := range pds {
+		var content string
+		if pd.Version.ReleaseNotes != "" {
+			content, err = markdown.RenderString(&markup.RenderContext{
+				Ctx:       ctx,
+				URLPrefix: packageLink,
+			}, pd.Version.ReleaseNotes)
+			if err != nil {
+				ctx.ServerError("RenderString", err)
+				return
+			}
+		}
+
+		feed.Items = append(feed.Items, &feeds.Item{
+			Title:       pd.Package.Name + " " + pd.Version.Version,
+			Link:        &feeds.Link{Href: pd.FullWebLink()},
+			Description: ctx.Tr("packages.feed.published_by", pd.Package.Name, pd.Version.Version, pd.Creator.DisplayName()),
+			Author: &feeds.Author{
+				Name:  pd.Creator.DisplayName(),
+				Email: pd.Creator.GetEmail(),
+			},
+			Id:      strconv.FormatInt(pd.Version.ID, 10),
+			Created: pd.Version.CreatedUnix.AsTime(),
+			Content: content,
+		})
+	}
+
+	writeFeed(ctx, feed, formatType)
+}
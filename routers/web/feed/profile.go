@@ -57,14 +57,15 @@ func showUserFeed(ctx *context.Context, formatType string) {
 
 // writeFeed write a feeds.Feed as atom or rss to ctx.Resp
 func writeFeed(ctx *context.Context, feed *feeds.Feed, formatType string) {
-	ctx.Resp.WriteHeader(http.StatusOK)
 	if formatType == "atom" {
 		ctx.Resp.Header().Set("Content-Type", "application/atom+xml;charset=utf-8")
+		ctx.Resp.WriteHeader(http.StatusOK)
 		if err := feed.WriteAtom(ctx.Resp); err != nil {
 			ctx.ServerError("Render Atom failed", err)
 		}
 	} else {
 		ctx.Resp.Header().Set("Content-Type", "application/rss+xml;charset=utf-8")
+		ctx.Resp.WriteHeader(http.StatusOK)
 		if err := feed.WriteRss(ctx.Resp); err != nil {
 			ctx.ServerError("Render RSS failed", err)
 		}
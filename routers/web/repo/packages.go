@@ -27,25 +27,35 @@ func Packages(ctx *context.Context) {
 		page = 1
 	}
 	query := ctx.FormTrim("q")
+	keyword := ctx.FormTrim("keyword")
 	packageType := ctx.FormTrim("type")
 
+	canReadPrivate, err := context.CanReadPrivatePackages(ctx, ctx.Doer, ctx.ContextUser)
+	if err != nil {
+		ctx.ServerError("CanReadPrivatePackages", err)
+		return
+	}
+
 	pvs, total, err := packages.SearchLatestVersions(ctx, &packages.PackageSearchOptions{
 		Paginator: &db.ListOptions{
 			PageSize: setting.UI.PackagesPagingNum,
 			Page:     page,
 		},
-		OwnerID:    ctx.ContextUser.ID,
-		RepoID:     ctx.Repo.Repository.ID,
-		Type:       packages.Type(packageType),
-		Name:       packages.SearchValue{Value: query},
-		IsInternal: util.OptionalBoolFalse,
+		OwnerID:            ctx.ContextUser.ID,
+		RepoID:             ctx.Repo.Repository.ID,
+		Type:               packages.Type(packageType),
+		Name:               packages.SearchValue{Value: query},
+		IncludeDescription: true,
+		Keyword:            keyword,
+		IsInternal:         util.OptionalBoolFalse,
+		HidePrivate:        !canReadPrivate,
 	})
 	if err != nil {
 		ctx.ServerError("SearchLatestVersions", err)
 		return
 	}
 
-	pds, err := packages.GetPackageDescriptors(ctx, pvs)
+	pds, err := packages.GetPackageDescriptors(ctx, pvs, packages.DescriptorLoadOptions{SkipMetadata: true})
 	if err != nil {
 		ctx.ServerError("GetPackageDescriptors", err)
 		return
@@ -61,6 +71,7 @@ func Packages(ctx *context.Context) {
 	ctx.Data["IsPackagesPage"] = true
 	ctx.Data["ContextUser"] = ctx.ContextUser
 	ctx.Data["Query"] = query
+	ctx.Data["Keyword"] = keyword
 	ctx.Data["PackageType"] = packageType
 	ctx.Data["HasPackages"] = hasPackages
 	if ctx.Repo != nil {
@@ -72,6 +83,7 @@ func Packages(ctx *context.Context) {
 
 	pager := context.NewPagination(int(total), setting.UI.PackagesPagingNum, page, 5)
 	pager.AddParam(ctx, "q", "Query")
+	pager.AddParam(ctx, "keyword", "Keyword")
 	pager.AddParam(ctx, "type", "PackageType")
 	ctx.Data["Page"] = pager
 
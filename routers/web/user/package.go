@@ -5,7 +5,11 @@
 package user
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"code.gitea.io/gitea/models/db"
 	org_model "code.gitea.io/gitea/models/organization"
@@ -14,12 +18,16 @@ import (
 	"code.gitea.io/gitea/models/perm"
 	access_model "code.gitea.io/gitea/models/perm/access"
 	repo_model "code.gitea.io/gitea/models/repo"
+	"code.gitea.io/gitea/models/unit"
+	user_model "code.gitea.io/gitea/models/user"
 	"code.gitea.io/gitea/modules/base"
 	"code.gitea.io/gitea/modules/context"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/timeutil"
 	"code.gitea.io/gitea/modules/util"
 	"code.gitea.io/gitea/modules/web"
+	"code.gitea.io/gitea/routers/web/feed"
 	"code.gitea.io/gitea/services/forms"
 	packages_service "code.gitea.io/gitea/services/packages"
 )
@@ -29,6 +37,8 @@ const (
 	tplPackagesView       base.TplName = "package/view"
 	tplPackageVersionList base.TplName = "user/overview/package_versions"
 	tplPackagesSettings   base.TplName = "package/settings"
+	tplPackagesAudit      base.TplName = "user/overview/package_audit"
+	tplPackagesBulkDelete base.TplName = "package/bulk_delete"
 )
 
 // ListPackages displays a list of all packages of the context user
@@ -38,24 +48,30 @@ func ListPackages(ctx *context.Context) {
 		page = 1
 	}
 	query := ctx.FormTrim("q")
+	keyword := ctx.FormTrim("keyword")
 	packageType := ctx.FormTrim("type")
+	sort := ctx.FormTrim("sort")
 
 	pvs, total, err := packages_model.SearchLatestVersions(ctx, &packages_model.PackageSearchOptions{
 		Paginator: &db.ListOptions{
 			PageSize: setting.UI.PackagesPagingNum,
 			Page:     page,
 		},
-		OwnerID:    ctx.ContextUser.ID,
-		Type:       packages_model.Type(packageType),
-		Name:       packages_model.SearchValue{Value: query},
-		IsInternal: util.OptionalBoolFalse,
+		OwnerID:            ctx.ContextUser.ID,
+		Type:               packages_model.Type(packageType),
+		Name:               packages_model.SearchValue{Value: query},
+		IncludeDescription: true,
+		Keyword:            keyword,
+		IsInternal:         util.OptionalBoolFalse,
+		HidePrivate:        !ctx.Package.CanReadPrivate,
+		Sort:               sort,
 	})
 	if err != nil {
 		ctx.ServerError("SearchLatestVersions", err)
 		return
 	}
 
-	pds, err := packages_model.GetPackageDescriptors(ctx, pvs)
+	pds, err := packages_model.GetPackageDescriptors(ctx, pvs, packages_model.DescriptorLoadOptions{SkipMetadata: true})
 	if err != nil {
 		ctx.ServerError("GetPackageDescriptors", err)
 		return
@@ -84,15 +100,48 @@ func ListPackages(ctx *context.Context) {
 		return
 	}
 
+	countByType := packages_model.CountPublicByType
+	if ctx.Package.CanReadPrivate {
+		countByType = packages_model.CountByType
+	}
+	counts, err := countByType(ctx, ctx.ContextUser.ID)
+	if err != nil {
+		ctx.ServerError("CountByType", err)
+		return
+	}
+	typeCounts := make(map[string]int64, len(counts))
+	for pt, count := range counts {
+		typeCounts[string(pt)] = count
+	}
+
 	ctx.Data["Title"] = ctx.Tr("packages.title")
 	ctx.Data["IsPackagesPage"] = true
 	ctx.Data["ContextUser"] = ctx.ContextUser
 	ctx.Data["Query"] = query
+	ctx.Data["Keyword"] = keyword
 	ctx.Data["PackageType"] = packageType
+	ctx.Data["SortType"] = sort
 	ctx.Data["HasPackages"] = hasPackages
+	ctx.Data["PackageTypeCounts"] = typeCounts
 	ctx.Data["PackageDescriptors"] = pds
 	ctx.Data["Total"] = total
 	ctx.Data["RepositoryAccessMap"] = repositoryAccessMap
+	ctx.Data["CanViewPackageAudit"] = ctx.Package.AccessMode >= perm.AccessModeAdmin
+
+	if ctx.Package.AccessMode >= perm.AccessModeAdmin {
+		stats, err := packages_model.GetOwnerStats(ctx, ctx.ContextUser.ID)
+		if err != nil {
+			ctx.ServerError("GetOwnerStats", err)
+			return
+		}
+		pq, err := packages_model.GetQuotaByOwnerID(ctx, ctx.ContextUser.ID)
+		if err != nil {
+			ctx.ServerError("GetQuotaByOwnerID", err)
+			return
+		}
+		ctx.Data["OwnerPackageStats"] = stats
+		ctx.Data["PackageSizeLimit"] = packages_service.QuotaSizeLimit(pq)
+	}
 
 	// TODO: context/org -> HandleOrgAssignment() can not be used
 	if ctx.ContextUser.IsOrganization() {
@@ -111,15 +160,76 @@ func ListPackages(ctx *context.Context) {
 
 	pager := context.NewPagination(int(total), setting.UI.PackagesPagingNum, page, 5)
 	pager.AddParam(ctx, "q", "Query")
+	pager.AddParam(ctx, "keyword", "Keyword")
 	pager.AddParam(ctx, "type", "PackageType")
+	pager.AddParam(ctx, "sort", "SortType")
 	ctx.Data["Page"] = pager
 
 	ctx.HTML(http.StatusOK, tplPackagesList)
 }
 
+// ListPackageAudit displays the audit log of the packages of the context user
+func ListPackageAudit(ctx *context.Context) {
+	page := ctx.FormInt("page")
+	if page <= 1 {
+		page = 1
+	}
+	action := ctx.FormTrim("action")
+
+	pas, total, err := packages_model.SearchAudits(ctx, &packages_model.AuditSearchOptions{
+		Paginator: &db.ListOptions{
+			PageSize: setting.UI.PackagesPagingNum,
+			Page:     page,
+		},
+		OwnerID: ctx.ContextUser.ID,
+		Action:  packages_model.AuditAction(action),
+	})
+	if err != nil {
+		ctx.ServerError("SearchAudits", err)
+		return
+	}
+
+	actorIDs := make([]int64, 0, len(pas))
+	for _, pa := range pas {
+		if pa.ActorID != 0 {
+			actorIDs = append(actorIDs, pa.ActorID)
+		}
+	}
+	actors, err := user_model.GetUsersByIDs(actorIDs)
+	if err != nil {
+		ctx.ServerError("GetUsersByIDs", err)
+		return
+	}
+	actorMap := make(map[int64]*user_model.User, len(actors))
+	for _, actor := range actors {
+		actorMap[actor.ID] = actor
+	}
+	for _, pa := range pas {
+		if _, has := actorMap[pa.ActorID]; pa.ActorID != 0 && !has {
+			actorMap[pa.ActorID] = user_model.NewGhostUser()
+		}
+	}
+
+	ctx.Data["Title"] = ctx.Tr("packages.audit")
+	ctx.Data["IsPackagesPage"] = true
+	ctx.Data["ContextUser"] = ctx.ContextUser
+	ctx.Data["Action"] = action
+	ctx.Data["AuditEntries"] = pas
+	ctx.Data["Actors"] = actorMap
+	ctx.Data["Total"] = total
+
+	pager := context.NewPagination(int(total), setting.UI.PackagesPagingNum, page, 5)
+	pager.AddParam(ctx, "action", "Action")
+	ctx.Data["Page"] = pager
+
+	ctx.HTML(http.StatusOK, tplPackagesAudit)
+}
+
 // RedirectToLastVersion redirects to the latest package version
 func RedirectToLastVersion(ctx *context.Context) {
-	p, err := packages_model.GetPackageByName(ctx, ctx.Package.Owner.ID, packages_model.Type(ctx.Params("type")), ctx.Params("name"))
+	isFeed, name, showFeedType := feed.GetFeedType(ctx.Params("name"), ctx.Req)
+
+	p, err := packages_model.GetPackageByName(ctx, ctx.Package.Owner.ID, packages_model.Type(ctx.Params("type")), name)
 	if err != nil {
 		if err == packages_model.ErrPackageNotExist {
 			ctx.NotFound("GetPackageByName", err)
@@ -129,14 +239,56 @@ func RedirectToLastVersion(ctx *context.Context) {
 		return
 	}
 
+	// the package assignment can't check the visibility if the name has a feed suffix
+	if !ctx.Package.CanReadPrivate {
+		private, err := packages_model.IsPackagePrivate(ctx, p)
+		if err != nil {
+			ctx.ServerError("IsPackagePrivate", err)
+			return
+		}
+		if private {
+			ctx.NotFound("GetPackageByName", packages_model.ErrPackageNotExist)
+			return
+		}
+	}
+
+	if isFeed {
+		feed.ShowPackageFeed(ctx, p, showFeedType)
+		return
+	}
+
 	pvs, _, err := packages_model.SearchLatestVersions(ctx, &packages_model.PackageSearchOptions{
-		PackageID:  p.ID,
-		IsInternal: util.OptionalBoolFalse,
+		PackageID:    p.ID,
+		IsInternal:   util.OptionalBoolFalse,
+		IsPrerelease: util.OptionalBoolFalse,
 	})
 	if err != nil {
-		ctx.ServerError("GetPackageByName", err)
+		ctx.ServerError("SearchLatestVersions", err)
 		return
 	}
+	if len(pvs) == 0 {
+		// there are prereleases only
+		pvs, _, err = packages_model.SearchLatestVersions(ctx, &packages_model.PackageSearchOptions{
+			PackageID:  p.ID,
+			IsInternal: util.OptionalBoolFalse,
+		})
+		if err != nil {
+			ctx.ServerError("SearchLatestVersions", err)
+			return
+		}
+	}
+	if len(pvs) == 0 {
+		// all versions are yanked
+		pvs, _, err = packages_model.SearchVersions(ctx, &packages_model.PackageSearchOptions{
+			Paginator:  db.NewAbsoluteListOptions(0, 1),
+			PackageID:  p.ID,
+			IsInternal: util.OptionalBoolFalse,
+		})
+		if err != nil {
+			ctx.ServerError("SearchVersions", err)
+			return
+		}
+	}
 	if len(pvs) == 0 {
 		ctx.NotFound("", err)
 		return
@@ -159,6 +311,7 @@ func ViewPackageVersion(ctx *context.Context) {
 	ctx.Data["IsPackagesPage"] = true
 	ctx.Data["ContextUser"] = ctx.ContextUser
 	ctx.Data["PackageDescriptor"] = pd
+	ctx.Data["FeedURL"] = pd.PackageWebLink()
 
 	var (
 		total int64
@@ -206,6 +359,15 @@ func ViewPackageVersion(ctx *context.Context) {
 	}
 	ctx.Data["HasRepositoryAccess"] = hasRepositoryAccess
 
+	if hasRepositoryAccess {
+		releases, err := packages_model.GetReleasesByVersionID(ctx, pd.Version.ID)
+		if err != nil {
+			ctx.ServerError("GetReleasesByVersionID", err)
+			return
+		}
+		ctx.Data["Releases"] = releases
+	}
+
 	ctx.HTML(http.StatusOK, tplPackagesView)
 }
 
@@ -240,6 +402,7 @@ func ListPackageVersions(ctx *context.Context) {
 		Owner:   ctx.Package.Owner,
 	}
 	ctx.Data["Query"] = query
+	ctx.Data["CanWritePackages"] = ctx.Package.AccessMode >= perm.AccessModeWrite || ctx.IsUserSiteAdmin()
 
 	pagerParams := map[string]string{
 		"q": query,
@@ -282,7 +445,7 @@ func ListPackageVersions(ctx *context.Context) {
 		}
 	}
 
-	ctx.Data["PackageDescriptors"], err = packages_model.GetPackageDescriptors(ctx, pvs)
+	ctx.Data["PackageDescriptors"], err = packages_model.GetPackageDescriptors(ctx, pvs, packages_model.DescriptorLoadOptions{SkipMetadata: true})
 	if err != nil {
 		ctx.ServerError("GetPackageDescriptors", err)
 		return
@@ -299,6 +462,129 @@ func ListPackageVersions(ctx *context.Context) {
 	ctx.HTML(http.StatusOK, tplPackageVersionList)
 }
 
+// parseBulkDeleteFilter parses the version filter of the bulk delete form and stores the values to fill the form again
+func parseBulkDeleteFilter(ctx *context.Context) (*packages_model.VersionFilter, bool) {
+	versionPattern := ctx.FormTrim("version_pattern")
+	createdBefore := ctx.FormTrim("created_before")
+	keepLatest := ctx.FormInt("keep_latest")
+
+	ctx.Data["VersionPattern"] = versionPattern
+	ctx.Data["CreatedBefore"] = createdBefore
+	ctx.Data["KeepLatest"] = keepLatest
+
+	if keepLatest < 0 {
+		return nil, false
+	}
+
+	filter := &packages_model.VersionFilter{
+		VersionPattern: versionPattern,
+		KeepLatest:     keepLatest,
+	}
+	if createdBefore != "" {
+		t, err := time.ParseInLocation("2006-01-02", createdBefore, setting.DefaultUILocation)
+		if err != nil {
+			return nil, false
+		}
+		filter.CreatedBefore = timeutil.TimeStamp(t.Unix())
+	}
+	return filter, true
+}
+
+// BulkDeletePackageVersions displays the versions which match the bulk delete filter without deleting them
+func BulkDeletePackageVersions(ctx *context.Context) {
+	p, err := packages_model.GetPackageByName(ctx, ctx.Package.Owner.ID, packages_model.Type(ctx.Params("type")), ctx.Params("name"))
+	if err != nil {
+		if err == packages_model.ErrPackageNotExist {
+			ctx.NotFound("GetPackageByName", err)
+		} else {
+			ctx.ServerError("GetPackageByName", err)
+		}
+		return
+	}
+
+	ctx.Data["Title"] = ctx.Tr("packages.versions.bulk_delete")
+	ctx.Data["IsPackagesPage"] = true
+	ctx.Data["ContextUser"] = ctx.ContextUser
+	ctx.Data["PackageDescriptor"] = &packages_model.PackageDescriptor{
+		Package: p,
+		Owner:   ctx.Package.Owner,
+	}
+
+	filter, ok := parseBulkDeleteFilter(ctx)
+	if !ok {
+		ctx.Data["Err_Filter"] = true
+		ctx.Flash.Error(ctx.Tr("packages.versions.bulk_delete.invalid_filter"), true)
+		ctx.HTML(http.StatusOK, tplPackagesBulkDelete)
+		return
+	}
+
+	result, err := packages_service.DeleteVersionsByFilter(ctx, ctx.Doer, ctx.Package.Owner.ID, p.ID, filter, true)
+	if err != nil {
+		switch err {
+		case packages_model.ErrInvalidVersionPattern:
+			ctx.Data["Err_Filter"] = true
+			ctx.Flash.Error(ctx.Tr("packages.versions.bulk_delete.invalid_filter"), true)
+		case packages_service.ErrVersionImmutable:
+			ctx.Flash.Error(ctx.Tr("packages.versions.bulk_delete.immutable"), true)
+		case packages_service.ErrVersionProtected:
+			ctx.Flash.Error(ctx.Tr("packages.versions.bulk_delete.protected"), true)
+		default:
+			ctx.ServerError("DeleteVersionsByFilter", err)
+			return
+		}
+		ctx.HTML(http.StatusOK, tplPackagesBulkDelete)
+		return
+	}
+
+	ctx.Data["Result"] = result
+
+	ctx.HTML(http.StatusOK, tplPackagesBulkDelete)
+}
+
+// BulkDeletePackageVersionsPost deletes the versions which match the bulk delete filter
+func BulkDeletePackageVersionsPost(ctx *context.Context) {
+	p, err := packages_model.GetPackageByName(ctx, ctx.Package.Owner.ID, packages_model.Type(ctx.Params("type")), ctx.Params("name"))
+	if err != nil {
+		if err == packages_model.ErrPackageNotExist {
+			ctx.NotFound("GetPackageByName", err)
+		} else {
+			ctx.ServerError("GetPackageByName", err)
+		}
+		return
+	}
+
+	versionsLink := fmt.Sprintf("%s/-/packages/%s/%s/versions", ctx.Package.Owner.HTMLURL(), string(p.Type), url.PathEscape(p.LowerName))
+
+	filter, ok := parseBulkDeleteFilter(ctx)
+	if !ok {
+		ctx.Flash.Error(ctx.Tr("packages.versions.bulk_delete.invalid_filter"))
+		ctx.Redirect(versionsLink)
+		return
+	}
+
+	result, err := packages_service.DeleteVersionsByFilter(ctx, ctx.Doer, ctx.Package.Owner.ID, p.ID, filter, false)
+	if err != nil {
+		switch err {
+		case packages_model.ErrInvalidVersionPattern:
+			ctx.Flash.Error(ctx.Tr("packages.versions.bulk_delete.invalid_filter"))
+		case packages_model.ErrEmptyVersionFilter:
+			ctx.Flash.Error(ctx.Tr("packages.versions.bulk_delete.empty_filter"))
+		case packages_service.ErrVersionImmutable:
+			ctx.Flash.Error(ctx.Tr("packages.versions.bulk_delete.immutable"))
+		case packages_service.ErrVersionProtected:
+			ctx.Flash.Error(ctx.Tr("packages.versions.bulk_delete.protected"))
+		default:
+			log.Error("Error deleting package versions: %v", err)
+			ctx.Flash.Error(ctx.Tr("packages.versions.bulk_delete.error"))
+		}
+		ctx.Redirect(versionsLink)
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("packages.versions.bulk_delete.success", len(result.Versions)))
+	ctx.Redirect(versionsLink)
+}
+
 // PackageSettings displays the package settings page
 func PackageSettings(ctx *context.Context) {
 	pd := ctx.Package.Descriptor
@@ -315,9 +601,74 @@ func PackageSettings(ctx *context.Context) {
 	ctx.Data["Repos"] = repos
 	ctx.Data["CanWritePackages"] = ctx.Package.AccessMode >= perm.AccessModeWrite || ctx.IsUserSiteAdmin()
 
+	canTransfer, err := packages_service.CanAdministrateOwnerPackages(ctx, ctx.Doer, pd.Owner)
+	if err != nil {
+		ctx.ServerError("CanAdministrateOwnerPackages", err)
+		return
+	}
+	ctx.Data["CanTransferPackage"] = canTransfer
+	ctx.Data["CanRenamePackage"] = canTransfer && packages_service.CanRenamePackage(pd.Package.Type)
+
+	isProtected, err := packages_model.IsVersionProtected(ctx, pd.Version.ID)
+	if err != nil {
+		ctx.ServerError("IsVersionProtected", err)
+		return
+	}
+	ctx.Data["IsVersionProtected"] = isProtected
+	ctx.Data["CanChangePackageVisibility"] = canTransfer
+	ctx.Data["PackageVisibility"] = pd.Package.Visibility.String()
+
+	if canTransfer && pd.Owner.IsOrganization() {
+		teamAccesses, err := getTeamPackageTypeAccesses(ctx, pd.Owner.ID, pd.Package.Type)
+		if err != nil {
+			ctx.ServerError("getTeamPackageTypeAccesses", err)
+			return
+		}
+		ctx.Data["TeamAccesses"] = teamAccesses
+	}
+
 	ctx.HTML(http.StatusOK, tplPackagesSettings)
 }
 
+// teamPackageTypeAccess is the access of a team to a package type, an empty access mode means the access mode of the packages unit applies
+type teamPackageTypeAccess struct {
+	Team       *org_model.Team
+	UnitAccess string
+	AccessMode string
+}
+
+// getTeamPackageTypeAccesses returns the access of the organization teams to the package type. Admin and owner teams always have full access and are omitted.
+func getTeamPackageTypeAccesses(ctx *context.Context, orgID int64, packageType packages_model.Type) ([]*teamPackageTypeAccess, error) {
+	teams, err := org_model.FindOrgTeams(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	tpts, err := org_model.GetOrgTeamPackageTypes(ctx, orgID, string(packageType))
+	if err != nil {
+		return nil, err
+	}
+	accessModes := make(map[int64]perm.AccessMode, len(tpts))
+	for _, tpt := range tpts {
+		accessModes[tpt.TeamID] = tpt.AccessMode
+	}
+
+	teamAccesses := make([]*teamPackageTypeAccess, 0, len(teams))
+	for _, t := range teams {
+		if t.AccessMode >= perm.AccessModeAdmin {
+			continue
+		}
+		ta := &teamPackageTypeAccess{
+			Team:       t,
+			UnitAccess: t.UnitAccessModeCtx(ctx, unit.TypePackages).String(),
+		}
+		if accessMode, ok := accessModes[t.ID]; ok {
+			ta.AccessMode = accessMode.String()
+		}
+		teamAccesses = append(teamAccesses, ta)
+	}
+	return teamAccesses, nil
+}
+
 // PackageSettingsPost updates the package settings
 func PackageSettingsPost(ctx *context.Context) {
 	pd := ctx.Package.Descriptor
@@ -326,9 +677,10 @@ func PackageSettingsPost(ctx *context.Context) {
 	switch form.Action {
 	case "link":
 		success := func() bool {
-			repoID := int64(0)
+			var repo *repo_model.Repository
 			if form.RepoID != 0 {
-				repo, err := repo_model.GetRepositoryByID(form.RepoID)
+				var err error
+				repo, err = repo_model.GetRepositoryByID(form.RepoID)
 				if err != nil {
 					log.Error("Error getting repository: %v", err)
 					return false
@@ -337,11 +689,9 @@ func PackageSettingsPost(ctx *context.Context) {
 				if repo.OwnerID != pd.Owner.ID {
 					return false
 				}
-
-				repoID = repo.ID
 			}
 
-			if err := packages_model.SetRepositoryLink(ctx, pd.Package.ID, repoID); err != nil {
+			if err := packages_service.LinkPackageToRepository(ctx.Doer, pd.Package, repo); err != nil {
 				log.Error("Error updating package: %v", err)
 				return false
 			}
@@ -355,10 +705,219 @@ func PackageSettingsPost(ctx *context.Context) {
 			ctx.Flash.Error(ctx.Tr("packages.settings.link.error"))
 		}
 
+		ctx.Redirect(ctx.Link)
+		return
+	case "transfer":
+		newOwner, err := func() (*user_model.User, error) {
+			newOwner, err := user_model.GetUserByName(ctx, form.NewOwner)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, owner := range []*user_model.User{pd.Owner, newOwner} {
+				canAdministrate, err := packages_service.CanAdministrateOwnerPackages(ctx, ctx.Doer, owner)
+				if err != nil {
+					return nil, err
+				}
+				if !canAdministrate {
+					return nil, user_model.ErrUserNotExist{Name: form.NewOwner}
+				}
+			}
+
+			return newOwner, packages_service.TransferPackage(ctx.Doer, pd.Package, newOwner)
+		}()
+		if err != nil {
+			if err == packages_model.ErrDuplicatePackage {
+				ctx.Flash.Error(ctx.Tr("packages.settings.transfer.duplicate", form.NewOwner))
+			} else if packages_service.IsErrQuotaExceeded(err) || packages_service.IsErrTypeLimitExceeded(err) {
+				ctx.Flash.Error(ctx.Tr("packages.settings.transfer.quota_exceeded", form.NewOwner))
+			} else if user_model.IsErrUserNotExist(err) {
+				ctx.Flash.Error(ctx.Tr("form.enterred_invalid_owner_name"))
+			} else {
+				log.Error("Error transferring package: %v", err)
+				ctx.Flash.Error(ctx.Tr("packages.settings.transfer.error"))
+			}
+			ctx.Redirect(ctx.Link)
+			return
+		}
+
+		ctx.Flash.Success(ctx.Tr("packages.settings.transfer.success", newOwner.Name))
+		ctx.Redirect(fmt.Sprintf("%s/-/packages/%s/%s", newOwner.HTMLURL(), string(pd.Package.Type), url.PathEscape(pd.Package.LowerName)))
+		return
+	case "rename":
+		canAdministrate, err := packages_service.CanAdministrateOwnerPackages(ctx, ctx.Doer, pd.Owner)
+		if err != nil {
+			ctx.ServerError("CanAdministrateOwnerPackages", err)
+			return
+		}
+		if !canAdministrate {
+			ctx.NotFound("", nil)
+			return
+		}
+
+		newName := strings.TrimSpace(form.NewName)
+		if ctx.HasError() || newName == "" {
+			ctx.Flash.Error(ctx.Tr("packages.settings.rename.error"))
+			ctx.Redirect(ctx.Link)
+			return
+		}
+
+		if err := packages_service.RenamePackage(ctx.Doer, pd.Package, newName); err != nil {
+			if err == packages_model.ErrDuplicatePackage {
+				ctx.Flash.Error(ctx.Tr("packages.settings.rename.duplicate", newName))
+			} else if err == packages_service.ErrInvalidPackageName {
+				ctx.Flash.Error(ctx.Tr("packages.settings.rename.invalid"))
+			} else if err == packages_service.ErrRenameNotSupported {
+				ctx.Flash.Error(ctx.Tr("packages.settings.rename.not_supported"))
+			} else {
+				log.Error("Error renaming package: %v", err)
+				ctx.Flash.Error(ctx.Tr("packages.settings.rename.error"))
+			}
+			ctx.Redirect(ctx.Link)
+			return
+		}
+
+		ctx.Flash.Success(ctx.Tr("packages.settings.rename.success", newName))
+		ctx.Redirect(fmt.Sprintf("%s/-/packages/%s/%s", pd.Owner.HTMLURL(), string(pd.Package.Type), url.PathEscape(pd.Package.LowerName)))
+		return
+	case "yank", "unyank":
+		if ctx.HasError() {
+			ctx.Flash.Error(ctx.GetErrMsg())
+			ctx.Redirect(ctx.Link)
+			return
+		}
+
+		yanked := form.Action == "yank"
+		if err := packages_service.SetPackageVersionYanked(ctx.Doer, pd.Version, yanked, form.YankReason); err != nil {
+			log.Error("Error yanking package version: %v", err)
+			ctx.Flash.Error(ctx.Tr("packages.settings.yank.error"))
+		} else if yanked {
+			ctx.Flash.Success(ctx.Tr("packages.settings.yank.success"))
+		} else {
+			ctx.Flash.Success(ctx.Tr("packages.settings.unyank.success"))
+		}
+
+		ctx.Redirect(ctx.Link)
+		return
+	case "release_notes":
+		if ctx.HasError() {
+			ctx.Flash.Error(ctx.GetErrMsg())
+			ctx.Redirect(ctx.Link)
+			return
+		}
+
+		if err := packages_service.SetPackageVersionReleaseNotes(ctx.Doer, pd.Version, form.ReleaseNotes); err != nil {
+			log.Error("Error updating package version release notes: %v", err)
+			ctx.Flash.Error(ctx.Tr("packages.settings.release_notes.error"))
+		} else {
+			ctx.Flash.Success(ctx.Tr("packages.settings.release_notes.success"))
+		}
+
+		ctx.Redirect(ctx.Link)
+		return
+	case "immutable", "mutable":
+		immutable := form.Action == "immutable"
+		if !immutable && !ctx.IsUserSiteAdmin() {
+			ctx.Flash.Error(ctx.Tr("packages.settings.immutable.admin_only"))
+			ctx.Redirect(ctx.Link)
+			return
+		}
+
+		if err := packages_service.SetPackageImmutable(ctx.Doer, pd.Package, immutable); err != nil {
+			log.Error("Error updating package immutability: %v", err)
+			ctx.Flash.Error(ctx.Tr("packages.settings.immutable.error"))
+		} else if immutable {
+			ctx.Flash.Success(ctx.Tr("packages.settings.immutable.success"))
+		} else {
+			ctx.Flash.Success(ctx.Tr("packages.settings.mutable.success"))
+		}
+
+		ctx.Redirect(ctx.Link)
+		return
+	case "visibility":
+		canAdministrate, err := packages_service.CanAdministrateOwnerPackages(ctx, ctx.Doer, pd.Owner)
+		if err != nil {
+			ctx.ServerError("CanAdministrateOwnerPackages", err)
+			return
+		}
+		if !canAdministrate {
+			ctx.Flash.Error(ctx.Tr("packages.settings.visibility.admin_only"))
+			ctx.Redirect(ctx.Link)
+			return
+		}
+
+		visibility, ok := packages_model.ParseVisibility(form.Visibility)
+		if !ok {
+			ctx.Flash.Error(ctx.Tr("packages.settings.visibility.error"))
+			ctx.Redirect(ctx.Link)
+			return
+		}
+
+		if err := packages_service.SetPackageVisibility(ctx.Doer, pd.Package, visibility); err != nil {
+			log.Error("Error updating package visibility: %v", err)
+			ctx.Flash.Error(ctx.Tr("packages.settings.visibility.error"))
+		} else {
+			ctx.Flash.Success(ctx.Tr("packages.settings.visibility.success"))
+		}
+
+		ctx.Redirect(ctx.Link)
+		return
+	case "team_access":
+		canAdministrate, err := packages_service.CanAdministrateOwnerPackages(ctx, ctx.Doer, pd.Owner)
+		if err != nil {
+			ctx.ServerError("CanAdministrateOwnerPackages", err)
+			return
+		}
+		if !canAdministrate || !pd.Owner.IsOrganization() {
+			ctx.NotFound("", nil)
+			return
+		}
+
+		success := func() bool {
+			team, err := org_model.GetTeamByID(ctx, form.TeamID)
+			if err != nil {
+				log.Error("Error getting team: %v", err)
+				return false
+			}
+			if team.OrgID != pd.Owner.ID || team.AccessMode >= perm.AccessModeAdmin {
+				return false
+			}
+
+			switch form.TeamAccess {
+			case "":
+				err = org_model.RemoveTeamPackageType(ctx, team.ID, string(pd.Package.Type))
+			case "none", "read", "write":
+				err = org_model.SetTeamPackageType(team, string(pd.Package.Type), perm.ParseAccessMode(form.TeamAccess))
+			default:
+				return false
+			}
+			if err != nil {
+				log.Error("Error updating team package type access: %v", err)
+				return false
+			}
+			return true
+		}()
+
+		if success {
+			ctx.Flash.Success(ctx.Tr("packages.settings.team_access.success"))
+		} else {
+			ctx.Flash.Error(ctx.Tr("packages.settings.team_access.error"))
+		}
+
 		ctx.Redirect(ctx.Link)
 		return
 	case "delete":
-		err := packages_service.RemovePackageVersion(ctx.Doer, ctx.Package.Descriptor.Version)
+		err := packages_service.RemovePackageVersion(ctx.Doer, ctx.Package.Descriptor.Version, form.Force)
+		if err == packages_service.ErrVersionImmutable {
+			ctx.Flash.Error(ctx.Tr("packages.settings.delete.immutable"))
+			ctx.Redirect(ctx.Link)
+			return
+		}
+		if err == packages_service.ErrVersionProtected {
+			ctx.Flash.Error(ctx.Tr("packages.settings.delete.protected"))
+			ctx.Redirect(ctx.Link)
+			return
+		}
 		if err != nil {
 			log.Error("Error deleting package: %v", err)
 			ctx.Flash.Error(ctx.Tr("packages.settings.delete.error"))
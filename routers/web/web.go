@@ -322,6 +322,9 @@ func RegisterRoutes(m *web.Route) {
 		m.Get("/users", explore.Users)
 		m.Get("/users/sitemap-{idx}.xml", explore.Users)
 		m.Get("/organizations", explore.Organizations)
+		if setting.Packages.Enabled {
+			m.Get("/packages", explore.Packages)
+		}
 		m.Get("/code", explore.Code)
 		m.Get("/topics/search", explore.TopicSearch)
 	}, ignExploreSignIn)
@@ -517,7 +520,10 @@ func RegisterRoutes(m *web.Route) {
 		if setting.Packages.Enabled {
 			m.Group("/packages", func() {
 				m.Get("", admin.Packages)
+				m.Get("/inventory", admin.PackageInventory)
 				m.Post("/delete", admin.DeletePackageVersion)
+				m.Post("/cleanup", admin.CleanupPackages)
+				m.Combo("/limits").Get(admin.PackageTypeLimits).Post(admin.PackageTypeLimitsPost)
 			})
 		}
 
@@ -724,9 +730,14 @@ func RegisterRoutes(m *web.Route) {
 		if setting.Packages.Enabled {
 			m.Group("/packages", func() {
 				m.Get("", user.ListPackages)
+				m.Get("/audit", reqPackageAccess(perm.AccessModeAdmin), user.ListPackageAudit)
 				m.Group("/{type}/{name}", func() {
 					m.Get("", user.RedirectToLastVersion)
 					m.Get("/versions", user.ListPackageVersions)
+					m.Group("/versions/bulk-delete", func() {
+						m.Get("", user.BulkDeletePackageVersions)
+						m.Post("", user.BulkDeletePackageVersionsPost)
+					}, reqPackageAccess(perm.AccessModeWrite))
 					m.Group("/{version}", func() {
 						m.Get("", user.ViewPackageVersion)
 						m.Get("/files/{fileid}", user.DownloadPackageFile)
@@ -155,6 +155,68 @@ func registerCleanupPackages() {
 	})
 }
 
+func registerRefreshPackageSizeSummaries() {
+	RegisterTaskFatal("refresh_package_size_summaries", &BaseConfig{
+		Enabled:    true,
+		RunAtStart: true,
+		Schedule:   "@midnight",
+	}, func(ctx context.Context, _ *user_model.User, _ Config) error {
+		return packages_service.RefreshSizeSummaries(ctx)
+	})
+}
+
+func registerCleanupPackageAudit() {
+	RegisterTaskFatal("cleanup_package_audit", &OlderThanConfig{
+		BaseConfig: BaseConfig{
+			Enabled:    true,
+			RunAtStart: false,
+			Schedule:   "@midnight",
+		},
+		OlderThan: 90 * 24 * time.Hour,
+	}, func(ctx context.Context, _ *user_model.User, config Config) error {
+		realConfig := config.(*OlderThanConfig)
+		return packages_service.CleanupAuditLog(ctx, realConfig.OlderThan)
+	})
+}
+
+func registerCleanupStaleInternalPackageVersions() {
+	RegisterTaskFatal("cleanup_stale_internal_package_versions", &OlderThanConfig{
+		BaseConfig: BaseConfig{
+			Enabled:    true,
+			RunAtStart: false,
+			Schedule:   "@midnight",
+		},
+		OlderThan: 7 * 24 * time.Hour,
+	}, func(ctx context.Context, _ *user_model.User, config Config) error {
+		realConfig := config.(*OlderThanConfig)
+		return packages_service.CleanupStaleInternalVersions(ctx, realConfig.OlderThan)
+	})
+}
+
+func registerCleanupVersionlessPackages() {
+	RegisterTaskFatal("cleanup_versionless_packages", &OlderThanConfig{
+		BaseConfig: BaseConfig{
+			Enabled:    true,
+			RunAtStart: false,
+			Schedule:   "@midnight",
+		},
+		OlderThan: 24 * time.Hour,
+	}, func(ctx context.Context, _ *user_model.User, config Config) error {
+		realConfig := config.(*OlderThanConfig)
+		return packages_service.CleanupVersionlessPackages(ctx, realConfig.OlderThan)
+	})
+}
+
+func registerCleanupPackageUploadSessions() {
+	RegisterTaskFatal("cleanup_package_upload_sessions", &BaseConfig{
+		Enabled:    true,
+		RunAtStart: true,
+		Schedule:   "@every 1h",
+	}, func(ctx context.Context, _ *user_model.User, _ Config) error {
+		return packages_service.CleanupExpiredUploadSessions(ctx)
+	})
+}
+
 func initBasicTasks() {
 	if setting.Mirror.Enabled {
 		registerUpdateMirrorTask()
@@ -170,5 +232,10 @@ func initBasicTasks() {
 	registerCleanupHookTaskTable()
 	if setting.Packages.Enabled {
 		registerCleanupPackages()
+		registerRefreshPackageSizeSummaries()
+		registerCleanupPackageAudit()
+		registerCleanupStaleInternalPackageVersions()
+		registerCleanupVersionlessPackages()
+		registerCleanupPackageUploadSessions()
 	}
 }
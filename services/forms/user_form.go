@@ -435,8 +435,16 @@ func (f *WebauthnDeleteForm) Validate(req *http.Request, errs binding.Errors) bi
 
 // PackageSettingForm form for package settings
 type PackageSettingForm struct {
-	Action string
-	RepoID int64 `form:"repo_id"`
+	Action       string
+	RepoID       int64  `form:"repo_id"`
+	NewOwner     string `form:"new_owner"`
+	NewName      string `form:"new_name" binding:"MaxSize(255)"`
+	YankReason   string `form:"yank_reason" binding:"MaxSize(255)"`
+	ReleaseNotes string `form:"release_notes" binding:"MaxSize(65535)"`
+	Visibility   string
+	TeamID       int64  `form:"team_id"`
+	TeamAccess   string `form:"team_access"`
+	Force        bool
 }
 
 // Validate validates the fields
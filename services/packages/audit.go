@@ -0,0 +1,46 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"time"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/timeutil"
+)
+
+// InsertAuditEntry records an operation in the package audit log.
+// pv is nil for operations on the whole package, doer is nil for operations executed by the system.
+func InsertAuditEntry(ctx context.Context, doer *user_model.User, action packages_model.AuditAction, p *packages_model.Package, pv *packages_model.PackageVersion, details string) error {
+	pa := &packages_model.PackageAudit{
+		OwnerID:     p.OwnerID,
+		Action:      action,
+		PackageID:   p.ID,
+		PackageType: p.Type,
+		PackageName: p.Name,
+		Details:     details,
+	}
+	if doer != nil {
+		pa.ActorID = doer.ID
+	}
+	if pv != nil {
+		pa.VersionID = pv.ID
+		pa.Version = pv.Version
+	}
+	return packages_model.InsertAudit(ctx, pa)
+}
+
+// CleanupAuditLog removes audit log entries older than the specified duration
+func CleanupAuditLog(ctx context.Context, olderThan time.Duration) error {
+	deleted, err := packages_model.DeleteAuditsOlderThan(ctx, timeutil.TimeStamp(time.Now().Add(-olderThan).Unix()))
+	if err != nil {
+		return err
+	}
+	log.Trace("Deleted %d package audit log entries", deleted)
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/modules/log"
+	packages_module "code.gitea.io/gitea/modules/packages"
+	"code.gitea.io/gitea/modules/storage"
+)
+
+var (
+	// ErrBlobMissing indicates that the content of a package blob does not exist in the storage
+	ErrBlobMissing = errors.New("Package blob content is missing")
+	// ErrBlobCorrupt indicates that the content of a package blob does not match its size or hash
+	ErrBlobCorrupt = errors.New("Package blob content is corrupt")
+)
+
+// BlobRestoreResult describes the blobs processed by RestoreBlobs
+type BlobRestoreResult struct {
+	Restored int
+	Present  int
+	// Failed are the blobs which could not be restored because they are missing or corrupt in the source
+	Failed []*packages_model.PackageBlob
+}
+
+// VerifyBlob checks if the content of a blob exists in the storage and has the expected size.
+// If checkHash is set, the content is read and its SHA256 hash is compared too.
+func VerifyBlob(s storage.ObjectStorage, pb *packages_model.PackageBlob, checkHash bool) error {
+	p := packages_module.KeyToRelativePath(packages_module.BlobHash256Key(pb.HashSHA256))
+
+	fi, err := s.Stat(p)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrBlobMissing
+		}
+		return err
+	}
+	if fi.Size() != pb.Size {
+		return ErrBlobCorrupt
+	}
+	if !checkHash {
+		return nil
+	}
+
+	obj, err := s.Open(p)
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+
+	return verifyBlobContent(pb, obj)
+}
+
+func verifyBlobContent(pb *packages_model.PackageBlob, r io.Reader) error {
+	h := sha256.New()
+	size, err := io.Copy(h, r)
+	if err != nil {
+		return err
+	}
+	if size != pb.Size || hex.EncodeToString(h.Sum(nil)) != pb.HashSHA256 {
+		return ErrBlobCorrupt
+	}
+	return nil
+}
+
+// RestoreBlobs copies the content of all blobs which are missing or corrupt in the package storage from the source storage.
+// The source must use the package storage layout, for example the data/packages directory of a dump.
+// The content is only copied if its hash matches the blob.
+func RestoreBlobs(ctx context.Context, src storage.ObjectStorage) (*BlobRestoreResult, error) {
+	result := &BlobRestoreResult{}
+	err := db.IterateObjects(ctx, func(pb *packages_model.PackageBlob) error {
+		err := VerifyBlob(storage.Packages, pb, false)
+		if err == nil {
+			result.Present++
+			return nil
+		}
+		if err != ErrBlobMissing && err != ErrBlobCorrupt {
+			return err
+		}
+
+		if err := VerifyBlob(src, pb, true); err != nil {
+			if err != ErrBlobMissing && err != ErrBlobCorrupt {
+				return err
+			}
+			log.Error("Unable to restore package blob %d (%s): %v", pb.ID, pb.HashSHA256, err)
+			result.Failed = append(result.Failed, pb)
+			return nil
+		}
+
+		p := packages_module.KeyToRelativePath(packages_module.BlobHash256Key(pb.HashSHA256))
+		if _, err := storage.Copy(storage.Packages, p, src, p); err != nil {
+			return err
+		}
+		result.Restored++
+		return nil
+	})
+	return result, err
+}
@@ -0,0 +1,127 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/notification"
+)
+
+// bulkDeleteBatchSize is the number of versions deleted in one transaction
+const bulkDeleteBatchSize = 50
+
+// BulkDeleteResult describes the versions deleted by DeleteVersionsByFilter
+type BulkDeleteResult struct {
+	// Versions are the matched versions, newest first
+	Versions []*packages_model.PackageVersion
+	// FileCount is the number of files of the versions
+	FileCount int64
+	// FreedSize is the size of the blobs which are not referenced anymore after the deletion.
+	// The blobs are removed by the package cleanup.
+	FreedSize int64
+	// DryRun is true if the versions were only matched but not deleted
+	DryRun bool
+}
+
+// DeleteVersionsByFilter deletes the versions of a package which match the filter.
+// If dryRun is set, the versions are only matched and nothing gets deleted.
+// The versions are deleted in batches with a transaction per batch. If an error occurs, the already deleted batches stay deleted.
+// Versions of immutable packages can only be deleted by site administrators, otherwise ErrVersionImmutable is returned.
+// Versions referenced by a release of the linked repository are never deleted in bulk, ErrVersionProtected is returned instead.
+// An empty filter would delete all versions, so it is only accepted for a dry run, otherwise ErrEmptyVersionFilter is returned.
+func DeleteVersionsByFilter(ctx context.Context, doer *user_model.User, ownerID, packageID int64, filter *packages_model.VersionFilter, dryRun bool) (*BulkDeleteResult, error) {
+	p, err := packages_model.GetPackageByID(ctx, packageID, false)
+	if err != nil {
+		return nil, err
+	}
+	if p.OwnerID != ownerID {
+		return nil, packages_model.ErrPackageNotExist
+	}
+	if !dryRun && filter.IsEmpty() {
+		return nil, packages_model.ErrEmptyVersionFilter
+	}
+
+	pvs, err := packages_model.FindVersionsByFilter(ctx, p.ID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if doer == nil || !doer.IsAdmin {
+		for _, pv := range pvs {
+			if IsVersionImmutable(p, pv) {
+				return nil, ErrVersionImmutable
+			}
+		}
+	}
+
+	versionIDs := make([]int64, 0, len(pvs))
+	for _, pv := range pvs {
+		versionIDs = append(versionIDs, pv.ID)
+	}
+
+	protected, err := packages_model.GetProtectedVersionIDs(ctx, versionIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(protected) > 0 {
+		return nil, ErrVersionProtected
+	}
+
+	usage, err := packages_model.GetVersionsUsage(ctx, versionIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BulkDeleteResult{
+		Versions:  pvs,
+		FileCount: usage.FileCount,
+		FreedSize: usage.FreeableSize,
+		DryRun:    dryRun,
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	for start := 0; start < len(pvs); start += bulkDeleteBatchSize {
+		end := start + bulkDeleteBatchSize
+		if end > len(pvs) {
+			end = len(pvs)
+		}
+
+		pds := make([]*packages_model.PackageDescriptor, 0, end-start)
+		if err := db.WithTx(func(ctx context.Context) error {
+			for _, pv := range pvs[start:end] {
+				pd, err := packages_model.GetPackageDescriptor(ctx, pv)
+				if err != nil {
+					return err
+				}
+
+				log.Trace("Deleting package: %v", pv.ID)
+
+				if err := DeletePackageVersionAndReferences(ctx, pv); err != nil {
+					return err
+				}
+				if err := InsertAuditEntry(ctx, doer, packages_model.AuditActionDeleteVersion, p, pv, ""); err != nil {
+					return err
+				}
+				pds = append(pds, pd)
+			}
+			return nil
+		}, ctx); err != nil {
+			return nil, err
+		}
+
+		for _, pd := range pds {
+			notification.NotifyPackageDelete(doer, pd)
+		}
+	}
+
+	return result, nil
+}
@@ -18,28 +18,9 @@ import (
 
 // Cleanup removes expired container data
 func Cleanup(ctx context.Context, olderThan time.Duration) error {
-	if err := cleanupExpiredBlobUploads(ctx, olderThan); err != nil {
-		return err
-	}
 	return cleanupExpiredUploadedBlobs(ctx, olderThan)
 }
 
-// cleanupExpiredBlobUploads removes expired blob uploads
-func cleanupExpiredBlobUploads(ctx context.Context, olderThan time.Duration) error {
-	pbus, err := packages_model.FindExpiredBlobUploads(ctx, olderThan)
-	if err != nil {
-		return err
-	}
-
-	for _, pbu := range pbus {
-		if err := RemoveBlobUploadByID(ctx, pbu.ID); err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
 // cleanupExpiredUploadedBlobs removes expired uploaded blobs not referenced by a manifest
 func cleanupExpiredUploadedBlobs(ctx context.Context, olderThan time.Duration) error {
 	pfs, err := container_model.SearchExpiredUploadedBlobs(ctx, olderThan)
@@ -0,0 +1,67 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/modules/packages/composer"
+	"code.gitea.io/gitea/modules/packages/conan"
+	"code.gitea.io/gitea/modules/packages/container"
+	"code.gitea.io/gitea/modules/packages/helm"
+	"code.gitea.io/gitea/modules/packages/maven"
+	"code.gitea.io/gitea/modules/packages/npm"
+	"code.gitea.io/gitea/modules/packages/nuget"
+	"code.gitea.io/gitea/modules/packages/pub"
+	"code.gitea.io/gitea/modules/packages/pypi"
+	"code.gitea.io/gitea/modules/packages/rubygems"
+	"code.gitea.io/gitea/modules/packages/vagrant"
+)
+
+// ExtractDescription gets the short description of the type specific metadata of a package version.
+// If the metadata contains a summary, it is preferred over the (possibly long) description.
+func ExtractDescription(metadata interface{}) string {
+	switch m := metadata.(type) {
+	case *composer.Metadata:
+		return m.Description
+	case *conan.Metadata:
+		return m.Description
+	case *container.Metadata:
+		return m.Description
+	case *helm.Metadata:
+		return m.Description
+	case helm.Metadata:
+		return m.Description
+	case *maven.Metadata:
+		return m.Description
+	case *npm.Metadata:
+		return m.Description
+	case npm.Metadata:
+		return m.Description
+	case *nuget.Metadata:
+		return m.Description
+	case *pub.Metadata:
+		return m.Description
+	case *pypi.Metadata:
+		if m.Summary != "" {
+			return m.Summary
+		}
+		return m.Description
+	case *rubygems.Metadata:
+		if m.Summary != "" {
+			return m.Summary
+		}
+		return m.Description
+	case *vagrant.Metadata:
+		return m.Description
+	}
+	return ""
+}
+
+// SetPackageDescription stores the description of the metadata as searchable description of the package
+func SetPackageDescription(ctx context.Context, packageID int64, metadata interface{}) error {
+	return packages_model.SetDescription(ctx, packageID, ExtractDescription(metadata))
+}
@@ -0,0 +1,86 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/modules/packages/composer"
+	"code.gitea.io/gitea/modules/packages/conan"
+	"code.gitea.io/gitea/modules/packages/helm"
+	"code.gitea.io/gitea/modules/packages/npm"
+	"code.gitea.io/gitea/modules/packages/nuget"
+
+	"xorm.io/builder"
+)
+
+// ExtractKeywords gets the normalized and deduplicated keywords of the type specific metadata of a package version
+func ExtractKeywords(metadata interface{}) []string {
+	var keywords []string
+	switch m := metadata.(type) {
+	case *composer.Metadata:
+		keywords = m.Keywords
+	case *conan.Metadata:
+		keywords = m.Keywords
+	case *helm.Metadata:
+		keywords = m.Keywords
+	case *npm.Metadata:
+		keywords = m.Keywords
+	case npm.Metadata:
+		keywords = m.Keywords
+	case *nuget.Metadata:
+		keywords = m.Tags
+	}
+
+	result := make([]string, 0, len(keywords))
+	seen := make(map[string]bool, len(keywords))
+	for _, keyword := range keywords {
+		keyword = packages_model.NormalizeKeyword(keyword)
+		if keyword == "" || seen[keyword] {
+			continue
+		}
+		seen[keyword] = true
+		result = append(result, keyword)
+	}
+	return result
+}
+
+// SetVersionKeywords replaces the stored keywords of a package version with the keywords of the metadata
+func SetVersionKeywords(ctx context.Context, versionID int64, metadata interface{}) error {
+	if err := packages_model.DeletePropertyByName(ctx, packages_model.PropertyTypeVersion, versionID, packages_model.PropertyKeyword); err != nil {
+		return err
+	}
+	for _, keyword := range ExtractKeywords(metadata) {
+		if _, err := packages_model.InsertProperty(ctx, packages_model.PropertyTypeVersion, versionID, packages_model.PropertyKeyword, keyword); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RebuildKeywords extracts the keywords of all package versions again and returns the number of processed versions.
+// This is needed for versions which were published before keywords got extracted.
+func RebuildKeywords(ctx context.Context) (int, error) {
+	count := 0
+	err := db.Iterate(
+		ctx,
+		new(packages_model.PackageVersion),
+		builder.Eq{"is_internal": false},
+		func(idx int, bean interface{}) error {
+			pd, err := packages_model.GetPackageDescriptor(ctx, bean.(*packages_model.PackageVersion))
+			if err != nil {
+				return err
+			}
+			if err := SetVersionKeywords(ctx, pd.Version.ID, pd.Metadata); err != nil {
+				return err
+			}
+			count++
+			return nil
+		},
+	)
+	return count, err
+}
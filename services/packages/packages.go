@@ -6,12 +6,15 @@ package packages
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"regexp"
 	"strings"
 	"time"
 
 	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/organization"
 	packages_model "code.gitea.io/gitea/models/packages"
 	repo_model "code.gitea.io/gitea/models/repo"
 	user_model "code.gitea.io/gitea/models/user"
@@ -19,10 +22,67 @@ import (
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/notification"
 	packages_module "code.gitea.io/gitea/modules/packages"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/timeutil"
 	"code.gitea.io/gitea/modules/util"
 	container_service "code.gitea.io/gitea/services/packages/container"
 )
 
+// ErrCopyNotSupported indicates that versions of the package type can not be copied to another owner
+var ErrCopyNotSupported = errors.New("Package versions of this type can not be copied")
+
+// ErrRenameNotSupported indicates that packages of the type can not be renamed because the name is part of the package metadata
+var ErrRenameNotSupported = errors.New("Packages of this type can not be renamed")
+
+// ErrInvalidPackageName indicates that a package can't be renamed to the name
+var ErrInvalidPackageName = errors.New("Package name is invalid")
+
+// ErrVersionImmutable indicates that a published version of an immutable package can't be overwritten or deleted
+var ErrVersionImmutable = errors.New("Package version is immutable")
+
+// ErrVersionProtected indicates that a version referenced by a release can't be deleted without force
+var ErrVersionProtected = errors.New("Package version is referenced by a release")
+
+// ErrQuotaExceeded represents a "QuotaExceeded" kind of error.
+type ErrQuotaExceeded struct {
+	OwnerID   int64
+	SizeLimit int64
+}
+
+// IsErrQuotaExceeded checks if an error is a ErrQuotaExceeded.
+func IsErrQuotaExceeded(err error) bool {
+	_, ok := err.(ErrQuotaExceeded)
+	return ok
+}
+
+func (err ErrQuotaExceeded) Error() string {
+	return fmt.Sprintf("package storage quota exceeded [owner_id: %d, limit: %d]", err.OwnerID, err.SizeLimit)
+}
+
+// Names of the package type limits
+const (
+	TypeLimitMaxVersions    = "max_versions"
+	TypeLimitMaxVersionSize = "max_version_size"
+	TypeLimitMaxOwnerSize   = "max_owner_size"
+)
+
+// ErrTypeLimitExceeded represents a "TypeLimitExceeded" kind of error.
+type ErrTypeLimitExceeded struct {
+	Type  packages_model.Type
+	Limit string
+	Value int64
+}
+
+// IsErrTypeLimitExceeded checks if an error is a ErrTypeLimitExceeded.
+func IsErrTypeLimitExceeded(err error) bool {
+	_, ok := err.(ErrTypeLimitExceeded)
+	return ok
+}
+
+func (err ErrTypeLimitExceeded) Error() string {
+	return fmt.Sprintf("package type limit exceeded [type: %s, limit: %s, value: %d]", err.Type, err.Limit, err.Value)
+}
+
 // PackageInfo describes a package
 type PackageInfo struct {
 	Owner       *user_model.User
@@ -39,6 +99,8 @@ type PackageCreationInfo struct {
 	Metadata          interface{}
 	PackageProperties map[string]string
 	VersionProperties map[string]string
+	Aliases           []string // additional names of the package, skipped if they belong to another package
+	ReleaseNotes      string   // markdown, only stored if the version gets created
 }
 
 // PackageFileInfo describes a package file
@@ -67,6 +129,11 @@ func CreatePackageOrAddFileToExisting(pvci *PackageCreationInfo, pfci *PackageFi
 }
 
 func createPackageAndAddFile(pvci *PackageCreationInfo, pfci *PackageFileCreationInfo, allowDuplicate bool) (*packages_model.PackageVersion, *packages_model.PackageFile, error) {
+	linkRepo, err := findRepositoryToLink(db.DefaultContext, pvci)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	ctx, committer, err := db.TxContext()
 	if err != nil {
 		return nil, nil, err
@@ -78,7 +145,7 @@ func createPackageAndAddFile(pvci *PackageCreationInfo, pfci *PackageFileCreatio
 		return nil, nil, err
 	}
 
-	pf, pb, blobCreated, err := addFileToPackageVersion(ctx, pv, pfci)
+	pf, pb, blobCreated, err := addFileToPackageVersion(ctx, pv, pfci, created)
 	removeBlob := false
 	defer func() {
 		if blobCreated && removeBlob {
@@ -93,12 +160,38 @@ func createPackageAndAddFile(pvci *PackageCreationInfo, pfci *PackageFileCreatio
 		return nil, nil, err
 	}
 
+	if created {
+		p, err := packages_model.GetPackageByID(ctx, pv.PackageID, false)
+		if err != nil {
+			removeBlob = true
+			return nil, nil, err
+		}
+		if err := InsertAuditEntry(ctx, pvci.Creator, packages_model.AuditActionPublish, p, pv, ""); err != nil {
+			removeBlob = true
+			return nil, nil, err
+		}
+		if linkRepo != nil && p.RepoID == 0 {
+			if err := packages_model.SetRepositoryLink(ctx, p.ID, linkRepo.ID); err != nil {
+				removeBlob = true
+				return nil, nil, err
+			}
+			if err := InsertAuditEntry(ctx, pvci.Creator, packages_model.AuditActionLinkRepository, p, nil, linkRepo.FullName()); err != nil {
+				removeBlob = true
+				return nil, nil, err
+			}
+		}
+	}
+
 	if err := committer.Commit(); err != nil {
 		removeBlob = true
 		return nil, nil, err
 	}
 
 	if created {
+		if err := EnforceVersionLimit(pv); err != nil {
+			return nil, nil, err
+		}
+
 		pd, err := packages_model.GetPackageDescriptor(ctx, pv)
 		if err != nil {
 			return nil, nil, err
@@ -113,15 +206,29 @@ func createPackageAndAddFile(pvci *PackageCreationInfo, pfci *PackageFileCreatio
 func createPackageAndVersion(ctx context.Context, pvci *PackageCreationInfo, allowDuplicate bool) (*packages_model.PackageVersion, bool, error) {
 	log.Trace("Creating package: %v, %v, %v, %s, %s, %+v, %+v, %v", pvci.Creator.ID, pvci.Owner.ID, pvci.PackageType, pvci.Name, pvci.Version, pvci.PackageProperties, pvci.VersionProperties, allowDuplicate)
 
+	if len(pvci.ReleaseNotes) > packages_model.MaxReleaseNotesLength {
+		return nil, false, packages_model.ErrReleaseNotesTooLong
+	}
+
+	metadataJSON, err := json.Marshal(pvci.Metadata)
+	if err != nil {
+		return nil, false, err
+	}
+	normalizedMetadataJSON, err := packages_model.NormalizeMetadata(pvci.PackageType, string(metadataJSON))
+	if err != nil {
+		log.Error("Error validating package metadata: %v", err)
+		return nil, false, err
+	}
+
 	packageCreated := true
 	p := &packages_model.Package{
 		OwnerID:          pvci.Owner.ID,
+		CreatorID:        pvci.Creator.ID,
 		Type:             pvci.PackageType,
 		Name:             pvci.Name,
-		LowerName:        strings.ToLower(pvci.Name),
+		LowerName:        packages_model.NormalizeName(pvci.Name),
 		SemverCompatible: pvci.SemverCompatible,
 	}
-	var err error
 	if p, err = packages_model.TryInsertPackage(ctx, p); err != nil {
 		if err == packages_model.ErrDuplicatePackage {
 			packageCreated = false
@@ -131,6 +238,17 @@ func createPackageAndVersion(ctx context.Context, pvci *PackageCreationInfo, all
 		}
 	}
 
+	for _, alias := range pvci.Aliases {
+		if err := packages_model.AddAlias(ctx, p, alias); err != nil {
+			if err == packages_model.ErrAliasCollision || err == packages_model.ErrDuplicateAlias {
+				log.Debug("Skipping alias %s of package %s: %v", alias, p.Name, err)
+				continue
+			}
+			log.Error("Error adding package alias: %v", err)
+			return nil, false, err
+		}
+	}
+
 	if packageCreated {
 		for name, value := range pvci.PackageProperties {
 			if _, err := packages_model.InsertProperty(ctx, packages_model.PropertyTypePackage, p.ID, name, value); err != nil {
@@ -140,18 +258,15 @@ func createPackageAndVersion(ctx context.Context, pvci *PackageCreationInfo, all
 		}
 	}
 
-	metadataJSON, err := json.Marshal(pvci.Metadata)
-	if err != nil {
-		return nil, false, err
-	}
-
 	versionCreated := true
 	pv := &packages_model.PackageVersion{
 		PackageID:    p.ID,
 		CreatorID:    pvci.Creator.ID,
 		Version:      pvci.Version,
-		LowerVersion: strings.ToLower(pvci.Version),
-		MetadataJSON: string(metadataJSON),
+		LowerVersion: packages_model.NormalizeVersion(pvci.Version),
+		MetadataJSON: normalizedMetadataJSON,
+		IsPrerelease: packages_module.IsPrerelease(string(pvci.PackageType), pvci.Version),
+		ReleaseNotes: pvci.ReleaseNotes,
 	}
 	if pv, err = packages_model.GetOrInsertVersion(ctx, pv); err != nil {
 		if err == packages_model.ErrDuplicatePackageVersion {
@@ -170,6 +285,14 @@ func createPackageAndVersion(ctx context.Context, pvci *PackageCreationInfo, all
 				return nil, false, err
 			}
 		}
+		if err := SetVersionKeywords(ctx, pv.ID, pvci.Metadata); err != nil {
+			log.Error("Error setting package version keywords: %v", err)
+			return nil, false, err
+		}
+		if err := SetPackageDescription(ctx, p.ID, pvci.Metadata); err != nil {
+			log.Error("Error setting package description: %v", err)
+			return nil, false, err
+		}
 	}
 
 	return pv, versionCreated, nil
@@ -188,7 +311,7 @@ func AddFileToExistingPackage(pvi *PackageInfo, pfci *PackageFileCreationInfo) (
 		return nil, nil, err
 	}
 
-	pf, pb, blobCreated, err := addFileToPackageVersion(ctx, pv, pfci)
+	pf, pb, blobCreated, err := addFileToPackageVersion(ctx, pv, pfci, false)
 	removeBlob := false
 	defer func() {
 		if removeBlob {
@@ -224,15 +347,15 @@ func NewPackageBlob(hsr packages_module.HashedSizeReader) *packages_model.Packag
 	}
 }
 
-func addFileToPackageVersion(ctx context.Context, pv *packages_model.PackageVersion, pfci *PackageFileCreationInfo) (*packages_model.PackageFile, *packages_model.PackageBlob, bool, error) {
+func addFileToPackageVersion(ctx context.Context, pv *packages_model.PackageVersion, pfci *PackageFileCreationInfo, isNewVersion bool) (*packages_model.PackageFile, *packages_model.PackageBlob, bool, error) {
 	log.Trace("Adding package file: %v, %s", pv.ID, pfci.Filename)
 
-	pb, exists, err := packages_model.GetOrInsertBlob(ctx, NewPackageBlob(pfci.Data))
+	pb, created, err := packages_model.GetOrInsertBlob(ctx, NewPackageBlob(pfci.Data))
 	if err != nil {
 		log.Error("Error inserting package blob: %v", err)
 		return nil, nil, false, err
 	}
-	if !exists {
+	if created {
 		contentStore := packages_module.NewContentStore()
 		if err := contentStore.Save(packages_module.BlobHash256Key(pb.HashSHA256), pfci.Data, pfci.Data.Size()); err != nil {
 			log.Error("Error saving package blob in content store: %v", err)
@@ -240,26 +363,42 @@ func addFileToPackageVersion(ctx context.Context, pv *packages_model.PackageVers
 		}
 	}
 
+	p, err := packages_model.GetPackageByID(ctx, pv.PackageID, false)
+	if err != nil {
+		return nil, pb, created, err
+	}
+
 	if pfci.OverwriteExisting {
 		pf, err := packages_model.GetFileForVersionByName(ctx, pv.ID, pfci.Filename, pfci.CompositeKey)
 		if err != nil && err != packages_model.ErrPackageFileNotExist {
-			return nil, pb, !exists, err
+			return nil, pb, created, err
 		}
 		if pf != nil {
 			// Short circuit if blob is the same
 			if pf.BlobID == pb.ID {
-				return pf, pb, !exists, nil
+				return pf, pb, created, nil
+			}
+
+			if IsVersionImmutable(p, pv) {
+				return nil, pb, created, ErrVersionImmutable
 			}
 
 			if err := packages_model.DeleteAllProperties(ctx, packages_model.PropertyTypeFile, pf.ID); err != nil {
-				return nil, pb, !exists, err
+				return nil, pb, created, err
 			}
 			if err := packages_model.DeleteFileByID(ctx, pf.ID); err != nil {
-				return nil, pb, !exists, err
+				return nil, pb, created, err
 			}
 		}
 	}
 
+	if err := CheckQuota(ctx, p.OwnerID, pb); err != nil {
+		return nil, pb, created, err
+	}
+	if err := CheckTypeLimits(ctx, p, pv, isNewVersion, pb); err != nil {
+		return nil, pb, created, err
+	}
+
 	pf := &packages_model.PackageFile{
 		VersionID:    pv.ID,
 		BlobID:       pb.ID,
@@ -272,17 +411,168 @@ func addFileToPackageVersion(ctx context.Context, pv *packages_model.PackageVers
 		if err != packages_model.ErrDuplicatePackageFile {
 			log.Error("Error inserting package file: %v", err)
 		}
-		return nil, pb, !exists, err
+		return nil, pb, created, err
 	}
 
 	for name, value := range pfci.Properties {
 		if _, err := packages_model.InsertProperty(ctx, packages_model.PropertyTypeFile, pf.ID, name, value); err != nil {
 			log.Error("Error setting package file property: %v", err)
-			return pf, pb, !exists, err
+			return pf, pb, created, err
+		}
+	}
+
+	return pf, pb, created, nil
+}
+
+// QuotaSizeLimit returns the package storage limit of the quota, the instance default if the quota has no override.
+// A negative limit means unlimited storage.
+func QuotaSizeLimit(pq *packages_model.PackageQuota) int64 {
+	if pq.SizeLimit == packages_model.QuotaSizeLimitDefault {
+		return setting.Packages.DefaultOwnerQuota
+	}
+	return pq.SizeLimit
+}
+
+// CheckQuota tests if the owner can store the blob without exceeding the package storage quota.
+// Blobs already referenced by the owner don't use additional storage.
+func CheckQuota(ctx context.Context, ownerID int64, pb *packages_model.PackageBlob) error {
+	pq, err := packages_model.GetQuotaByOwnerID(ctx, ownerID)
+	if err != nil {
+		return err
+	}
+
+	sizeLimit := QuotaSizeLimit(pq)
+	if sizeLimit < 0 || pq.UsedSize+pb.Size <= sizeLimit {
+		return nil
+	}
+
+	referenced, err := packages_model.IsBlobReferencedByOwner(ctx, ownerID, pb.ID)
+	if err != nil {
+		return err
+	}
+	if referenced {
+		return nil
+	}
+	return ErrQuotaExceeded{OwnerID: ownerID, SizeLimit: sizeLimit}
+}
+
+// GetEffectiveTypeLimit gets the limits which apply to a package type.
+// Limits stored by an administrator override the configured defaults.
+func GetEffectiveTypeLimit(ctx context.Context, packageType packages_model.Type) (*packages_model.PackageTypeLimit, error) {
+	ptl, err := packages_model.GetTypeLimit(ctx, packageType)
+	if err != nil {
+		return nil, err
+	}
+
+	defaults := setting.GetTypeLimits(string(packageType))
+	if ptl.MaxVersions == packages_model.TypeLimitDefault {
+		ptl.MaxVersions = defaults.MaxVersions
+	}
+	if ptl.MaxVersionSize == packages_model.TypeLimitDefault {
+		ptl.MaxVersionSize = defaults.MaxVersionSize
+	}
+	if ptl.MaxOwnerSize == packages_model.TypeLimitDefault {
+		ptl.MaxOwnerSize = defaults.MaxOwnerSize
+	}
+	return ptl, nil
+}
+
+// SetTypeLimits stores the limit overrides of the package types
+func SetTypeLimits(limits []*packages_model.PackageTypeLimit) error {
+	ctx, committer, err := db.TxContext()
+	if err != nil {
+		return err
+	}
+	defer committer.Close()
+
+	for _, ptl := range limits {
+		if err := packages_model.SetTypeLimit(ctx, ptl); err != nil {
+			return err
+		}
+	}
+
+	return committer.Commit()
+}
+
+// CheckTypeLimits tests if the blob can be added to the package version without exceeding the limits of the package type.
+// The number of versions is only checked if the version was just created. The blob may be nil if only the version gets checked.
+func CheckTypeLimits(ctx context.Context, p *packages_model.Package, pv *packages_model.PackageVersion, isNewVersion bool, pb *packages_model.PackageBlob) error {
+	ptl, err := GetEffectiveTypeLimit(ctx, p.Type)
+	if err != nil {
+		return err
+	}
+
+	if isNewVersion && !pv.IsInternal && ptl.MaxVersions >= 0 {
+		if err := checkVersionLimit(ctx, p, pv, ptl.MaxVersions); err != nil {
+			return err
+		}
+	}
+
+	checkVersionSize := pb != nil && !pv.IsInternal && ptl.MaxVersionSize >= 0
+	checkOwnerSize := pb != nil && ptl.MaxOwnerSize >= 0
+	if !checkVersionSize && !checkOwnerSize {
+		return nil
+	}
+
+	usage, err := packages_model.GetTypeLimitUsage(ctx, p.OwnerID, p.Type, p.ID, pv.ID)
+	if err != nil {
+		return err
+	}
+
+	if checkVersionSize && usage.VersionSize+pb.Size > ptl.MaxVersionSize {
+		return ErrTypeLimitExceeded{Type: p.Type, Limit: TypeLimitMaxVersionSize, Value: ptl.MaxVersionSize}
+	}
+	if checkOwnerSize && usage.OwnerSize+pb.Size > ptl.MaxOwnerSize {
+		referenced, err := packages_model.IsBlobReferencedByOwnerAndType(ctx, p.OwnerID, p.Type, pb.ID)
+		if err != nil {
+			return err
+		}
+		if !referenced {
+			return ErrTypeLimitExceeded{Type: p.Type, Limit: TypeLimitMaxOwnerSize, Value: ptl.MaxOwnerSize}
+		}
+	}
+	return nil
+}
+
+// checkTransferLimits tests if the new owner can store the package without exceeding the package storage quota
+// or the owner size limit of the package type. Blobs already referenced by the new owner don't use additional storage.
+func checkTransferLimits(ctx context.Context, p *packages_model.Package, newOwnerID int64) error {
+	pq, err := packages_model.GetQuotaByOwnerID(ctx, newOwnerID)
+	if err != nil {
+		return err
+	}
+
+	if sizeLimit := QuotaSizeLimit(pq); sizeLimit >= 0 {
+		added, err := packages_model.GetPackageSizeAddedToOwner(ctx, p.ID, newOwnerID, "")
+		if err != nil {
+			return err
+		}
+		if added > 0 && pq.UsedSize+added > sizeLimit {
+			return ErrQuotaExceeded{OwnerID: newOwnerID, SizeLimit: sizeLimit}
 		}
 	}
 
-	return pf, pb, !exists, nil
+	ptl, err := GetEffectiveTypeLimit(ctx, p.Type)
+	if err != nil {
+		return err
+	}
+	if ptl.MaxOwnerSize < 0 {
+		return nil
+	}
+
+	added, err := packages_model.GetPackageSizeAddedToOwner(ctx, p.ID, newOwnerID, p.Type)
+	if err != nil || added == 0 {
+		return err
+	}
+
+	usage, err := packages_model.GetTypeLimitUsage(ctx, newOwnerID, p.Type, 0, 0)
+	if err != nil {
+		return err
+	}
+	if usage.OwnerSize+added > ptl.MaxOwnerSize {
+		return ErrTypeLimitExceeded{Type: p.Type, Limit: TypeLimitMaxOwnerSize, Value: ptl.MaxOwnerSize}
+	}
+	return nil
 }
 
 // RemovePackageVersionByNameAndVersion deletes a package version and all associated files
@@ -292,11 +582,14 @@ func RemovePackageVersionByNameAndVersion(doer *user_model.User, pvi *PackageInf
 		return err
 	}
 
-	return RemovePackageVersion(doer, pv)
+	return RemovePackageVersion(doer, pv, false)
 }
 
-// RemovePackageVersion deletes the package version and all associated files
-func RemovePackageVersion(doer *user_model.User, pv *packages_model.PackageVersion) error {
+// RemovePackageVersion deletes the package version and all associated files.
+// Versions of immutable packages can only be deleted by site administrators, otherwise ErrVersionImmutable is returned.
+// Versions referenced by a release of the linked repository can only be deleted by site administrators with force set,
+// otherwise ErrVersionProtected is returned.
+func RemovePackageVersion(doer *user_model.User, pv *packages_model.PackageVersion, force bool) error {
 	ctx, committer, err := db.TxContext()
 	if err != nil {
 		return err
@@ -308,12 +601,20 @@ func RemovePackageVersion(doer *user_model.User, pv *packages_model.PackageVersi
 		return err
 	}
 
+	if err := CheckVersionDeletable(ctx, doer, pd.Package, pv, force); err != nil {
+		return err
+	}
+
 	log.Trace("Deleting package: %v", pv.ID)
 
 	if err := DeletePackageVersionAndReferences(ctx, pv); err != nil {
 		return err
 	}
 
+	if err := InsertAuditEntry(ctx, doer, packages_model.AuditActionDeleteVersion, pd.Package, pv, ""); err != nil {
+		return err
+	}
+
 	if err := committer.Commit(); err != nil {
 		return err
 	}
@@ -323,11 +624,313 @@ func RemovePackageVersion(doer *user_model.User, pv *packages_model.PackageVersi
 	return nil
 }
 
-// DeletePackageVersionAndReferences deletes the package version and its properties and files
+// CheckVersionDeletable tests if the user may delete the package version.
+// Versions of immutable packages can only be deleted by site administrators, otherwise ErrVersionImmutable is returned.
+// Versions referenced by a release of the linked repository can only be deleted by site administrators with force set,
+// otherwise ErrVersionProtected is returned.
+func CheckVersionDeletable(ctx context.Context, doer *user_model.User, p *packages_model.Package, pv *packages_model.PackageVersion, force bool) error {
+	isAdmin := doer != nil && doer.IsAdmin
+
+	if IsVersionImmutable(p, pv) && !isAdmin {
+		return ErrVersionImmutable
+	}
+
+	if force && isAdmin {
+		return nil
+	}
+	protected, err := packages_model.IsVersionProtected(ctx, pv.ID)
+	if err != nil {
+		return err
+	}
+	if protected {
+		return ErrVersionProtected
+	}
+	return nil
+}
+
+// CanAdministrateOwnerPackages tests if the user may administrate all packages of the owner
+func CanAdministrateOwnerPackages(ctx context.Context, doer, owner *user_model.User) (bool, error) {
+	if doer == nil || doer.IsGhost() {
+		return false, nil
+	}
+	if doer.IsAdmin || doer.ID == owner.ID {
+		return true, nil
+	}
+	if owner.IsOrganization() {
+		return organization.IsOrganizationOwner(ctx, owner.ID, doer.ID)
+	}
+	return false, nil
+}
+
+// TransferPackage transfers the ownership of a package to a new owner.
+// If the new owner has a package with the same type and name already, ErrDuplicatePackage is returned.
+// If the package doesn't fit into the package storage of the new owner, ErrQuotaExceeded or ErrTypeLimitExceeded is returned.
+func TransferPackage(doer *user_model.User, p *packages_model.Package, newOwner *user_model.User) error {
+	ctx, committer, err := db.TxContext()
+	if err != nil {
+		return err
+	}
+	defer committer.Close()
+
+	oldOwner, err := user_model.GetUserByIDCtx(ctx, p.OwnerID)
+	if err != nil {
+		return err
+	}
+
+	log.Trace("Transferring package: %v, %v -> %v", p.ID, oldOwner.ID, newOwner.ID)
+
+	if err := InsertAuditEntry(ctx, doer, packages_model.AuditActionTransfer, p, nil, oldOwner.Name+" -> "+newOwner.Name); err != nil {
+		return err
+	}
+
+	if err := checkTransferLimits(ctx, p, newOwner.ID); err != nil {
+		return err
+	}
+
+	if err := packages_model.TransferOwnership(ctx, p, newOwner.ID); err != nil {
+		return err
+	}
+
+	if err := committer.Commit(); err != nil {
+		return err
+	}
+
+	notification.NotifyPackageTransfer(doer, oldOwner, p)
+
+	return nil
+}
+
+// LinkPackageToRepository links the package to a repository. If repo is nil, an existing link is removed.
+func LinkPackageToRepository(doer *user_model.User, p *packages_model.Package, repo *repo_model.Repository) error {
+	ctx, committer, err := db.TxContext()
+	if err != nil {
+		return err
+	}
+	defer committer.Close()
+
+	var repoID int64
+	var details string
+	if repo != nil {
+		repoID = repo.ID
+		details = repo.FullName()
+	}
+
+	if err := packages_model.SetRepositoryLink(ctx, p.ID, repoID); err != nil {
+		return err
+	}
+
+	if err := InsertAuditEntry(ctx, doer, packages_model.AuditActionLinkRepository, p, nil, details); err != nil {
+		return err
+	}
+
+	return committer.Commit()
+}
+
+var renamePackageNameRegex = regexp.MustCompile(`\A[A-Za-z0-9\.\_\-\+]+\z`)
+
+// CanRenamePackage tests if packages of the type can be renamed.
+// Most package managers store the name in the metadata of the published files, which would no longer match.
+func CanRenamePackage(packageType packages_model.Type) bool {
+	return packageType == packages_model.TypeGeneric || packageType == packages_model.TypeVagrant
+}
+
+// RenamePackage changes the name of a package. The old name is kept as alias, so requests for the old name still resolve.
+// ErrRenameNotSupported is returned if packages of the type can't be renamed.
+func RenamePackage(doer *user_model.User, p *packages_model.Package, newName string) error {
+	if !CanRenamePackage(p.Type) {
+		return ErrRenameNotSupported
+	}
+	if !renamePackageNameRegex.MatchString(newName) {
+		return ErrInvalidPackageName
+	}
+
+	ctx, committer, err := db.TxContext()
+	if err != nil {
+		return err
+	}
+	defer committer.Close()
+
+	oldName := p.Name
+
+	if err := packages_model.RenamePackage(ctx, p, newName); err != nil {
+		return err
+	}
+
+	if err := InsertAuditEntry(ctx, doer, packages_model.AuditActionRename, p, nil, oldName+" -> "+newName); err != nil {
+		return err
+	}
+
+	return committer.Commit()
+}
+
+// IsVersionImmutable tests if the package version can't be overwritten or deleted because the package is immutable.
+// Internal versions are never immutable. Maven snapshot versions are only immutable if configured.
+func IsVersionImmutable(p *packages_model.Package, pv *packages_model.PackageVersion) bool {
+	if !p.IsImmutable || pv.IsInternal {
+		return false
+	}
+	if p.Type == packages_model.TypeMaven && strings.HasSuffix(pv.Version, "-SNAPSHOT") {
+		return setting.Packages.ImmutableMavenSnapshots
+	}
+	return true
+}
+
+// SetPackageImmutable sets if the published versions of the package can't be overwritten or deleted
+func SetPackageImmutable(doer *user_model.User, p *packages_model.Package, immutable bool) error {
+	ctx, committer, err := db.TxContext()
+	if err != nil {
+		return err
+	}
+	defer committer.Close()
+
+	if err := packages_model.SetImmutable(ctx, p.ID, immutable); err != nil {
+		return err
+	}
+
+	action := packages_model.AuditActionMakeImmutable
+	if !immutable {
+		action = packages_model.AuditActionMakeMutable
+	}
+	if err := InsertAuditEntry(ctx, doer, action, p, nil, ""); err != nil {
+		return err
+	}
+
+	return committer.Commit()
+}
+
+// SetPackageVisibility changes the visibility of a package
+func SetPackageVisibility(doer *user_model.User, p *packages_model.Package, visibility packages_model.Visibility) error {
+	ctx, committer, err := db.TxContext()
+	if err != nil {
+		return err
+	}
+	defer committer.Close()
+
+	if err := packages_model.SetVisibility(ctx, p.ID, visibility); err != nil {
+		return err
+	}
+
+	if err := InsertAuditEntry(ctx, doer, packages_model.AuditActionChangeVisibility, p, nil, p.Visibility.String()+" -> "+visibility.String()); err != nil {
+		return err
+	}
+
+	return committer.Commit()
+}
+
+// SetPackageVersionYanked yanks a package version with an optional reason or reverts the yank
+func SetPackageVersionYanked(doer *user_model.User, pv *packages_model.PackageVersion, yanked bool, reason string) error {
+	ctx, committer, err := db.TxContext()
+	if err != nil {
+		return err
+	}
+	defer committer.Close()
+
+	p, err := packages_model.GetPackageByID(ctx, pv.PackageID, false)
+	if err != nil {
+		return err
+	}
+
+	if err := packages_model.SetVersionYanked(ctx, pv.ID, yanked, reason); err != nil {
+		return err
+	}
+
+	action := packages_model.AuditActionYank
+	if !yanked {
+		action = packages_model.AuditActionUnyank
+		reason = ""
+	}
+	if err := InsertAuditEntry(ctx, doer, action, p, pv, reason); err != nil {
+		return err
+	}
+
+	return committer.Commit()
+}
+
+// SetPackageVersionReleaseNotes replaces the release notes of a package version
+func SetPackageVersionReleaseNotes(doer *user_model.User, pv *packages_model.PackageVersion, notes string) error {
+	ctx, committer, err := db.TxContext()
+	if err != nil {
+		return err
+	}
+	defer committer.Close()
+
+	p, err := packages_model.GetPackageByID(ctx, pv.PackageID, false)
+	if err != nil {
+		return err
+	}
+
+	if err := packages_model.SetVersionReleaseNotes(ctx, pv.ID, notes); err != nil {
+		return err
+	}
+
+	if err := InsertAuditEntry(ctx, doer, packages_model.AuditActionEditReleaseNotes, p, pv, ""); err != nil {
+		return err
+	}
+
+	return committer.Commit()
+}
+
+// CopyPackageVersion copies a package version with its files to the package with the same name of the new owner.
+// The blobs are shared and not copied. If the version exists already at the new owner, ErrDuplicatePackageVersion is returned
+func CopyPackageVersion(doer *user_model.User, pv *packages_model.PackageVersion, newOwner *user_model.User) (*packages_model.PackageVersion, error) {
+	ctx, committer, err := db.TxContext()
+	if err != nil {
+		return nil, err
+	}
+	defer committer.Close()
+
+	p, err := packages_model.GetPackageByID(ctx, pv.PackageID, false)
+	if err != nil {
+		return nil, err
+	}
+	// container images consist of several versions which reference each other
+	if p.Type == packages_model.TypeContainer {
+		return nil, ErrCopyNotSupported
+	}
+
+	log.Trace("Copying package version: %v -> %v", pv.ID, newOwner.ID)
+
+	npv, err := packages_model.CopyVersion(ctx, pv, newOwner.ID, doer.ID, func(ctx context.Context, np *packages_model.Package, npv *packages_model.PackageVersion, pb *packages_model.PackageBlob) error {
+		if err := CheckQuota(ctx, np.OwnerID, pb); err != nil {
+			return err
+		}
+		return CheckTypeLimits(ctx, np, npv, false, pb)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pd, err := packages_model.GetPackageDescriptor(ctx, npv)
+	if err != nil {
+		return nil, err
+	}
+
+	// the version limit is checked once the copy is complete
+	if err := CheckTypeLimits(ctx, pd.Package, npv, true, nil); err != nil {
+		return nil, err
+	}
+
+	if err := InsertAuditEntry(ctx, doer, packages_model.AuditActionPublish, pd.Package, npv, ""); err != nil {
+		return nil, err
+	}
+
+	if err := committer.Commit(); err != nil {
+		return nil, err
+	}
+
+	notification.NotifyPackageCreate(doer, pd)
+
+	return npv, nil
+}
+
+// DeletePackageVersionAndReferences deletes the package version and its properties, files and release links
 func DeletePackageVersionAndReferences(ctx context.Context, pv *packages_model.PackageVersion) error {
 	if err := packages_model.DeleteAllProperties(ctx, packages_model.PropertyTypeVersion, pv.ID); err != nil {
 		return err
 	}
+	if err := packages_model.DeleteVersionReleasesByVersionID(ctx, pv.ID); err != nil {
+		return err
+	}
 
 	pfs, err := packages_model.GetFilesByVersionID(ctx, pv.ID)
 	if err != nil {
@@ -351,56 +954,125 @@ func DeletePackageFile(ctx context.Context, pf *packages_model.PackageFile) erro
 	return packages_model.DeleteFileByID(ctx, pf.ID)
 }
 
+// CleanupResult contains the statistics of a cleanup run
+type CleanupResult struct {
+	DeletedBlobs     int
+	DeletedBlobsSize int64
+	// Completed is false if the cleanup was cancelled before all expired blobs were deleted
+	Completed bool
+}
+
 // Cleanup removes expired package data
-func Cleanup(unused context.Context, olderThan time.Duration) error {
+func Cleanup(taskCtx context.Context, olderThan time.Duration) error {
+	_, err := CleanupWithResult(taskCtx, olderThan, false)
+	return err
+}
+
+// CleanupWithResult removes expired package data and reports the reclaimed blobs.
+// Immutable packages without versions are kept unless force is set.
+// If taskCtx is cancelled (e.g. because its deadline is reached), the blobs deleted so far are kept deleted and the cleanup stops.
+func CleanupWithResult(taskCtx context.Context, olderThan time.Duration, force bool) (*CleanupResult, error) {
 	ctx, committer, err := db.TxContext()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer committer.Close()
 
 	if err := container_service.Cleanup(ctx, olderThan); err != nil {
-		return err
+		return nil, err
 	}
 
 	ps, err := packages_model.FindUnreferencedPackages(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	for _, p := range ps {
+		if p.IsImmutable && !force {
+			continue
+		}
+		if err := InsertAuditEntry(ctx, nil, packages_model.AuditActionDeletePackage, p, nil, ""); err != nil {
+			return nil, err
+		}
 		if err := packages_model.DeleteAllProperties(ctx, packages_model.PropertyTypePackage, p.ID); err != nil {
-			return err
+			return nil, err
 		}
 		if err := packages_model.DeletePackageByID(ctx, p.ID); err != nil {
-			return err
+			return nil, err
 		}
 	}
 
 	pbs, err := packages_model.FindExpiredUnreferencedBlobs(ctx, olderThan)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	for _, pb := range pbs {
+	log.Trace("Cleanup: found %d expired unreferenced package blobs", len(pbs))
+
+	result := &CleanupResult{Completed: true}
+	deleted := make([]*packages_model.PackageBlob, 0, len(pbs))
+	for i, pb := range pbs {
+		if err := taskCtx.Err(); err != nil {
+			log.Warn("Cleanup: cancelled after deleting %d of %d package blobs: %v", i, len(pbs), err)
+			result.Completed = false
+			break
+		}
+
 		if err := packages_model.DeleteBlobByID(ctx, pb.ID); err != nil {
-			return err
+			return nil, err
+		}
+		deleted = append(deleted, pb)
+		result.DeletedBlobs++
+		result.DeletedBlobsSize += pb.Size
+
+		if (i+1)%100 == 0 {
+			log.Debug("Cleanup: deleted %d of %d package blobs", i+1, len(pbs))
 		}
 	}
 
 	if err := committer.Commit(); err != nil {
-		return err
+		return nil, err
 	}
 
 	contentStore := packages_module.NewContentStore()
-	for _, pb := range pbs {
+	for _, pb := range deleted {
 		if err := contentStore.Delete(packages_module.BlobHash256Key(pb.HashSHA256)); err != nil {
 			log.Error("Error deleting package blob [%v]: %v", pb.ID, err)
 		}
 	}
 
+	log.Info("Cleanup: deleted %d package blobs (%d bytes)", result.DeletedBlobs, result.DeletedBlobsSize)
+
+	return result, nil
+}
+
+// CleanupStaleInternalVersions removes internal versions which were not modified for the specified duration, e.g. of aborted uploads.
+// The blobs of the removed files are deleted by the package cleanup once they are expired.
+func CleanupStaleInternalVersions(ctx context.Context, olderThan time.Duration) error {
+	removed, err := packages_model.CleanupStaleInternalVersions(ctx, olderThan)
+	if err != nil {
+		return err
+	}
+	log.Trace("Removed %d stale internal package versions", removed)
+	return nil
+}
+
+// CleanupVersionlessPackages removes packages without any versions which were created more than olderThan ago
+func CleanupVersionlessPackages(ctx context.Context, olderThan time.Duration) error {
+	deleted, err := packages_model.CleanupVersionlessPackages(ctx, olderThan)
+	if err != nil {
+		return err
+	}
+	log.Info("Removed %d packages without versions", deleted)
 	return nil
 }
 
+// RefreshSizeSummaries recalculates the stored package size summaries
+func RefreshSizeSummaries(ctx context.Context) error {
+	return db.WithTx(func(ctx context.Context) error {
+		return packages_model.RefreshSizeSummaries(ctx)
+	}, ctx)
+}
+
 // GetFileStreamByPackageNameAndVersion returns the content of the specific package file
 func GetFileStreamByPackageNameAndVersion(ctx context.Context, pvi *PackageInfo, pfi *PackageFileInfo) (io.ReadSeekCloser, *packages_model.PackageFile, error) {
 	log.Trace("Getting package file stream: %v, %v, %s, %s, %s, %s", pvi.Owner.ID, pvi.PackageType, pvi.Name, pvi.Version, pfi.Filename, pfi.CompositeKey)
@@ -429,7 +1101,7 @@ func GetFileStreamByPackageVersionAndFileID(ctx context.Context, owner *user_mod
 		return nil, nil, err
 	}
 
-	p, err := packages_model.GetPackageByID(ctx, pv.PackageID)
+	p, err := packages_model.GetPackageByID(ctx, pv.PackageID, false)
 	if err != nil {
 		log.Error("Error getting package: %v", err)
 		return nil, nil, err
@@ -471,35 +1143,47 @@ func GetPackageFileStream(ctx context.Context, pf *packages_model.PackageFile) (
 			if err := packages_model.IncrementDownloadCounter(ctx, pf.VersionID); err != nil {
 				log.Error("Error incrementing download counter: %v", err)
 			}
+			if err := packages_model.UpdateVersionLastDownload(ctx, pf.VersionID, timeutil.TimeStampNow()); err != nil {
+				log.Error("Error updating last download timestamp: %v", err)
+			}
+		}
+		if err := packages_model.IncrementFileDownloadCounter(ctx, pf.ID); err != nil {
+			log.Error("Error incrementing file download counter: %v", err)
 		}
 	}
 	return s, pf, err
 }
 
-// RemoveAllPackages for User
-func RemoveAllPackages(ctx context.Context, userID int64) (int, error) {
-	count := 0
-	for {
-		pkgVersions, _, err := packages_model.SearchVersions(ctx, &packages_model.PackageSearchOptions{
-			Paginator: &db.ListOptions{
-				PageSize: repo_model.RepositoryListDefaultPageSize,
-				Page:     1,
-			},
-			OwnerID:    userID,
-			IsInternal: util.OptionalBoolNone,
+// DeleteOwnerPackages deletes all packages of a user or organization with their versions.
+// An audit entry is written for every package and a delete notification is sent for every version
+// once the batch of the package is committed. The blobs are left for the cleanup. It returns the number of deleted packages.
+func DeleteOwnerPackages(ctx context.Context, doer *user_model.User, ownerID int64) (int, error) {
+	// descriptors of the current batch only, so the memory use is bounded by the batch size
+	pds := make([]*packages_model.PackageDescriptor, 0, 10)
+	count, err := packages_model.DeleteAllPackages(ctx, ownerID, func(ctx context.Context, p *packages_model.Package) error {
+		pvs, _, err := packages_model.SearchVersions(ctx, &packages_model.PackageSearchOptions{
+			PackageID:  p.ID,
+			IsInternal: util.OptionalBoolFalse,
 		})
 		if err != nil {
-			return count, fmt.Errorf("GetOwnedPackages[%d]: %w", userID, err)
+			return err
 		}
-		if len(pkgVersions) == 0 {
-			break
+		versionPds, err := packages_model.GetPackageDescriptors(ctx, pvs, packages_model.DescriptorLoadOptions{})
+		if err != nil {
+			return err
 		}
-		for _, pv := range pkgVersions {
-			if err := DeletePackageVersionAndReferences(ctx, pv); err != nil {
-				return count, fmt.Errorf("unable to delete package %d:%s[%d]. Error: %w", pv.PackageID, pv.Version, pv.ID, err)
-			}
-			count++
+		pds = append(pds, versionPds...)
+
+		return InsertAuditEntry(ctx, doer, packages_model.AuditActionDeletePackage, p, nil, "")
+	}, func() {
+		for _, pd := range pds {
+			notification.NotifyPackageDelete(doer, pd)
 		}
+		pds = pds[:0]
+	})
+	if err != nil {
+		return count, fmt.Errorf("DeleteAllPackages[%d]: %w", ownerID, err)
 	}
+
 	return count, nil
 }
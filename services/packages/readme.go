@@ -0,0 +1,27 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"code.gitea.io/gitea/modules/packages/npm"
+	"code.gitea.io/gitea/modules/packages/nuget"
+	"code.gitea.io/gitea/modules/packages/pub"
+)
+
+// ExtractReadme gets the raw README of the type specific metadata of a package version.
+// An empty string is returned for package types which don't carry a README.
+func ExtractReadme(metadata interface{}) string {
+	switch m := metadata.(type) {
+	case *npm.Metadata:
+		return m.Readme
+	case npm.Metadata:
+		return m.Readme
+	case *nuget.Metadata:
+		return m.Readme
+	case *pub.Metadata:
+		return m.Readme
+	}
+	return ""
+}
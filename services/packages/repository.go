@@ -0,0 +1,93 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"strconv"
+
+	access_model "code.gitea.io/gitea/models/perm/access"
+	repo_model "code.gitea.io/gitea/models/repo"
+	user_model "code.gitea.io/gitea/models/user"
+	packages_module "code.gitea.io/gitea/modules/packages"
+	"code.gitea.io/gitea/modules/packages/conan"
+	"code.gitea.io/gitea/modules/packages/container"
+	"code.gitea.io/gitea/modules/packages/npm"
+	"code.gitea.io/gitea/modules/packages/nuget"
+	"code.gitea.io/gitea/modules/packages/pub"
+	"code.gitea.io/gitea/modules/packages/vagrant"
+	"code.gitea.io/gitea/modules/setting"
+)
+
+// ExtractRepositoryURL gets the source repository url of the type specific metadata of a package version
+func ExtractRepositoryURL(metadata interface{}) string {
+	switch m := metadata.(type) {
+	case *conan.Metadata:
+		return m.RepositoryURL
+	case *container.Metadata:
+		return m.RepositoryURL
+	case *npm.Metadata:
+		return m.RepositoryURL
+	case npm.Metadata:
+		return m.RepositoryURL
+	case *nuget.Metadata:
+		return m.RepositoryURL
+	case *pub.Metadata:
+		return m.RepositoryURL
+	case *vagrant.Metadata:
+		return m.RepositoryURL
+	}
+	return ""
+}
+
+// IsAutoLinkRepositoryEnabled checks if the owner opted in to link published packages to the repository of their metadata
+func IsAutoLinkRepositoryEnabled(ownerID int64) (bool, error) {
+	val, err := user_model.GetUserSetting(ownerID, user_model.SettingsKeyPackagesAutoLinkRepository, "false")
+	if err != nil {
+		return false, err
+	}
+	enabled, _ := strconv.ParseBool(val)
+	return enabled, nil
+}
+
+// SetAutoLinkRepository enables or disables linking published packages of the owner to the repository of their metadata
+func SetAutoLinkRepository(ownerID int64, enabled bool) error {
+	return user_model.SetUserSetting(ownerID, user_model.SettingsKeyPackagesAutoLinkRepository, strconv.FormatBool(enabled))
+}
+
+// findRepositoryToLink returns the repository referenced by the metadata of the package to create.
+// The repository must be hosted on this instance, belong to the package owner and be visible to the creator.
+// nil is returned if there is no such repository or the owner has not enabled automatic linking.
+func findRepositoryToLink(ctx context.Context, pvci *PackageCreationInfo) (*repo_model.Repository, error) {
+	ownerName, repoName, ok := packages_module.ParseRepositoryURL(setting.AppURL, ExtractRepositoryURL(pvci.Metadata))
+	if !ok {
+		return nil, nil
+	}
+
+	enabled, err := IsAutoLinkRepositoryEnabled(pvci.Owner.ID)
+	if err != nil || !enabled {
+		return nil, err
+	}
+
+	repo, err := repo_model.GetRepositoryByOwnerAndNameCtx(ctx, ownerName, repoName)
+	if err != nil {
+		if repo_model.IsErrRepoNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if repo.OwnerID != pvci.Owner.ID {
+		return nil, nil
+	}
+
+	perm, err := access_model.GetUserRepoPermission(ctx, repo, pvci.Creator)
+	if err != nil {
+		return nil, err
+	}
+	if !perm.HasAccess() {
+		return nil, nil
+	}
+	return repo, nil
+}
@@ -0,0 +1,206 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	packages_model "code.gitea.io/gitea/models/packages"
+	user_model "code.gitea.io/gitea/models/user"
+	"code.gitea.io/gitea/modules/log"
+	packages_module "code.gitea.io/gitea/modules/packages"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/timeutil"
+)
+
+var (
+	// ErrUploadOffsetMismatch indicates that a chunk does not start at the end of the already received data
+	ErrUploadOffsetMismatch = errors.New("Upload offset does not match the received data")
+	// errWriteAfterRead occurs if Append is called after a read operation
+	errWriteAfterRead = errors.New("write is unsupported after a read operation")
+	// errFileOffsetMismatch occurs if the file offset is different than the model
+	errFileOffsetMismatch = errors.New("offset mismatch between file and model")
+)
+
+// UploadSession is an opened chunked upload which receives the data of a package file
+type UploadSession struct {
+	*packages_model.PackageUploadSession
+	*packages_module.MultiHasher
+	file    *os.File
+	reading bool
+}
+
+func buildUploadSessionFilePath(id string) string {
+	return filepath.Join(setting.Packages.ChunkedUploadPath, path.Clean("/" + strings.ReplaceAll(id, "\\", "/"))[1:])
+}
+
+// CreateUploadSession creates a new upload session for the file of a package version
+func CreateUploadSession(ctx context.Context, pi *PackageInfo, filename string, doer *user_model.User) (*packages_model.PackageUploadSession, error) {
+	pus := &packages_model.PackageUploadSession{
+		OwnerID:        pi.Owner.ID,
+		Type:           pi.PackageType,
+		PackageName:    pi.Name,
+		PackageVersion: pi.Version,
+		Filename:       filename,
+		ExpiresUnix:    timeutil.TimeStampNow().AddDuration(setting.Packages.UploadSessionTimeout),
+	}
+	if doer != nil {
+		pus.CreatorID = doer.ID
+	}
+
+	return packages_model.CreateUploadSession(ctx, pus)
+}
+
+// OpenUploadSession opens the upload session with the given id.
+// ErrPackageUploadSessionNotExist is returned if the session does not exist or is expired.
+func OpenUploadSession(ctx context.Context, id string) (*UploadSession, error) {
+	pus, err := packages_model.GetUploadSessionByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if pus.IsExpired() {
+		return nil, packages_model.ErrPackageUploadSessionNotExist
+	}
+
+	hash := packages_module.NewMultiHasher()
+	if len(pus.HashStateBytes) != 0 {
+		if err := hash.UnmarshalBinary(pus.HashStateBytes); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := os.OpenFile(buildUploadSessionFilePath(pus.ID), os.O_RDWR|os.O_CREATE, 0o666)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UploadSession{
+		pus,
+		hash,
+		f,
+		false,
+	}, nil
+}
+
+// Close implements io.Closer
+func (s *UploadSession) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// Append appends a chunk of data which must start at offset and extends the expiry of the session.
+// ErrUploadOffsetMismatch is returned if offset is not the number of already received bytes.
+func (s *UploadSession) Append(ctx context.Context, offset int64, r io.Reader) error {
+	if s.reading {
+		return errWriteAfterRead
+	}
+	if offset != s.BytesReceived {
+		return ErrUploadOffsetMismatch
+	}
+
+	fileOffset, err := s.file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if fileOffset != s.BytesReceived {
+		return errFileOffsetMismatch
+	}
+
+	n, err := io.Copy(io.MultiWriter(s.file, s.MultiHasher), r)
+	if err != nil {
+		return err
+	}
+
+	// fast path if nothing was written
+	if n == 0 {
+		return nil
+	}
+
+	s.BytesReceived += n
+
+	s.HashStateBytes, err = s.MultiHasher.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	s.ExpiresUnix = timeutil.TimeStampNow().AddDuration(setting.Packages.UploadSessionTimeout)
+
+	return packages_model.UpdateUploadSession(ctx, s.PackageUploadSession)
+}
+
+// Size returns the number of received bytes
+func (s *UploadSession) Size() int64 {
+	return s.BytesReceived
+}
+
+// Read implements io.Reader
+func (s *UploadSession) Read(p []byte) (int, error) {
+	if !s.reading {
+		_, err := s.file.Seek(0, io.SeekStart)
+		if err != nil {
+			return 0, err
+		}
+
+		s.reading = true
+	}
+
+	return s.file.Read(p)
+}
+
+// Complete passes the received data to store and removes the session afterwards.
+// If store fails, the session is kept and the error is returned.
+func (s *UploadSession) Complete(ctx context.Context, store func(packages_module.HashedSizeReader) error) error {
+	if err := store(s); err != nil {
+		return err
+	}
+
+	if err := s.Close(); err != nil {
+		return err
+	}
+
+	return AbortUploadSession(ctx, s.ID)
+}
+
+// AbortUploadSession deletes the data and the model of an upload session
+func AbortUploadSession(ctx context.Context, id string) error {
+	if err := packages_model.DeleteUploadSessionByID(ctx, id); err != nil {
+		return err
+	}
+
+	err := os.Remove(buildUploadSessionFilePath(id))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// CleanupExpiredUploadSessions removes the expired upload sessions
+func CleanupExpiredUploadSessions(ctx context.Context) error {
+	puss, err := packages_model.FindExpiredUploadSessions(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, pus := range puss {
+		if err := AbortUploadSession(ctx, pus.ID); err != nil {
+			return err
+		}
+	}
+
+	log.Trace("Removed %d expired package upload sessions", len(puss))
+
+	return nil
+}
@@ -0,0 +1,164 @@
+// Copyright 2022 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package packages
+
+import (
+	"context"
+	"fmt"
+
+	"code.gitea.io/gitea/models/db"
+	packages_model "code.gitea.io/gitea/models/packages"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
+)
+
+// checkVersionLimit tests if the new version can be added to the package without exceeding maxVersions.
+// If pruning is enabled for the package type, the limit may be exceeded as long as enough versions can be pruned by EnforceVersionLimit.
+func checkVersionLimit(ctx context.Context, p *packages_model.Package, pv *packages_model.PackageVersion, maxVersions int64) error {
+	count, err := packages_model.CountPackageVersions(ctx, p.ID)
+	if err != nil {
+		return err
+	}
+	if count <= maxVersions {
+		return nil
+	}
+
+	if canPruneVersions(p.Type) {
+		p, err := packages_model.GetPackageByID(ctx, p.ID, true)
+		if err != nil {
+			return err
+		}
+		prunable, err := prunableVersions(ctx, p, pv)
+		if err != nil {
+			return err
+		}
+		if int64(len(p.Versions)-len(prunable)) <= maxVersions {
+			return nil
+		}
+	}
+
+	return ErrTypeLimitExceeded{Type: p.Type, Limit: TypeLimitMaxVersions, Value: maxVersions}
+}
+
+// canPruneVersions tests if the oldest versions get deleted if a package of the type exceeds the version limit.
+// Container images are never pruned because their manifests may be referenced by image indexes.
+func canPruneVersions(packageType packages_model.Type) bool {
+	return packageType != packages_model.TypeContainer && setting.GetTypeLimits(string(packageType)).PruneVersions
+}
+
+// prunableVersions returns the versions of the package (loaded with its versions) which may be pruned, oldest first.
+// The new version, the latest stable version, immutable versions and versions referenced by a release are never pruned.
+func prunableVersions(ctx context.Context, p *packages_model.Package, newVersion *packages_model.PackageVersion) ([]*packages_model.PackageVersion, error) {
+	var latestStableID int64
+	for _, pv := range p.Versions {
+		if !pv.IsPrerelease {
+			latestStableID = pv.ID
+			break
+		}
+	}
+
+	versionIDs := make([]int64, 0, len(p.Versions))
+	for _, pv := range p.Versions {
+		versionIDs = append(versionIDs, pv.ID)
+	}
+	protected, err := packages_model.GetProtectedVersionIDs(ctx, versionIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	prunable := make([]*packages_model.PackageVersion, 0, len(p.Versions))
+	for i := len(p.Versions) - 1; i >= 0; i-- {
+		pv := p.Versions[i]
+		if pv.ID == newVersion.ID || pv.ID == latestStableID || IsVersionImmutable(p, pv) || protected[pv.ID] {
+			continue
+		}
+		prunable = append(prunable, pv)
+	}
+	return prunable, nil
+}
+
+// EnforceVersionLimit ensures that the package of a just published version does not exceed the version limit of the package type.
+// Concurrent publishes can't see the uncommitted versions of each other, so the limit is checked again after the version was committed.
+// If pruning is enabled, the oldest prunable versions are deleted and recorded in the audit log.
+// Otherwise the published version is deleted and ErrTypeLimitExceeded is returned if older versions already reach the limit.
+func EnforceVersionLimit(pv *packages_model.PackageVersion) error {
+	ctx, committer, err := db.TxContext()
+	if err != nil {
+		return err
+	}
+	defer committer.Close()
+
+	count, err := packages_model.CountPackageVersions(ctx, pv.PackageID)
+	if err != nil {
+		return err
+	}
+
+	p, err := packages_model.GetPackageByID(ctx, pv.PackageID, false)
+	if err != nil {
+		return err
+	}
+
+	ptl, err := GetEffectiveTypeLimit(ctx, p.Type)
+	if err != nil {
+		return err
+	}
+	if ptl.MaxVersions < 0 || count <= ptl.MaxVersions {
+		return nil
+	}
+
+	if p, err = packages_model.GetPackageByID(ctx, p.ID, true); err != nil {
+		return err
+	}
+
+	if canPruneVersions(p.Type) {
+		excess := int64(len(p.Versions)) - ptl.MaxVersions
+		prunable, err := prunableVersions(ctx, p, pv)
+		if err != nil {
+			return err
+		}
+		for _, old := range prunable {
+			if excess <= 0 {
+				break
+			}
+
+			log.Info("Pruning version %s of package %s [id: %d]: limit of %d versions exceeded", old.Version, p.Name, p.ID, ptl.MaxVersions)
+
+			if err := DeletePackageVersionAndReferences(ctx, old); err != nil {
+				return err
+			}
+			if err := InsertAuditEntry(ctx, nil, packages_model.AuditActionDeleteVersion, p, old, fmt.Sprintf("pruned, limit of %d versions exceeded", ptl.MaxVersions)); err != nil {
+				return err
+			}
+			excess--
+		}
+		return committer.Commit()
+	}
+
+	// the versions are sorted newest first, the oldest versions within the limit are kept
+	exceeds := false
+	for i, other := range p.Versions {
+		if other.ID == pv.ID {
+			exceeds = int64(len(p.Versions)-i) > ptl.MaxVersions
+			break
+		}
+	}
+	if !exceeds {
+		return nil
+	}
+
+	log.Info("Removing version %s of package %s [id: %d]: limit of %d versions exceeded by a concurrent publish", pv.Version, p.Name, p.ID, ptl.MaxVersions)
+
+	if err := DeletePackageVersionAndReferences(ctx, pv); err != nil {
+		return err
+	}
+	if err := InsertAuditEntry(ctx, nil, packages_model.AuditActionDeleteVersion, p, pv, fmt.Sprintf("limit of %d versions exceeded", ptl.MaxVersions)); err != nil {
+		return err
+	}
+	if err := committer.Commit(); err != nil {
+		return err
+	}
+
+	return ErrTypeLimitExceeded{Type: p.Type, Limit: TypeLimitMaxVersions, Value: ptl.MaxVersions}
+}
@@ -13,6 +13,7 @@ import (
 	"code.gitea.io/gitea/models"
 	"code.gitea.io/gitea/models/db"
 	git_model "code.gitea.io/gitea/models/git"
+	packages_model "code.gitea.io/gitea/models/packages"
 	repo_model "code.gitea.io/gitea/models/repo"
 	user_model "code.gitea.io/gitea/models/user"
 	"code.gitea.io/gitea/modules/git"
@@ -328,6 +329,10 @@ func DeleteReleaseByID(ctx context.Context, id int64, doer *user_model.User, del
 		if err := repo_model.DeleteReleaseByID(id); err != nil {
 			return fmt.Errorf("DeleteReleaseByID: %v", err)
 		}
+
+		if err := packages_model.DeleteVersionReleasesByReleaseID(ctx, id); err != nil {
+			return fmt.Errorf("DeleteVersionReleasesByReleaseID: %v", err)
+		}
 	} else {
 		rel.IsTag = true
 
@@ -115,7 +115,7 @@ func DeleteUser(ctx context.Context, u *user_model.User, purge bool) error {
 			for _, org := range orgs {
 				if err := models.RemoveOrgUser(org.ID, u.ID); err != nil {
 					if organization.IsErrLastOrgOwner(err) {
-						err = organization.DeleteOrganization(ctx, org)
+						err = deleteLastOwnerOrganization(ctx, org)
 					}
 					if err != nil {
 						return fmt.Errorf("unable to remove user %s[%d] from org %s[%d]. Error: %v", u.Name, u.ID, org.Name, org.ID, err)
@@ -126,7 +126,7 @@ func DeleteUser(ctx context.Context, u *user_model.User, purge bool) error {
 
 		// Delete Packages
 		if setting.Packages.Enabled {
-			if _, err := packages.RemoveAllPackages(ctx, u.ID); err != nil {
+			if _, err := packages.DeleteOwnerPackages(ctx, nil, u.ID); err != nil {
 				return err
 			}
 		}
@@ -280,3 +280,14 @@ func DeleteAvatar(u *user_model.User) error {
 	}
 	return nil
 }
+
+// deleteLastOwnerOrganization deletes an organization of a purged user who is its last owner.
+// The packages of the organization are deleted first, they would be left without an owner otherwise.
+func deleteLastOwnerOrganization(ctx context.Context, org *organization.Organization) error {
+	if setting.Packages.Enabled {
+		if _, err := packages.DeleteOwnerPackages(ctx, nil, org.ID); err != nil {
+			return err
+		}
+	}
+	return organization.DeleteOrganization(ctx, org)
+}
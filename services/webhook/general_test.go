@@ -271,6 +271,40 @@ func repositoryTestPayload() *api.RepositoryPayload {
 	}
 }
 
+func packageTestPayload() *api.PackagePayload {
+	return &api.PackagePayload{
+		Action: api.HookPackageCreated,
+		Sender: &api.User{
+			UserName:  "user1",
+			AvatarURL: "http://localhost:3000/user1/avatar",
+		},
+		Repository: nil,
+		Organization: &api.User{
+			UserName:  "org3",
+			AvatarURL: "http://localhost:3000/org3/avatar",
+		},
+		Package: &api.Package{
+			Owner: &api.User{
+				UserName:  "org3",
+				AvatarURL: "http://localhost:3000/org3/avatar",
+			},
+			Type:    "generic",
+			Name:    "test-package",
+			Version: "1.0.0",
+		},
+		Files: []*api.PackageFile{
+			{
+				Size:       3,
+				Name:       "file.bin",
+				HashMD5:    "900150983cd24fb0d6963f7d28e17f72",
+				HashSHA1:   "a9993e364706816aba3e25717850c26c9cd0d89d",
+				HashSHA256: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+				HashSHA512: "ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f",
+			},
+		},
+	}
+}
+
 func TestGetIssuesPayloadInfo(t *testing.T) {
 	p := issueTestPayload()
 
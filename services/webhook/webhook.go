@@ -12,6 +12,7 @@ import (
 
 	"code.gitea.io/gitea/models/db"
 	repo_model "code.gitea.io/gitea/models/repo"
+	user_model "code.gitea.io/gitea/models/user"
 	webhook_model "code.gitea.io/gitea/models/webhook"
 	"code.gitea.io/gitea/modules/git"
 	"code.gitea.io/gitea/modules/graceful"
@@ -140,7 +141,7 @@ func addToTask(repoID int64) error {
 
 // PrepareWebhook adds special webhook to task queue for given payload.
 func PrepareWebhook(w *webhook_model.Webhook, repo *repo_model.Repository, event webhook_model.HookEventType, p api.Payloader) error {
-	if err := prepareWebhook(w, repo, event, p); err != nil {
+	if err := prepareWebhook(w, repo.ID, event, p); err != nil {
 		return err
 	}
 
@@ -162,7 +163,7 @@ func checkBranch(w *webhook_model.Webhook, branch string) bool {
 	return g.Match(branch)
 }
 
-func prepareWebhook(w *webhook_model.Webhook, repo *repo_model.Repository, event webhook_model.HookEventType, p api.Payloader) error {
+func prepareWebhook(w *webhook_model.Webhook, repoID int64, event webhook_model.HookEventType, p api.Payloader) error {
 	// Skip sending if webhooks are disabled.
 	if setting.DisableWebhooks {
 		return nil
@@ -208,7 +209,7 @@ func prepareWebhook(w *webhook_model.Webhook, repo *repo_model.Repository, event
 	}
 
 	if err = webhook_model.CreateHookTask(&webhook_model.HookTask{
-		RepoID:    repo.ID,
+		RepoID:    repoID,
 		HookID:    w.ID,
 		Payloader: payloader,
 		EventType: event,
@@ -218,29 +219,57 @@ func prepareWebhook(w *webhook_model.Webhook, repo *repo_model.Repository, event
 	return nil
 }
 
+// EventSource is the source of an event which triggers webhooks.
+// Events of a repository trigger the webhooks of the repository and of its owner.
+// Events without a repository (e.g. of packages not linked to a repository) trigger the webhooks of the owner only.
+type EventSource struct {
+	Repository *repo_model.Repository
+	Owner      *user_model.User
+}
+
 // PrepareWebhooks adds new webhooks to task queue for given payload.
 func PrepareWebhooks(repo *repo_model.Repository, event webhook_model.HookEventType, p api.Payloader) error {
-	if err := prepareWebhooks(db.DefaultContext, repo, event, p); err != nil {
+	return PrepareWebhooksForSource(EventSource{Repository: repo}, event, p)
+}
+
+// PrepareWebhooksForSource adds new webhooks of the event source to task queue for given payload.
+func PrepareWebhooksForSource(source EventSource, event webhook_model.HookEventType, p api.Payloader) error {
+	if err := prepareWebhooks(db.DefaultContext, source, event, p); err != nil {
 		return err
 	}
 
-	return addToTask(repo.ID)
+	var repoID int64
+	if source.Repository != nil {
+		repoID = source.Repository.ID
+	}
+	return addToTask(repoID)
 }
 
-func prepareWebhooks(ctx context.Context, repo *repo_model.Repository, event webhook_model.HookEventType, p api.Payloader) error {
-	ws, err := webhook_model.ListWebhooksByOpts(ctx, &webhook_model.ListWebhookOptions{
-		RepoID:   repo.ID,
-		IsActive: util.OptionalBoolTrue,
-	})
-	if err != nil {
-		return fmt.Errorf("GetActiveWebhooksByRepoID: %v", err)
+func prepareWebhooks(ctx context.Context, source EventSource, event webhook_model.HookEventType, p api.Payloader) error {
+	var ws []*webhook_model.Webhook
+	var repoID int64
+
+	owner := source.Owner
+	if source.Repository != nil {
+		repoID = source.Repository.ID
+
+		repoHooks, err := webhook_model.ListWebhooksByOpts(ctx, &webhook_model.ListWebhookOptions{
+			RepoID:   repoID,
+			IsActive: util.OptionalBoolTrue,
+		})
+		if err != nil {
+			return fmt.Errorf("GetActiveWebhooksByRepoID: %v", err)
+		}
+		ws = append(ws, repoHooks...)
+
+		owner = source.Repository.MustOwner()
 	}
 
-	// check if repo belongs to org and append additional webhooks
-	if repo.MustOwner().IsOrganization() {
+	// check if the owner is an org and append additional webhooks
+	if owner != nil && owner.IsOrganization() {
 		// get hooks for org
 		orgHooks, err := webhook_model.ListWebhooksByOpts(ctx, &webhook_model.ListWebhookOptions{
-			OrgID:    repo.OwnerID,
+			OrgID:    owner.ID,
 			IsActive: util.OptionalBoolTrue,
 		})
 		if err != nil {
@@ -261,7 +290,7 @@ func prepareWebhooks(ctx context.Context, repo *repo_model.Repository, event web
 	}
 
 	for _, w := range ws {
-		if err = prepareWebhook(w, repo, event, p); err != nil {
+		if err = prepareWebhook(w, repoID, event, p); err != nil {
 			return err
 		}
 	}
@@ -7,9 +7,12 @@ package webhook
 import (
 	"testing"
 
+	"code.gitea.io/gitea/models/db"
 	repo_model "code.gitea.io/gitea/models/repo"
 	"code.gitea.io/gitea/models/unittest"
+	user_model "code.gitea.io/gitea/models/user"
 	webhook_model "code.gitea.io/gitea/models/webhook"
+	"code.gitea.io/gitea/modules/json"
 	api "code.gitea.io/gitea/modules/structs"
 
 	"github.com/stretchr/testify/assert"
@@ -77,6 +80,62 @@ func TestPrepareWebhooksBranchFilterNoMatch(t *testing.T) {
 	}
 }
 
+func TestPrepareWebhooksForSource(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	org := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 3})
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 3, OwnerID: org.ID})
+
+	createHook := func(repoID, orgID int64) *webhook_model.Webhook {
+		w := &webhook_model.Webhook{
+			RepoID:      repoID,
+			OrgID:       orgID,
+			URL:         "http://www.example.com/package",
+			ContentType: webhook_model.ContentTypeJSON,
+			HookEvent: &webhook_model.HookEvent{
+				ChooseEvents: true,
+				HookEvents: webhook_model.HookEvents{
+					Package: true,
+				},
+			},
+			IsActive: true,
+			Type:     webhook_model.GITEA,
+		}
+		assert.NoError(t, w.UpdateEvent())
+		assert.NoError(t, webhook_model.CreateWebhook(db.DefaultContext, w))
+		return w
+	}
+
+	orgHook := createHook(0, org.ID)
+	repoHook := createHook(repo.ID, 0)
+
+	// a package without repository triggers the hooks of the owner only
+	assert.NoError(t, PrepareWebhooksForSource(EventSource{Owner: org}, webhook_model.HookEventPackage, packageTestPayload()))
+
+	task := unittest.AssertExistsAndLoadBean(t, &webhook_model.HookTask{RepoID: 0, HookID: orgHook.ID, EventType: webhook_model.HookEventPackage})
+	unittest.AssertNotExistsBean(t, &webhook_model.HookTask{HookID: repoHook.ID, EventType: webhook_model.HookEventPackage})
+	// the existing org hook is not subscribed to package events
+	unittest.AssertNotExistsBean(t, &webhook_model.HookTask{HookID: 3, EventType: webhook_model.HookEventPackage})
+
+	var payload api.PackagePayload
+	assert.NoError(t, json.Unmarshal([]byte(task.PayloadContent), &payload))
+	assert.Equal(t, api.HookPackageCreated, payload.Action)
+	assert.Nil(t, payload.Repository)
+	assert.Equal(t, "test-package", payload.Package.Name)
+	assert.Len(t, payload.Files, 1)
+	assert.Equal(t, "file.bin", payload.Files[0].Name)
+	assert.EqualValues(t, 3, payload.Files[0].Size)
+	assert.Equal(t, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad", payload.Files[0].HashSHA256)
+
+	// a package linked to a repository triggers the hooks of the repository and of the owner
+	p := packageTestPayload()
+	p.Action = api.HookPackageDeleted
+	assert.NoError(t, PrepareWebhooksForSource(EventSource{Repository: repo, Owner: org}, webhook_model.HookEventPackage, p))
+
+	unittest.AssertExistsAndLoadBean(t, &webhook_model.HookTask{RepoID: repo.ID, HookID: repoHook.ID, EventType: webhook_model.HookEventPackage})
+	unittest.AssertExistsAndLoadBean(t, &webhook_model.HookTask{RepoID: repo.ID, HookID: orgHook.ID, EventType: webhook_model.HookEventPackage})
+}
+
 // TODO TestHookTask_deliver
 
 // TODO TestDeliverHooks
@@ -181,7 +181,7 @@ func TestPackageContainer(t *testing.T) {
 				uuid := resp.Header().Get("Docker-Upload-Uuid")
 				assert.NotEmpty(t, uuid)
 
-				pbu, err := packages_model.GetBlobUploadByID(db.DefaultContext, uuid)
+				pbu, err := packages_model.GetUploadSessionByID(db.DefaultContext, uuid)
 				assert.NoError(t, err)
 				assert.EqualValues(t, 0, pbu.BytesReceived)
 
@@ -205,7 +205,7 @@ func TestPackageContainer(t *testing.T) {
 				assert.Equal(t, uuid, resp.Header().Get("Docker-Upload-Uuid"))
 				assert.Equal(t, contentRange, resp.Header().Get("Range"))
 
-				pbu, err = packages_model.GetBlobUploadByID(db.DefaultContext, uuid)
+				pbu, err = packages_model.GetUploadSessionByID(db.DefaultContext, uuid)
 				assert.NoError(t, err)
 				assert.EqualValues(t, len(blobContent), pbu.BytesReceived)
 
@@ -8,12 +8,14 @@ import (
 	"bytes"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"code.gitea.io/gitea/models/db"
 	"code.gitea.io/gitea/models/packages"
 	"code.gitea.io/gitea/models/unittest"
 	user_model "code.gitea.io/gitea/models/user"
+	packages_service "code.gitea.io/gitea/services/packages"
 	"code.gitea.io/gitea/tests"
 
 	"github.com/stretchr/testify/assert"
@@ -128,6 +130,31 @@ func TestPackageGeneric(t *testing.T) {
 		})
 	})
 
+	t.Run("Feed", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		feedURL := fmt.Sprintf("/%s/-/packages/generic/%s", user.Name, packageName)
+
+		for _, feedType := range []string{"rss", "atom"} {
+			req := NewRequest(t, "GET", feedURL+"."+feedType)
+			resp := MakeRequest(t, req, http.StatusOK)
+
+			assert.Equal(t, "application/"+feedType+"+xml;charset=utf-8", resp.Header().Get("Content-Type"))
+			assert.Contains(t, resp.Body.String(), packageVersion)
+			assert.NotEmpty(t, resp.Header().Get("Last-Modified"))
+
+			etag := resp.Header().Get("Etag")
+			assert.NotEmpty(t, etag)
+
+			req = NewRequest(t, "GET", feedURL+"."+feedType)
+			req.Header.Set("If-None-Match", etag)
+			MakeRequest(t, req, http.StatusNotModified)
+		}
+
+		req := NewRequest(t, "GET", fmt.Sprintf("/%s/-/packages/generic/not-found.rss", user.Name))
+		MakeRequest(t, req, http.StatusNotFound)
+	})
+
 	t.Run("Delete", func(t *testing.T) {
 		defer tests.PrintCurrentTest(t)()
 
@@ -192,3 +219,90 @@ func TestPackageGeneric(t *testing.T) {
 		})
 	})
 }
+
+func TestPackageGenericChunkedUpload(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+
+	content := []byte("chunked upload content")
+	url := fmt.Sprintf("/api/packages/%s/generic/chunked/1.0.0/file.bin", user.Name)
+
+	uploadChunk := func(start, end int, total string, expectedStatus int) *httptest.ResponseRecorder {
+		req := NewRequestWithBody(t, "PATCH", url, bytes.NewReader(content[start:end+1]))
+		req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%s", start, end, total))
+		AddBasicAuthHeader(req, user.Name)
+		return MakeRequest(t, req, expectedStatus)
+	}
+
+	t.Run("InvalidContentRange", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequestWithBody(t, "PATCH", url, bytes.NewReader(content))
+		AddBasicAuthHeader(req, user.Name)
+		MakeRequest(t, req, http.StatusBadRequest)
+
+		req = NewRequestWithBody(t, "PATCH", url, bytes.NewReader(content))
+		req.Header.Set("Content-Range", "bytes 5-2/10")
+		AddBasicAuthHeader(req, user.Name)
+		MakeRequest(t, req, http.StatusBadRequest)
+	})
+
+	t.Run("OutOfOrder", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		total := fmt.Sprint(len(content))
+
+		// no session exists for a chunk not starting at 0
+		uploadChunk(5, 9, total, http.StatusRequestedRangeNotSatisfiable)
+
+		resp := uploadChunk(0, 4, "*", http.StatusAccepted)
+		assert.Equal(t, "bytes=0-4", resp.Header().Get("Range"))
+
+		// a chunk after a gap is rejected and the received range is reported
+		resp = uploadChunk(10, 14, total, http.StatusRequestedRangeNotSatisfiable)
+		assert.Equal(t, "bytes=0-4", resp.Header().Get("Range"))
+
+		// a repeated chunk is rejected too
+		uploadChunk(3, 4, total, http.StatusRequestedRangeNotSatisfiable)
+
+		resp = uploadChunk(5, 9, total, http.StatusAccepted)
+		assert.Equal(t, "bytes=0-9", resp.Header().Get("Range"))
+
+		_, err := packages.GetVersionByNameAndVersion(db.DefaultContext, user.ID, packages.TypeGeneric, "chunked", "1.0.0")
+		assert.ErrorIs(t, err, packages.ErrPackageNotExist)
+
+		uploadChunk(10, len(content)-1, total, http.StatusCreated)
+
+		req := NewRequest(t, "GET", url)
+		resp = MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, content, resp.Body.Bytes())
+
+		_, err = packages.GetUploadSessionForFile(db.DefaultContext, user.ID, packages.TypeGeneric, "chunked", "1.0.0", "file.bin")
+		assert.ErrorIs(t, err, packages.ErrPackageUploadSessionNotExist)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		url = fmt.Sprintf("/api/packages/%s/generic/chunked/2.0.0/file.bin", user.Name)
+
+		uploadChunk(0, 4, "*", http.StatusAccepted)
+
+		pus, err := packages.GetUploadSessionForFile(db.DefaultContext, user.ID, packages.TypeGeneric, "chunked", "2.0.0", "file.bin")
+		assert.NoError(t, err)
+		assert.EqualValues(t, 5, pus.BytesReceived)
+
+		_, err = db.GetEngine(db.DefaultContext).ID(pus.ID).Cols("expires_unix").Update(&packages.PackageUploadSession{ExpiresUnix: 1})
+		assert.NoError(t, err)
+
+		uploadChunk(5, 9, "*", http.StatusRequestedRangeNotSatisfiable)
+
+		assert.NoError(t, packages_service.CleanupExpiredUploadSessions(db.DefaultContext))
+
+		_, err = packages.GetUploadSessionByID(db.DefaultContext, pus.ID)
+		assert.ErrorIs(t, err, packages.ErrPackageUploadSessionNotExist)
+
+		// the upload can be restarted
+		uploadChunk(0, len(content)-1, fmt.Sprint(len(content)), http.StatusCreated)
+	})
+}
@@ -66,6 +66,16 @@ func TestPackageMaven(t *testing.T) {
 		pb, err := packages.GetBlobByID(db.DefaultContext, pfs[0].BlobID)
 		assert.NoError(t, err)
 		assert.Equal(t, int64(4), pb.Size)
+
+		pps, err := packages.GetPropertiesByName(db.DefaultContext, packages.PropertyTypePackage, pd.Package.ID, maven.PropertyGroupID)
+		assert.NoError(t, err)
+		assert.Len(t, pps, 1)
+		assert.Equal(t, groupID, pps[0].Value)
+
+		ps, err := packages.SearchPackagesByNamespace(db.DefaultContext, user.ID, packages.TypeMaven, groupID+":")
+		assert.NoError(t, err)
+		assert.Len(t, ps, 1)
+		assert.Equal(t, packageName, ps[0].Name)
 	})
 
 	t.Run("UploadExists", func(t *testing.T) {
@@ -18,6 +18,7 @@ import (
 	user_model "code.gitea.io/gitea/models/user"
 	"code.gitea.io/gitea/modules/packages/npm"
 	"code.gitea.io/gitea/modules/setting"
+	packages_service "code.gitea.io/gitea/services/packages"
 	"code.gitea.io/gitea/tests"
 
 	"github.com/stretchr/testify/assert"
@@ -277,3 +278,81 @@ func TestPackageNpm(t *testing.T) {
 		})
 	})
 }
+
+func TestPackageNpmRepositoryLink(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+
+	token := fmt.Sprintf("Bearer %s", getTokenForLoggedInUser(t, loginUser(t, user.Name)))
+
+	data := "H4sIAAAAAAAA/ytITM5OTE/VL4DQelnF+XkMVAYGBgZmJiYK2MRBwNDcSIHB2NTMwNDQzMwAqA7IMDUxA9LUdgg2UFpcklgEdAql5kD8ogCnhwio5lJQUMpLzE1VslJQcihOzi9I1S9JLS7RhSYIJR2QgrLUouLM/DyQGkM9Az1D3YIiqExKanFyUWZBCVQ2BKhVwQVJDKwosbQkI78IJO/tZ+LsbRykxFXLNdA+HwWjYBSMgpENACgAbtAACAAA"
+
+	upload := func(t *testing.T, packageName, repositoryURL string) *packages.Package {
+		body := `{
+			"_id": "` + packageName + `",
+			"name": "` + packageName + `",
+			"dist-tags": {
+			  "latest": "1.0.0"
+			},
+			"versions": {
+			  "1.0.0": {
+				"name": "` + packageName + `",
+				"version": "1.0.0",
+				"repository": {
+				  "type": "git",
+				  "url": "` + repositoryURL + `"
+				},
+				"dist": {
+				  "integrity": "sha512-yA4FJsVhetynGfOC1jFf79BuS+jrHbm0fhh+aHzCQkOaOBXKf9oBnC4a6DnLLnEsHQDRLYd00cwj8sCXpC+wIg==",
+				  "shasum": "aaa7eaf852a948b0aa05afeda35b1badca155d90"
+				}
+			  }
+			},
+			"_attachments": {
+			  "` + packageName + `-1.0.0.tgz": {
+				"data": "` + data + `"
+			  }
+			}
+		  }`
+
+		req := NewRequestWithBody(t, "PUT", fmt.Sprintf("/api/packages/%s/npm/%s", user.Name, url.QueryEscape(packageName)), strings.NewReader(body))
+		req = addTokenAuthHeader(req, token)
+		MakeRequest(t, req, http.StatusCreated)
+
+		p, err := packages.GetPackageByName(db.DefaultContext, user.ID, packages.TypeNpm, packageName)
+		assert.NoError(t, err)
+		return p
+	}
+
+	t.Run("NotEnabled", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		p := upload(t, "link-disabled", setting.AppURL+"user2/repo1.git")
+		assert.EqualValues(t, 0, p.RepoID)
+	})
+
+	assert.NoError(t, packages_service.SetAutoLinkRepository(user.ID, true))
+
+	t.Run("NearMiss", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		for i, repositoryURL := range []string{
+			setting.AppURL + "user2/repo1/issues",
+			setting.AppURL + "user2",
+			setting.AppURL + "user2/repo-not-exists",
+			setting.AppURL + "user3/repo3",
+			"https://example.com/user2/repo1",
+			strings.Replace(setting.AppURL, "://", "://user@", 1) + "user2/repo1",
+		} {
+			p := upload(t, fmt.Sprintf("link-near-miss-%d", i), repositoryURL)
+			assert.EqualValues(t, 0, p.RepoID, repositoryURL)
+		}
+	})
+
+	t.Run("Linked", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		p := upload(t, "link-enabled", "git+"+setting.AppURL+"User2/Repo1.git")
+		assert.EqualValues(t, 1, p.RepoID)
+	})
+}
@@ -12,10 +12,14 @@ import (
 	"time"
 
 	"code.gitea.io/gitea/models/db"
+	"code.gitea.io/gitea/models/organization"
 	packages_model "code.gitea.io/gitea/models/packages"
 	container_model "code.gitea.io/gitea/models/packages/container"
+	"code.gitea.io/gitea/models/perm"
 	"code.gitea.io/gitea/models/unittest"
 	user_model "code.gitea.io/gitea/models/user"
+	packages_module "code.gitea.io/gitea/modules/packages"
+	"code.gitea.io/gitea/modules/setting"
 	api "code.gitea.io/gitea/modules/structs"
 	packages_service "code.gitea.io/gitea/services/packages"
 	"code.gitea.io/gitea/tests"
@@ -53,6 +57,65 @@ func TestPackageAPI(t *testing.T) {
 		assert.Equal(t, packageVersion, apiPackages[0].Version)
 		assert.NotNil(t, apiPackages[0].Creator)
 		assert.Equal(t, user.Name, apiPackages[0].Creator.UserName)
+		assert.NotNil(t, apiPackages[0].PackageCreator)
+		assert.Equal(t, user.Name, apiPackages[0].PackageCreator.UserName)
+	})
+
+	t.Run("ListPackageTypeCounts", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", fmt.Sprintf("/api/v1/packages/%s/-/types?token=%s", user.Name, token))
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		var apiCounts []*api.PackageTypeCount
+		DecodeJSON(t, resp, &apiCounts)
+
+		assert.Equal(t, []*api.PackageTypeCount{{Type: string(packages_model.TypeGeneric), Count: 1}}, apiCounts)
+	})
+
+	t.Run("GetOwnerPackageStats", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", fmt.Sprintf("/api/v1/packages/%s/-/stats?token=%s", user.Name, token))
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		var apiStats *api.OwnerPackageStats
+		DecodeJSON(t, resp, &apiStats)
+
+		assert.EqualValues(t, 1, apiStats.PackageCount)
+		assert.EqualValues(t, 1, apiStats.VersionCount)
+		assert.EqualValues(t, 1, apiStats.FileCount)
+		assert.EqualValues(t, -1, apiStats.SizeLimit)
+		assert.Len(t, apiStats.Types, 1)
+		assert.Equal(t, string(packages_model.TypeGeneric), apiStats.Types[0].Type)
+
+		// only the owner can see the statistics
+		otherToken := getUserToken(t, "user2")
+		req = NewRequest(t, "GET", fmt.Sprintf("/api/v1/packages/%s/-/stats?token=%s", user.Name, otherToken))
+		MakeRequest(t, req, http.StatusForbidden)
+	})
+
+	t.Run("AdminInventory", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", fmt.Sprintf("/api/v1/admin/packages/inventory?owner=%s&token=%s", user.Name, token))
+		MakeRequest(t, req, http.StatusForbidden)
+
+		adminToken := getUserToken(t, "user1")
+
+		req = NewRequest(t, "GET", fmt.Sprintf("/api/v1/admin/packages/inventory?owner=%s&type=generic&sort=size&token=%s", user.Name, adminToken))
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		var apiEntries []*api.PackageInventoryEntry
+		DecodeJSON(t, resp, &apiEntries)
+
+		assert.Len(t, apiEntries, 1)
+		assert.Equal(t, user.ID, apiEntries[0].OwnerID)
+		assert.Equal(t, packageName, apiEntries[0].Name)
+		assert.EqualValues(t, 0, apiEntries[0].TotalSize)
+
+		req = NewRequest(t, "GET", fmt.Sprintf("/api/v1/admin/packages/inventory?owner=not-existing&token=%s", adminToken))
+		MakeRequest(t, req, http.StatusUnprocessableEntity)
 	})
 
 	t.Run("GetPackage", func(t *testing.T) {
@@ -72,6 +135,8 @@ func TestPackageAPI(t *testing.T) {
 		assert.Equal(t, packageVersion, p.Version)
 		assert.NotNil(t, p.Creator)
 		assert.Equal(t, user.Name, p.Creator.UserName)
+		assert.NotNil(t, p.PackageCreator)
+		assert.Equal(t, user.Name, p.PackageCreator.UserName)
 
 		t.Run("RepositoryLink", func(t *testing.T) {
 			defer tests.PrintCurrentTest(t)()
@@ -144,6 +209,69 @@ func TestPackageAPI(t *testing.T) {
 	})
 }
 
+func TestPackageBulkDelete(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 4})
+	session := loginUser(t, user.Name)
+	token := getTokenForLoggedInUser(t, session)
+
+	packageName := "bulk-delete-package"
+
+	for i, version := range []string{"1.0.0", "1.1.0-nightly.1", "1.1.0-nightly.2", "1.1.0-nightly.3"} {
+		req := NewRequestWithBody(t, "PUT", fmt.Sprintf("/api/packages/%s/generic/%s/%s/file.bin", user.Name, packageName, version), bytes.NewReader([]byte{byte(i)}))
+		AddBasicAuthHeader(req, user.Name)
+		MakeRequest(t, req, http.StatusCreated)
+	}
+
+	url := fmt.Sprintf("/api/v1/packages/%s/generic/%s/-/bulk-delete?token=%s", user.Name, packageName, token)
+
+	req := NewRequestWithJSON(t, "POST", url, &api.BulkDeletePackageVersionsOption{
+		VersionPattern: "(",
+		DryRun:         true,
+	})
+	MakeRequest(t, req, http.StatusUnprocessableEntity)
+
+	// an empty filter would delete all versions
+	req = NewRequestWithJSON(t, "POST", url, &api.BulkDeletePackageVersionsOption{})
+	MakeRequest(t, req, http.StatusUnprocessableEntity)
+
+	req = NewRequestWithJSON(t, "POST", url, &api.BulkDeletePackageVersionsOption{
+		VersionPattern: `.*-nightly\..*`,
+		KeepLatest:     1,
+		DryRun:         true,
+	})
+	resp := MakeRequest(t, req, http.StatusOK)
+
+	var result *api.PackageBulkDeleteResult
+	DecodeJSON(t, resp, &result)
+	assert.True(t, result.DryRun)
+	assert.ElementsMatch(t, []string{"1.1.0-nightly.1", "1.1.0-nightly.2"}, result.Versions)
+	assert.EqualValues(t, 2, result.FileCount)
+	assert.EqualValues(t, 2, result.FreedSize)
+
+	pvs, err := packages_model.GetVersionsByPackageName(db.DefaultContext, user.ID, packages_model.TypeGeneric, packageName)
+	assert.NoError(t, err)
+	assert.Len(t, pvs, 4)
+
+	req = NewRequestWithJSON(t, "POST", url, &api.BulkDeletePackageVersionsOption{
+		VersionPattern: `.*-nightly\..*`,
+		KeepLatest:     1,
+	})
+	resp = MakeRequest(t, req, http.StatusOK)
+
+	DecodeJSON(t, resp, &result)
+	assert.False(t, result.DryRun)
+	assert.Len(t, result.Versions, 2)
+
+	pvs, err = packages_model.GetVersionsByPackageName(db.DefaultContext, user.ID, packages_model.TypeGeneric, packageName)
+	assert.NoError(t, err)
+	versions := make([]string, 0, len(pvs))
+	for _, pv := range pvs {
+		versions = append(versions, pv.Version)
+	}
+	assert.ElementsMatch(t, []string{"1.0.0", "1.1.0-nightly.3"}, versions)
+}
+
 func TestPackageCleanup(t *testing.T) {
 	defer tests.PrepareTestEnv(t)()
 
@@ -156,7 +284,7 @@ func TestPackageCleanup(t *testing.T) {
 	_, err = packages_model.GetInternalVersionByNameAndVersion(db.DefaultContext, 2, packages_model.TypeContainer, "test", container_model.UploadVersion)
 	assert.NoError(t, err)
 
-	err = packages_service.Cleanup(nil, time.Duration(0))
+	err = packages_service.Cleanup(db.DefaultContext, time.Duration(0))
 	assert.NoError(t, err)
 
 	pbs, err = packages_model.FindExpiredUnreferencedBlobs(db.DefaultContext, time.Duration(0))
@@ -166,3 +294,466 @@ func TestPackageCleanup(t *testing.T) {
 	_, err = packages_model.GetInternalVersionByNameAndVersion(db.DefaultContext, 2, packages_model.TypeContainer, "test", container_model.UploadVersion)
 	assert.ErrorIs(t, err, packages_model.ErrPackageNotExist)
 }
+
+func TestPackageImmutableVersions(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 4})
+	admin := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 1})
+
+	packageName := "immutable-package"
+	packageVersion := "1.0.0"
+	url := fmt.Sprintf("/api/packages/%s/generic/%s/%s", user.Name, packageName, packageVersion)
+
+	req := NewRequestWithBody(t, "PUT", url+"/file.bin", bytes.NewReader([]byte{1}))
+	AddBasicAuthHeader(req, user.Name)
+	MakeRequest(t, req, http.StatusCreated)
+
+	p, err := packages_model.GetPackageByName(db.DefaultContext, user.ID, packages_model.TypeGeneric, packageName)
+	assert.NoError(t, err)
+	assert.NoError(t, packages_service.SetPackageImmutable(user, p, true))
+
+	addFile := func(t *testing.T, pi packages_service.PackageInfo, filename string, content []byte) error {
+		buf, err := packages_module.CreateHashedBufferFromReader(bytes.NewReader(content), 1024)
+		assert.NoError(t, err)
+		defer buf.Close()
+
+		_, _, err = packages_service.CreatePackageOrAddFileToExisting(
+			&packages_service.PackageCreationInfo{
+				PackageInfo: pi,
+				Creator:     user,
+			},
+			&packages_service.PackageFileCreationInfo{
+				PackageFileInfo: packages_service.PackageFileInfo{
+					Filename: filename,
+				},
+				Data:              buf,
+				OverwriteExisting: true,
+			},
+		)
+		return err
+	}
+
+	t.Run("Overwrite", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		pi := packages_service.PackageInfo{
+			Owner:       user,
+			PackageType: packages_model.TypeGeneric,
+			Name:        packageName,
+			Version:     packageVersion,
+		}
+
+		// re-uploading the same content doesn't modify the version
+		assert.NoError(t, addFile(t, pi, "file.bin", []byte{1}))
+		assert.ErrorIs(t, addFile(t, pi, "file.bin", []byte{2}), packages_service.ErrVersionImmutable)
+		assert.NoError(t, addFile(t, pi, "other.bin", []byte{2}))
+
+		req := NewRequestWithBody(t, "PUT", url+"/file.bin", bytes.NewReader([]byte{2}))
+		AddBasicAuthHeader(req, user.Name)
+		MakeRequest(t, req, http.StatusConflict)
+	})
+
+	t.Run("MavenSnapshot", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		pi := packages_service.PackageInfo{
+			Owner:       user,
+			PackageType: packages_model.TypeMaven,
+			Name:        "com.gitea-immutable",
+			Version:     "1.0-SNAPSHOT",
+		}
+
+		assert.NoError(t, addFile(t, pi, "maven-metadata.xml", []byte{1}))
+
+		mp, err := packages_model.GetPackageByName(db.DefaultContext, user.ID, packages_model.TypeMaven, pi.Name)
+		assert.NoError(t, err)
+		assert.NoError(t, packages_service.SetPackageImmutable(user, mp, true))
+
+		assert.NoError(t, addFile(t, pi, "maven-metadata.xml", []byte{2}))
+
+		defer func(immutable bool) {
+			setting.Packages.ImmutableMavenSnapshots = immutable
+		}(setting.Packages.ImmutableMavenSnapshots)
+		setting.Packages.ImmutableMavenSnapshots = true
+
+		assert.ErrorIs(t, addFile(t, pi, "maven-metadata.xml", []byte{3}), packages_service.ErrVersionImmutable)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "DELETE", url+"/file.bin")
+		AddBasicAuthHeader(req, user.Name)
+		MakeRequest(t, req, http.StatusConflict)
+
+		req = NewRequest(t, "DELETE", url)
+		AddBasicAuthHeader(req, user.Name)
+		MakeRequest(t, req, http.StatusConflict)
+
+		req = NewRequest(t, "DELETE", url)
+		AddBasicAuthHeader(req, admin.Name)
+		MakeRequest(t, req, http.StatusNoContent)
+	})
+
+	t.Run("Cleanup", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		// the package has no versions anymore but is kept because it is immutable
+		assert.NoError(t, packages_service.Cleanup(db.DefaultContext, time.Duration(0)))
+
+		_, err := packages_model.GetPackageByName(db.DefaultContext, user.ID, packages_model.TypeGeneric, packageName)
+		assert.NoError(t, err)
+
+		_, err = packages_service.CleanupWithResult(db.DefaultContext, time.Duration(0), true)
+		assert.NoError(t, err)
+
+		_, err = packages_model.GetPackageByName(db.DefaultContext, user.ID, packages_model.TypeGeneric, packageName)
+		assert.ErrorIs(t, err, packages_model.ErrPackageNotExist)
+	})
+
+	t.Run("InternalVersion", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		cp, err := packages_model.GetPackageByName(db.DefaultContext, 2, packages_model.TypeContainer, "test")
+		assert.NoError(t, err)
+		assert.NoError(t, packages_service.SetPackageImmutable(user, cp, true))
+		cp.IsImmutable = true
+
+		pv, err := packages_model.GetInternalVersionByNameAndVersion(db.DefaultContext, 2, packages_model.TypeContainer, "test", container_model.UploadVersion)
+		assert.NoError(t, err)
+		assert.False(t, packages_service.IsVersionImmutable(cp, pv))
+
+		// internal versions of immutable packages are still removed by the cleanup
+		time.Sleep(time.Second)
+		assert.NoError(t, packages_service.Cleanup(db.DefaultContext, time.Duration(0)))
+
+		_, err = packages_model.GetInternalVersionByNameAndVersion(db.DefaultContext, 2, packages_model.TypeContainer, "test", container_model.UploadVersion)
+		assert.ErrorIs(t, err, packages_model.ErrPackageNotExist)
+	})
+}
+
+func TestPackageVersionLimit(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 4})
+
+	defer func(limits map[string]setting.PackageTypeLimits) {
+		setting.Packages.TypeLimits = limits
+	}(setting.Packages.TypeLimits)
+
+	setLimits := func(prune bool) {
+		setting.Packages.TypeLimits = map[string]setting.PackageTypeLimits{
+			string(packages_model.TypeGeneric): {MaxVersions: 2, MaxVersionSize: -1, MaxOwnerSize: -1, PruneVersions: prune},
+		}
+	}
+
+	upload := func(t *testing.T, packageName, packageVersion string, expectedStatus int) {
+		req := NewRequestWithBody(t, "PUT", fmt.Sprintf("/api/packages/%s/generic/%s/%s/file.bin", user.Name, packageName, packageVersion), bytes.NewReader([]byte(packageVersion)))
+		AddBasicAuthHeader(req, user.Name)
+		MakeRequest(t, req, expectedStatus)
+	}
+
+	versionsOf := func(t *testing.T, packageName string) []string {
+		p, err := packages_model.GetPackageByName(db.DefaultContext, user.ID, packages_model.TypeGeneric, packageName)
+		assert.NoError(t, err)
+		p, err = packages_model.GetPackageByID(db.DefaultContext, p.ID, true)
+		assert.NoError(t, err)
+		versions := make([]string, 0, len(p.Versions))
+		for _, pv := range p.Versions {
+			versions = append(versions, pv.Version)
+		}
+		return versions
+	}
+
+	// insertVersion adds a version like a concurrent publish which passed the limit check before the other version was committed
+	insertVersion := func(t *testing.T, packageName, packageVersion string) *packages_model.PackageVersion {
+		p, err := packages_model.GetPackageByName(db.DefaultContext, user.ID, packages_model.TypeGeneric, packageName)
+		assert.NoError(t, err)
+		pv, err := packages_model.GetOrInsertVersion(db.DefaultContext, &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			CreatorID:    user.ID,
+			Version:      packageVersion,
+			LowerVersion: packageVersion,
+			MetadataJSON: "null",
+		})
+		assert.NoError(t, err)
+		return pv
+	}
+
+	t.Run("Reject", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		setLimits(false)
+
+		upload(t, "version-limit-reject", "1.0.0", http.StatusCreated)
+		upload(t, "version-limit-reject", "2.0.0", http.StatusCreated)
+		upload(t, "version-limit-reject", "3.0.0", http.StatusRequestEntityTooLarge)
+
+		assert.Equal(t, []string{"2.0.0", "1.0.0"}, versionsOf(t, "version-limit-reject"))
+	})
+
+	t.Run("RejectConcurrent", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		setLimits(false)
+
+		upload(t, "version-limit-race", "1.0.0", http.StatusCreated)
+
+		// both publishes counted one existing version and were committed
+		first := insertVersion(t, "version-limit-race", "2.0.0")
+		second := insertVersion(t, "version-limit-race", "3.0.0")
+
+		err := packages_service.EnforceVersionLimit(second)
+		assert.True(t, packages_service.IsErrTypeLimitExceeded(err))
+		assert.NoError(t, packages_service.EnforceVersionLimit(first))
+
+		assert.Equal(t, []string{"2.0.0", "1.0.0"}, versionsOf(t, "version-limit-race"))
+	})
+
+	t.Run("Prune", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		setLimits(true)
+
+		upload(t, "version-limit-prune", "1.0.0", http.StatusCreated)
+		upload(t, "version-limit-prune", "2.0.0", http.StatusCreated)
+		upload(t, "version-limit-prune", "3.0.0", http.StatusCreated)
+
+		assert.Equal(t, []string{"3.0.0", "2.0.0"}, versionsOf(t, "version-limit-prune"))
+
+		p, err := packages_model.GetPackageByName(db.DefaultContext, user.ID, packages_model.TypeGeneric, "version-limit-prune")
+		assert.NoError(t, err)
+
+		pas, _, err := packages_model.SearchAudits(db.DefaultContext, &packages_model.AuditSearchOptions{
+			PackageID: p.ID,
+			Action:    packages_model.AuditActionDeleteVersion,
+		})
+		assert.NoError(t, err)
+		assert.Len(t, pas, 1)
+		assert.Equal(t, "1.0.0", pas[0].Version)
+		assert.EqualValues(t, 0, pas[0].ActorID)
+	})
+
+	t.Run("PruneConcurrent", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		setLimits(true)
+
+		first := insertVersion(t, "version-limit-prune", "4.0.0")
+		second := insertVersion(t, "version-limit-prune", "5.0.0")
+
+		assert.NoError(t, packages_service.EnforceVersionLimit(second))
+		assert.NoError(t, packages_service.EnforceVersionLimit(first))
+
+		assert.Equal(t, []string{"5.0.0", "4.0.0"}, versionsOf(t, "version-limit-prune"))
+	})
+
+	t.Run("PruneImmutable", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		setLimits(true)
+
+		p, err := packages_model.GetPackageByName(db.DefaultContext, user.ID, packages_model.TypeGeneric, "version-limit-prune")
+		assert.NoError(t, err)
+		assert.NoError(t, packages_service.SetPackageImmutable(user, p, true))
+
+		// immutable versions are never pruned, so the new version is rejected
+		upload(t, "version-limit-prune", "6.0.0", http.StatusRequestEntityTooLarge)
+
+		assert.Equal(t, []string{"5.0.0", "4.0.0"}, versionsOf(t, "version-limit-prune"))
+	})
+}
+
+func TestPackageTransferQuota(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+	org := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 3})
+	token := getUserToken(t, user.Name)
+
+	content := []byte("transfer-quota")
+
+	upload := func(t *testing.T, owner *user_model.User, packageName string) {
+		req := NewRequestWithBody(t, "PUT", fmt.Sprintf("/api/packages/%s/generic/%s/1.0.0/file.bin", owner.Name, packageName), bytes.NewReader(content))
+		AddBasicAuthHeader(req, user.Name)
+		MakeRequest(t, req, http.StatusCreated)
+	}
+
+	transfer := func(t *testing.T, expectedStatus int) {
+		req := NewRequestWithJSON(t, "POST", fmt.Sprintf("/api/v1/packages/%s/generic/transfer-quota/-/transfer?token=%s", user.Name, token), &api.TransferPackageOption{
+			NewOwner: org.Name,
+		})
+		MakeRequest(t, req, expectedStatus)
+	}
+
+	upload(t, user, "transfer-quota")
+
+	// the package does not fit into the quota of the new owner
+	assert.NoError(t, packages_model.SetQuotaSizeLimit(db.DefaultContext, org.ID, int64(len(content))-1))
+
+	transfer(t, http.StatusRequestEntityTooLarge)
+
+	p, err := packages_model.GetPackageByName(db.DefaultContext, user.ID, packages_model.TypeGeneric, "transfer-quota")
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, p.OwnerID)
+
+	// blobs the new owner references already don't use additional storage
+	assert.NoError(t, packages_model.SetQuotaSizeLimit(db.DefaultContext, org.ID, int64(len(content))))
+
+	upload(t, org, "transfer-quota-existing")
+
+	transfer(t, http.StatusNoContent)
+
+	p, err = packages_model.GetPackageByID(db.DefaultContext, p.ID, false)
+	assert.NoError(t, err)
+	assert.Equal(t, org.ID, p.OwnerID)
+
+	pq, err := packages_model.GetQuotaByOwnerID(db.DefaultContext, org.ID)
+	assert.NoError(t, err)
+	assert.EqualValues(t, len(content), pq.UsedSize)
+}
+
+func TestPackageVisibility(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 4})
+	other := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 5})
+	token := getTokenForLoggedInUser(t, loginUser(t, user.Name))
+
+	packageName := "visibility-package"
+	packageURL := fmt.Sprintf("/api/packages/%s/generic/%s/1.0.0/file.bin", user.Name, packageName)
+
+	req := NewRequestWithBody(t, "PUT", packageURL, bytes.NewReader([]byte{1}))
+	AddBasicAuthHeader(req, user.Name)
+	MakeRequest(t, req, http.StatusCreated)
+
+	setVisibility := func(t *testing.T, visibility string, expectedStatus int) {
+		req := NewRequestWithJSON(t, "POST", fmt.Sprintf("/api/v1/packages/%s/generic/%s/-/visibility?token=%s", user.Name, packageName, token), &api.SetPackageVisibilityOption{
+			Visibility: visibility,
+		})
+		MakeRequest(t, req, expectedStatus)
+	}
+
+	listPackages := func(t *testing.T, doer *user_model.User) []*api.Package {
+		req := NewRequest(t, "GET", fmt.Sprintf("/api/v1/packages/%s", user.Name))
+		if doer != nil {
+			AddBasicAuthHeader(req, doer.Name)
+		}
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		var apiPackages []*api.Package
+		DecodeJSON(t, resp, &apiPackages)
+		return apiPackages
+	}
+
+	download := func(t *testing.T, doer *user_model.User, expectedStatus int) {
+		req := NewRequest(t, "GET", packageURL)
+		if doer != nil {
+			AddBasicAuthHeader(req, doer.Name)
+		}
+		MakeRequest(t, req, expectedStatus)
+	}
+
+	readFeeds := func(t *testing.T, doer *user_model.User, expectedStatus int) {
+		for _, ext := range []string{".rss", ".atom"} {
+			req := NewRequest(t, "GET", fmt.Sprintf("/%s/-/packages/generic/%s%s", user.Name, packageName, ext))
+			if doer != nil {
+				loginUser(t, doer.Name).MakeRequest(t, req, expectedStatus)
+			} else {
+				MakeRequest(t, req, expectedStatus)
+			}
+		}
+	}
+
+	t.Run("Inherit", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		readFeeds(t, nil, http.StatusOK)
+		readFeeds(t, other, http.StatusOK)
+
+		apiPackages := listPackages(t, nil)
+		assert.Len(t, apiPackages, 1)
+		assert.Equal(t, "inherit", apiPackages[0].Visibility)
+		assert.False(t, apiPackages[0].Private)
+
+		download(t, nil, http.StatusOK)
+		download(t, other, http.StatusOK)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		setVisibility(t, "public", http.StatusUnprocessableEntity)
+	})
+
+	t.Run("Private", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		setVisibility(t, "private", http.StatusNoContent)
+
+		assert.Empty(t, listPackages(t, nil))
+		assert.Empty(t, listPackages(t, other))
+		download(t, nil, http.StatusNotFound)
+		download(t, other, http.StatusNotFound)
+		readFeeds(t, nil, http.StatusNotFound)
+		readFeeds(t, other, http.StatusNotFound)
+		readFeeds(t, user, http.StatusOK)
+
+		apiPackages := listPackages(t, user)
+		assert.Len(t, apiPackages, 1)
+		assert.Equal(t, "private", apiPackages[0].Visibility)
+		assert.True(t, apiPackages[0].Private)
+		download(t, user, http.StatusOK)
+
+		setVisibility(t, "inherit", http.StatusNoContent)
+		download(t, nil, http.StatusOK)
+	})
+}
+
+func TestPackageTeamAccess(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+	org := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 3})
+	owner := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+	member := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 4})
+	team := unittest.AssertExistsAndLoadBean(t, &organization.Team{ID: 2})
+	memberToken := getTokenForLoggedInUser(t, loginUser(t, member.Name))
+
+	upload := func(t *testing.T, doer *user_model.User, version string, expectedStatus int) {
+		req := NewRequestWithBody(t, "PUT", fmt.Sprintf("/api/packages/%s/generic/team-access/%s/file.bin", org.Name, version), bytes.NewReader([]byte(version)))
+		AddBasicAuthHeader(req, doer.Name)
+		MakeRequest(t, req, expectedStatus)
+	}
+
+	t.Run("Default", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		upload(t, member, "1.0.0", http.StatusCreated)
+	})
+
+	t.Run("ReadOnly", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		assert.NoError(t, organization.SetTeamPackageType(team, string(packages_model.TypeGeneric), perm.AccessModeRead))
+
+		upload(t, member, "1.0.1", http.StatusUnauthorized)
+		upload(t, owner, "1.0.1", http.StatusCreated)
+
+		req := NewRequest(t, "DELETE", fmt.Sprintf("/api/packages/%s/generic/team-access/1.0.0", org.Name))
+		AddBasicAuthHeader(req, member.Name)
+		MakeRequest(t, req, http.StatusUnauthorized)
+
+		// tokens resolve through the same check
+		req = NewRequest(t, "DELETE", fmt.Sprintf("/api/v1/packages/%s/generic/team-access/1.0.0?token=%s", org.Name, memberToken))
+		MakeRequest(t, req, http.StatusForbidden)
+
+		// reading is still possible
+		req = NewRequest(t, "GET", fmt.Sprintf("/api/packages/%s/generic/team-access/1.0.0/file.bin", org.Name))
+		AddBasicAuthHeader(req, member.Name)
+		MakeRequest(t, req, http.StatusOK)
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		assert.NoError(t, organization.RemoveTeamPackageType(db.DefaultContext, team.ID, string(packages_model.TypeGeneric)))
+
+		upload(t, member, "1.0.2", http.StatusCreated)
+	})
+}